@@ -0,0 +1,89 @@
+package resp
+
+// DEFAULT_ARENA_CHUNK is the size of the chunks of memory an Arena allocates
+// by default.
+const DEFAULT_ARENA_CHUNK = 64 << 10
+
+// An Arena allocates the bytes of the objects read by Readers it's set on
+// (see Reader.SetArena) and the slices returned by its Objects method from
+// large chunks of memory, so that reading many replies, or walking a large
+// aggregate reply, takes fewer allocations: only storing each object in an
+// Object interface still takes one. The objects are freed all at once by
+// Reset. An Arena must not be used by multiple goroutines at once. The zero
+// value is ready to use.
+type Arena struct {
+	// ChunkSize is the size of the chunks of memory the Arena allocates,
+	// DEFAULT_ARENA_CHUNK if it's 0. Objects larger than a quarter of a
+	// chunk are allocated on their own.
+	ChunkSize int
+
+	bytes   []byte
+	objects []Object
+	// used is the number of objects in use, from the start of objects.
+	used int
+}
+
+// Objects returns the objects contained in the RESP array or RESP3 aggregate
+// obj, like Array.Objects, in a slice allocated from the Arena. The objects
+// point into obj.
+func (a *Arena) Objects(obj Object) ([]Object, error) {
+	switch obj.(type) {
+	case Array, Map, Set, Push, Attribute:
+		return aggregateObjectsIn(a, obj.Raw())
+	default:
+		return nil, ErrSyntaxError
+	}
+}
+
+// Reset frees everything the Arena allocated, to be reused. Objects read
+// into the Arena, and slices returned by Objects, must not be used
+// afterwards.
+func (a *Arena) Reset() {
+	a.bytes = a.bytes[:0]
+	// Drop the references to the objects, and so to old chunks
+	for i := range a.objects[:a.used] {
+		a.objects[i] = nil
+	}
+	a.used = 0
+}
+
+func (a *Arena) chunkSize() int {
+	if a.ChunkSize > 0 {
+		return a.ChunkSize
+	}
+	return DEFAULT_ARENA_CHUNK
+}
+
+// alloc returns a slice of n bytes allocated from a, or on its own if a is
+// nil.
+func (a *Arena) alloc(n int) []byte {
+	if a == nil || n > a.chunkSize()/4 {
+		return make([]byte, n)
+	}
+	if len(a.bytes)+n > cap(a.bytes) {
+		a.bytes = make([]byte, 0, a.chunkSize())
+	}
+	start := len(a.bytes)
+	a.bytes = a.bytes[:start+n]
+	return a.bytes[start : start+n : start+n]
+}
+
+// objectSlice returns a slice of n objects allocated from a, or on its own
+// if a is nil.
+func (a *Arena) objectSlice(n int) []Object {
+	if a == nil {
+		return make([]Object, n)
+	}
+	// Chunks hold a sixteenth as many objects as bytes
+	size := a.chunkSize() / 16
+	if n > size/4 {
+		return make([]Object, n)
+	}
+	if a.used+n > len(a.objects) {
+		a.objects = make([]Object, size)
+		a.used = 0
+	}
+	start := a.used
+	a.used += n
+	return a.objects[start:a.used:a.used]
+}
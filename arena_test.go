@@ -0,0 +1,99 @@
+package resp
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArena(t *testing.T) {
+	a := &Arena{ChunkSize: 256}
+	reply := "*3\r\n$3\r\nfoo\r\n*2\r\n:1\r\n%1\r\n+a\r\n+b\r\n$-1\r\n"
+	large := "$100\r\n" + strings.Repeat("x", 100) + "\r\n"
+	r := NewReader(strings.NewReader(reply + "+OK\r\n" + large))
+	r.SetArena(a)
+
+	obj, err := r.ReadObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := a.Objects(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := a.Objects(objects[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs, err := a.Objects(nested[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Object{String("+a\r\n"), String("+b\r\n")}; !reflect.DeepEqual(expected, pairs) {
+		t.Errorf("expected %q, got %q", expected, pairs)
+	}
+	if _, err := a.Objects(objects[0]); err != ErrSyntaxError {
+		t.Errorf("expected ErrSyntaxError, got %v", err)
+	}
+	if a.used != 3+2+2 {
+		t.Errorf("expected 7 objects in use, got %d", a.used)
+	}
+
+	// Objects are allocated from the same chunk, except for those larger
+	// than a quarter of a chunk.
+	ok, _ := r.ReadObject()
+	if &ok.Raw()[0] != &a.bytes[len(reply)] {
+		t.Errorf("expected %q to be allocated from the arena", ok)
+	}
+	if obj, _ := r.ReadObject(); string(obj.Raw()) != large || len(a.bytes) != len(reply)+len(OK) {
+		t.Errorf("expected %q to be allocated on its own, got %q", large, obj)
+	}
+
+	// Reset reuses the chunks
+	a.Reset()
+	r = NewReader(strings.NewReader("+OK\r\n"))
+	r.SetArena(a)
+	obj, _ = r.ReadObject()
+	if !bytes.Equal(OK, obj.Raw()) || &obj.Raw()[0] != &a.bytes[0] {
+		t.Errorf("expected %q to be allocated at the start of the arena", obj)
+	}
+	if a.used != 0 {
+		t.Errorf("expected no objects in use, got %d", a.used)
+	}
+}
+
+func BenchmarkArena(b *testing.B) {
+	var reply []byte
+	for i := 0; i < 100; i++ {
+		reply = append(reply, NewArray(NewBulkString("key"), NewInteger(int64(i)))...)
+	}
+	b.Run("none", func(b *testing.B) { benchmarkArena(b, reply, nil) })
+	b.Run("arena", func(b *testing.B) { benchmarkArena(b, reply, &Arena{}) })
+}
+
+func benchmarkArena(b *testing.B, reply []byte, a *Arena) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r := NewReader(bytes.NewReader(reply))
+		r.SetArena(a)
+		for j := 0; j < 100; j++ {
+			obj, err := r.ReadObject()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if a != nil {
+				_, err = a.Objects(obj)
+			} else {
+				_, err = obj.(Array).Objects()
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		r.Release()
+		if a != nil {
+			a.Reset()
+		}
+	}
+}
@@ -4,8 +4,22 @@ package resp
 // objects.
 type Array []byte
 
+// NewArray returns an Array containing the given RESP objects.
+func NewArray(objects ...Object) Array {
+	buf := []byte{ARRAY_PREFIX}
+	buf = AppendInt(buf, int64(len(objects)))
+	buf = append(buf, lineSuffix...)
+	for _, object := range objects {
+		buf = append(buf, object.Raw()...)
+	}
+	return Array(buf)
+}
+
 func (a Array) Raw() []byte { return a }
 
-// TODO
-// func NewArray(objects interface{}...) Array {}
-// func (a Array) Objects() ([]interface{}, error) {}
+// Objects returns the RESP objects contained in this Array. The objects point
+// into the Array's bytes. It returns nil for a null array and ErrSyntaxError if
+// the array is invalid.
+func (a Array) Objects() ([]Object, error) {
+	return aggregateObjects(a)
+}
@@ -0,0 +1,40 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArrayObjects(t *testing.T) {
+	array := Array("*3\r\n$3\r\nfoo\r\n:1\r\n*1\r\n+OK\r\n")
+	objects, err := array.Objects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Object{
+		String("$3\r\nfoo\r\n"),
+		Integer(":1\r\n"),
+		Array("*1\r\n+OK\r\n"),
+	}
+	if !reflect.DeepEqual(expected, objects) {
+		t.Errorf("expected: %v\ngot: %v", expected, objects)
+	}
+
+	objects, err = Array("*-1\r\n").Objects()
+	if err != nil || objects != nil {
+		t.Errorf("expected nil objects for null array, got %v, %v", objects, err)
+	}
+
+	_, err = Array("*2\r\n+OK\r\n").Objects()
+	if err == nil {
+		t.Errorf("expected an error but didn't get one")
+	}
+}
+
+func TestNewArray(t *testing.T) {
+	array := NewArray(NewBulkString("foo"), NewInteger(1), NewArray())
+	expected := Array("*3\r\n$3\r\nfoo\r\n:1\r\n*0\r\n")
+	if !reflect.DeepEqual(expected, array) {
+		t.Errorf("expected: %q\ngot: %q", expected, array)
+	}
+}
@@ -0,0 +1,210 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of sending a command while a
+// CircuitBreaker is open.
+var ErrCircuitOpen = errors.New("resp: circuit breaker is open")
+
+const (
+	// DEFAULT_BREAKER_THRESHOLD is the error rate at which a CircuitBreaker
+	// opens if its Threshold isn't set.
+	DEFAULT_BREAKER_THRESHOLD = 0.5
+	// DEFAULT_BREAKER_MIN_REQUESTS is the number of requests a CircuitBreaker
+	// must see in a window before it can open if its MinRequests isn't set.
+	DEFAULT_BREAKER_MIN_REQUESTS = 20
+	// DEFAULT_BREAKER_WINDOW is the period over which a CircuitBreaker
+	// measures the error rate if its Window isn't set.
+	DEFAULT_BREAKER_WINDOW = 10 * time.Second
+	// DEFAULT_BREAKER_COOLDOWN is how long a CircuitBreaker stays open before
+	// probing if its Cooldown isn't set.
+	DEFAULT_BREAKER_COOLDOWN = 5 * time.Second
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BREAKER_CLOSED lets every request through.
+	BREAKER_CLOSED BreakerState = iota
+	// BREAKER_OPEN fails every request with ErrCircuitOpen.
+	BREAKER_OPEN
+	// BREAKER_HALF_OPEN lets a limited number of probe requests through. The
+	// breaker closes if they succeed and opens again if any fails.
+	BREAKER_HALF_OPEN
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BREAKER_CLOSED:
+		return "closed"
+	case BREAKER_OPEN:
+		return "open"
+	case BREAKER_HALF_OPEN:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// A CircuitBreaker sheds requests to an upstream that keeps failing, so that
+// callers fail fast instead of piling up behind timeouts. Only connection
+// errors and timeouts count as failures; error replies don't, and neither do
+// requests whose context was canceled or whose Conn was closed by the caller.
+// A CircuitBreaker is safe for concurrent use and is usually shared by every
+// Conn to the same upstream through their Dialer.
+type CircuitBreaker struct {
+	// Threshold is the error rate, between 0 and 1, that opens the breaker.
+	// Defaults to DEFAULT_BREAKER_THRESHOLD.
+	Threshold float64
+	// MinRequests is the number of requests the breaker must see in a window
+	// before it can open. Defaults to DEFAULT_BREAKER_MIN_REQUESTS.
+	MinRequests int
+	// Window is the period over which the error rate is measured. Defaults to
+	// DEFAULT_BREAKER_WINDOW.
+	Window time.Duration
+	// Cooldown is how long the breaker stays open before letting probes
+	// through. Defaults to DEFAULT_BREAKER_COOLDOWN.
+	Cooldown time.Duration
+	// Probes is the number of requests let through while half-open, all of
+	// which must succeed for the breaker to close. Defaults to 1.
+	Probes int
+	// OnStateChange, if set, is called with the new state whenever it
+	// changes. It's called with the breaker locked, so it must not call the
+	// breaker.
+	OnStateChange func(BreakerState)
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     int
+	probed      int
+	// now is replaced in tests.
+	now func() time.Time
+}
+
+// Allow returns ErrCircuitOpen if a request shouldn't be sent. Otherwise, the
+// request's outcome must be reported with Done.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock()
+	switch b.state {
+	case BREAKER_OPEN:
+		if now.Sub(b.openedAt) < b.cooldown() {
+			return ErrCircuitOpen
+		}
+		b.setState(BREAKER_HALF_OPEN)
+		b.probing, b.probed = 0, 0
+		fallthrough
+	case BREAKER_HALF_OPEN:
+		if b.probing+b.probed >= b.probes() {
+			return ErrCircuitOpen
+		}
+		b.probing++
+	}
+	return nil
+}
+
+// Done reports the outcome of a request that Allow let through.
+func (b *CircuitBreaker) Done(err error) {
+	failed := err != nil && err != context.Canceled && err != ErrConnClosed
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock()
+	switch b.state {
+	case BREAKER_HALF_OPEN:
+		if b.probing > 0 {
+			b.probing--
+		}
+		if failed {
+			b.open(now)
+			return
+		}
+		b.probed++
+		if b.probed >= b.probes() {
+			b.setState(BREAKER_CLOSED)
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+	case BREAKER_CLOSED:
+		window := b.Window
+		if window <= 0 {
+			window = DEFAULT_BREAKER_WINDOW
+		}
+		if now.Sub(b.windowStart) >= window {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+
+		minRequests := b.MinRequests
+		if minRequests <= 0 {
+			minRequests = DEFAULT_BREAKER_MIN_REQUESTS
+		}
+		threshold := b.Threshold
+		if threshold <= 0 {
+			threshold = DEFAULT_BREAKER_THRESHOLD
+		}
+		if b.requests >= minRequests && float64(b.failures) >= threshold*float64(b.requests) {
+			b.open(now)
+		}
+	}
+}
+
+// State returns the breaker's current state. An open breaker whose cooldown
+// has passed is reported as half-open.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BREAKER_OPEN && b.clock().Sub(b.openedAt) >= b.cooldown() {
+		return BREAKER_HALF_OPEN
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) open(now time.Time) {
+	b.openedAt = now
+	b.setState(BREAKER_OPEN)
+}
+
+func (b *CircuitBreaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.OnStateChange != nil {
+		b.OnStateChange(state)
+	}
+}
+
+func (b *CircuitBreaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+func (b *CircuitBreaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return DEFAULT_BREAKER_COOLDOWN
+	}
+	return b.Cooldown
+}
+
+func (b *CircuitBreaker) probes() int {
+	if b.Probes <= 0 {
+		return 1
+	}
+	return b.Probes
+}
@@ -0,0 +1,182 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var states []BreakerState
+	b := &CircuitBreaker{
+		MinRequests:   4,
+		Cooldown:      time.Second,
+		Probes:        2,
+		OnStateChange: func(s BreakerState) { states = append(states, s) },
+		now:           func() time.Time { return now },
+	}
+
+	// Error replies, cancellations, and too few requests don't open it
+	for _, err := range []error{nil, context.Canceled, io.EOF} {
+		if b.Allow() != nil {
+			t.Fatal("expected the breaker to be closed")
+		}
+		b.Done(err)
+	}
+	if b.State() != BREAKER_CLOSED {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+	b.Allow()
+	b.Done(io.EOF)
+	if b.State() != BREAKER_OPEN || b.Allow() != ErrCircuitOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+
+	// Half-open lets Probes requests through, and a failed probe reopens
+	now = now.Add(time.Second)
+	if b.State() != BREAKER_HALF_OPEN {
+		t.Fatalf("expected half-open, got %s", b.State())
+	}
+	if b.Allow() != nil || b.Allow() != nil || b.Allow() != ErrCircuitOpen {
+		t.Fatal("expected exactly two probes")
+	}
+	b.Done(nil)
+	b.Done(errors.New("timeout"))
+	if b.State() != BREAKER_OPEN {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+
+	// Successful probes close it
+	now = now.Add(time.Second)
+	b.Allow()
+	b.Allow()
+	b.Done(nil)
+	b.Done(nil)
+	if b.State() != BREAKER_CLOSED || b.Allow() != nil {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+
+	expected := []BreakerState{BREAKER_OPEN, BREAKER_HALF_OPEN, BREAKER_OPEN, BREAKER_HALF_OPEN, BREAKER_CLOSED}
+	if len(states) != len(expected) {
+		t.Fatalf("expected transitions %v, got %v", expected, states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Errorf("expected transitions %v, got %v", expected, states)
+			break
+		}
+	}
+}
+
+func TestDialer_Timeouts(t *testing.T) {
+	d := &Dialer{
+		Timeout:         time.Second,
+		CommandTimeouts: map[string]time.Duration{"KEYS": 5 * time.Second, "DEBUG": 0},
+	}
+	tests := []struct {
+		commands []Command
+		expected time.Duration
+	}{
+		{[]Command{NewCommand("GET", "a")}, time.Second},
+		{[]Command{NewCommand("get", "a"), NewCommand("keys", "*")}, 5 * time.Second},
+		{[]Command{NewCommand("DEBUG", "SLEEP", "10")}, 0},
+		{[]Command{NewCommand("BLPOP", "a", "2")}, 3 * time.Second},
+		{[]Command{NewCommand("BLPOP", "a", "0")}, 0},
+	}
+	for i, test := range tests {
+		if got := d.timeout(test.commands); got != test.expected {
+			t.Errorf("tests[%d]: expected %s, got %s", i, test.expected, got)
+		}
+	}
+}
+
+func TestConn_Breaker(t *testing.T) {
+	d := &Dialer{
+		NetDial: func(ctx context.Context) (net.Conn, error) {
+			client, server := net.Pipe()
+			go func() {
+				// Never reply
+				NewReader(server).ReadCommand()
+			}()
+			return client, nil
+		},
+		Timeout:   10 * time.Millisecond,
+		Reconnect: ExponentialBackoff{MaxAttempts: 1},
+		Breaker:   &CircuitBreaker{MinRequests: 6}, // including the dial and reconnects
+	}
+	conn, err := d.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := conn.Do("PING"); err != context.DeadlineExceeded {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	}
+	start := time.Now()
+	if _, err := conn.Do("PING"); err != ErrCircuitOpen {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := d.Dial(); err != ErrCircuitOpen {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Millisecond {
+		t.Errorf("expected to fail fast, took %s", elapsed)
+	}
+}
+
+func TestConn_ReconnectBreaker(t *testing.T) {
+	var dials int
+	hang := false
+	d := &Dialer{
+		NetDial: func(ctx context.Context) (net.Conn, error) {
+			dials++
+			if hang {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		},
+		ConnectTimeout: 10 * time.Millisecond,
+		Reconnect:      ExponentialBackoff{MaxAttempts: 1},
+		Breaker:        &CircuitBreaker{MinRequests: 3}, // including the dial
+	}
+	conn, err := d.Dial()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Hung reconnects are bounded by ConnectTimeout, and count as failures
+	hang = true
+	start := time.Now()
+	if err := conn.Send("PING"); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the reconnect to time out, took %s", elapsed)
+	}
+
+	// The breaker is open now, so reconnects fail fast without dialing
+	n := dials
+	if err := conn.Send("PING"); err != ErrCircuitOpen {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := conn.Do("PING"); err != ErrCircuitOpen {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if dials != n {
+		t.Errorf("expected no dials, got %d", dials-n)
+	}
+}
@@ -0,0 +1,363 @@
+package resp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DEFAULT_PUBSUB_BUFFER is the default Broker.MaxBuffer, the hard
+// client-output-buffer-limit Redis sets for pub/sub clients.
+const DEFAULT_PUBSUB_BUFFER = 32 * 1024 * 1024
+
+// A Broker implements Redis pub/sub for the clients of a Server. Its
+// ServeRESP answers SUBSCRIBE, UNSUBSCRIBE, PSUBSCRIBE, PUNSUBSCRIBE, PUBLISH,
+// and PUBSUB, so it's typically registered for those commands with a
+// ServeMux. Messages can also be published with Publish.
+//
+// Messages are queued for each subscribed connection and written to it
+// between the replies to its commands: as pushes to RESP3 clients and as
+// arrays to RESP2 clients. Connections whose queue grows beyond MaxBuffer
+// because they don't read their messages fast enough are closed, as Redis
+// does. The zero value is ready to use, and a Broker is safe for concurrent
+// use.
+type Broker struct {
+	// MaxBuffer is the size a subscriber's queue of messages may reach.
+	// Defaults to DEFAULT_PUBSUB_BUFFER.
+	MaxBuffer int
+
+	mu       sync.Mutex
+	channels map[string]map[*subscriber]struct{}
+	patterns map[string]map[*subscriber]struct{}
+}
+
+// Publish sends message to the subscribers of channel and of the patterns
+// that match it. It returns the number of subscriptions the message was sent
+// through, like PUBLISH.
+func (b *Broker) Publish(channel string, message []byte) int {
+	limit := b.MaxBuffer
+	if limit <= 0 {
+		limit = DEFAULT_PUBSUB_BUFFER
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	receivers := 0
+	if subs := b.channels[channel]; len(subs) > 0 {
+		frame := NewPush(NewBulkString("message"), NewBulkString(channel), NewBulkString(string(message)))
+		for s := range subs {
+			s.send(frame, limit)
+			receivers++
+		}
+	}
+	name := []byte(channel)
+	for pattern, subs := range b.patterns {
+		if !matchPattern([]byte(pattern), name) {
+			continue
+		}
+		frame := NewPush(NewBulkString("pmessage"), NewBulkString(pattern), NewBulkString(channel), NewBulkString(string(message)))
+		for s := range subs {
+			s.send(frame, limit)
+			receivers++
+		}
+	}
+	return receivers
+}
+
+// Channels returns the channels that have subscribers and match pattern,
+// like PUBSUB CHANNELS. An empty pattern matches all channels.
+func (b *Broker) Channels(pattern string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	channels := []string{}
+	for channel := range b.channels {
+		if pattern == "" || matchPattern([]byte(pattern), []byte(channel)) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// NumSub returns the number of subscribers of channel, not counting pattern
+// subscriptions, like PUBSUB NUMSUB.
+func (b *Broker) NumSub(channel string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.channels[channel])
+}
+
+// NumPat returns the number of patterns that have subscribers, like PUBSUB
+// NUMPAT.
+func (b *Broker) NumPat() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.patterns)
+}
+
+// ServeRESP answers a pub/sub command. Subscriptions are only possible for
+// commands received by a Server.
+func (b *Broker) ServeRESP(w *Writer, cmd Command) {
+	name, args, _ := ParseCommand(cmd)
+	if err := ValidateCommand(name, args); err != nil {
+		w.WriteObject(err.(Error))
+		return
+	}
+
+	upper := strings.ToUpper(name)
+	switch upper {
+	case "PUBLISH":
+		w.WriteObject(NewInteger(int64(b.Publish(string(args[0]), args[1]))))
+	case "PUBSUB":
+		b.pubsub(w, args)
+	case "SUBSCRIBE", "PSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		s := b.subscriber(w.conn)
+		if s == nil {
+			w.WriteObject(NewError("ERR " + upper + " isn't supported on this connection"))
+			return
+		}
+		names := make([]string, len(args))
+		for i, arg := range args {
+			names[i] = string(arg)
+		}
+		var frames []Object
+		switch upper {
+		case "SUBSCRIBE":
+			frames = b.subscribe(s, false, names)
+		case "PSUBSCRIBE":
+			frames = b.subscribe(s, true, names)
+		case "UNSUBSCRIBE":
+			frames = b.unsubscribe(s, false, names)
+		case "PUNSUBSCRIBE":
+			frames = b.unsubscribe(s, true, names)
+		}
+		for _, frame := range frames {
+			w.WriteObject(frame)
+		}
+	default:
+		w.WriteObject(unknownCommandError(name, args))
+	}
+}
+
+func (b *Broker) pubsub(w *Writer, args [][]byte) {
+	switch {
+	case CommandEquals(args[0], "CHANNELS") && len(args) <= 2:
+		pattern := ""
+		if len(args) == 2 {
+			pattern = string(args[1])
+		}
+		channels := b.Channels(pattern)
+		objects := make([]Object, len(channels))
+		for i, channel := range channels {
+			objects[i] = NewBulkString(channel)
+		}
+		w.WriteObject(NewArray(objects...))
+	case CommandEquals(args[0], "NUMSUB"):
+		objects := make([]Object, 0, 2*(len(args)-1))
+		for _, channel := range args[1:] {
+			objects = append(objects, NewBulkString(string(channel)), NewInteger(int64(b.NumSub(string(channel)))))
+		}
+		w.WriteObject(NewArray(objects...))
+	case CommandEquals(args[0], "NUMPAT") && len(args) == 1:
+		w.WriteObject(NewInteger(int64(b.NumPat())))
+	case CommandEquals(args[0], "CHANNELS"), CommandEquals(args[0], "NUMPAT"):
+		w.WriteObject(wrongArityError("pubsub|" + string(args[0])))
+	default:
+		w.WriteObject(NewError(fmt.Sprintf("ERR unknown subcommand '%s'. Try PUBSUB HELP.", args[0])))
+	}
+}
+
+// subscriber returns the subscriber for a Server connection, creating it if
+// needed. It returns nil if c is nil or closed, or if c is subscribed
+// through another Broker.
+func (b *Broker) subscriber(c *serverConn) *subscriber {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	if c.sub == nil {
+		c.sub = &subscriber{
+			broker:   b,
+			conn:     c,
+			channels: map[string]struct{}{},
+			patterns: map[string]struct{}{},
+			ready:    make(chan struct{}, 1),
+		}
+		go c.sub.deliver()
+	}
+	if c.sub.broker != b {
+		return nil
+	}
+	return c.sub
+}
+
+// registry returns the channel or pattern registry and the corresponding
+// subscriptions of s. The Broker's mu must be held.
+func (b *Broker) registry(s *subscriber, pattern bool) (map[string]map[*subscriber]struct{}, map[string]struct{}) {
+	if b.channels == nil {
+		b.channels = map[string]map[*subscriber]struct{}{}
+		b.patterns = map[string]map[*subscriber]struct{}{}
+	}
+	if pattern {
+		return b.patterns, s.patterns
+	}
+	return b.channels, s.channels
+}
+
+// subscribe subscribes s to each of names and returns the confirmations.
+func (b *Broker) subscribe(s *subscriber, pattern bool, names []string) []Object {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	registry, subscribed := b.registry(s, pattern)
+	kind := "subscribe"
+	if pattern {
+		kind = "psubscribe"
+	}
+
+	frames := make([]Object, len(names))
+	for i, name := range names {
+		if _, ok := subscribed[name]; !ok {
+			subscribed[name] = struct{}{}
+			if registry[name] == nil {
+				registry[name] = map[*subscriber]struct{}{}
+			}
+			registry[name][s] = struct{}{}
+		}
+		frames[i] = NewPush(NewBulkString(kind), NewBulkString(name), NewInteger(int64(s.count())))
+	}
+	return frames
+}
+
+// unsubscribe unsubscribes s from each of names, or from all of its channels
+// or patterns if names is empty, and returns the confirmations.
+func (b *Broker) unsubscribe(s *subscriber, pattern bool, names []string) []Object {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	registry, subscribed := b.registry(s, pattern)
+	kind := "unsubscribe"
+	if pattern {
+		kind = "punsubscribe"
+	}
+
+	if len(names) == 0 {
+		for name := range subscribed {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return []Object{NewPush(NewBulkString(kind), NULL, NewInteger(int64(s.count())))}
+		}
+	}
+
+	frames := make([]Object, len(names))
+	for i, name := range names {
+		if _, ok := subscribed[name]; ok {
+			delete(subscribed, name)
+			delete(registry[name], s)
+			if len(registry[name]) == 0 {
+				delete(registry, name)
+			}
+		}
+		frames[i] = NewPush(NewBulkString(kind), NewBulkString(name), NewInteger(int64(s.count())))
+	}
+	return frames
+}
+
+// A subscriber is a Server connection subscribed to a Broker's channels or
+// patterns.
+type subscriber struct {
+	broker *Broker
+	conn   *serverConn
+	// channels and patterns are guarded by the Broker's mu.
+	channels map[string]struct{}
+	patterns map[string]struct{}
+
+	// mu guards the queue of messages waiting to be written. ready is
+	// signaled when messages are queued and closed with the queue.
+	mu     sync.Mutex
+	queue  []Object
+	size   int
+	ready  chan struct{}
+	closed bool
+}
+
+// count returns the number of subscriptions of s and records it on its
+// connection. The Broker's mu must be held.
+func (s *subscriber) count() int {
+	n := len(s.channels) + len(s.patterns)
+	atomic.StoreInt32(&s.conn.subscriptions, int32(n))
+	return n
+}
+
+// send queues frame for writing. If the queue grows beyond limit, the
+// connection is closed instead.
+func (s *subscriber) send(frame Object, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.size+len(frame.Raw()) > limit {
+		s.closeQueue()
+		s.conn.conn.Close()
+		return
+	}
+	s.queue = append(s.queue, frame)
+	s.size += len(frame.Raw())
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// deliver writes queued messages to the connection until the queue is
+// closed. Messages are written while the connection isn't answering
+// commands.
+func (s *subscriber) deliver() {
+	c := s.conn
+	for range s.ready {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue, s.size = nil, 0
+		s.mu.Unlock()
+		if len(queue) == 0 {
+			continue
+		}
+
+		c.wmu.Lock()
+		for _, frame := range queue {
+			c.w.WriteObject(frame)
+		}
+		err := c.w.Flush()
+		c.wmu.Unlock()
+		if err != nil {
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// closeQueue discards the queued messages and stops deliver. s.mu must be
+// held.
+func (s *subscriber) closeQueue() {
+	if !s.closed {
+		s.closed = true
+		s.queue, s.size = nil, 0
+		close(s.ready)
+	}
+}
+
+// close removes all of the subscriptions of s and stops deliver.
+func (s *subscriber) close() {
+	s.broker.unsubscribe(s, false, nil)
+	s.broker.unsubscribe(s, true, nil)
+	s.mu.Lock()
+	s.closeQueue()
+	s.mu.Unlock()
+}
@@ -0,0 +1,165 @@
+package resp
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startBroker serves b, and echoServerHandler for other commands, on a local
+// port and returns its address.
+func startBroker(t *testing.T, b *Broker) string {
+	mux := &ServeMux{NotFound: echoServerHandler}
+	for _, name := range []string{"SUBSCRIBE", "PSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "PUBLISH", "PUBSUB"} {
+		mux.Handle(name, b)
+	}
+	return startServer(t, &Server{Handler: mux})
+}
+
+// waitFor polls cond until it's true or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// expectReplies reads a reply from r for each of expected.
+func expectReplies(t *testing.T, r *Reader, expected []string) {
+	for i, e := range expected {
+		if reply, err := r.ReadObject(); err != nil || string(reply.Raw()) != e {
+			t.Errorf("replies[%d]: expected %q, got %q, %v", i, e, reply, err)
+		}
+	}
+}
+
+func TestBroker(t *testing.T) {
+	b := &Broker{}
+	addr := startBroker(t, b)
+
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPubSub(conn)
+	defer p.Close()
+	p.Subscribe("news.a")
+	p.PSubscribe("news.*")
+	waitFor(t, func() bool { return b.NumSub("news.a") == 1 && b.NumPat() == 1 })
+
+	publisher, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	if reply, err := publisher.Do("PUBLISH", "news.a", "hi"); err != nil || string(reply.Raw()) != ":2\r\n" {
+		t.Errorf("unexpected PUBLISH reply: %q, %v", reply, err)
+	}
+	expected := []Message{
+		{Channel: "news.a", Payload: []byte("hi")},
+		{Channel: "news.a", Pattern: "news.*", Payload: []byte("hi")},
+	}
+	for i, e := range expected {
+		if message := receiveMessage(t, p.Messages()); !reflect.DeepEqual(e, message) {
+			t.Errorf("messages[%d]: expected %+v, got %+v", i, e, message)
+		}
+	}
+
+	replies, err := publisher.DoPipeline([]Command{
+		NewCommand("PUBSUB", "CHANNELS"),
+		NewCommand("PUBSUB", "CHANNELS", "weather.*"),
+		NewCommand("PUBSUB", "NUMSUB", "news.a", "news.b"),
+		NewCommand("PUBSUB", "NUMPAT"),
+		NewCommand("PUBSUB", "NOPE"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedReplies := []Object{
+		NewArray(NewBulkString("news.a")),
+		NewArray(),
+		NewArray(NewBulkString("news.a"), NewInteger(1), NewBulkString("news.b"), NewInteger(0)),
+		NewInteger(1),
+		NewError("ERR unknown subcommand 'NOPE'. Try PUBSUB HELP."),
+	}
+	if !reflect.DeepEqual(expectedReplies, replies) {
+		t.Errorf("expected %q, got %q", expectedReplies, replies)
+	}
+
+	// Subscriptions end with the connection
+	p.Close()
+	waitFor(t, func() bool { return b.NumSub("news.a") == 0 && b.NumPat() == 0 })
+	if n := b.Publish("news.a", []byte("hi")); n != 0 {
+		t.Errorf("expected no receivers, got %d", n)
+	}
+}
+
+func TestBroker_RESP2(t *testing.T) {
+	addr := startBroker(t, &Broker{})
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("SUBSCRIBE a b\r\nECHO x\r\nPING\r\nUNSUBSCRIBE\r\nUNSUBSCRIBE\r\nPING\r\n"))
+	expectReplies(t, NewReader(conn), []string{
+		"*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n",
+		"*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:2\r\n",
+		"-ERR Can't execute 'echo': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context\r\n",
+		"*2\r\n$4\r\npong\r\n$0\r\n\r\n",
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:1\r\n",
+		"*3\r\n$11\r\nunsubscribe\r\n$1\r\nb\r\n:0\r\n",
+		"*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n",
+		"+PONG\r\n",
+	})
+}
+
+func TestBroker_RESP3(t *testing.T) {
+	b := &Broker{}
+	addr := startBroker(t, b)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("HELLO 3\r\nPSUBSCRIBE n*\r\nECHO x\r\n"))
+	r := NewReader(conn)
+	if _, err := r.ReadObject(); err != nil {
+		t.Fatal(err)
+	}
+	// Other commands are allowed while subscribed
+	expectReplies(t, r, []string{
+		">3\r\n$10\r\npsubscribe\r\n$2\r\nn*\r\n:1\r\n",
+		"$1\r\nx\r\n",
+	})
+	if n := b.Publish("news", []byte("hi")); n != 1 {
+		t.Errorf("expected 1 receiver, got %d", n)
+	}
+	expectReplies(t, r, []string{">4\r\n$8\r\npmessage\r\n$2\r\nn*\r\n$4\r\nnews\r\n$2\r\nhi\r\n"})
+}
+
+func TestBroker_MaxBuffer(t *testing.T) {
+	b := &Broker{MaxBuffer: 64}
+	addr := startBroker(t, b)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("SUBSCRIBE a\r\n"))
+	r := NewReader(conn)
+	expectReplies(t, r, []string{"*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n"})
+
+	// The subscriber is disconnected instead of being sent a message larger
+	// than its buffer.
+	b.Publish("a", []byte(strings.Repeat("x", 100)))
+	if reply, err := r.ReadObject(); err == nil {
+		t.Errorf("expected the connection to be closed, got %q", reply)
+	}
+	waitFor(t, func() bool { return b.NumSub("a") == 0 })
+}
@@ -0,0 +1,125 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// A BulkLoader writes a stream of commands without waiting for replies, as
+// the input of redis-cli --pipe or straight to a connection, for mass
+// insertion of data. It counts the commands written, so that their replies
+// can be checked with Verify.
+type BulkLoader struct {
+	w     *Writer
+	count int64
+}
+
+// A BulkLoadResult counts the replies to the commands of a bulk load.
+// FirstError is the first error reply, if any.
+type BulkLoadResult struct {
+	OK         int64
+	Errors     int64
+	Other      int64
+	FirstError error
+}
+
+// NewBulkLoader returns a BulkLoader writing to w.
+func NewBulkLoader(w io.Writer) *BulkLoader {
+	return &BulkLoader{w: NewWriter(w)}
+}
+
+// WriteCommand writes a command. Commands are buffered until Flush.
+func (l *BulkLoader) WriteCommand(cmd Command) error {
+	if _, err := l.w.Write(cmd); err != nil {
+		return err
+	}
+	l.count++
+	return nil
+}
+
+// Load writes the commands returned by next until it returns io.EOF, and
+// then flushes them. Other errors returned by next stop the load and are
+// returned.
+func (l *BulkLoader) Load(next func() (Command, error)) error {
+	for {
+		cmd, err := next()
+		if err == io.EOF {
+			return l.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if err := l.WriteCommand(cmd); err != nil {
+			return err
+		}
+	}
+}
+
+// LoadInline writes the commands in r, a line per command split into
+// arguments as by SplitInline, such as a file of "SET key value" lines, and
+// then flushes them. Empty lines are skipped. A line with unbalanced quotes
+// stops the load with ErrUnbalancedQuotes.
+func (l *BulkLoader) LoadInline(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) > 0 {
+			args, splitErr := SplitInline(line)
+			if splitErr != nil {
+				return splitErr
+			}
+			if len(args) > 0 {
+				if err := l.WriteCommand(newCommand(string(args[0]), args[1:])); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return l.Flush()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Flush writes any buffered commands.
+func (l *BulkLoader) Flush() error {
+	return l.w.Flush()
+}
+
+// Count returns the number of commands written.
+func (l *BulkLoader) Count() int64 {
+	return l.count
+}
+
+// Verify reads a reply for each command written from r, usually the
+// connection the commands were written to, and counts them: +OK replies,
+// error replies, and any other replies, such as the integers returned by
+// RPUSH. It returns early if reading fails.
+func (l *BulkLoader) Verify(r io.Reader) (BulkLoadResult, error) {
+	var result BulkLoadResult
+	rd := NewReader(r)
+	rd.SetMaxSize(DEFAULT_MAX_BUFFER)
+	for i := int64(0); i < l.count; i++ {
+		reply, err := rd.ReadObjectSlice()
+		if err != nil {
+			return result, err
+		}
+		switch {
+		case string(reply) == "+OK\r\n":
+			result.OK++
+		case reply[0] == ERROR_PREFIX || reply[0] == BLOB_ERROR_PREFIX:
+			result.Errors++
+			if result.FirstError == nil {
+				result.FirstError = Parse(append([]byte(nil), reply...)).(error)
+			}
+		default:
+			result.Other++
+		}
+	}
+	return result, nil
+}
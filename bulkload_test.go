@@ -0,0 +1,66 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBulkLoader(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewBulkLoader(&buf)
+	commands := []Command{NewCommand("SET", "a", "1"), NewCommand("RPUSH", "l", "x")}
+	err := l.Load(func() (Command, error) {
+		if len(commands) == 0 {
+			return nil, io.EOF
+		}
+		cmd := commands[0]
+		commands = commands[1:]
+		return cmd, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.LoadInline(strings.NewReader("SET b \"two words\"\r\n\nDEL a\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := string(NewCommand("SET", "a", "1")) + string(NewCommand("RPUSH", "l", "x")) +
+		string(NewCommand("SET", "b", "two words")) + string(NewCommand("DEL", "a"))
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+	if l.Count() != 4 {
+		t.Errorf("expected 4 commands, got %d", l.Count())
+	}
+
+	result, err := l.Verify(strings.NewReader("+OK\r\n:1\r\n-ERR bad\r\n-ERR worse\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK != 1 || result.Other != 1 || result.Errors != 2 || result.FirstError == nil || result.FirstError.Error() != "ERR bad" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	result, err = l.Verify(strings.NewReader("+OK\r\n"))
+	if err != io.EOF || result.OK != 1 {
+		t.Errorf("expected EOF after one reply, got %+v, %v", result, err)
+	}
+}
+
+func TestBulkLoader_Errors(t *testing.T) {
+	l := NewBulkLoader(io.Discard)
+	if err := l.LoadInline(strings.NewReader("SET a \"b\n")); err != ErrUnbalancedQuotes {
+		t.Errorf("expected ErrUnbalancedQuotes, got %v", err)
+	}
+	if err := l.Load(func() (Command, error) { return nil, ErrSyntaxError }); err != ErrSyntaxError {
+		t.Errorf("expected ErrSyntaxError, got %v", err)
+	}
+
+	l = NewBulkLoader(failingWriter{})
+	l.WriteCommand(NewCommand("SET", "a", strings.Repeat("x", 2*DEFAULT_BUFFER)))
+	if err := l.Flush(); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,109 @@
+package resp
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// CanonicalCommand returns a canonical encoding of a command, suitable for use
+// as a map key by response caches and request coalescers. The command name is
+// upper cased and, for commands whose reply doesn't depend on the order of
+// their keys (DEL, UNLINK, EXISTS, TOUCH, SINTER, SUNION, PFCOUNT,
+// SINTERSTORE, and SUNIONSTORE), the keys are sorted. Other arguments are
+// left as they are.
+func CanonicalCommand(name string, args [][]byte) Command {
+	name = strings.ToUpper(name)
+
+	var unordered [][]byte
+	switch name {
+	case "DEL", "UNLINK", "EXISTS", "TOUCH", "SINTER", "SUNION", "PFCOUNT":
+		unordered = args
+	case "SINTERSTORE", "SUNIONSTORE":
+		// The destination key comes first
+		if len(args) > 0 {
+			unordered = args[1:]
+		}
+	}
+
+	if len(unordered) > 1 {
+		sorted := make([][]byte, len(args))
+		copy(sorted, args)
+		sort.Sort(byteSlices(sorted[len(args)-len(unordered):]))
+		args = sorted
+	}
+
+	return newCommand(name, args)
+}
+
+// byteSlices implements sort.Interface for byte slices.
+type byteSlices [][]byte
+
+func (b byteSlices) Len() int           { return len(b) }
+func (b byteSlices) Less(i, j int) bool { return bytes.Compare(b[i], b[j]) < 0 }
+func (b byteSlices) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// Normalize returns a normal form of a reply, so that replies from different
+// servers, or sent with different protocol versions, can be compared byte for
+// byte. The fields of maps and attributes are sorted by key and the members
+// of sets are sorted, as their order isn't meaningful, and null bulk strings
+// and null arrays become RESP3 nulls. Nested objects are normalized too.
+// Other objects, including invalid ones, are returned as they are.
+func Normalize(obj Object) Object {
+	b := obj.Raw()
+	normal := appendNormal(nil, b)
+	if normal == nil {
+		return obj
+	}
+	return Parse(normal)
+}
+
+// appendNormal appends the normal form of the object b to buf. It returns
+// nil if b is invalid.
+func appendNormal(buf []byte, b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	switch string(b) {
+	case "$-1\r\n", "*-1\r\n", "_\r\n":
+		return append(buf, NULL...)
+	}
+	switch b[0] {
+	case ARRAY_PREFIX, PUSH_PREFIX, SET_PREFIX, MAP_PREFIX, ATTRIBUTE_PREFIX:
+	default:
+		return append(buf, b...)
+	}
+	objects, err := aggregateObjects(b)
+	if err != nil {
+		return nil
+	}
+
+	step := 1
+	if b[0] == MAP_PREFIX || b[0] == ATTRIBUTE_PREFIX {
+		step = 2
+	}
+	// The members of sets, or the keys and values of maps, each normalized
+	members := make([][]byte, 0, len(objects)/step)
+	for i := 0; i < len(objects); i += step {
+		var member []byte
+		for _, object := range objects[i : i+step] {
+			if member = appendNormal(member, object.Raw()); member == nil {
+				return nil
+			}
+		}
+		members = append(members, member)
+	}
+	if step == 2 || b[0] == SET_PREFIX {
+		// RESP objects are never a prefix of one another, so sorting
+		// keys and values together sorts by key first
+		sort.Sort(byteSlices(members))
+	}
+
+	buf = append(buf, b[0])
+	buf = AppendInt(buf, int64(len(members)))
+	buf = append(buf, lineSuffix...)
+	for _, member := range members {
+		buf = append(buf, member...)
+	}
+	return buf
+}
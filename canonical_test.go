@@ -0,0 +1,67 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+type canonicalCommandTest struct {
+	args     []string
+	expected Command
+}
+
+func TestCanonicalCommand(t *testing.T) {
+	tests := []canonicalCommandTest{
+		{[]string{"get", "Foo"}, NewCommand("GET", "Foo")},
+		{[]string{"del", "c", "a", "b"}, NewCommand("DEL", "a", "b", "c")},
+		{[]string{"MGET", "c", "a"}, NewCommand("MGET", "c", "a")},
+		{[]string{"sunionstore", "z", "c", "a"}, NewCommand("SUNIONSTORE", "z", "a", "c")},
+		{[]string{"PING"}, NewCommand("PING")},
+	}
+
+	for i, test := range tests {
+		args := make([][]byte, len(test.args)-1)
+		for j, arg := range test.args[1:] {
+			args[j] = []byte(arg)
+		}
+		original := make([][]byte, len(args))
+		copy(original, args)
+
+		got := CanonicalCommand(test.args[0], args)
+		if !reflect.DeepEqual(test.expected, got) {
+			t.Errorf("tests[%d]:\nexpected: %q\ngot: %q", i, test.expected, got)
+		}
+		if !reflect.DeepEqual(original, args) {
+			t.Errorf("tests[%d]: arguments were modified", i)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{OK, "+OK\r\n"},
+		{String("$-1\r\n"), "_\r\n"},
+		{Array("*-1\r\n"), "_\r\n"},
+		{NULL, "_\r\n"},
+		{NewArray(PONG, OK), "*2\r\n+PONG\r\n+OK\r\n"},
+		{NewSet(PONG, OK, NewInteger(1)), "~3\r\n+OK\r\n+PONG\r\n:1\r\n"},
+		{NewMap(NewBulkString("b"), NewInteger(1), NewBulkString("a"), NewInteger(2)), "%2\r\n$1\r\na\r\n:2\r\n$1\r\nb\r\n:1\r\n"},
+		{NewArray(NewMap(PONG, String("$-1\r\n"), OK, NewSet(TRUE, FALSE))), "*1\r\n%2\r\n+OK\r\n~2\r\n#f\r\n#t\r\n+PONG\r\n_\r\n"},
+		{NewPush(PONG, Array("*-1\r\n")), ">2\r\n+PONG\r\n_\r\n"},
+		{InvalidObject("*2\r\n+OK\r\n"), "*2\r\n+OK\r\n"},
+	}
+
+	for i, test := range tests {
+		normal := Normalize(test.obj)
+		if string(normal.Raw()) != test.expected {
+			t.Errorf("tests[%d]: expected %q, got %q", i, test.expected, normal.Raw())
+		}
+	}
+
+	if _, ok := Normalize(NewMap(OK, OK)).(Map); !ok {
+		t.Errorf("expected a Map")
+	}
+}
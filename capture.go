@@ -0,0 +1,122 @@
+package resp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// CAPTURE_MAGIC starts every capture file.
+const CAPTURE_MAGIC = "RESPCAP\x01"
+
+// captureHeaderSize is the size of a record header: the timestamp in Unix
+// nanoseconds, the connection ID, the direction, and the frame length.
+const captureHeaderSize = 8 + 8 + 1 + 4
+
+// ErrFrameTooLarge is returned by CaptureWriter.WriteRecord for frames whose
+// length doesn't fit in a record header.
+var ErrFrameTooLarge = errors.New("resp: capture frame too large")
+
+// A Direction tells whether a captured frame was read or written.
+type Direction byte
+
+const (
+	// DIRECTION_READ marks frames read from a connection, e.g. commands on
+	// the server side or replies on the client side.
+	DIRECTION_READ Direction = 'r'
+	// DIRECTION_WRITTEN marks frames written to a connection.
+	DIRECTION_WRITTEN Direction = 'w'
+)
+
+// A CaptureRecord is one frame of captured traffic.
+type CaptureRecord struct {
+	Time      time.Time
+	Conn      int64
+	Direction Direction
+	// Frame holds the raw bytes read or written, which are one or more
+	// complete objects, or part of an object too large for the Writer's
+	// buffer.
+	Frame []byte
+}
+
+// A CaptureWriter records traffic to a capture file, for offline analysis or
+// to be replayed with a ReplayReader. The file starts with CAPTURE_MAGIC and
+// is followed by records, each a 21 byte header and the frame. The header
+// holds, in big endian order, the record's time in Unix nanoseconds (8
+// bytes), the connection ID (8 bytes), the direction (1 byte), and the length
+// of the frame (4 bytes).
+//
+// A CaptureWriter is safe for concurrent use, so any number of connections
+// can be recorded to the same file by setting the Traces it returns on their
+// Readers and Writers, or by setting its Trace method as a Server's NewTrace.
+// Records are buffered; Flush must be called once recording is done.
+type CaptureWriter struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	header [captureHeaderSize]byte
+	err    error
+}
+
+// NewCaptureWriter returns a CaptureWriter that writes a capture file to w.
+func NewCaptureWriter(w io.Writer) *CaptureWriter {
+	c := &CaptureWriter{w: bufio.NewWriter(w)}
+	c.w.WriteString(CAPTURE_MAGIC)
+	return c
+}
+
+// WriteRecord buffers a record. Once writing to the underlying io.Writer
+// fails, all further records are dropped and the error is returned.
+func (c *CaptureWriter) WriteRecord(rec CaptureRecord) error {
+	if uint64(len(rec.Frame)) > math.MaxUint32 {
+		return ErrFrameTooLarge
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	binary.BigEndian.PutUint64(c.header[0:], uint64(rec.Time.UnixNano()))
+	binary.BigEndian.PutUint64(c.header[8:], uint64(rec.Conn))
+	c.header[16] = byte(rec.Direction)
+	binary.BigEndian.PutUint32(c.header[17:], uint32(len(rec.Frame)))
+	if _, err := c.w.Write(c.header[:]); err != nil {
+		c.err = err
+		return err
+	}
+	if _, err := c.w.Write(rec.Frame); err != nil {
+		c.err = err
+		return err
+	}
+	return nil
+}
+
+// Trace returns a Trace that records the objects read and written by the
+// Reader and Writer it's set on as the traffic of connection conn. Errors
+// recording them are returned by Flush.
+func (c *CaptureWriter) Trace(conn int64) *Trace {
+	return &Trace{
+		ObjectRead: func(obj []byte) {
+			c.WriteRecord(CaptureRecord{Time: time.Now(), Conn: conn, Direction: DIRECTION_READ, Frame: obj})
+		},
+		ObjectWritten: func(b []byte) {
+			c.WriteRecord(CaptureRecord{Time: time.Now(), Conn: conn, Direction: DIRECTION_WRITTEN, Frame: b})
+		},
+	}
+}
+
+// Flush writes any buffered records to the underlying io.Writer and returns
+// the first error writing them, if any.
+func (c *CaptureWriter) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.err = c.w.Flush()
+	return c.err
+}
@@ -0,0 +1,101 @@
+package resp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// readCaptureRecords decodes a capture file.
+func readCaptureRecords(t *testing.T, b []byte) []CaptureRecord {
+	if !bytes.HasPrefix(b, []byte(CAPTURE_MAGIC)) {
+		t.Fatalf("expected the capture to start with %q, got %q", CAPTURE_MAGIC, b)
+	}
+	b = b[len(CAPTURE_MAGIC):]
+	var records []CaptureRecord
+	for len(b) > 0 {
+		if len(b) < captureHeaderSize {
+			t.Fatalf("truncated header %q", b)
+		}
+		n := int(binary.BigEndian.Uint32(b[17:]))
+		records = append(records, CaptureRecord{
+			Time:      time.Unix(0, int64(binary.BigEndian.Uint64(b))),
+			Conn:      int64(binary.BigEndian.Uint64(b[8:])),
+			Direction: Direction(b[16]),
+			Frame:     b[captureHeaderSize : captureHeaderSize+n],
+		})
+		b = b[captureHeaderSize+n:]
+	}
+	return records
+}
+
+func TestCaptureWriter(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCaptureWriter(&buf)
+	now := time.Unix(1700000000, 123)
+	c.WriteRecord(CaptureRecord{Time: now, Conn: 7, Direction: DIRECTION_READ, Frame: []byte("+OK\r\n")})
+	c.WriteRecord(CaptureRecord{Time: now, Conn: 8, Direction: DIRECTION_WRITTEN})
+	if buf.Len() != 0 {
+		t.Errorf("expected records to be buffered")
+	}
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := CAPTURE_MAGIC +
+		"\x17\x97\x9c\xfe\x36\x2a\x00\x7b" + "\x00\x00\x00\x00\x00\x00\x00\x07" + "r" + "\x00\x00\x00\x05" + "+OK\r\n" +
+		"\x17\x97\x9c\xfe\x36\x2a\x00\x7b" + "\x00\x00\x00\x00\x00\x00\x00\x08" + "w" + "\x00\x00\x00\x00"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	c = NewCaptureWriter(failingWriter{})
+	c.WriteRecord(CaptureRecord{Frame: bytes.Repeat([]byte("x"), 8192)})
+	if err := c.Flush(); err == nil {
+		t.Errorf("expected an error")
+	}
+	if err := c.WriteRecord(CaptureRecord{}); err == nil {
+		t.Errorf("expected the error to persist")
+	}
+}
+
+func TestCaptureWriter_Server(t *testing.T) {
+	var buf bytes.Buffer
+	capture := NewCaptureWriter(&buf)
+	addr := startServer(t, &Server{Handler: echoServerHandler, NewTrace: capture.Trace})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write(append(NewCommand("ECHO", "hi"), NewCommand("PING")...))
+	expectReplies(t, NewReader(conn), []string{"$2\r\nhi\r\n", "+PONG\r\n"})
+	if err := capture.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readCaptureRecords(t, buf.Bytes())
+	var read, written []byte
+	for _, rec := range records {
+		if rec.Conn != 1 {
+			t.Errorf("expected connection 1, got %d", rec.Conn)
+		}
+		switch rec.Direction {
+		case DIRECTION_READ:
+			read = append(read, rec.Frame...)
+		case DIRECTION_WRITTEN:
+			written = append(written, rec.Frame...)
+		default:
+			t.Errorf("unexpected direction %q", rec.Direction)
+		}
+	}
+	if expected := append(NewCommand("ECHO", "hi"), NewCommand("PING")...); !bytes.Equal(expected, read) {
+		t.Errorf("expected %q read, got %q", expected, read)
+	}
+	if expected := "$2\r\nhi\r\n+PONG\r\n"; string(written) != expected {
+		t.Errorf("expected %q written, got %q", expected, written)
+	}
+}
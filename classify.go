@@ -0,0 +1,111 @@
+package resp
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// IsReadOnly returns true if the named command only reads data. Read-only
+// commands can be served by replicas.
+func IsReadOnly(name string) bool {
+	return hasFlags(name, FLAG_READONLY)
+}
+
+// IsWrite returns true if the named command may modify data.
+func IsWrite(name string) bool {
+	return hasFlags(name, FLAG_WRITE)
+}
+
+// IsBlocking returns true if the named command may block the connection
+// waiting for data. See BlockingTimeout for commands that only block when
+// given certain arguments.
+func IsBlocking(name string) bool {
+	return hasFlags(name, FLAG_BLOCKING)
+}
+
+// BlockingTimeout determines whether the named command will block given its
+// arguments (not including the command name itself) and, if so, for how long.
+// A blocking command with a timeout of 0 blocks indefinitely. Besides the
+// commands for which IsBlocking is true, it handles XREAD and XREADGROUP,
+// which only block when given BLOCK, and WAIT and WAITAOF. It returns
+// ErrInvalidArguments if the timeout argument is invalid.
+func BlockingTimeout(name string, args [][]byte) (timeout time.Duration, blocking bool, err error) {
+	info := lookupCommandInfo([]byte(name))
+	if info == nil {
+		return 0, false, nil
+	}
+
+	switch info.Name {
+	case "BLPOP", "BRPOP", "BZPOPMIN", "BZPOPMAX", "BRPOPLPUSH", "BLMOVE":
+		// The timeout is the final argument, in seconds
+		if len(args) == 0 {
+			return 0, true, ErrInvalidArguments
+		}
+		return secondsTimeout(args[len(args)-1])
+	case "BLMPOP", "BZMPOP":
+		// BLMPOP timeout numkeys key [key ...] ...
+		if len(args) == 0 {
+			return 0, true, ErrInvalidArguments
+		}
+		return secondsTimeout(args[0])
+	case "XREAD", "XREADGROUP":
+		for i := 0; i < len(args)-1; i++ {
+			if CommandEquals(args[i], "STREAMS") {
+				break
+			}
+			if CommandEquals(args[i], "BLOCK") {
+				return millisecondsTimeout(args[i+1])
+			}
+		}
+		return 0, false, nil
+	case "WAIT", "WAITAOF":
+		// The timeout is the final argument, in milliseconds
+		if len(args) == 0 {
+			return 0, true, ErrInvalidArguments
+		}
+		return millisecondsTimeout(args[len(args)-1])
+	}
+
+	return 0, info.Is(FLAG_BLOCKING), nil
+}
+
+func secondsTimeout(arg []byte) (time.Duration, bool, error) {
+	seconds, err := strconv.ParseFloat(string(arg), 64)
+	// Like Redis, reject NaN and infinity, and timeouts too long to represent
+	if err != nil || !(seconds >= 0 && seconds < math.MaxInt64/float64(time.Second)) {
+		return 0, true, ErrInvalidArguments
+	}
+	return time.Duration(seconds * float64(time.Second)), true, nil
+}
+
+func millisecondsTimeout(arg []byte) (time.Duration, bool, error) {
+	ms, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil || ms < 0 || ms > math.MaxInt64/int64(time.Millisecond) {
+		return 0, true, ErrInvalidArguments
+	}
+	return time.Duration(ms) * time.Millisecond, true, nil
+}
+
+// IsPubSub returns true if the named command is a pub/sub command.
+func IsPubSub(name string) bool {
+	return hasFlags(name, FLAG_PUBSUB)
+}
+
+// IsTransaction returns true if the named command is MULTI, EXEC, DISCARD,
+// WATCH, or UNWATCH.
+func IsTransaction(name string) bool {
+	return hasFlags(name, FLAG_TRANSACTION)
+}
+
+// IsAdmin returns true if the named command is an administrative command.
+func IsAdmin(name string) bool {
+	return hasFlags(name, FLAG_ADMIN)
+}
+
+// hasFlags returns true if the named command is known and has all of the
+// given flags. Classification can be changed with RegisterCommand.
+func hasFlags(name string, flags CommandFlags) bool {
+	info := lookupCommandInfo([]byte(name))
+	return info != nil && info.Is(flags)
+}
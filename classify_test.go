@@ -0,0 +1,141 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassification(t *testing.T) {
+	if !IsReadOnly("get") || IsWrite("get") {
+		t.Errorf("expected GET to be read-only")
+	}
+	if !IsWrite("SET") || IsReadOnly("SET") {
+		t.Errorf("expected SET to be a write")
+	}
+	if !IsBlocking("brpoplpush") {
+		t.Errorf("expected BRPOPLPUSH to be blocking")
+	}
+	if !IsPubSub("subscribe") {
+		t.Errorf("expected SUBSCRIBE to be pub/sub")
+	}
+	if !IsTransaction("multi") || !IsTransaction("WATCH") {
+		t.Errorf("expected MULTI and WATCH to be transaction commands")
+	}
+	if !IsAdmin("monitor") {
+		t.Errorf("expected MONITOR to be admin")
+	}
+	if IsReadOnly("nope") || IsWrite("nope") {
+		t.Errorf("expected unknown commands not to be classified")
+	}
+}
+
+// restoreCommandTable restores the command table, as it is when called, when
+// the test ends.
+func restoreCommandTable(t *testing.T) {
+	index := commandIndex
+	infos := map[*CommandInfo]CommandInfo{}
+	for _, buckets := range commandIndex {
+		for _, bucket := range buckets {
+			for _, info := range bucket {
+				infos[info] = *info
+			}
+		}
+	}
+	t.Cleanup(func() {
+		commandIndex = index
+		for info, saved := range infos {
+			*info = saved
+		}
+	})
+}
+
+func TestRegisterCommand(t *testing.T) {
+	restoreCommandTable(t)
+	RegisterCommand(CommandInfo{"myget", 2, FLAG_READONLY, 1, 1, 1})
+	if !IsReadOnly("MYGET") {
+		t.Errorf("expected registered command to be read-only")
+	}
+	keys, err := ExtractKeys("MyGet", [][]byte{[]byte("foo")})
+	if err != nil || len(keys) != 1 || string(keys[0]) != "foo" {
+		t.Errorf("expected registered command keys, got %q, %v", keys, err)
+	}
+
+	RegisterCommand(CommandInfo{"keys", 2, FLAG_READONLY | FLAG_ADMIN, 0, 0, 0})
+	if !IsAdmin("KEYS") {
+		t.Errorf("expected overridden command to be admin")
+	}
+
+	// Specs are copies
+	CommandSpec("GET").Flags = FLAG_WRITE
+	if !IsReadOnly("GET") {
+		t.Errorf("expected changing a spec not to change the table")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected a panic for an invalid name")
+		}
+	}()
+	RegisterCommand(CommandInfo{Name: "not valid"})
+}
+
+type blockingTimeoutTest struct {
+	command  string
+	timeout  time.Duration
+	blocking bool
+}
+
+func TestBlockingTimeout_Valid(t *testing.T) {
+	tests := []blockingTimeoutTest{
+		{"GET foo", 0, false},
+		{"BLPOP a b 1.5", 1500 * time.Millisecond, true},
+		{"BRPOPLPUSH a b 0", 0, true},
+		{"BLMOVE a b LEFT RIGHT 2", 2 * time.Second, true},
+		{"BLMPOP 3 2 a b LEFT", 3 * time.Second, true},
+		{"XREAD COUNT 1 STREAMS s 0", 0, false},
+		{"XREAD BLOCK 250 STREAMS s $", 250 * time.Millisecond, true},
+		{"XREADGROUP GROUP g c BLOCK 0 STREAMS s >", 0, true},
+		{"WAIT 1 100", 100 * time.Millisecond, true},
+		{"WAITAOF 1 0 0", 0, true},
+	}
+
+	for i, test := range tests {
+		parts := strings.Split(test.command, " ")
+		args := make([][]byte, len(parts)-1)
+		for j, part := range parts[1:] {
+			args[j] = []byte(part)
+		}
+		timeout, blocking, err := BlockingTimeout(parts[0], args)
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err.Error())
+		} else if timeout != test.timeout || blocking != test.blocking {
+			t.Errorf("tests[%d]: expected: %v %v, got: %v %v", i, test.timeout, test.blocking, timeout, blocking)
+		}
+	}
+}
+
+func TestBlockingTimeout_Invalid(t *testing.T) {
+	tests := []string{
+		"BLPOP a nope",
+		"BLPOP a -1",
+		"BLPOP a nan",
+		"BLPOP a inf",
+		"BLPOP a +Inf",
+		"BLPOP a 1e300",
+		"XREAD BLOCK x STREAMS s 0",
+		"XREAD BLOCK 9223372036854775807 STREAMS s 0",
+	}
+
+	for i, test := range tests {
+		parts := strings.Split(test, " ")
+		args := make([][]byte, len(parts)-1)
+		for j, part := range parts[1:] {
+			args[j] = []byte(part)
+		}
+		_, _, err := BlockingTimeout(parts[0], args)
+		if err == nil {
+			t.Errorf("tests[%d]: expected an error but didn't get one", i)
+		}
+	}
+}
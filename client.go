@@ -0,0 +1,113 @@
+package resp
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A ClientInfo is a connection described by CLIENT LIST or CLIENT INFO.
+// Fields holds every field of the connection, including the ones decoded into
+// the other members.
+type ClientInfo struct {
+	ID    int64
+	Addr  string
+	LAddr string
+	Name  string
+	Age   time.Duration
+	Idle  time.Duration
+	// Flags are the connection's flag letters, e.g. "N" for a normal
+	// client, "S" for a replica, or "x" for one in a MULTI block.
+	Flags string
+	DB    int
+	// RESP is the protocol version the client uses, 2 or 3.
+	RESP int
+	// Cmd is the last command the client ran, with subcommands after a
+	// "|", e.g. "client|list".
+	Cmd    string
+	User   string
+	Fields map[string]string
+}
+
+// HasFlag returns true if the connection has the given flag.
+func (c ClientInfo) HasFlag(flag byte) bool {
+	return strings.IndexByte(c.Flags, flag) >= 0
+}
+
+// ParseClientList decodes a CLIENT LIST reply, a bulk string or, from RESP3
+// servers, a verbatim string with a line per connection. An Error reply is
+// returned as an error, and other replies as ErrUnexpectedReply.
+func ParseClientList(obj Object) ([]ClientInfo, error) {
+	text, err := objectText(obj)
+	if err != nil {
+		return nil, err
+	}
+	clients := []ClientInfo{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		client, err := ParseClientLine(line)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// ParseClientInfo decodes a CLIENT INFO reply, which describes the connection
+// it was sent on like a line of CLIENT LIST.
+func ParseClientInfo(obj Object) (ClientInfo, error) {
+	text, err := objectText(obj)
+	if err != nil {
+		return ClientInfo{}, err
+	}
+	return ParseClientLine(strings.TrimRight(text, "\r\n"))
+}
+
+// ParseClientLine decodes a line of CLIENT LIST output, made of space
+// separated "name=value" fields, such as:
+//
+//	id=3 addr=127.0.0.1:52555 laddr=127.0.0.1:6379 fd=8 name= age=2 idle=0 flags=N db=0 resp=2 cmd=client|list user=default
+//
+// Missing fields are left zero. It returns ErrUnexpectedReply if a field isn't
+// a "name=value" pair or a numeric field isn't a number.
+func ParseClientLine(line string) (ClientInfo, error) {
+	fields := strings.Fields(line)
+	client := ClientInfo{Fields: make(map[string]string, len(fields))}
+	for _, field := range fields {
+		eq := strings.IndexByte(field, '=')
+		if eq < 0 {
+			return ClientInfo{}, ErrUnexpectedReply
+		}
+		client.Fields[field[:eq]] = field[eq+1:]
+	}
+
+	client.Addr = client.Fields["addr"]
+	client.LAddr = client.Fields["laddr"]
+	client.Name = client.Fields["name"]
+	client.Flags = client.Fields["flags"]
+	client.Cmd = client.Fields["cmd"]
+	client.User = client.Fields["user"]
+
+	var ints [5]int64
+	for i, name := range []string{"id", "age", "idle", "db", "resp"} {
+		value, ok := client.Fields[name]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return ClientInfo{}, ErrUnexpectedReply
+		}
+		ints[i] = n
+	}
+	client.ID = ints[0]
+	client.Age = time.Duration(ints[1]) * time.Second
+	client.Idle = time.Duration(ints[2]) * time.Second
+	client.DB = int(ints[3])
+	client.RESP = int(ints[4])
+	return client, nil
+}
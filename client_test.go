@@ -0,0 +1,70 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseClientList(t *testing.T) {
+	list := "id=3 addr=127.0.0.1:52555 laddr=127.0.0.1:6379 fd=8 name=worker age=120 idle=5 flags=N db=2 resp=3 cmd=client|list user=default\n" +
+		"id=4 addr=/tmp/redis.sock:0 laddr=/tmp/redis.sock fd=9 name= age=1 idle=1 flags=Sx db=0 resp=2 cmd=psync user=repl\n"
+	for _, obj := range []Object{
+		NewBulkString(list),
+		VerbatimString(appendBlob(nil, VERBATIM_STRING_PREFIX, []byte("txt:"+list))),
+	} {
+		clients, err := ParseClientList(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(clients) != 2 {
+			t.Fatalf("expected 2 clients, got %+v", clients)
+		}
+		c := clients[0]
+		expected := ClientInfo{
+			ID:    3,
+			Addr:  "127.0.0.1:52555",
+			LAddr: "127.0.0.1:6379",
+			Name:  "worker",
+			Age:   120 * time.Second,
+			Idle:  5 * time.Second,
+			Flags: "N",
+			DB:    2,
+			RESP:  3,
+			Cmd:   "client|list",
+			User:  "default",
+		}
+		expected.Fields = c.Fields
+		if !reflect.DeepEqual(expected, c) {
+			t.Errorf("expected %+v, got %+v", expected, c)
+		}
+		if c.Fields["fd"] != "8" || len(c.Fields) != 12 {
+			t.Errorf("unexpected fields %v", c.Fields)
+		}
+		if !clients[1].HasFlag('S') || !clients[1].HasFlag('x') || clients[1].HasFlag('N') || clients[1].Name != "" {
+			t.Errorf("unexpected client %+v", clients[1])
+		}
+	}
+
+	if clients, err := ParseClientList(NewBulkString("")); err != nil || len(clients) != 0 {
+		t.Errorf("expected no clients, got %v, %v", clients, err)
+	}
+	for _, obj := range []Object{NewBulkString("id=x"), NewBulkString("id=1 junk"), NewInteger(1)} {
+		if _, err := ParseClientList(obj); err != ErrUnexpectedReply {
+			t.Errorf("expected ErrUnexpectedReply for %q, got %v", obj.Raw(), err)
+		}
+	}
+}
+
+func TestParseClientInfo(t *testing.T) {
+	c, err := ParseClientInfo(NewBulkString("id=7 addr=10.0.0.1:4000 age=0 idle=0 flags=N db=0 resp=2 cmd=client|info\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != 7 || c.Addr != "10.0.0.1:4000" || c.Cmd != "client|info" || c.RESP != 2 {
+		t.Errorf("unexpected client %+v", c)
+	}
+	if _, err := ParseClientInfo(NewError("ERR nope")); err == nil || err.Error() != "ERR nope" {
+		t.Errorf("expected the error reply, got %v", err)
+	}
+}
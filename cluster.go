@@ -0,0 +1,385 @@
+package resp
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// A SlotNode is a node serving a slot range in a CLUSTER SLOTS reply. ID and
+// Hostname are only given by newer versions of Redis. IP is "?" when the node
+// doesn't know its own preferred endpoint, and empty when the reply gave a
+// null endpoint, which stands for the host the request was sent to.
+type SlotNode struct {
+	IP       string
+	Port     int
+	ID       string
+	Hostname string
+}
+
+// Addr returns the node's address in host:port form.
+func (n SlotNode) Addr() string {
+	return net.JoinHostPort(n.IP, strconv.Itoa(n.Port))
+}
+
+// A SlotRange is a range of slots and the nodes serving them, from a CLUSTER
+// SLOTS reply. Start and End are inclusive.
+type SlotRange struct {
+	Start    uint16
+	End      uint16
+	Master   SlotNode
+	Replicas []SlotNode
+}
+
+// ParseClusterSlots decodes a CLUSTER SLOTS reply. It returns
+// ErrUnexpectedReply if obj isn't shaped like a CLUSTER SLOTS reply.
+func ParseClusterSlots(obj Object) ([]SlotRange, error) {
+	entries, ok := objectArray(obj)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+
+	ranges := make([]SlotRange, len(entries))
+	for i, entry := range entries {
+		fields, ok := objectArray(entry)
+		if !ok || len(fields) < 3 {
+			return nil, ErrUnexpectedReply
+		}
+		start, ok1 := objectInt(fields[0])
+		end, ok2 := objectInt(fields[1])
+		if !ok1 || !ok2 || start < 0 || end < start || end >= SLOT_COUNT {
+			return nil, ErrUnexpectedReply
+		}
+
+		nodes := make([]SlotNode, len(fields)-2)
+		for j, field := range fields[2:] {
+			node, ok := parseSlotNode(field)
+			if !ok {
+				return nil, ErrUnexpectedReply
+			}
+			nodes[j] = node
+		}
+
+		ranges[i] = SlotRange{uint16(start), uint16(end), nodes[0], nodes[1:]}
+	}
+
+	return ranges, nil
+}
+
+// parseSlotNode decodes [ip, port, id, metadata] where id and metadata are
+// optional and metadata is a flat list of key/value pairs.
+func parseSlotNode(obj Object) (SlotNode, bool) {
+	fields, ok := objectArray(obj)
+	if !ok || len(fields) < 2 {
+		return SlotNode{}, false
+	}
+
+	var node SlotNode
+	ok1 := objectIsNull(fields[0])
+	if !ok1 {
+		node.IP, ok1 = objectString(fields[0])
+	}
+	port, ok2 := objectInt(fields[1])
+	if !ok1 || !ok2 {
+		return SlotNode{}, false
+	}
+	node.Port = int(port)
+
+	if len(fields) > 2 {
+		node.ID, _ = objectString(fields[2])
+	}
+	if len(fields) > 3 {
+		metadata, _ := objectArray(fields[3])
+		for i := 0; i+1 < len(metadata); i += 2 {
+			if key, _ := objectString(metadata[i]); key == "hostname" {
+				node.Hostname, _ = objectString(metadata[i+1])
+			}
+		}
+	}
+
+	return node, true
+}
+
+// A SlotSpan is an inclusive range of slots.
+type SlotSpan struct {
+	Start uint16
+	End   uint16
+}
+
+// A ShardNode is a node in a CLUSTER SHARDS reply. Optional fields that the
+// node didn't report are left empty.
+type ShardNode struct {
+	ID                string
+	Port              int
+	TLSPort           int
+	IP                string
+	Endpoint          string
+	Hostname          string
+	Role              string
+	ReplicationOffset int64
+	Health            string
+}
+
+// Addr returns the node's preferred address in host:port form, falling back to
+// its IP if it has no known endpoint and to its TLS port if it has no plain
+// text port.
+func (n ShardNode) Addr() string {
+	host := n.Endpoint
+	if host == "" || host == "?" {
+		host = n.IP
+	}
+	port := n.Port
+	if port == 0 {
+		port = n.TLSPort
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// A Shard is a shard from a CLUSTER SHARDS reply: the slots it serves and its
+// master and replica nodes.
+type Shard struct {
+	Slots []SlotSpan
+	Nodes []ShardNode
+}
+
+// Master returns the shard's master node, if it has one.
+func (s Shard) Master() (ShardNode, bool) {
+	for _, node := range s.Nodes {
+		if node.Role == "master" {
+			return node, true
+		}
+	}
+	return ShardNode{}, false
+}
+
+// ParseClusterShards decodes a CLUSTER SHARDS reply. Unknown fields are
+// ignored. It returns ErrUnexpectedReply if obj isn't shaped like a CLUSTER
+// SHARDS reply.
+func ParseClusterShards(obj Object) ([]Shard, error) {
+	entries, ok := objectArray(obj)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+
+	shards := make([]Shard, len(entries))
+	for i, entry := range entries {
+		pairs, ok := objectPairs(entry)
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		for j := 0; j < len(pairs); j += 2 {
+			key, _ := objectString(pairs[j])
+			switch key {
+			case "slots":
+				shards[i].Slots, ok = parseSlotSpans(pairs[j+1])
+			case "nodes":
+				shards[i].Nodes, ok = parseShardNodes(pairs[j+1])
+			}
+			if !ok {
+				return nil, ErrUnexpectedReply
+			}
+		}
+	}
+
+	return shards, nil
+}
+
+// parseSlotSpans decodes a flat list of start and end slots.
+func parseSlotSpans(obj Object) ([]SlotSpan, bool) {
+	bounds, ok := objectArray(obj)
+	if !ok || len(bounds)%2 != 0 {
+		return nil, false
+	}
+
+	spans := make([]SlotSpan, len(bounds)/2)
+	for i := range spans {
+		start, ok1 := objectInt(bounds[i*2])
+		end, ok2 := objectInt(bounds[i*2+1])
+		if !ok1 || !ok2 || start < 0 || end < start || end >= SLOT_COUNT {
+			return nil, false
+		}
+		spans[i] = SlotSpan{uint16(start), uint16(end)}
+	}
+	return spans, true
+}
+
+func parseShardNodes(obj Object) ([]ShardNode, bool) {
+	entries, ok := objectArray(obj)
+	if !ok {
+		return nil, false
+	}
+
+	nodes := make([]ShardNode, len(entries))
+	for i, entry := range entries {
+		pairs, ok := objectPairs(entry)
+		if !ok {
+			return nil, false
+		}
+		node := &nodes[i]
+		for j := 0; j < len(pairs); j += 2 {
+			key, _ := objectString(pairs[j])
+			value := pairs[j+1]
+			switch key {
+			case "id":
+				node.ID, _ = objectString(value)
+			case "port":
+				port, _ := objectInt(value)
+				node.Port = int(port)
+			case "tls-port":
+				port, _ := objectInt(value)
+				node.TLSPort = int(port)
+			case "ip":
+				node.IP, _ = objectString(value)
+			case "endpoint":
+				node.Endpoint, _ = objectString(value)
+			case "hostname":
+				node.Hostname, _ = objectString(value)
+			case "role":
+				node.Role, _ = objectString(value)
+			case "replication-offset":
+				node.ReplicationOffset, _ = objectInt(value)
+			case "health":
+				node.Health, _ = objectString(value)
+			}
+		}
+	}
+	return nodes, true
+}
+
+// A ClusterNode is a node from a CLUSTER NODES reply. MasterID is empty for
+// masters. Migrating maps slots being migrated away from the node to the ID of
+// the destination node and Importing maps slots being imported to the node to
+// the ID of the source node.
+type ClusterNode struct {
+	ID          string
+	Addr        string
+	BusPort     int
+	Hostname    string
+	Flags       []string
+	MasterID    string
+	PingSent    int64
+	PongRecv    int64
+	ConfigEpoch int64
+	LinkState   string
+	Slots       []SlotSpan
+	Migrating   map[uint16]string
+	Importing   map[uint16]string
+}
+
+// HasFlag returns true if the node has the given flag, e.g. "master",
+// "myself", or "fail?".
+func (n ClusterNode) HasFlag(flag string) bool {
+	for _, f := range n.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClusterNodes parses the text of a CLUSTER NODES reply (the contents of
+// the bulk string) into nodes. It returns ErrUnexpectedReply if a line is
+// invalid.
+func ParseClusterNodes(text []byte) ([]ClusterNode, error) {
+	var nodes []ClusterNode
+	for _, line := range bytes.Split(text, []byte("\n")) {
+		fields := strings.Fields(string(line))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 8 {
+			return nil, ErrUnexpectedReply
+		}
+
+		node := ClusterNode{
+			ID:        fields[0],
+			Flags:     strings.Split(fields[2], ","),
+			LinkState: fields[7],
+		}
+
+		// ip:port@cport[,hostname[,aux=value...]]
+		address := strings.Split(fields[1], ",")
+		node.Addr = address[0]
+		if at := strings.IndexByte(node.Addr, '@'); at >= 0 {
+			busPort, err := strconv.Atoi(node.Addr[at+1:])
+			if err != nil {
+				return nil, ErrUnexpectedReply
+			}
+			node.Addr, node.BusPort = node.Addr[:at], busPort
+		}
+		if len(address) > 1 && !strings.Contains(address[1], "=") {
+			node.Hostname = address[1]
+		}
+
+		if fields[3] != "-" {
+			node.MasterID = fields[3]
+		}
+
+		var err1, err2, err3 error
+		node.PingSent, err1 = strconv.ParseInt(fields[4], 10, 64)
+		node.PongRecv, err2 = strconv.ParseInt(fields[5], 10, 64)
+		node.ConfigEpoch, err3 = strconv.ParseInt(fields[6], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, ErrUnexpectedReply
+		}
+
+		for _, field := range fields[8:] {
+			if !parseClusterNodesSlot(&node, field) {
+				return nil, ErrUnexpectedReply
+			}
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// parseClusterNodesSlot parses one of "<slot>", "<start>-<end>",
+// "[<slot>->-<node id>]" (migrating), or "[<slot>-<-<node id>]" (importing).
+func parseClusterNodesSlot(node *ClusterNode, field string) bool {
+	if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+		field = field[1 : len(field)-1]
+		var target *map[uint16]string
+		var parts []string
+		if parts = strings.SplitN(field, "->-", 2); len(parts) == 2 {
+			target = &node.Migrating
+		} else if parts = strings.SplitN(field, "-<-", 2); len(parts) == 2 {
+			target = &node.Importing
+		} else {
+			return false
+		}
+		slot, ok := parseSlot(parts[0])
+		if !ok {
+			return false
+		}
+		if *target == nil {
+			*target = map[uint16]string{}
+		}
+		(*target)[slot] = parts[1]
+		return true
+	}
+
+	bounds := strings.SplitN(field, "-", 2)
+	start, ok := parseSlot(bounds[0])
+	if !ok {
+		return false
+	}
+	end := start
+	if len(bounds) == 2 {
+		if end, ok = parseSlot(bounds[1]); !ok || end < start {
+			return false
+		}
+	}
+	node.Slots = append(node.Slots, SlotSpan{start, end})
+	return true
+}
+
+func parseSlot(s string) (uint16, bool) {
+	slot, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || slot >= SLOT_COUNT {
+		return 0, false
+	}
+	return uint16(slot), true
+}
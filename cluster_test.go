@@ -0,0 +1,172 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClusterSlots(t *testing.T) {
+	reply := NewArray(
+		NewArray(
+			NewInteger(0), NewInteger(5460),
+			NewArray(NewBulkString("127.0.0.1"), NewInteger(30001), NewBulkString("09dbe9720cda62f7865eabc5fd8857c5d2678366"), NewArray(NewBulkString("hostname"), NewBulkString("host-1.redis.example.com"))),
+			NewArray(NewBulkString("127.0.0.1"), NewInteger(30004), NewBulkString("821d8ca00d7ccf931ed3ffc7e3db0599d2271abf"), NewArray()),
+		),
+		NewArray(
+			NewInteger(5461), NewInteger(10922),
+			NewArray(NewBulkString("127.0.0.1"), NewInteger(30002)),
+		),
+		NewArray(
+			NewInteger(10923), NewInteger(16383),
+			NewArray(String("$-1\r\n"), NewInteger(30003)),
+		),
+	)
+
+	ranges, err := ParseClusterSlots(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []SlotRange{
+		{
+			0, 5460,
+			SlotNode{"127.0.0.1", 30001, "09dbe9720cda62f7865eabc5fd8857c5d2678366", "host-1.redis.example.com"},
+			[]SlotNode{{"127.0.0.1", 30004, "821d8ca00d7ccf931ed3ffc7e3db0599d2271abf", ""}},
+		},
+		{
+			5461, 10922,
+			SlotNode{"127.0.0.1", 30002, "", ""},
+			[]SlotNode{},
+		},
+		{
+			10923, 16383,
+			SlotNode{"", 30003, "", ""},
+			[]SlotNode{},
+		},
+	}
+	if !reflect.DeepEqual(expected, ranges) {
+		t.Errorf("expected: %v\ngot: %v", expected, ranges)
+	}
+	if addr := ranges[0].Master.Addr(); addr != "127.0.0.1:30001" {
+		t.Errorf("unexpected address: %s", addr)
+	}
+
+	invalid := []Object{
+		NewError("ERR This instance has cluster support disabled"),
+		NewArray(NewArray(NewInteger(0), NewInteger(1))),
+		NewArray(NewArray(NewInteger(10), NewInteger(1), NewArray(NewBulkString("h"), NewInteger(1)))),
+		NewArray(NewArray(NewInteger(0), NewInteger(1), NewArray(NewBulkString("h")))),
+	}
+	for i, test := range invalid {
+		if _, err := ParseClusterSlots(test); err != ErrUnexpectedReply {
+			t.Errorf("invalid[%d]: expected ErrUnexpectedReply, got %v", i, err)
+		}
+	}
+}
+
+func TestParseClusterShards(t *testing.T) {
+	node := func(id string, port int64, role string, offset int64) Object {
+		return NewArray(
+			NewBulkString("id"), NewBulkString(id),
+			NewBulkString("port"), NewInteger(port),
+			NewBulkString("ip"), NewBulkString("127.0.0.1"),
+			NewBulkString("endpoint"), NewBulkString("127.0.0.1"),
+			NewBulkString("role"), NewBulkString(role),
+			NewBulkString("replication-offset"), NewInteger(offset),
+			NewBulkString("health"), NewBulkString("online"),
+		)
+	}
+	reply := NewArray(
+		NewArray(
+			NewBulkString("slots"), NewArray(NewInteger(0), NewInteger(5460), NewInteger(10923), NewInteger(10923)),
+			NewBulkString("nodes"), NewArray(node("a", 30001, "master", 72156), node("b", 30004, "replica", 72156)),
+		),
+	)
+
+	shards, err := ParseClusterShards(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Shard{{
+		Slots: []SlotSpan{{0, 5460}, {10923, 10923}},
+		Nodes: []ShardNode{
+			{ID: "a", Port: 30001, IP: "127.0.0.1", Endpoint: "127.0.0.1", Role: "master", ReplicationOffset: 72156, Health: "online"},
+			{ID: "b", Port: 30004, IP: "127.0.0.1", Endpoint: "127.0.0.1", Role: "replica", ReplicationOffset: 72156, Health: "online"},
+		},
+	}}
+	if !reflect.DeepEqual(expected, shards) {
+		t.Errorf("expected: %v\ngot: %v", expected, shards)
+	}
+
+	master, ok := shards[0].Master()
+	if !ok || master.Addr() != "127.0.0.1:30001" {
+		t.Errorf("unexpected master: %v", master)
+	}
+	if addr := (ShardNode{IP: "10.0.0.1", Endpoint: "?", TLSPort: 6380}).Addr(); addr != "10.0.0.1:6380" {
+		t.Errorf("unexpected address: %s", addr)
+	}
+
+	invalid := []Object{
+		NewError("ERR oops"),
+		NewArray(NewArray(NewBulkString("slots"))),
+		NewArray(NewArray(NewBulkString("slots"), NewArray(NewInteger(1)))),
+	}
+	for i, test := range invalid {
+		if _, err := ParseClusterShards(test); err != ErrUnexpectedReply {
+			t.Errorf("invalid[%d]: expected ErrUnexpectedReply, got %v", i, err)
+		}
+	}
+}
+
+func TestParseClusterNodes(t *testing.T) {
+	text := []byte("07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004,host-4 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected\n" +
+		"e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001 myself,master - 0 0 1 connected 0-5460 5462 [5461->-67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1] [5463-<-292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f]\n" +
+		"\n")
+
+	nodes, err := ParseClusterNodes(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ClusterNode{
+		{
+			ID:          "07c37dfeb235213a872192d90877d0cd55635b91",
+			Addr:        "127.0.0.1:30004",
+			BusPort:     31004,
+			Hostname:    "host-4",
+			Flags:       []string{"slave"},
+			MasterID:    "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca",
+			PongRecv:    1426238317239,
+			ConfigEpoch: 4,
+			LinkState:   "connected",
+		},
+		{
+			ID:          "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca",
+			Addr:        "127.0.0.1:30001",
+			BusPort:     31001,
+			Flags:       []string{"myself", "master"},
+			ConfigEpoch: 1,
+			LinkState:   "connected",
+			Slots:       []SlotSpan{{0, 5460}, {5462, 5462}},
+			Migrating:   map[uint16]string{5461: "67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1"},
+			Importing:   map[uint16]string{5463: "292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f"},
+		},
+	}
+	if !reflect.DeepEqual(expected, nodes) {
+		t.Errorf("expected: %+v\ngot: %+v", expected, nodes)
+	}
+	if !nodes[1].HasFlag("myself") || nodes[0].HasFlag("master") {
+		t.Errorf("unexpected flags")
+	}
+
+	invalid := []string{
+		"abc 127.0.0.1:30001@31001 master - 0 0",
+		"abc 127.0.0.1:30001@31001 master - 0 0 1 connected 16384",
+		"abc 127.0.0.1:30001@31001 master - 0 0 1 connected 10-5",
+		"abc 127.0.0.1:30001@31001 master - 0 0 1 connected [1=abc]",
+		"abc 127.0.0.1:30001@x master - 0 0 1 connected",
+	}
+	for i, test := range invalid {
+		if _, err := ParseClusterNodes([]byte(test)); err != ErrUnexpectedReply {
+			t.Errorf("invalid[%d]: expected ErrUnexpectedReply, got %v", i, err)
+		}
+	}
+}
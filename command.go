@@ -3,6 +3,7 @@ package resp
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 )
 
 // A Command contains a RESP array of bulk strings comprising the raw command
@@ -22,8 +23,105 @@ func NewCommand(args ...string) Command {
 	return buf.Bytes()
 }
 
+// newCommand returns a Command for the given command name and arguments.
+func newCommand(name string, args [][]byte) Command {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(name), name)
+
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n", len(arg))
+		buf.Write(arg)
+		buf.Write(lineSuffix)
+	}
+
+	return buf.Bytes()
+}
+
+// FormatCommand returns a Command for the given command name and arguments.
+// Strings and byte slices are sent as-is, integers and floats in decimal,
+// bools as 1 or 0, nil as an empty string, and any other value as formatted
+// by fmt.Sprint.
+func FormatCommand(name string, args ...interface{}) Command {
+	buf := make([]byte, 0, 16+len(name)+16*len(args))
+	buf = append(buf, ARRAY_PREFIX)
+	buf = AppendInt(buf, int64(len(args)+1))
+	buf = append(buf, lineSuffix...)
+	buf = appendBulkString(buf, name)
+
+	var scratch [64]byte
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case string:
+			buf = appendBulkString(buf, a)
+		case []byte:
+			buf = appendBulkBytes(buf, a)
+		case int:
+			buf = appendBulkBytes(buf, AppendInt(scratch[:0], int64(a)))
+		case int64:
+			buf = appendBulkBytes(buf, AppendInt(scratch[:0], a))
+		case uint64:
+			buf = appendBulkBytes(buf, strconv.AppendUint(scratch[:0], a, 10))
+		case float64:
+			buf = appendBulkBytes(buf, strconv.AppendFloat(scratch[:0], a, 'g', -1, 64))
+		case bool:
+			if a {
+				buf = appendBulkString(buf, "1")
+			} else {
+				buf = appendBulkString(buf, "0")
+			}
+		case nil:
+			buf = appendBulkString(buf, "")
+		default:
+			buf = appendBulkString(buf, fmt.Sprint(a))
+		}
+	}
+	return buf
+}
+
+func appendBulkString(buf []byte, s string) []byte {
+	buf = append(buf, BULK_STRING_PREFIX)
+	buf = AppendInt(buf, int64(len(s)))
+	buf = append(buf, lineSuffix...)
+	buf = append(buf, s...)
+	return append(buf, lineSuffix...)
+}
+
+func appendBulkBytes(buf []byte, b []byte) []byte {
+	buf = append(buf, BULK_STRING_PREFIX)
+	buf = AppendInt(buf, int64(len(b)))
+	buf = append(buf, lineSuffix...)
+	buf = append(buf, b...)
+	return append(buf, lineSuffix...)
+}
+
 func (c Command) Raw() []byte { return c }
 
+// ParseCommand validates that frame is a RESP array of bulk strings and splits
+// it into the command name and its arguments. The arguments point into frame,
+// and so does the name in builds with the resp_unsafe tag. It returns a
+// ErrSyntaxError error if frame isn't a valid command.
+func ParseCommand(frame []byte) (name string, args [][]byte, err error) {
+	slices, err := Command(frame).Slices()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(slices) == 0 {
+		return "", nil, ErrSyntaxError
+	}
+	return bytesToString(slices[0]), slices[1:], nil
+}
+
+// ParseCommandObject is the same as ParseCommand except that it takes a RESP
+// object, such as one returned by Reader.ReadObject.
+func ParseCommandObject(obj Object) (name string, args [][]byte, err error) {
+	switch obj.(type) {
+	case Command, Array:
+		return ParseCommand(obj.Raw())
+	default:
+		return "", nil, ErrSyntaxError
+	}
+}
+
 // Slices returns a slice of byte slices that point to each argument in this
 // Command. It returns a ErrSyntaxError error if the command RESP bytes are
 // invalid.
@@ -43,7 +141,7 @@ func (c Command) Slices() ([][]byte, error) {
 	var end, length int
 	for i, _ := range args {
 		cursor += 1
-		if cursor >= len(c) {
+		if cursor >= len(c) || c[cursor] != BULK_STRING_PREFIX {
 			return nil, ErrSyntaxError
 		}
 
@@ -0,0 +1,251 @@
+package resp
+
+// commandTable mirrors the output of Redis 7's COMMAND INFO for every
+// top-level command: name, arity, flags, and the legacy first key, last key,
+// and key step. Container commands (CLIENT, CONFIG, ...) are listed without
+// flags because their flags depend on the subcommand. FLAG_TRANSACTION isn't a
+// Redis flag; it's added here to mark the commands that make up transactions.
+// The table is generated from a live server by gen_command_table.go.
+var commandTable = []CommandInfo{
+	{"ACL", -2, 0, 0, 0, 0},
+	{"APPEND", 3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"ASKING", 1, FLAG_FAST, 0, 0, 0},
+	{"AUTH", -2, FLAG_NOSCRIPT | FLAG_FAST, 0, 0, 0},
+	{"BGREWRITEAOF", 1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"BGSAVE", -1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"BITCOUNT", -2, FLAG_READONLY, 1, 1, 1},
+	{"BITFIELD", -2, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"BITFIELD_RO", -2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"BITOP", -4, FLAG_WRITE | FLAG_DENYOOM, 2, -1, 1},
+	{"BITPOS", -3, FLAG_READONLY, 1, 1, 1},
+	{"BLMOVE", 6, FLAG_WRITE | FLAG_DENYOOM | FLAG_BLOCKING, 1, 2, 1},
+	{"BLMPOP", -5, FLAG_WRITE | FLAG_BLOCKING | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"BLPOP", -3, FLAG_WRITE | FLAG_BLOCKING, 1, -2, 1},
+	{"BRPOP", -3, FLAG_WRITE | FLAG_BLOCKING, 1, -2, 1},
+	{"BRPOPLPUSH", 4, FLAG_WRITE | FLAG_DENYOOM | FLAG_BLOCKING, 1, 2, 1},
+	{"BZMPOP", -5, FLAG_WRITE | FLAG_BLOCKING | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"BZPOPMAX", -3, FLAG_WRITE | FLAG_BLOCKING | FLAG_FAST, 1, -2, 1},
+	{"BZPOPMIN", -3, FLAG_WRITE | FLAG_BLOCKING | FLAG_FAST, 1, -2, 1},
+	{"CLIENT", -2, 0, 0, 0, 0},
+	{"CLUSTER", -2, 0, 0, 0, 0},
+	{"COMMAND", -1, 0, 0, 0, 0},
+	{"CONFIG", -2, 0, 0, 0, 0},
+	{"COPY", -3, FLAG_WRITE | FLAG_DENYOOM, 1, 2, 1},
+	{"DBSIZE", 1, FLAG_READONLY | FLAG_FAST, 0, 0, 0},
+	{"DEBUG", -2, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"DECR", 2, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"DECRBY", 3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"DEL", -2, FLAG_WRITE, 1, -1, 1},
+	{"DISCARD", 1, FLAG_NOSCRIPT | FLAG_FAST | FLAG_TRANSACTION, 0, 0, 0},
+	{"DUMP", 2, FLAG_READONLY, 1, 1, 1},
+	{"ECHO", 2, FLAG_FAST, 0, 0, 0},
+	{"EVAL", -3, FLAG_NOSCRIPT | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"EVALSHA", -3, FLAG_NOSCRIPT | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"EVALSHA_RO", -3, FLAG_READONLY | FLAG_NOSCRIPT | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"EVAL_RO", -3, FLAG_READONLY | FLAG_NOSCRIPT | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"EXEC", 1, FLAG_NOSCRIPT | FLAG_TRANSACTION, 0, 0, 0},
+	{"EXISTS", -2, FLAG_READONLY | FLAG_FAST, 1, -1, 1},
+	{"EXPIRE", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"EXPIREAT", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"EXPIRETIME", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"FAILOVER", -1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"FCALL", -3, FLAG_NOSCRIPT | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"FCALL_RO", -3, FLAG_READONLY | FLAG_NOSCRIPT | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"FLUSHALL", -1, FLAG_WRITE, 0, 0, 0},
+	{"FLUSHDB", -1, FLAG_WRITE, 0, 0, 0},
+	{"FUNCTION", -2, 0, 0, 0, 0},
+	{"GEOADD", -5, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"GEODIST", -4, FLAG_READONLY, 1, 1, 1},
+	{"GEOHASH", -2, FLAG_READONLY, 1, 1, 1},
+	{"GEOPOS", -2, FLAG_READONLY, 1, 1, 1},
+	{"GEORADIUS", -6, FLAG_WRITE | FLAG_DENYOOM | FLAG_MOVABLEKEYS, 1, 1, 1},
+	{"GEORADIUSBYMEMBER", -5, FLAG_WRITE | FLAG_DENYOOM | FLAG_MOVABLEKEYS, 1, 1, 1},
+	{"GEORADIUSBYMEMBER_RO", -5, FLAG_READONLY, 1, 1, 1},
+	{"GEORADIUS_RO", -6, FLAG_READONLY, 1, 1, 1},
+	{"GEOSEARCH", -7, FLAG_READONLY, 1, 1, 1},
+	{"GEOSEARCHSTORE", -8, FLAG_WRITE | FLAG_DENYOOM, 1, 2, 1},
+	{"GET", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"GETBIT", 3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"GETDEL", 2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"GETEX", -2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"GETRANGE", 4, FLAG_READONLY, 1, 1, 1},
+	{"GETSET", 3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"HDEL", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"HELLO", -1, FLAG_NOSCRIPT | FLAG_FAST, 0, 0, 0},
+	{"HEXISTS", 3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"HGET", 3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"HGETALL", 2, FLAG_READONLY, 1, 1, 1},
+	{"HINCRBY", 4, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"HINCRBYFLOAT", 4, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"HKEYS", 2, FLAG_READONLY, 1, 1, 1},
+	{"HLEN", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"HMGET", -3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"HMSET", -4, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"HRANDFIELD", -2, FLAG_READONLY, 1, 1, 1},
+	{"HSCAN", -3, FLAG_READONLY, 1, 1, 1},
+	{"HSET", -4, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"HSETNX", 4, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"HSTRLEN", 3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"HVALS", 2, FLAG_READONLY, 1, 1, 1},
+	{"INCR", 2, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"INCRBY", 3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"INCRBYFLOAT", 3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"INFO", -1, 0, 0, 0, 0},
+	{"KEYS", 2, FLAG_READONLY, 0, 0, 0},
+	{"LASTSAVE", 1, FLAG_FAST, 0, 0, 0},
+	{"LATENCY", -2, 0, 0, 0, 0},
+	{"LCS", -3, FLAG_READONLY, 1, 2, 1},
+	{"LINDEX", 3, FLAG_READONLY, 1, 1, 1},
+	{"LINSERT", 5, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"LLEN", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"LMOVE", 5, FLAG_WRITE | FLAG_DENYOOM, 1, 2, 1},
+	{"LMPOP", -4, FLAG_WRITE | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"LOLWUT", -1, FLAG_READONLY | FLAG_FAST, 0, 0, 0},
+	{"LPOP", -2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"LPOS", -3, FLAG_READONLY, 1, 1, 1},
+	{"LPUSH", -3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"LPUSHX", -3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"LRANGE", 4, FLAG_READONLY, 1, 1, 1},
+	{"LREM", 4, FLAG_WRITE, 1, 1, 1},
+	{"LSET", 4, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"LTRIM", 4, FLAG_WRITE, 1, 1, 1},
+	{"MEMORY", -2, 0, 0, 0, 0},
+	{"MGET", -2, FLAG_READONLY | FLAG_FAST, 1, -1, 1},
+	{"MIGRATE", -6, FLAG_WRITE | FLAG_MOVABLEKEYS, 3, 3, 1},
+	{"MODULE", -2, 0, 0, 0, 0},
+	{"MONITOR", 1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"MOVE", 3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"MSET", -3, FLAG_WRITE | FLAG_DENYOOM, 1, -1, 2},
+	{"MSETNX", -3, FLAG_WRITE | FLAG_DENYOOM, 1, -1, 2},
+	{"MULTI", 1, FLAG_NOSCRIPT | FLAG_FAST | FLAG_TRANSACTION, 0, 0, 0},
+	{"OBJECT", -2, 0, 0, 0, 0},
+	{"PERSIST", 2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"PEXPIRE", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"PEXPIREAT", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"PEXPIRETIME", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"PFADD", -2, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"PFCOUNT", -2, FLAG_READONLY, 1, -1, 1},
+	{"PFDEBUG", 3, FLAG_WRITE | FLAG_DENYOOM | FLAG_ADMIN, 2, 2, 1},
+	{"PFMERGE", -2, FLAG_WRITE | FLAG_DENYOOM, 1, -1, 1},
+	{"PFSELFTEST", 1, FLAG_ADMIN, 0, 0, 0},
+	{"PING", -1, FLAG_FAST, 0, 0, 0},
+	{"PSETEX", 4, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"PSUBSCRIBE", -2, FLAG_PUBSUB | FLAG_NOSCRIPT, 0, 0, 0},
+	{"PSYNC", -3, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"PTTL", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"PUBLISH", 3, FLAG_PUBSUB | FLAG_FAST, 0, 0, 0},
+	{"PUBSUB", -2, 0, 0, 0, 0},
+	{"PUNSUBSCRIBE", -1, FLAG_PUBSUB | FLAG_NOSCRIPT, 0, 0, 0},
+	{"QUIT", -1, FLAG_NOSCRIPT | FLAG_FAST, 0, 0, 0},
+	{"RANDOMKEY", 1, FLAG_READONLY, 0, 0, 0},
+	{"READONLY", 1, FLAG_FAST, 0, 0, 0},
+	{"READWRITE", 1, FLAG_FAST, 0, 0, 0},
+	{"RENAME", 3, FLAG_WRITE, 1, 2, 1},
+	{"RENAMENX", 3, FLAG_WRITE | FLAG_FAST, 1, 2, 1},
+	{"REPLCONF", -1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"REPLICAOF", 3, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"RESET", 1, FLAG_NOSCRIPT | FLAG_FAST, 0, 0, 0},
+	{"RESTORE", -4, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"RESTORE-ASKING", -4, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"ROLE", 1, FLAG_NOSCRIPT | FLAG_FAST, 0, 0, 0},
+	{"RPOP", -2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"RPOPLPUSH", 3, FLAG_WRITE | FLAG_DENYOOM, 1, 2, 1},
+	{"RPUSH", -3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"RPUSHX", -3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"SADD", -3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"SAVE", 1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"SCAN", -2, FLAG_READONLY, 0, 0, 0},
+	{"SCARD", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"SCRIPT", -2, 0, 0, 0, 0},
+	{"SDIFF", -2, FLAG_READONLY, 1, -1, 1},
+	{"SDIFFSTORE", -3, FLAG_WRITE | FLAG_DENYOOM, 1, -1, 1},
+	{"SELECT", 2, FLAG_FAST, 0, 0, 0},
+	{"SET", -3, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"SETBIT", 4, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"SETEX", 4, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"SETNX", 3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"SETRANGE", 4, FLAG_WRITE | FLAG_DENYOOM, 1, 1, 1},
+	{"SHUTDOWN", -1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"SINTER", -2, FLAG_READONLY, 1, -1, 1},
+	{"SINTERCARD", -3, FLAG_READONLY | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"SINTERSTORE", -3, FLAG_WRITE | FLAG_DENYOOM, 1, -1, 1},
+	{"SISMEMBER", 3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"SLAVEOF", 3, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"SLOWLOG", -2, 0, 0, 0, 0},
+	{"SMEMBERS", 2, FLAG_READONLY, 1, 1, 1},
+	{"SMISMEMBER", -3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"SMOVE", 4, FLAG_WRITE | FLAG_FAST, 1, 2, 1},
+	{"SORT", -2, FLAG_WRITE | FLAG_DENYOOM | FLAG_MOVABLEKEYS, 1, 1, 1},
+	{"SORT_RO", -2, FLAG_READONLY | FLAG_MOVABLEKEYS, 1, 1, 1},
+	{"SPOP", -2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"SPUBLISH", 3, FLAG_PUBSUB | FLAG_FAST, 1, 1, 1},
+	{"SRANDMEMBER", -2, FLAG_READONLY, 1, 1, 1},
+	{"SREM", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"SSCAN", -3, FLAG_READONLY, 1, 1, 1},
+	{"SSUBSCRIBE", -2, FLAG_PUBSUB | FLAG_NOSCRIPT, 1, -1, 1},
+	{"STRLEN", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"SUBSCRIBE", -2, FLAG_PUBSUB | FLAG_NOSCRIPT, 0, 0, 0},
+	{"SUBSTR", 4, FLAG_READONLY, 1, 1, 1},
+	{"SUNION", -2, FLAG_READONLY, 1, -1, 1},
+	{"SUNIONSTORE", -3, FLAG_WRITE | FLAG_DENYOOM, 1, -1, 1},
+	{"SUNSUBSCRIBE", -1, FLAG_PUBSUB | FLAG_NOSCRIPT, 1, -1, 1},
+	{"SWAPDB", 3, FLAG_WRITE | FLAG_FAST, 0, 0, 0},
+	{"SYNC", 1, FLAG_ADMIN | FLAG_NOSCRIPT, 0, 0, 0},
+	{"TIME", 1, FLAG_FAST, 0, 0, 0},
+	{"TOUCH", -2, FLAG_READONLY | FLAG_FAST, 1, -1, 1},
+	{"TTL", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"TYPE", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"UNLINK", -2, FLAG_WRITE | FLAG_FAST, 1, -1, 1},
+	{"UNSUBSCRIBE", -1, FLAG_PUBSUB | FLAG_NOSCRIPT, 0, 0, 0},
+	{"UNWATCH", 1, FLAG_NOSCRIPT | FLAG_FAST | FLAG_TRANSACTION, 0, 0, 0},
+	{"WAIT", 3, 0, 0, 0, 0},
+	{"WAITAOF", 4, FLAG_NOSCRIPT, 0, 0, 0},
+	{"WATCH", -2, FLAG_NOSCRIPT | FLAG_FAST | FLAG_TRANSACTION, 1, -1, 1},
+	{"XACK", -4, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"XADD", -5, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"XAUTOCLAIM", -6, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"XCLAIM", -6, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"XDEL", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"XGROUP", -2, 0, 0, 0, 0},
+	{"XINFO", -2, 0, 0, 0, 0},
+	{"XLEN", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"XPENDING", -3, FLAG_READONLY, 1, 1, 1},
+	{"XRANGE", -4, FLAG_READONLY, 1, 1, 1},
+	{"XREAD", -4, FLAG_READONLY | FLAG_BLOCKING | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"XREADGROUP", -7, FLAG_WRITE | FLAG_BLOCKING | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"XREVRANGE", -4, FLAG_READONLY, 1, 1, 1},
+	{"XSETID", -3, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"XTRIM", -4, FLAG_WRITE, 1, 1, 1},
+	{"ZADD", -4, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"ZCARD", 2, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"ZCOUNT", 4, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"ZDIFF", -3, FLAG_READONLY | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"ZDIFFSTORE", -4, FLAG_WRITE | FLAG_DENYOOM | FLAG_MOVABLEKEYS, 1, 1, 1},
+	{"ZINCRBY", 4, FLAG_WRITE | FLAG_DENYOOM | FLAG_FAST, 1, 1, 1},
+	{"ZINTER", -3, FLAG_READONLY | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"ZINTERCARD", -3, FLAG_READONLY | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"ZINTERSTORE", -4, FLAG_WRITE | FLAG_DENYOOM | FLAG_MOVABLEKEYS, 1, 1, 1},
+	{"ZLEXCOUNT", 4, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"ZMPOP", -4, FLAG_WRITE | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"ZMSCORE", -3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"ZPOPMAX", -2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"ZPOPMIN", -2, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"ZRANDMEMBER", -2, FLAG_READONLY, 1, 1, 1},
+	{"ZRANGE", -4, FLAG_READONLY, 1, 1, 1},
+	{"ZRANGEBYLEX", -4, FLAG_READONLY, 1, 1, 1},
+	{"ZRANGEBYSCORE", -4, FLAG_READONLY, 1, 1, 1},
+	{"ZRANGESTORE", -5, FLAG_WRITE | FLAG_DENYOOM, 1, 2, 1},
+	{"ZRANK", -3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"ZREM", -3, FLAG_WRITE | FLAG_FAST, 1, 1, 1},
+	{"ZREMRANGEBYLEX", 4, FLAG_WRITE, 1, 1, 1},
+	{"ZREMRANGEBYRANK", 4, FLAG_WRITE, 1, 1, 1},
+	{"ZREMRANGEBYSCORE", 4, FLAG_WRITE, 1, 1, 1},
+	{"ZREVRANGE", -4, FLAG_READONLY, 1, 1, 1},
+	{"ZREVRANGEBYLEX", -4, FLAG_READONLY, 1, 1, 1},
+	{"ZREVRANGEBYSCORE", -4, FLAG_READONLY, 1, 1, 1},
+	{"ZREVRANK", -3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"ZSCAN", -3, FLAG_READONLY, 1, 1, 1},
+	{"ZSCORE", 3, FLAG_READONLY | FLAG_FAST, 1, 1, 1},
+	{"ZUNION", -3, FLAG_READONLY | FLAG_MOVABLEKEYS, 0, 0, 0},
+	{"ZUNIONSTORE", -4, FLAG_WRITE | FLAG_DENYOOM | FLAG_MOVABLEKEYS, 1, 1, 1},
+}
@@ -62,6 +62,8 @@ func TestCommandSlice_Invalid(t *testing.T) {
 		[]byte("*1\r\n$3\r\nLOL\r\n"),
 		// bad line ending
 		[]byte("*1\r\n$4\r\nPING\r"),
+		// nested array
+		[]byte("*1\r\n*1\r\n$4\r\nPING\r\n"),
 	}
 
 	for i, test := range tests {
@@ -84,6 +86,40 @@ func TestCommandSlice_Invalid(t *testing.T) {
 	}
 }
 
+func TestParseCommand(t *testing.T) {
+	name, args, err := ParseCommand([]byte("*3\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "SET" {
+		t.Errorf("expected: SET\ngot: %s", name)
+	}
+	expected := [][]byte{[]byte("foo"), []byte("bar")}
+	if !reflect.DeepEqual(expected, args) {
+		t.Errorf("expected: %v\ngot: %v", expected, args)
+	}
+
+	name, args, err = ParseCommandObject(Array("*1\r\n$4\r\nPING\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "PING" || len(args) != 0 {
+		t.Errorf("expected PING with no args, got %s %v", name, args)
+	}
+
+	invalid := []Object{
+		NewSimpleString("PING"),
+		Array("*0\r\n$4\r\nPING\r\n"),
+		Array("*1\r\n:1234567890\r\n"),
+	}
+	for i, test := range invalid {
+		_, _, err = ParseCommandObject(test)
+		if err == nil {
+			t.Errorf("invalid[%d]: expected an error but didn't get one", i)
+		}
+	}
+}
+
 type newCommandStringsTest struct {
 	args  []string
 	bytes []byte
@@ -105,6 +141,17 @@ func TestNewCommandStrings(t *testing.T) {
 	}
 }
 
+func TestFormatCommand(t *testing.T) {
+	command := FormatCommand("SET", "k", []byte("v"), 12, int64(-3), uint64(7), 1.5, true, nil, struct{ A int }{1})
+	expected := NewCommand("SET", "k", "v", "12", "-3", "7", "1.5", "1", "", "{1}")
+	if !reflect.DeepEqual(expected, command) {
+		t.Errorf("expected: %q\ngot: %q", expected, command)
+	}
+	if command := FormatCommand("PING"); !reflect.DeepEqual(NewCommand("PING"), command) {
+		t.Errorf("unexpected command: %q", command)
+	}
+}
+
 func BenchmarkCommandSlices(b *testing.B) {
 	raw := Command("*2\r\n$4\r\nINFO\r\n$3\r\nALL\r\n")
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,191 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+//go:generate go run gen_command_table.go -o command_table.go
+
+// MAX_COMMAND_NAME_LENGTH is the maximum length of a command name in the
+// command table.
+const MAX_COMMAND_NAME_LENGTH = 20
+
+// CommandFlags describe the behavior of a command, as reported by Redis'
+// COMMAND INFO.
+type CommandFlags uint32
+
+const (
+	FLAG_WRITE CommandFlags = 1 << iota
+	FLAG_READONLY
+	FLAG_DENYOOM
+	FLAG_ADMIN
+	FLAG_PUBSUB
+	FLAG_NOSCRIPT
+	FLAG_BLOCKING
+	FLAG_FAST
+	// The command's keys can't be found with FirstKey, LastKey, and Step
+	// alone.
+	FLAG_MOVABLEKEYS
+	// The command is part of MULTI/EXEC transaction handling.
+	FLAG_TRANSACTION
+)
+
+// A CommandInfo describes a Redis command. Arity counts the command name
+// itself and is negative when it is a minimum rather than an exact count.
+// FirstKey, LastKey, and Step give the positions of the command's keys in the
+// same terms: a LastKey of -1 means the final argument and -2 means the one
+// before it. A FirstKey of 0 means the command has no keys at fixed positions.
+type CommandInfo struct {
+	Name     string
+	Arity    int
+	Flags    CommandFlags
+	FirstKey int
+	LastKey  int
+	Step     int
+}
+
+// Is returns true if the command has all of the given flags.
+func (c *CommandInfo) Is(flags CommandFlags) bool {
+	return c.Flags&flags == flags
+}
+
+// validArity returns true if argc, which counts the command name, satisfies
+// the command's arity.
+func (c *CommandInfo) validArity(argc int) bool {
+	if c.Arity < 0 {
+		return argc >= -c.Arity
+	}
+	return argc == c.Arity
+}
+
+// commandIndex buckets commandTable by name length and first letter so that
+// LookupCommand only compares against a handful of candidates.
+var commandIndex [MAX_COMMAND_NAME_LENGTH + 1][26][]*CommandInfo
+
+func init() {
+	for _, info := range commandTable {
+		RegisterCommand(info)
+	}
+}
+
+// RegisterCommand adds a command to the command table, or replaces the table's
+// entry for a known command, so that lookups, key extraction, validation, and
+// classification take it into account. It isn't safe to call RegisterCommand
+// concurrently with anything else that uses the command table, so it should
+// be called during initialization. It panics if the name isn't made up of
+// letters, digits, '_', and '-', doesn't begin with a letter, or is longer
+// than MAX_COMMAND_NAME_LENGTH.
+func RegisterCommand(info CommandInfo) {
+	name := strings.ToUpper(info.Name)
+	if len(name) == 0 || len(name) > MAX_COMMAND_NAME_LENGTH || name[0] < 'A' || name[0] > 'Z' {
+		panic("resp: invalid command name " + info.Name)
+	}
+	for _, b := range []byte(name) {
+		if (b < 'A' || b > 'Z') && (b < '0' || b > '9') && b != '_' && b != '-' {
+			panic("resp: invalid command name " + info.Name)
+		}
+	}
+	info.Name = name
+
+	if existing := lookupCommandInfo([]byte(name)); existing != nil {
+		*existing = info
+		return
+	}
+	bucket := &commandIndex[len(name)][name[0]-'A']
+	*bucket = append(*bucket, &info)
+}
+
+// CommandEquals returns true if arg is equal to upper when compared case
+// insensitively. upper must be upper case. It doesn't allocate.
+func CommandEquals(arg []byte, upper string) bool {
+	if len(arg) != len(upper) {
+		return false
+	}
+	for i, b := range arg {
+		if b >= 'a' && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+		if b != upper[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupCommand identifies the command named by arg, ignoring case, and
+// returns its canonical upper case name. It returns false if the command is
+// unknown. It doesn't allocate.
+func LookupCommand(arg []byte) (string, bool) {
+	info := lookupCommandInfo(arg)
+	if info == nil {
+		return "", false
+	}
+	return info.Name, true
+}
+
+// ValidateCommand checks the number of arguments given for the named command
+// (not including the command name itself) against the command table. If the
+// command is unknown or has the wrong number of arguments, it returns the Error
+// reply Redis would send.
+func ValidateCommand(name string, args [][]byte) error {
+	info := lookupCommandInfo([]byte(name))
+	if info == nil {
+		return unknownCommandError(name, args)
+	}
+	if !info.validArity(len(args) + 1) {
+		return wrongArityError(name)
+	}
+	return nil
+}
+
+// unknownCommandError returns the Error reply Redis sends for unknown
+// commands.
+func unknownCommandError(name string, args [][]byte) Error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "ERR unknown command '%s', with args beginning with: ", name)
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "'%s' ", arg)
+	}
+	return NewError(buf.String())
+}
+
+// wrongArityError returns the Error reply Redis sends for commands with the
+// wrong number of arguments.
+func wrongArityError(name string) Error {
+	return NewError(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+// CommandSpec returns a copy of the CommandInfo for the named command,
+// ignoring case. It returns nil if the command is unknown. Changing the copy
+// doesn't change the command table; see RegisterCommand.
+func CommandSpec(name string) *CommandInfo {
+	info := lookupCommandInfo([]byte(name))
+	if info == nil {
+		return nil
+	}
+	spec := *info
+	return &spec
+}
+
+func lookupCommandInfo(arg []byte) *CommandInfo {
+	if len(arg) == 0 || len(arg) > MAX_COMMAND_NAME_LENGTH {
+		return nil
+	}
+
+	first := arg[0]
+	if first >= 'a' && first <= 'z' {
+		first -= 'a' - 'A'
+	}
+	if first < 'A' || first > 'Z' {
+		return nil
+	}
+
+	for _, info := range commandIndex[len(arg)][first-'A'] {
+		if CommandEquals(arg, info.Name) {
+			return info
+		}
+	}
+	return nil
+}
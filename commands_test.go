@@ -0,0 +1,110 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandEquals(t *testing.T) {
+	if !CommandEquals([]byte("get"), "GET") {
+		t.Errorf("expected get to equal GET")
+	}
+	if !CommandEquals([]byte("GeT"), "GET") {
+		t.Errorf("expected GeT to equal GET")
+	}
+	if CommandEquals([]byte("GETS"), "GET") {
+		t.Errorf("expected GETS not to equal GET")
+	}
+	if CommandEquals([]byte("g{t"), "G[T") {
+		t.Errorf("expected g{t not to equal G[T")
+	}
+}
+
+type lookupCommandTest struct {
+	given    []byte
+	expected string
+	ok       bool
+}
+
+func TestLookupCommand(t *testing.T) {
+	tests := []lookupCommandTest{
+		{[]byte("get"), "GET", true},
+		{[]byte("ZREMRANGEBYSCORE"), "ZREMRANGEBYSCORE", true},
+		{[]byte("restore-asking"), "RESTORE-ASKING", true},
+		{[]byte("georadiusbymember_ro"), "GEORADIUSBYMEMBER_RO", true},
+		{[]byte("nope"), "", false},
+		{[]byte(""), "", false},
+		{[]byte("1GET"), "", false},
+		{[]byte("THISISAVERYLONGCOMMANDNAME"), "", false},
+	}
+
+	for i, test := range tests {
+		name, ok := LookupCommand(test.given)
+		if name != test.expected || ok != test.ok {
+			t.Errorf("tests[%d]: expected: %q %v, got: %q %v", i, test.expected, test.ok, name, ok)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		LookupCommand([]byte("hgetall"))
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestCommandSpec(t *testing.T) {
+	info := CommandSpec("mset")
+	if info == nil {
+		t.Fatal("expected MSET to be known")
+	}
+	expected := CommandInfo{"MSET", -3, FLAG_WRITE | FLAG_DENYOOM, 1, -1, 2}
+	if *info != expected {
+		t.Errorf("expected: %#v\ngot: %#v", expected, *info)
+	}
+	if !info.Is(FLAG_WRITE) || info.Is(FLAG_WRITE|FLAG_READONLY) {
+		t.Errorf("unexpected flags: %v", info.Flags)
+	}
+
+	if !CommandSpec("blpop").Is(FLAG_BLOCKING) {
+		t.Errorf("expected BLPOP to be blocking")
+	}
+	if CommandSpec("nope") != nil {
+		t.Errorf("expected unknown command to have no spec")
+	}
+}
+
+type validateCommandTest struct {
+	name     string
+	args     []string
+	expected error
+}
+
+func TestValidateCommand(t *testing.T) {
+	tests := []validateCommandTest{
+		{"GET", []string{"foo"}, nil},
+		{"ping", []string{}, nil},
+		{"mset", []string{"a", "1", "b", "2"}, nil},
+		{"GET", []string{}, NewError("ERR wrong number of arguments for 'get' command")},
+		{"Set", []string{"foo"}, NewError("ERR wrong number of arguments for 'set' command")},
+		{"nope", []string{"a", "b"}, NewError("ERR unknown command 'nope', with args beginning with: 'a' 'b' ")},
+	}
+
+	for i, test := range tests {
+		args := make([][]byte, len(test.args))
+		for j, arg := range test.args {
+			args[j] = []byte(arg)
+		}
+		err := ValidateCommand(test.name, args)
+		if !reflect.DeepEqual(test.expected, err) {
+			t.Errorf("tests[%d]:\nexpected: %v\ngot: %v", i, test.expected, err)
+		}
+	}
+}
+
+func BenchmarkLookupCommand(b *testing.B) {
+	arg := []byte("hgetall")
+	for i := 0; i < b.N; i++ {
+		LookupCommand(arg)
+	}
+}
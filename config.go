@@ -0,0 +1,119 @@
+package resp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A Config is a CONFIG GET reply, mapping parameter names to their values.
+type Config map[string]string
+
+// A SavePoint is one of the snapshotting rules of the save parameter: save
+// after Seconds if at least Changes keys changed.
+type SavePoint struct {
+	Seconds int
+	Changes int
+}
+
+// ParseConfig decodes a CONFIG GET reply, a flat array of parameter names and
+// values or, from RESP3 servers, a map. An Error reply is returned as an
+// error, and other replies as ErrUnexpectedReply.
+func ParseConfig(obj Object) (Config, error) {
+	if e, ok := obj.(Error); ok {
+		return nil, e
+	}
+	pairs, ok := objectPairs(obj)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	config := make(Config, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		name, ok := objectString(pairs[i])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		value, ok := objectString(pairs[i+1])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		config[name] = value
+	}
+	return config, nil
+}
+
+// Match returns the parameters matching any of the glob-style patterns,
+// matched case-insensitively as by CONFIG GET. It splits the reply to a
+// CONFIG GET with several patterns, e.g. CONFIG GET maxmemory* save, into the
+// parameters of each.
+func (c Config) Match(patterns ...string) Config {
+	matched := Config{}
+	for name, value := range c {
+		for _, pattern := range patterns {
+			if matchPattern([]byte(strings.ToLower(pattern)), []byte(strings.ToLower(name))) {
+				matched[name] = value
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// Int returns the value of a parameter as an integer, e.g. maxmemory, which
+// CONFIG GET reports in bytes. It returns false if the parameter is missing
+// or isn't an integer.
+func (c Config) Int(name string) (int64, bool) {
+	value, ok := c[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	return n, err == nil
+}
+
+// Bool returns the value of a yes/no parameter, e.g. appendonly. It returns
+// false for ok if the parameter is missing or isn't yes or no.
+func (c Config) Bool(name string) (value bool, ok bool) {
+	switch c[name] {
+	case "yes":
+		return true, true
+	case "no":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// Fields returns the space separated values of a multi-value parameter, such
+// as bind or client-output-buffer-limit. It returns nil if the parameter is
+// missing or empty.
+func (c Config) Fields(name string) []string {
+	fields := strings.Fields(c[name])
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// SavePoints returns the snapshotting rules of the save parameter, a list of
+// "<seconds> <changes>" pairs, e.g. "3600 1 300 100". It returns nil if
+// snapshotting is disabled or the parameter is missing, and
+// ErrUnexpectedReply if the parameter can't be parsed.
+func (c Config) SavePoints() ([]SavePoint, error) {
+	fields := c.Fields("save")
+	if len(fields)%2 != 0 {
+		return nil, ErrUnexpectedReply
+	}
+	var points []SavePoint
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return nil, ErrUnexpectedReply
+		}
+		changes, err := strconv.Atoi(fields[i+1])
+		if err != nil {
+			return nil, ErrUnexpectedReply
+		}
+		points = append(points, SavePoint{Seconds: seconds, Changes: changes})
+	}
+	return points, nil
+}
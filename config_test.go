@@ -0,0 +1,72 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	pairs := []Object{
+		NewBulkString("maxmemory"), NewBulkString("1073741824"),
+		NewBulkString("maxmemory-policy"), NewBulkString("allkeys-lru"),
+		NewBulkString("save"), NewBulkString("3600 1 300 100"),
+		NewBulkString("appendonly"), NewBulkString("no"),
+		NewBulkString("bind"), NewBulkString("127.0.0.1 -::1"),
+	}
+	for _, obj := range []Object{NewArray(pairs...), NewMap(pairs...)} {
+		config, err := ParseConfig(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(config) != 5 || config["maxmemory-policy"] != "allkeys-lru" {
+			t.Errorf("unexpected config %v", config)
+		}
+		if n, ok := config.Int("maxmemory"); !ok || n != 1073741824 {
+			t.Errorf("expected 1073741824, got %d, %v", n, ok)
+		}
+		if _, ok := config.Int("maxmemory-policy"); ok {
+			t.Errorf("expected a policy not to be an integer")
+		}
+		if value, ok := config.Bool("appendonly"); !ok || value {
+			t.Errorf("expected no, got %v, %v", value, ok)
+		}
+		if _, ok := config.Bool("save"); ok {
+			t.Errorf("expected save not to be a bool")
+		}
+		if fields := config.Fields("bind"); !reflect.DeepEqual(fields, []string{"127.0.0.1", "-::1"}) {
+			t.Errorf("unexpected fields %q", fields)
+		}
+		if fields := config.Fields("missing"); fields != nil {
+			t.Errorf("expected nil, got %q", fields)
+		}
+		points, err := config.SavePoints()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := []SavePoint{{3600, 1}, {300, 100}}; !reflect.DeepEqual(expected, points) {
+			t.Errorf("expected %v, got %v", expected, points)
+		}
+		expected := Config{"maxmemory": "1073741824", "maxmemory-policy": "allkeys-lru", "save": "3600 1 300 100"}
+		if matched := config.Match("MAXMEMORY*", "save"); !reflect.DeepEqual(expected, matched) {
+			t.Errorf("expected %v, got %v", expected, matched)
+		}
+	}
+
+	for _, config := range []Config{{"save": ""}, {}} {
+		if points, err := config.SavePoints(); points != nil || err != nil {
+			t.Errorf("expected no save points, got %v, %v", points, err)
+		}
+	}
+	if _, err := (Config{"save": "3600"}).SavePoints(); err != ErrUnexpectedReply {
+		t.Errorf("expected ErrUnexpectedReply, got %v", err)
+	}
+
+	if _, err := ParseConfig(NewError("ERR nope")); err == nil || err.Error() != "ERR nope" {
+		t.Errorf("expected the error reply, got %v", err)
+	}
+	for _, obj := range []Object{NewInteger(1), NewArray(NewBulkString("a")), NewArray(NewInteger(1), NewBulkString("a"))} {
+		if _, err := ParseConfig(obj); err != ErrUnexpectedReply {
+			t.Errorf("expected ErrUnexpectedReply for %q, got %v", obj.Raw(), err)
+		}
+	}
+}
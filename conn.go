@@ -0,0 +1,353 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrNoPendingReplies is returned by Conn.Receive when every reply has been
+// received.
+var ErrNoPendingReplies = errors.New("resp: no pending replies")
+
+// A Conn is a client connection to a Redis server. A Conn isn't safe for
+// concurrent use.
+//
+// Commands can be pipelined by queueing them with Send, writing them with
+// Flush, and reading their replies in order with Receive.
+//
+// Error replies are returned as replies, not as errors. Errors are reserved
+// for failures of the connection itself, after which the Conn is closed and
+// every call returns the same error, unless the Conn was opened by a Dialer
+// that reconnects.
+//
+// The Context variants of the methods apply the context's deadline to the
+// connection's reads and writes and abort them when the context is canceled.
+// Because a partially read or written command can't be recovered, a Conn
+// whose operation was aborted is broken.
+type Conn struct {
+	conn net.Conn
+	r    *Reader
+	w    *Writer
+	err  error
+	// pending is the number of replies that haven't been received yet.
+	pending int
+	dialer  *Dialer
+	// scripts holds the digests of the Scripts known to be loaded on the
+	// server.
+	scripts      map[string]bool
+	trace        *Trace
+	instrumenter Instrumenter
+}
+
+// Dial connects to the Redis server at address on the named network. See
+// net.Dial.
+func Dial(network, address string) (*Conn, error) {
+	return DialContext(context.Background(), network, address)
+}
+
+// DialContext is the same as Dial except that ctx bounds connecting.
+func DialContext(ctx context.Context, network, address string) (*Conn, error) {
+	d := &Dialer{Network: network, Address: address}
+	return d.DialContext(ctx)
+}
+
+// NewConn returns a Conn that uses conn, which must not be used for anything
+// else afterwards.
+func NewConn(conn net.Conn) *Conn {
+	c := &Conn{
+		conn: conn,
+		r:    NewReader(conn),
+		w:    NewWriter(conn),
+	}
+	c.r.SetMaxSize(DEFAULT_MAX_BUFFER)
+	return c
+}
+
+// Do sends a command and returns its reply. The arguments are formatted as by
+// FormatCommand. The replies to any commands queued with Send are read and
+// discarded first.
+func (c *Conn) Do(name string, args ...interface{}) (Object, error) {
+	return c.DoContext(context.Background(), name, args...)
+}
+
+// DoContext is the same as Do except that ctx bounds the round trip.
+func (c *Conn) DoContext(ctx context.Context, name string, args ...interface{}) (Object, error) {
+	replies, err := c.DoPipelineContext(ctx, []Command{FormatCommand(name, args...)})
+	if err != nil {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// DoPipeline sends commands in a single write and returns their replies. The
+// replies to any commands queued with Send are read and discarded first.
+func (c *Conn) DoPipeline(commands []Command) ([]Object, error) {
+	return c.DoPipelineContext(context.Background(), commands)
+}
+
+// DoPipelineContext is the same as DoPipeline except that ctx bounds the
+// round trip, including reconnecting.
+func (c *Conn) DoPipelineContext(ctx context.Context, commands []Command) (replies []Object, err error) {
+	if inst := c.instrumenter; inst != nil {
+		exchanges, contexts := startExchanges(inst, ctx, commands)
+		defer func() {
+			for i, e := range exchanges {
+				if err != nil {
+					e.end(nil, err)
+				} else {
+					e.end(replies[i].Raw(), nil)
+				}
+				inst.EndExchange(contexts[i], e)
+			}
+		}()
+	}
+	// Reconnection attempts go through the Breaker on their own, so they're
+	// made before the round trip is let through
+	if err := c.maybeReconnect(ctx); err != nil {
+		return nil, err
+	}
+	if d := c.dialer; d != nil {
+		if d.Breaker != nil {
+			if err := d.Breaker.Allow(); err != nil {
+				return nil, err
+			}
+			defer func() { d.Breaker.Done(err) }()
+		}
+		if timeout := d.timeout(commands); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+
+	err = c.withContext(ctx, func() error {
+		for _, cmd := range commands {
+			if err := c.SendCommand(cmd); err != nil {
+				return err
+			}
+		}
+		if err := c.flush(); err != nil {
+			return err
+		}
+		for c.pending > len(commands) {
+			if _, err := c.receive(); err != nil {
+				return err
+			}
+		}
+
+		replies = make([]Object, len(commands))
+		for i := range replies {
+			reply, err := c.receive()
+			if err != nil {
+				return err
+			}
+			replies[i] = reply
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replies, nil
+}
+
+// Send queues a command without writing it. The arguments are formatted as by
+// FormatCommand.
+func (c *Conn) Send(name string, args ...interface{}) error {
+	return c.SendCommand(FormatCommand(name, args...))
+}
+
+// SendCommand is the same as Send except that it takes a Command. If the
+// connection is broken and the Conn's Dialer reconnects, SendCommand
+// reconnects first.
+func (c *Conn) SendCommand(cmd Command) error {
+	if err := c.maybeReconnect(context.Background()); err != nil {
+		return err
+	}
+	if c.err != nil {
+		return c.err
+	}
+	if _, err := c.w.Write(cmd); err != nil {
+		return c.fatal(err)
+	}
+	c.pending++
+	return nil
+}
+
+// Flush writes the queued commands.
+func (c *Conn) Flush() error {
+	return c.flush()
+}
+
+// FlushContext is the same as Flush except that ctx bounds the write.
+func (c *Conn) FlushContext(ctx context.Context) error {
+	return c.withContext(ctx, c.flush)
+}
+
+func (c *Conn) flush() error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.w.Flush(); err != nil {
+		return c.fatal(err)
+	}
+	return nil
+}
+
+// Receive returns the reply to the oldest command whose reply hasn't been
+// received yet, flushing queued commands first if needed. It returns
+// ErrNoPendingReplies if every reply has been received.
+func (c *Conn) Receive() (Object, error) {
+	return c.receive()
+}
+
+// ReceiveContext is the same as Receive except that ctx bounds the read.
+func (c *Conn) ReceiveContext(ctx context.Context) (Object, error) {
+	var reply Object
+	err := c.withContext(ctx, func() (err error) {
+		reply, err = c.receive()
+		return err
+	})
+	return reply, err
+}
+
+func (c *Conn) receive() (Object, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.pending == 0 {
+		return nil, ErrNoPendingReplies
+	}
+	if c.w.Buffered() > 0 {
+		if err := c.flush(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.r.ReadObject()
+	if err != nil {
+		return nil, c.fatal(err)
+	}
+	c.pending--
+	return reply, nil
+}
+
+// maybeReconnect reconnects if the connection is broken and the Conn's
+// Dialer reconnects.
+func (c *Conn) maybeReconnect(ctx context.Context) error {
+	if c.err != nil && c.err != ErrConnClosed && c.dialer != nil && c.dialer.Reconnect != nil {
+		return c.dialer.reconnect(ctx, c)
+	}
+	return nil
+}
+
+// aLongTimeAgo is a deadline in the past, used to abort blocked reads and
+// writes.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// withContext runs fn with ctx's deadline set on the connection, aborting
+// fn's reads and writes if ctx is canceled. If ctx ends before fn returns,
+// the connection is broken with ctx's error.
+func (c *Conn) withContext(ctx context.Context, fn func() error) error {
+	if ctx.Done() == nil {
+		return fn()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	conn := c.conn
+	deadline, hasDeadline := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(aLongTimeAgo)
+		case <-stop:
+		}
+	}()
+
+	err := fn()
+	close(stop)
+	<-stopped
+	if err != nil && err == c.err {
+		// The connection's deadline can expire slightly before ctx's timer
+		// fires.
+		if ctx.Err() != nil {
+			c.err = ctx.Err()
+		} else if hasDeadline && !time.Now().Before(deadline) {
+			c.err = context.DeadlineExceeded
+		}
+		err = c.err
+	}
+	conn.SetDeadline(time.Time{})
+	return err
+}
+
+// markScript records that the script with the given digest is loaded.
+func (c *Conn) markScript(hash string) {
+	if c.scripts == nil {
+		c.scripts = map[string]bool{}
+	}
+	c.scripts[hash] = true
+}
+
+// Pending returns the number of replies that haven't been received yet.
+func (c *Conn) Pending() int {
+	return c.pending
+}
+
+// fatal closes the connection after err and returns err.
+func (c *Conn) fatal(err error) error {
+	if c.err == nil {
+		c.err = err
+		c.conn.Close()
+	}
+	return c.err
+}
+
+// Err returns the error that broke the connection, if any.
+func (c *Conn) Err() error {
+	return c.err
+}
+
+// NetConn returns the underlying connection.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// SetTrace sets the hooks called as commands are written to the connection
+// and replies read from it, e.g. those of LatencyStats.Trace. They're kept
+// across reconnections, but not called for the handshake of new connections.
+// A nil Trace removes them.
+func (c *Conn) SetTrace(t *Trace) {
+	c.trace = t
+	c.r.SetTrace(t)
+	c.w.SetTrace(t)
+}
+
+// SetInstrumenter sets an Instrumenter notified of the exchanges of Do,
+// DoPipeline, and their Context variants. Commands sent with Send and their
+// replies aren't reported. A nil Instrumenter removes it.
+func (c *Conn) SetInstrumenter(inst Instrumenter) {
+	c.instrumenter = inst
+}
+
+// Close closes the connection. Calls made after Close return ErrConnClosed,
+// even if the connection was broken, and the Conn doesn't reconnect.
+func (c *Conn) Close() error {
+	if c.err == ErrConnClosed {
+		return nil
+	}
+	broken := c.err != nil
+	c.err = ErrConnClosed
+	if broken {
+		// fatal already closed the connection
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,181 @@
+package resp
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer returns a Conn connected to a goroutine that answers each
+// command with the reply returned by handle. The server hangs up when handle
+// returns nil.
+func fakeServer(handle func(name string, args [][]byte) Object) *Conn {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		r := NewReader(server)
+		w := NewWriter(server)
+		for {
+			cmd, err := r.ReadCommand()
+			if err != nil {
+				return
+			}
+			name, args, _ := ParseCommand(cmd)
+			reply := handle(name, args)
+			if reply == nil {
+				return
+			}
+			w.WriteObject(reply)
+			if r.Buffered() == 0 {
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return NewConn(client)
+}
+
+// echoHandler answers ECHO with its argument, PING with PONG, QUIT by hanging
+// up, and anything else with an error.
+func echoHandler(name string, args [][]byte) Object {
+	switch strings.ToUpper(name) {
+	case "ECHO":
+		return NewBulkString(string(args[0]))
+	case "PING":
+		return PONG
+	case "QUIT":
+		return nil
+	default:
+		return NewError("ERR unknown command '" + name + "'")
+	}
+}
+
+func TestConnDo(t *testing.T) {
+	conn := fakeServer(echoHandler)
+	defer conn.Close()
+
+	reply, err := conn.Do("ECHO", 42)
+	if err != nil || string(reply.Raw()) != "$2\r\n42\r\n" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+
+	// Error replies aren't errors
+	reply, err = conn.Do("NOPE")
+	if _, ok := reply.(Error); !ok || err != nil {
+		t.Errorf("expected an error reply, got %q, %v", reply, err)
+	}
+
+	// Connection errors are sticky
+	if _, err := conn.Do("QUIT"); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+	if _, err := conn.Do("PING"); err != io.EOF || conn.Err() != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestConnClose(t *testing.T) {
+	conn := fakeServer(echoHandler)
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("PING"); err != ErrConnClosed {
+		t.Errorf("expected ErrConnClosed, got %v", err)
+	}
+}
+
+func TestConnPipeline(t *testing.T) {
+	conn := fakeServer(echoHandler)
+	defer conn.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := conn.Send("ECHO", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if conn.Pending() != 3 {
+		t.Errorf("expected 3 pending replies, got %d", conn.Pending())
+	}
+	for i := 0; i < 3; i++ {
+		reply, err := conn.Receive()
+		if err != nil || reply.(String).String() != string('0'+rune(i)) {
+			t.Errorf("replies[%d]: unexpected reply: %q, %v", i, reply, err)
+		}
+	}
+	if _, err := conn.Receive(); err != ErrNoPendingReplies {
+		t.Errorf("expected ErrNoPendingReplies, got %v", err)
+	}
+
+	// Receive flushes, and Do discards unreceived replies
+	conn.Send("ECHO", "a")
+	if reply, err := conn.Receive(); err != nil || reply.(String).String() != "a" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+	conn.Send("ECHO", "b")
+	if reply, err := conn.Do("PING"); err != nil || string(reply.Raw()) != "+PONG\r\n" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+
+	replies, err := conn.DoPipeline([]Command{NewCommand("ECHO", "x"), NewCommand("NOPE"), NewCommand("PING")})
+	if err != nil || len(replies) != 3 {
+		t.Fatalf("unexpected replies: %q, %v", replies, err)
+	}
+	if replies[0].(String).String() != "x" || string(replies[2].Raw()) != "+PONG\r\n" {
+		t.Errorf("unexpected replies: %q", replies)
+	}
+	if _, ok := replies[1].(Error); !ok {
+		t.Errorf("expected an error reply, got %q", replies[1])
+	}
+}
+
+func TestConnDoContext(t *testing.T) {
+	slowHandler := func(name string, args [][]byte) Object {
+		if name == "SLOW" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return echoHandler(name, args)
+	}
+
+	// Contexts that end before anything is sent don't break the connection
+	conn := fakeServer(slowHandler)
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := conn.DoContext(ctx, "PING"); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if reply, err := conn.DoContext(ctx, "ECHO", "a"); err != nil || reply.(String).String() != "a" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+
+	// Deadlines abort the round trip and break the connection
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := conn.DoContext(ctx, "SLOW"); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if conn.Err() != context.DeadlineExceeded {
+		t.Errorf("expected the connection to be broken, got %v", conn.Err())
+	}
+
+	// So does cancellation
+	conn = fakeServer(slowHandler)
+	defer conn.Close()
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := conn.Send("SLOW"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ReceiveContext(ctx); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+package resp
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// A ReplyMode is a client's CLIENT REPLY setting.
+type ReplyMode int
+
+const (
+	// REPLY_ON is the default: every command is answered.
+	REPLY_ON ReplyMode = iota
+	// REPLY_OFF turns replies off until CLIENT REPLY ON.
+	REPLY_OFF
+	// REPLY_SKIP drops the reply to the next command.
+	REPLY_SKIP
+)
+
+func (m ReplyMode) String() string {
+	switch m {
+	case REPLY_ON:
+		return "on"
+	case REPLY_OFF:
+		return "off"
+	case REPLY_SKIP:
+		return "skip"
+	}
+	return "unknown"
+}
+
+// A ConnState describes a client connection of a Server, as set up by the
+// commands the Server answers itself: HELLO, SELECT, and CLIENT SETNAME,
+// SETINFO, and REPLY.
+type ConnState struct {
+	// ID is the connection's unique ID, as returned by CLIENT ID, and Addr
+	// the client's address.
+	ID   int64
+	Addr string
+	// DB is the database selected with SELECT.
+	DB int
+	// Name is the name set with CLIENT SETNAME or HELLO SETNAME.
+	Name string
+	// LibName and LibVersion are the client library's name and version,
+	// set with CLIENT SETINFO.
+	LibName    string
+	LibVersion string
+	// Protocol is the RESP version set with HELLO, 2 or 3.
+	Protocol int
+	// ReplyMode is the CLIENT REPLY setting.
+	ReplyMode ReplyMode
+}
+
+// ConnState returns the state of the Server connection w writes replies to.
+// It returns false if w doesn't write to a Server connection.
+func (w *Writer) ConnState() (ConnState, bool) {
+	if w.conn == nil {
+		return ConnState{}, false
+	}
+	return w.conn.connState(), true
+}
+
+func (c *serverConn) connState() ConnState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// setState changes the connection's state with fn. It must only be called
+// by the serving goroutine.
+func (c *serverConn) setState(fn func(state *ConnState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(&c.state)
+}
+
+// isBuiltin returns true for the commands the Server answers itself.
+func isBuiltin(cmd Command) bool {
+	name, ok := commandName(cmd)
+	if !ok {
+		return false
+	}
+	switch {
+	case CommandEquals(name, "HELLO"), CommandEquals(name, "SELECT"):
+		return true
+	case CommandEquals(name, "CLIENT"):
+		_, args, _ := ParseCommand(cmd)
+		if len(args) == 0 {
+			return false
+		}
+		for _, sub := range []string{"SETNAME", "GETNAME", "SETINFO", "REPLY", "ID"} {
+			if CommandEquals(args[0], sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serveBuiltin answers a command for which isBuiltin is true.
+func (c *serverConn) serveBuiltin(w *Writer, cmd Command) {
+	name, args, _ := ParseCommand(cmd)
+	switch {
+	case CommandEquals([]byte(name), "HELLO"):
+		c.hello(w, args)
+	case !c.authenticated(w):
+		w.WriteObject(NOAUTH_ERROR)
+	case CommandEquals([]byte(name), "SELECT"):
+		c.selectDB(w, args)
+	default:
+		c.client(w, args)
+	}
+}
+
+// hello answers HELLO [protover [AUTH username password] [SETNAME
+// clientname]], switching the connection to protover.
+func (c *serverConn) hello(w *Writer, args [][]byte) {
+	protocol := c.state.Protocol
+	if len(args) > 0 {
+		version, err := strconv.Atoi(string(args[0]))
+		if err != nil {
+			w.WriteObject(NewError("ERR Protocol version is not an integer or out of range"))
+			return
+		}
+		if version != 2 && version != 3 {
+			w.WriteObject(NewError("NOPROTO unsupported protocol version"))
+			return
+		}
+		protocol = version
+	}
+
+	name := c.state.Name
+	var credentials [][]byte
+	for i := 1; i < len(args); i++ {
+		switch {
+		case CommandEquals(args[i], "AUTH") && i+2 < len(args):
+			credentials = args[i+1 : i+3]
+			i += 2
+		case CommandEquals(args[i], "SETNAME") && i+1 < len(args):
+			if !validClientName(args[i+1]) {
+				w.WriteObject(NewError("ERR Client names cannot contain spaces, newlines or special characters."))
+				return
+			}
+			name = string(args[i+1])
+			i++
+		default:
+			w.WriteObject(NewError("ERR Syntax error in HELLO option '" + string(args[i]) + "'"))
+			return
+		}
+	}
+
+	if credentials != nil {
+		if e := c.authenticate(credentials); e != nil {
+			w.WriteObject(e)
+			return
+		}
+	} else if !c.authenticated(w) {
+		w.WriteObject(NewError("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time"))
+		return
+	}
+
+	c.setState(func(state *ConnState) {
+		state.Name = name
+		state.Protocol = protocol
+	})
+	c.w.SetProtocol(protocol)
+	c.discard.SetProtocol(protocol)
+	serverName, version := c.server.ServerName, c.server.ServerVersion
+	if serverName == "" {
+		serverName = DEFAULT_SERVER_NAME
+	}
+	if version == "" {
+		version = DEFAULT_SERVER_VERSION
+	}
+	w.WriteObject(NewMap(
+		NewBulkString("server"), NewBulkString(serverName),
+		NewBulkString("version"), NewBulkString(version),
+		NewBulkString("proto"), NewInteger(int64(protocol)),
+		NewBulkString("id"), NewInteger(c.state.ID),
+		NewBulkString("mode"), NewBulkString("standalone"),
+		NewBulkString("role"), NewBulkString("master"),
+		NewBulkString("modules"), NewArray(),
+	))
+}
+
+// authenticate passes the credentials given to HELLO to the Handler as
+// AUTH username password and returns its Error reply, if any. Handlers that
+// don't know AUTH are taken to be for a server without passwords, which
+// accepts any credentials.
+func (c *serverConn) authenticate(credentials [][]byte) Object {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.conn = c
+	c.handle(w, newCommand("AUTH", credentials))
+	w.Flush()
+	w.Release()
+	r := NewReader(&buf)
+	defer r.Release()
+	reply, err := r.ReadObject()
+	if err != nil {
+		return nil
+	}
+	if e, ok := reply.(Error); ok && !strings.HasPrefix(string(e.Slice()), "ERR unknown command") {
+		return e
+	}
+	return nil
+}
+
+// authenticated returns false if the Server's Handler refuses commands until
+// clients authenticate, as RequirePass's does, and the client hasn't.
+func (c *serverConn) authenticated(w *Writer) bool {
+	gate, ok := c.server.Handler.(authGate)
+	return !ok || gate.authenticated(w)
+}
+
+// selectDB answers SELECT index.
+func (c *serverConn) selectDB(w *Writer, args [][]byte) {
+	if len(args) != 1 {
+		w.WriteObject(wrongArityError("select"))
+		return
+	}
+	db, err := strconv.Atoi(string(args[0]))
+	if err != nil {
+		w.WriteObject(NewError("ERR value is not an integer or out of range"))
+		return
+	}
+	databases := c.server.Databases
+	if databases <= 0 {
+		databases = DEFAULT_DATABASES
+	}
+	if db < 0 || db >= databases {
+		w.WriteObject(NewError("ERR DB index is out of range"))
+		return
+	}
+	c.setState(func(state *ConnState) { state.DB = db })
+	w.WriteObject(OK)
+}
+
+// clientArity is the number of arguments of the CLIENT subcommands the
+// Server answers, including the subcommand.
+var clientArity = map[string]int{"setname": 2, "getname": 1, "setinfo": 3, "reply": 2, "id": 1}
+
+// client answers CLIENT SETNAME, GETNAME, SETINFO, REPLY, and ID.
+func (c *serverConn) client(w *Writer, args [][]byte) {
+	sub := strings.ToLower(string(args[0]))
+	if len(args) != clientArity[sub] {
+		w.WriteObject(wrongArityError("client|" + sub))
+		return
+	}
+
+	switch sub {
+	case "setname":
+		if !validClientName(args[1]) {
+			w.WriteObject(NewError("ERR Client names cannot contain spaces, newlines or special characters."))
+			return
+		}
+		c.setState(func(state *ConnState) { state.Name = string(args[1]) })
+		w.WriteObject(OK)
+	case "getname":
+		if c.state.Name == "" {
+			w.WriteObject(NULL)
+			return
+		}
+		w.WriteObject(NewBulkString(c.state.Name))
+	case "setinfo":
+		value := string(args[2])
+		if !validClientName(args[2]) {
+			w.WriteObject(NewError("ERR " + string(args[1]) + " cannot contain spaces, newlines or special characters."))
+			return
+		}
+		switch {
+		case CommandEquals(args[1], "LIB-NAME"):
+			c.setState(func(state *ConnState) { state.LibName = value })
+		case CommandEquals(args[1], "LIB-VER"):
+			c.setState(func(state *ConnState) { state.LibVersion = value })
+		default:
+			w.WriteObject(NewError("ERR Unrecognized option '" + string(args[1]) + "'"))
+			return
+		}
+		w.WriteObject(OK)
+	case "reply":
+		switch {
+		case CommandEquals(args[1], "ON"):
+			c.setState(func(state *ConnState) { state.ReplyMode = REPLY_ON })
+			// ON is answered even if replies were off
+			c.w.WriteObject(OK)
+		case CommandEquals(args[1], "OFF"):
+			c.setState(func(state *ConnState) { state.ReplyMode = REPLY_OFF })
+		case CommandEquals(args[1], "SKIP"):
+			if c.state.ReplyMode != REPLY_OFF {
+				c.setState(func(state *ConnState) { state.ReplyMode = REPLY_SKIP })
+			}
+		default:
+			w.WriteObject(NewError("ERR syntax error"))
+		}
+	case "id":
+		w.WriteObject(NewInteger(c.state.ID))
+	}
+}
+
+// validClientName returns true if name can be set with CLIENT SETNAME: it
+// can't contain spaces or non-printable characters.
+func validClientName(name []byte) bool {
+	for _, b := range name {
+		if b < '!' || b > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package resp
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestServer_ConnState(t *testing.T) {
+	handler := HandlerFunc(func(w *Writer, cmd Command) {
+		state, ok := w.ConnState()
+		if !ok {
+			w.WriteObject(NewError("ERR no state"))
+			return
+		}
+		w.WriteObject(NewBulkString(fmt.Sprintf("db=%d name=%s lib=%s-%s proto=%d reply=%s", state.DB, state.Name, state.LibName, state.LibVersion, state.Protocol, state.ReplyMode)))
+	})
+	for _, concurrency := range []int{0, 4} {
+		addr := startServer(t, &Server{Handler: handler, PipelineConcurrency: concurrency})
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commands := []string{
+			"SELECT 3",
+			"SELECT 16",
+			"CLIENT SETNAME app",
+			"CLIENT SETNAME 'a b'",
+			"client setinfo lib-name go-resp",
+			"CLIENT SETINFO LIB-VER 1.0",
+			"CLIENT GETNAME",
+			"CLIENT ID",
+			"STATE",
+			"CLIENT REPLY OFF",
+			"STATE",
+			"CLIENT REPLY SKIP",
+			"CLIENT REPLY ON",
+			"CLIENT REPLY SKIP",
+			"STATE",
+			"HELLO 3",
+			"STATE",
+		}
+		conn.Write([]byte(strings.Join(commands, "\r\n") + "\r\n"))
+		r := NewReader(conn)
+		expected := []string{
+			"+OK\r\n",
+			"-ERR DB index is out of range\r\n",
+			"+OK\r\n",
+			"-ERR Client names cannot contain spaces, newlines or special characters.\r\n",
+			"+OK\r\n",
+			"+OK\r\n",
+			"$3\r\napp\r\n",
+			":1\r\n",
+			"$46\r\ndb=3 name=app lib=go-resp-1.0 proto=2 reply=on\r\n",
+			"+OK\r\n",
+		}
+		for i, e := range expected {
+			if reply, err := r.ReadObject(); err != nil || string(reply.Raw()) != e {
+				t.Errorf("concurrency %d: replies[%d]: expected %q, got %q, %v", concurrency, i, e, reply, err)
+			}
+		}
+		if _, err := r.ReadObject(); err != nil {
+			t.Fatal(err)
+		}
+		e := "$46\r\ndb=3 name=app lib=go-resp-1.0 proto=3 reply=on\r\n"
+		if reply, err := r.ReadObject(); err != nil || string(reply.Raw()) != e {
+			t.Errorf("concurrency %d: expected %q, got %q, %v", concurrency, e, reply, err)
+		}
+		conn.Close()
+	}
+
+	// Writers that don't write to a Server connection have no state
+	if _, ok := NewWriter(&strings.Builder{}).ConnState(); ok {
+		t.Errorf("expected no state")
+	}
+}
@@ -0,0 +1,117 @@
+package resp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Request is a command recorded by a Correlator, along with whatever the
+// caller needs to route its reply once it arrives.
+type Request struct {
+	Command Command
+	// Client identifies the client connection the reply belongs to.
+	Client interface{}
+	// Position is the index of the request among the subcommands of a split
+	// command (see Subcommand.Position), or 0.
+	Position int
+	// Transform, if set, is applied to the reply by Correlator.Match.
+	Transform func(Object) (Object, error)
+	// Sent, if set, is when the request was written, for
+	// LatencyStats.RecordRequest.
+	Sent time.Time
+}
+
+// An OrphanReplyError is returned by Correlator.Match for a reply that arrived
+// when no request was outstanding. It means the upstream connection is out of
+// sync and should be closed.
+type OrphanReplyError struct {
+	Reply Object
+}
+
+func (e *OrphanReplyError) Error() string {
+	return fmt.Sprintf("resp: orphaned reply %q", e.Reply.Raw())
+}
+
+// A MissingReplyError is returned by Correlator.Fail for requests whose
+// replies will never arrive because the upstream connection failed.
+type MissingReplyError struct {
+	Requests []*Request
+	// Err is the error that ended the connection.
+	Err error
+}
+
+func (e *MissingReplyError) Error() string {
+	return fmt.Sprintf("resp: %d replies missing: %v", len(e.Requests), e.Err)
+}
+
+func (e *MissingReplyError) Unwrap() error {
+	return e.Err
+}
+
+// A Correlator pairs the replies read from a pipelined upstream connection
+// with the requests written to it. Redis replies to commands in the order it
+// receives them, so requests must be recorded in the order they're written.
+// A Correlator is safe for concurrent use, so requests can be recorded by the
+// writing goroutine while replies are matched by the reading goroutine. The
+// zero value is ready to use.
+type Correlator struct {
+	mu    sync.Mutex
+	queue []*Request
+}
+
+// Record adds req to the end of the queue of requests awaiting replies.
+func (c *Correlator) Record(req *Request) {
+	c.mu.Lock()
+	c.queue = append(c.queue, req)
+	c.mu.Unlock()
+}
+
+// Match pairs reply with the oldest outstanding request and returns the
+// request and the reply, transformed by the request's Transform if it has
+// one. RESP3 pushes aren't replies to requests; for a Push, Match returns a
+// nil request and the push itself. If no request is outstanding, Match
+// returns an *OrphanReplyError.
+func (c *Correlator) Match(reply Object) (*Request, Object, error) {
+	if _, ok := reply.(Push); ok {
+		return nil, reply, nil
+	}
+
+	c.mu.Lock()
+	if len(c.queue) == 0 {
+		c.mu.Unlock()
+		return nil, reply, &OrphanReplyError{reply}
+	}
+	req := c.queue[0]
+	c.queue[0] = nil
+	c.queue = c.queue[1:]
+	c.mu.Unlock()
+
+	if req.Transform != nil {
+		transformed, err := req.Transform(reply)
+		return req, transformed, err
+	}
+	return req, reply, nil
+}
+
+// Pending returns the number of outstanding requests.
+func (c *Correlator) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
+
+// Fail empties the queue after the upstream connection failed with err. If
+// any requests were outstanding, it returns a *MissingReplyError listing them
+// so that their clients can be sent errors. Otherwise it returns nil.
+func (c *Correlator) Fail(err error) error {
+	c.mu.Lock()
+	queue := c.queue
+	c.queue = nil
+	c.mu.Unlock()
+
+	if len(queue) == 0 {
+		return nil
+	}
+	return &MissingReplyError{queue, err}
+}
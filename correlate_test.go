@@ -0,0 +1,62 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestCorrelator(t *testing.T) {
+	var c Correlator
+	transform := func(obj Object) (Object, error) {
+		return NewSimpleString("TRANSFORMED"), nil
+	}
+	c.Record(&Request{Command: NewCommand("GET", "a"), Client: "client-1"})
+	c.Record(&Request{Command: NewCommand("GET", "b"), Client: "client-2", Position: 1, Transform: transform})
+	if c.Pending() != 2 {
+		t.Errorf("expected 2 pending requests, got %d", c.Pending())
+	}
+
+	req, reply, err := c.Match(NewBulkString("1"))
+	if err != nil || req.Client != "client-1" || string(reply.Raw()) != "$1\r\n1\r\n" {
+		t.Errorf("unexpected match: %v, %q, %v", req, reply, err)
+	}
+
+	// Pushes are passed through without consuming a request
+	push := Push(">2\r\n$10\r\ninvalidate\r\n*1\r\n$1\r\na\r\n")
+	if req, reply, err := c.Match(push); req != nil || err != nil || string(reply.Raw()) != string(push) {
+		t.Errorf("unexpected push match: %v, %q, %v", req, reply, err)
+	}
+
+	req, reply, err = c.Match(NewBulkString("2"))
+	if err != nil || req.Position != 1 || string(reply.Raw()) != "+TRANSFORMED\r\n" {
+		t.Errorf("unexpected match: %v, %q, %v", req, reply, err)
+	}
+
+	_, _, err = c.Match(OK)
+	var orphan *OrphanReplyError
+	if !errors.As(err, &orphan) || string(orphan.Reply.Raw()) != "+OK\r\n" {
+		t.Errorf("expected an OrphanReplyError, got %v", err)
+	}
+}
+
+func TestCorrelator_Fail(t *testing.T) {
+	var c Correlator
+	if err := c.Fail(io.EOF); err != nil {
+		t.Errorf("expected no error without pending requests, got %v", err)
+	}
+
+	c.Record(&Request{Command: NewCommand("GET", "a")})
+	c.Record(&Request{Command: NewCommand("GET", "b")})
+	err := c.Fail(io.EOF)
+	var missing *MissingReplyError
+	if !errors.As(err, &missing) || len(missing.Requests) != 2 {
+		t.Fatalf("expected a MissingReplyError, got %v", err)
+	}
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("expected the error to wrap io.EOF")
+	}
+	if c.Pending() != 0 {
+		t.Errorf("expected the queue to be empty")
+	}
+}
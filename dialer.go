@@ -0,0 +1,294 @@
+package resp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidURL is returned by ParseURL for URLs that aren't redis://,
+// rediss://, or unix:// URLs.
+var ErrInvalidURL = errors.New("resp: invalid redis URL")
+
+// DEFAULT_RECONNECT_TIMEOUT bounds each reconnection attempt of a Conn if its
+// Dialer's ConnectTimeout is zero.
+const DEFAULT_RECONNECT_TIMEOUT = 10 * time.Second
+
+// A Dialer opens client connections and prepares them for use with a
+// handshake, such as AUTH and SELECT. Conns opened by a Dialer with a
+// Reconnect policy reconnect automatically, replaying the handshake.
+type Dialer struct {
+	// Network and Address are passed to net.Dial, e.g. "tcp" and
+	// "10.0.0.1:6379" or "unix" and "/run/redis.sock". Network defaults to
+	// "tcp".
+	Network string
+	Address string
+	// NetDial, if set, opens connections instead of net.Dial, e.g. to go
+	// through a proxy or an in-process listener. Network and Address are
+	// ignored, except that Address is still used for the TLS ServerName.
+	NetDial func(ctx context.Context) (net.Conn, error)
+	// TLSConfig, if set, makes connections use TLS. If it doesn't set a
+	// ServerName, the host part of Address is used. Client certificates are
+	// configured through its Certificates.
+	TLSConfig *tls.Config
+	// Handshake is sent on every new connection before it's used, e.g.
+	// HELLO, AUTH, SELECT, CLIENT SETNAME, or READONLY. An error reply to
+	// any of the commands fails the connection attempt.
+	Handshake []Command
+	// Reconnect decides whether and when a broken Conn reconnects. The
+	// reconnection happens when the next command is sent, so the commands
+	// whose replies were lost still fail. A nil Reconnect disables
+	// reconnecting.
+	Reconnect RetryPolicy
+	// OnReconnect, if set, is called after a Conn reconnected and replayed
+	// the handshake.
+	OnReconnect func(*Conn)
+	// ConnectTimeout, if positive, bounds each connection attempt: dialing,
+	// the TLS handshake, and the Handshake commands, on top of any deadline
+	// of the context. Reconnections, which calls without a context such as
+	// Send can trigger, are bounded by DEFAULT_RECONNECT_TIMEOUT if it's
+	// zero.
+	ConnectTimeout time.Duration
+	// Timeout, if positive, bounds every round trip of the Conns opened by
+	// the Dialer, on top of any deadline of the context.
+	Timeout time.Duration
+	// CommandTimeouts overrides Timeout for the commands it names, in upper
+	// case. A timeout of 0 disables the timeout for that command. For
+	// blocking commands, the time the command may block for is added to the
+	// timeout, and commands that block indefinitely have no timeout; see
+	// BlockingTimeout.
+	CommandTimeouts map[string]time.Duration
+	// Breaker, if set, fails connection attempts and round trips fast with
+	// ErrCircuitOpen while the upstream keeps failing.
+	Breaker *CircuitBreaker
+}
+
+// Dial opens a connection and sends the handshake.
+func (d *Dialer) Dial() (*Conn, error) {
+	return d.DialContext(context.Background())
+}
+
+// DialContext is the same as Dial except that ctx bounds connecting, the TLS
+// handshake, and the Handshake commands.
+func (d *Dialer) DialContext(ctx context.Context) (*Conn, error) {
+	c, err := d.attempt(ctx, d.ConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c.dialer = d
+	return c, nil
+}
+
+// attempt makes a connection attempt bounded by timeout, if it's positive,
+// and by the Breaker.
+func (d *Dialer) attempt(ctx context.Context, timeout time.Duration) (*Conn, error) {
+	if d.Breaker != nil {
+		if err := d.Breaker.Allow(); err != nil {
+			return nil, err
+		}
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	c, err := d.connect(ctx)
+	if d.Breaker != nil {
+		d.Breaker.Done(err)
+	}
+	return c, err
+}
+
+func (d *Dialer) dial(ctx context.Context) (net.Conn, error) {
+	if d.NetDial != nil {
+		return d.NetDial(ctx)
+	}
+	network := d.Network
+	if network == "" {
+		network = "tcp"
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, d.Address)
+}
+
+func (d *Dialer) connect(ctx context.Context) (*Conn, error) {
+	conn, err := d.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if d.TLSConfig != nil {
+		config := d.TLSConfig
+		if config.ServerName == "" {
+			config = config.Clone()
+			if host, _, err := net.SplitHostPort(d.Address); err == nil {
+				config.ServerName = host
+			} else {
+				config.ServerName = d.Address
+			}
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	c := NewConn(conn)
+	if len(d.Handshake) == 0 {
+		return c, nil
+	}
+	replies, err := c.DoPipelineContext(ctx, d.Handshake)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	for _, reply := range replies {
+		if e, ok := reply.(Error); ok {
+			c.Close()
+			return nil, e
+		}
+	}
+	return c, nil
+}
+
+// timeout returns how long a round trip of commands may take, or 0 if it
+// isn't bounded.
+func (d *Dialer) timeout(commands []Command) time.Duration {
+	if d.Timeout <= 0 && d.CommandTimeouts == nil {
+		return 0
+	}
+
+	var longest time.Duration
+	for _, cmd := range commands {
+		name, args, err := ParseCommand(cmd)
+		if err != nil {
+			continue
+		}
+		timeout, ok := d.CommandTimeouts[strings.ToUpper(name)]
+		if !ok {
+			timeout = d.Timeout
+		}
+		if timeout <= 0 {
+			return 0
+		}
+		if blockFor, blocking, err := BlockingTimeout(name, args); blocking && err == nil {
+			if blockFor == 0 {
+				return 0
+			}
+			timeout += blockFor
+		}
+		if timeout > longest {
+			longest = timeout
+		}
+	}
+	return longest
+}
+
+// reconnect replaces the broken connection of c with a new one, retrying as
+// long as the Reconnect policy and ctx allow.
+func (d *Dialer) reconnect(ctx context.Context, c *Conn) error {
+	timeout := d.ConnectTimeout
+	if timeout <= 0 {
+		timeout = DEFAULT_RECONNECT_TIMEOUT
+	}
+	for attempt := 1; ; attempt++ {
+		fresh, err := d.attempt(ctx, timeout)
+		if err == ErrCircuitOpen {
+			return err
+		}
+		if err == nil {
+			c.conn, c.r, c.w = fresh.conn, fresh.r, fresh.w
+			c.r.SetTrace(c.trace)
+			c.w.SetTrace(c.trace)
+			c.err = nil
+			c.pending = 0
+			c.scripts = nil
+			if d.OnReconnect != nil {
+				d.OnReconnect(c)
+			}
+			return nil
+		}
+
+		decision := d.Reconnect.Retry(attempt, err)
+		if !decision.Retry {
+			return err
+		}
+		select {
+		case <-time.After(decision.Backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// DialTLS connects to the Redis server at address on the named network over
+// TLS.
+func DialTLS(network, address string, config *tls.Config) (*Conn, error) {
+	d := &Dialer{Network: network, Address: address, TLSConfig: config}
+	if d.TLSConfig == nil {
+		d.TLSConfig = &tls.Config{}
+	}
+	return d.Dial()
+}
+
+// ParseURL returns a Dialer for a URL of the form
+// redis://[[user]:password@]host[:port][/db], rediss:// for TLS, or
+// unix://[[user]:password@]/path/to/socket[?db=db] for a unix domain socket.
+// The port defaults to 6379. A password adds AUTH to the handshake and a
+// database number adds SELECT.
+func ParseURL(rawurl string) (*Dialer, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Dialer{Network: "tcp"}
+	db := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		d.TLSConfig = &tls.Config{}
+	case "unix":
+		if u.Path == "" {
+			return nil, ErrInvalidURL
+		}
+		d.Network = "unix"
+		d.Address = u.Path
+		db = u.Query().Get("db")
+	default:
+		return nil, ErrInvalidURL
+	}
+	if d.Network == "tcp" {
+		if u.Hostname() == "" {
+			return nil, ErrInvalidURL
+		}
+		port := u.Port()
+		if port == "" {
+			port = "6379"
+		}
+		d.Address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			if user := u.User.Username(); user != "" {
+				d.Handshake = append(d.Handshake, NewCommand("AUTH", user, password))
+			} else {
+				d.Handshake = append(d.Handshake, NewCommand("AUTH", password))
+			}
+		}
+	}
+	if db != "" {
+		if _, err := strconv.Atoi(db); err != nil {
+			return nil, ErrInvalidURL
+		}
+		d.Handshake = append(d.Handshake, NewCommand("SELECT", db))
+	}
+	return d, nil
+}
@@ -0,0 +1,83 @@
+package resp
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"sort"
+)
+
+// Digest returns a 64-bit FNV-1a based hash of obj, for deduplicating replies,
+// using them as cache keys, or comparing the replies of different servers.
+// Unlike a hash of obj's bytes, it doesn't depend on the order of the members
+// of sets or the fields of maps and attributes, which isn't meaningful, so
+// the same set from two servers has the same digest. The order of arrays and
+// pushes is kept. Digests are stable across processes and versions of this
+// package. Invalid objects are hashed as plain bytes.
+func Digest(obj Object) uint64 {
+	return binary.BigEndian.Uint64(digest(obj.Raw(), func() hash.Hash { return fnv.New64a() }))
+}
+
+// Digest128 is the same as Digest except that it returns a 128-bit hash, for
+// uses where collisions must be vanishingly rare.
+func Digest128(obj Object) [16]byte {
+	var sum [16]byte
+	copy(sum[:], digest(obj.Raw(), fnv.New128a))
+	return sum
+}
+
+// DigestFrame returns the Digest of the object in a raw frame, such as a
+// Frame or the bytes returned by Reader.ReadObjectSlice.
+func DigestFrame(b []byte) uint64 {
+	return Digest(InvalidObject(b))
+}
+
+// digest hashes the object b with hashes returned by newHash. The members of
+// aggregates are hashed on their own, and the hashes of the members of sets
+// and of the pairs of maps are sorted before being combined.
+func digest(b []byte, newHash func() hash.Hash) []byte {
+	h := newHash()
+	if len(b) == 0 {
+		return h.Sum(nil)
+	}
+	switch b[0] {
+	case ARRAY_PREFIX, PUSH_PREFIX, SET_PREFIX, MAP_PREFIX, ATTRIBUTE_PREFIX:
+	default:
+		h.Write(b)
+		return h.Sum(nil)
+	}
+	objects, err := aggregateObjects(b)
+	if err != nil || objects == nil {
+		// Null arrays and invalid aggregates
+		h.Write(b)
+		return h.Sum(nil)
+	}
+
+	members := make([][]byte, 0, len(objects))
+	switch b[0] {
+	case MAP_PREFIX, ATTRIBUTE_PREFIX:
+		for i := 0; i < len(objects); i += 2 {
+			pair := newHash()
+			pair.Write(digest(objects[i].Raw(), newHash))
+			pair.Write(digest(objects[i+1].Raw(), newHash))
+			members = append(members, pair.Sum(nil))
+		}
+		sort.Sort(byteSlices(members))
+	default:
+		for _, object := range objects {
+			members = append(members, digest(object.Raw(), newHash))
+		}
+		if b[0] == SET_PREFIX {
+			sort.Sort(byteSlices(members))
+		}
+	}
+
+	var header [9]byte
+	header[0] = b[0]
+	binary.BigEndian.PutUint64(header[1:], uint64(len(members)))
+	h.Write(header[:])
+	for _, member := range members {
+		h.Write(member)
+	}
+	return h.Sum(nil)
+}
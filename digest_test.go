@@ -0,0 +1,49 @@
+package resp
+
+import (
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	equal := [][2]Object{
+		{NewSet(OK, NewInteger(1)), NewSet(NewInteger(1), OK)},
+		{NewMap(OK, NewInteger(1), PONG, NewInteger(2)), NewMap(PONG, NewInteger(2), OK, NewInteger(1))},
+		{NewArray(NewSet(OK, PONG)), NewArray(NewSet(PONG, OK))},
+		{Attribute(newAggregate(ATTRIBUTE_PREFIX, 2, []Object{OK, TRUE, PONG, FALSE})), Attribute(newAggregate(ATTRIBUTE_PREFIX, 2, []Object{PONG, FALSE, OK, TRUE}))},
+	}
+	for i, test := range equal {
+		if Digest(test[0]) != Digest(test[1]) {
+			t.Errorf("equal[%d]: expected %q and %q to have the same digest", i, test[0].Raw(), test[1].Raw())
+		}
+		if Digest128(test[0]) != Digest128(test[1]) {
+			t.Errorf("equal[%d]: expected %q and %q to have the same 128-bit digest", i, test[0].Raw(), test[1].Raw())
+		}
+	}
+
+	different := [][2]Object{
+		{NewArray(OK, NewInteger(1)), NewArray(NewInteger(1), OK)},
+		{NewArray(OK), NewSet(OK)},
+		{NewSet(OK, OK), NewSet(OK)},
+		{NewMap(OK, NewInteger(1), PONG, NewInteger(2)), NewMap(OK, NewInteger(2), PONG, NewInteger(1))},
+		{NewSimpleString("a"), NewBulkString("a")},
+		{NewArray(), Array("*-1\r\n")},
+		{NewArray(NewArray(OK), PONG), NewArray(NewArray(OK, PONG))},
+	}
+	for i, test := range different {
+		if Digest(test[0]) == Digest(test[1]) {
+			t.Errorf("different[%d]: expected %q and %q to have different digests", i, test[0].Raw(), test[1].Raw())
+		}
+		if Digest128(test[0]) == Digest128(test[1]) {
+			t.Errorf("different[%d]: expected %q and %q to have different 128-bit digests", i, test[0].Raw(), test[1].Raw())
+		}
+	}
+
+	obj := NewArray(NewSet(OK, PONG), NewBulkString("x"))
+	if Digest(obj) != DigestFrame(obj.Raw()) {
+		t.Errorf("expected DigestFrame to match Digest")
+	}
+	// Digests must not change between releases
+	if d := Digest(OK); d != 0x18a923c7367632ab {
+		t.Errorf("unexpected digest %#x", d)
+	}
+}
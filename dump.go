@@ -0,0 +1,133 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// prefixNames are the names of the types of objects, by prefix.
+var prefixNames = map[byte]string{
+	SIMPLE_STRING_PREFIX:   "simple string",
+	ERROR_PREFIX:           "error",
+	INTEGER_PREFIX:         "integer",
+	BULK_STRING_PREFIX:     "bulk string",
+	ARRAY_PREFIX:           "array",
+	NULL_PREFIX:            "null",
+	PUSH_PREFIX:            "push",
+	MAP_PREFIX:             "map",
+	SET_PREFIX:             "set",
+	ATTRIBUTE_PREFIX:       "attribute",
+	BOOLEAN_PREFIX:         "boolean",
+	DOUBLE_PREFIX:          "double",
+	BIG_NUMBER_PREFIX:      "big number",
+	VERBATIM_STRING_PREFIX: "verbatim string",
+	BLOB_ERROR_PREFIX:      "blob error",
+}
+
+// A DumpWriter renders the RESP written to it as a hex and ASCII dump, like
+// hexdump -C, to help diagnose interoperability problems. Each object starts
+// a new block, headed by its number, type, size, and offset in the stream:
+//
+//	#1 array, 22 bytes at 0
+//	00000000  2a 32 0d 0a 24 33 0d 0a  47 45 54 0d 0a 24 33 0d  |*2..$3..GET..$3.|
+//	00000010  0a 66 6f 6f 0d 0a                                 |.foo..|
+//
+// Lines that aren't RESP, such as inline commands, are dumped on their own.
+// Objects are dumped once they're complete, so the end of a stream may remain
+// buffered until Flush is called. A DumpWriter can watch a connection's
+// traffic with io.TeeReader or io.MultiWriter.
+type DumpWriter struct {
+	out io.Writer
+	// buf holds the incomplete object at offset, if any.
+	buf    []byte
+	offset int64
+	scan   objectScanner
+	count  int
+	line   []byte
+	err    error
+}
+
+// NewDumpWriter returns a DumpWriter that writes dumps to out.
+func NewDumpWriter(out io.Writer) *DumpWriter {
+	return &DumpWriter{out: out}
+}
+
+// Write dumps the objects completed by p. It only fails if writing to the
+// underlying io.Writer does.
+func (d *DumpWriter) Write(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+	d.buf = append(d.buf, p...)
+
+	i := 0
+	for i < len(d.buf) && d.err == nil {
+		end, err := d.scan.scan(d.buf[i:])
+		if err != nil {
+			// Resynchronize at the next line
+			end = bytes.IndexByte(d.buf[i:], '\n')
+			if end < 0 {
+				break
+			}
+			d.scan.reset()
+			d.dump("not RESP", d.buf[i:i+end+1])
+			i += end + 1
+			continue
+		}
+		if end < 0 {
+			break
+		}
+		d.scan.reset()
+		d.count++
+		d.dump(fmt.Sprintf("#%d %s", d.count, prefixNames[d.buf[i]]), d.buf[i:i+end+1])
+		i += end + 1
+	}
+	if i > 0 {
+		d.buf = append(d.buf[:0], d.buf[i:]...)
+	}
+	return len(p), d.err
+}
+
+// Flush dumps any incomplete object or line written so far.
+func (d *DumpWriter) Flush() error {
+	if d.err == nil && len(d.buf) > 0 {
+		d.dump("incomplete", d.buf)
+		d.buf = d.buf[:0]
+		d.scan.reset()
+	}
+	return d.err
+}
+
+// dump writes a block for b, which starts at d.offset.
+func (d *DumpWriter) dump(header string, b []byte) {
+	d.line = append(d.line[:0], header...)
+	d.line = append(d.line, fmt.Sprintf(", %d bytes at %d\n", len(b), d.offset)...)
+	for i := 0; i < len(b); i += 16 {
+		row := b[i:]
+		if len(row) > 16 {
+			row = row[:16]
+		}
+		d.line = append(d.line, fmt.Sprintf("%08x ", d.offset+int64(i))...)
+		for j := 0; j < 16; j++ {
+			if j == 8 {
+				d.line = append(d.line, ' ')
+			}
+			if j < len(row) {
+				d.line = append(d.line, fmt.Sprintf(" %02x", row[j])...)
+			} else {
+				d.line = append(d.line, "   "...)
+			}
+		}
+		d.line = append(d.line, "  |"...)
+		for _, c := range row {
+			if c < ' ' || c > '~' {
+				c = '.'
+			}
+			d.line = append(d.line, c)
+		}
+		d.line = append(d.line, "|\n"...)
+	}
+	d.offset += int64(len(b))
+	_, d.err = d.out.Write(d.line)
+}
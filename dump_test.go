@@ -0,0 +1,67 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDumpWriter(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDumpWriter(&out)
+	stream := string(NewCommand("GET", "foo")) + "PING\r\n" + "%1\r\n+a\r\n:1\r\n" + "$5\r\nhel"
+	// Written a few bytes at a time
+	for i := 0; i < len(stream); i += 3 {
+		end := i + 3
+		if end > len(stream) {
+			end = len(stream)
+		}
+		if _, err := d.Write([]byte(stream[i:end])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := "" +
+		"#1 array, 22 bytes at 0\n" +
+		"00000000  2a 32 0d 0a 24 33 0d 0a  47 45 54 0d 0a 24 33 0d  |*2..$3..GET..$3.|\n" +
+		"00000010  0a 66 6f 6f 0d 0a                                 |.foo..|\n" +
+		"not RESP, 6 bytes at 22\n" +
+		"00000016  50 49 4e 47 0d 0a                                 |PING..|\n" +
+		"#2 map, 12 bytes at 28\n" +
+		"0000001c  25 31 0d 0a 2b 61 0d 0a  3a 31 0d 0a              |%1..+a..:1..|\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected = "incomplete, 7 bytes at 40\n" +
+		"00000028  24 35 0d 0a 68 65 6c                              |$5..hel|\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestDumpWriter_Tee(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDumpWriter(&out)
+	r := NewReader(io.TeeReader(strings.NewReader("+OK\r\n:1\r\n"), d))
+	for {
+		if _, err := r.ReadObject(); err != nil {
+			break
+		}
+	}
+	if !strings.Contains(out.String(), "#1 simple string, 5 bytes at 0\n") || !strings.Contains(out.String(), "#2 integer, 4 bytes at 5\n") {
+		t.Errorf("unexpected dump:\n%s", out.String())
+	}
+
+	d = NewDumpWriter(failingWriter{})
+	if _, err := d.Write([]byte("+OK\r\n")); err == nil {
+		t.Errorf("expected an error")
+	}
+	if _, err := d.Write([]byte("+OK\r\n")); err == nil {
+		t.Errorf("expected the error to persist")
+	}
+}
@@ -36,3 +36,26 @@ func (e Error) Bytes() []byte {
 func (e Error) Error() string {
 	return string(e.Slice())
 }
+
+// As allows errors.As to extract a *Redirect from a -MOVED or -ASK Error.
+func (e Error) As(target interface{}) bool {
+	redirect, ok := target.(**Redirect)
+	if !ok {
+		return false
+	}
+	parsed, ok := ParseRedirect(e)
+	if ok {
+		*redirect = parsed
+	}
+	return ok
+}
+
+// Code returns the first word of the error message, which Redis uses as the
+// error's kind, e.g. "ERR", "WRONGTYPE", or "MOVED".
+func (e Error) Code() string {
+	msg := e.Slice()
+	if i := bytes.IndexByte(msg, ' '); i >= 0 {
+		msg = msg[:i]
+	}
+	return string(msg)
+}
@@ -29,3 +29,17 @@ func TestNewError(t *testing.T) {
 		t.Errorf("expected: %v\ngot: %v", expected, e)
 	}
 }
+
+func TestErrorCode(t *testing.T) {
+	tests := map[string]string{
+		"-ERR unknown command\r\n":       "ERR",
+		"-LOADING\r\n":                   "LOADING",
+		"-MOVED 3999 127.0.0.1:6381\r\n": "MOVED",
+		"-\r\n":                          "",
+	}
+	for given, expected := range tests {
+		if code := Error(given).Code(); code != expected {
+			t.Errorf("%q: expected %q, got %q", given, expected, code)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package resp
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrFanoutTimeout is returned by Fanout.Execute when a subcommand isn't
+// answered before the Fanout's Timeout.
+var ErrFanoutTimeout = errors.New("resp: timed out waiting for subcommand reply")
+
+var (
+	// FANOUT_TIMEOUT_ERROR is merged into FANOUT_BEST_EFFORT replies for
+	// subcommands that time out.
+	FANOUT_TIMEOUT_ERROR = NewError("ERR fan-out subcommand timed out")
+	// FANOUT_DISPATCH_ERROR is merged into FANOUT_BEST_EFFORT replies for
+	// subcommands whose Dispatch failed. The error's text isn't sent, since
+	// it's meant for the proxy rather than its clients.
+	FANOUT_DISPATCH_ERROR = NewError("ERR fan-out subcommand failed")
+)
+
+// A FanoutPolicy decides how a Fanout handles subcommands that fail.
+type FanoutPolicy int
+
+const (
+	// FANOUT_FAIL_FAST makes Execute return as soon as any subcommand fails,
+	// without waiting for the rest.
+	FANOUT_FAIL_FAST FanoutPolicy = iota
+	// FANOUT_BEST_EFFORT makes Execute wait for every subcommand and merge
+	// failed ones into the reply as Errors, so that e.g. an MGET reply only
+	// has errors for the keys that failed.
+	FANOUT_BEST_EFFORT
+)
+
+// A Fanout executes multi-key commands against a cluster by splitting them
+// with SplitCommand, dispatching the subcommands concurrently, and merging
+// their replies with MergeReplies.
+type Fanout struct {
+	// Dispatch sends a subcommand to the upstream that serves its key and
+	// returns the reply. It's called concurrently from multiple goroutines.
+	Dispatch func(Subcommand) (Object, error)
+	// Timeout limits the time Execute waits for all replies. Zero means no
+	// limit. Dispatch calls that are still running when Execute returns are
+	// left to finish on their own.
+	Timeout time.Duration
+	Policy  FanoutPolicy
+}
+
+type fanoutResult struct {
+	position int
+	reply    Object
+	err      error
+}
+
+// Execute runs a command and returns the merged reply. A subcommand fails if
+// Dispatch returns an error, if its reply is an Error, or if it times out.
+// With FANOUT_FAIL_FAST, the first failure is returned: an Error reply as the
+// reply and any other failure as the error. With FANOUT_BEST_EFFORT, failures
+// that aren't Error replies are turned into FANOUT_TIMEOUT_ERROR or
+// FANOUT_DISPATCH_ERROR for merging, unless Dispatch returned an Error.
+// Commands that can't be split are the Error reply Redis would send for them.
+func (f *Fanout) Execute(name string, args [][]byte) (Object, error) {
+	subcommands, err := SplitCommand(name, args)
+	if err != nil {
+		if e, ok := err.(Error); ok {
+			return e, nil
+		}
+		return nil, err
+	}
+
+	results := make(chan fanoutResult, len(subcommands))
+	for _, sub := range subcommands {
+		go func(sub Subcommand) {
+			reply, err := f.Dispatch(sub)
+			results <- fanoutResult{sub.Position, reply, err}
+		}(sub)
+	}
+
+	var timeout <-chan time.Time
+	if f.Timeout > 0 {
+		timer := time.NewTimer(f.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	parts := make([]Object, len(subcommands))
+	for received := 0; received < len(subcommands); received++ {
+		var result fanoutResult
+		select {
+		case result = <-results:
+		case <-timeout:
+			if f.Policy == FANOUT_FAIL_FAST {
+				return nil, ErrFanoutTimeout
+			}
+			for i := range parts {
+				if parts[i] == nil {
+					parts[i] = FANOUT_TIMEOUT_ERROR
+				}
+			}
+			return MergeReplies(name, parts)
+		}
+
+		if f.Policy == FANOUT_FAIL_FAST {
+			if result.err != nil {
+				return nil, result.err
+			}
+			if e, ok := result.reply.(Error); ok {
+				return e, nil
+			}
+		}
+		if e, ok := result.err.(Error); ok {
+			result.reply = e
+		} else if result.err != nil {
+			result.reply = FANOUT_DISPATCH_ERROR
+		}
+		parts[result.position] = result.reply
+	}
+	return MergeReplies(name, parts)
+}
@@ -0,0 +1,87 @@
+package resp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func byteArgs(args ...string) [][]byte {
+	slices := make([][]byte, len(args))
+	for i, arg := range args {
+		slices[i] = []byte(arg)
+	}
+	return slices
+}
+
+// fakeDispatch answers GET subcommands with the key, fails for the key
+// "broken", fails with an Error for "denied", returns an error reply for
+// "wrongtype", and takes a second to answer for "slow".
+func fakeDispatch(sub Subcommand) (Object, error) {
+	key := string(sub.Args[0])
+	switch key {
+	case "broken":
+		return nil, errors.New("connection refused")
+	case "denied":
+		return nil, NewError("NOPERM this user has no permissions to access the key")
+	case "wrongtype":
+		return NewError("WRONGTYPE Operation against a key holding the wrong kind of value"), nil
+	case "slow":
+		time.Sleep(time.Second)
+	}
+	if CommandEquals([]byte(sub.Name), "DEL") {
+		return NewInteger(1), nil
+	}
+	return NewArray(NewBulkString(key)), nil
+}
+
+func TestFanout(t *testing.T) {
+	fanout := &Fanout{Dispatch: fakeDispatch, Policy: FANOUT_BEST_EFFORT, Timeout: 50 * time.Millisecond}
+
+	reply, err := fanout.Execute("MGET", byteArgs("a", "broken", "denied", "wrongtype", "slow", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := NewArray(
+		NewBulkString("a"),
+		FANOUT_DISPATCH_ERROR,
+		NewError("NOPERM this user has no permissions to access the key"),
+		NewError("WRONGTYPE Operation against a key holding the wrong kind of value"),
+		FANOUT_TIMEOUT_ERROR,
+		NewBulkString("b"),
+	)
+	if string(expected) != string(reply.Raw()) {
+		t.Errorf("expected: %q\ngot: %q", expected, reply.Raw())
+	}
+
+	reply, err = fanout.Execute("DEL", byteArgs("a", "b", "c"))
+	if err != nil || string(reply.Raw()) != ":3\r\n" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+
+	reply, err = fanout.Execute("MGET", nil)
+	if _, ok := reply.(Error); !ok || err != nil {
+		t.Errorf("expected an error reply, got %q, %v", reply, err)
+	}
+}
+
+func TestFanout_FailFast(t *testing.T) {
+	fanout := &Fanout{Dispatch: fakeDispatch, Timeout: 50 * time.Millisecond}
+
+	if _, err := fanout.Execute("MGET", byteArgs("a", "broken")); err == nil || err.Error() != "connection refused" {
+		t.Errorf("expected the dispatch error, got %v", err)
+	}
+
+	reply, err := fanout.Execute("MGET", byteArgs("a", "wrongtype"))
+	if e, ok := reply.(Error); !ok || err != nil || e.Error() != "WRONGTYPE Operation against a key holding the wrong kind of value" {
+		t.Errorf("expected the error reply, got %q, %v", reply, err)
+	}
+
+	start := time.Now()
+	if _, err := fanout.Execute("MGET", byteArgs("a", "slow")); err != ErrFanoutTimeout {
+		t.Errorf("expected ErrFanoutTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected Execute to return at the deadline, took %v", elapsed)
+	}
+}
@@ -0,0 +1,176 @@
+package resp
+
+import (
+	"sync/atomic"
+)
+
+// A FrameQueue hands Frames from one goroutine, the producer, to another, the
+// consumer, e.g. from a goroutine reading objects from a client to one
+// forwarding them to a server. It's a ring buffer of a fixed size, which the
+// producer and consumer share without locks. The producer blocks while the
+// queue is full, which in turn stops it from reading more, and the consumer
+// while it's empty. A FrameQueue must not be used by multiple producers or
+// multiple consumers.
+type FrameQueue struct {
+	// head is the number of Frames popped, which only the consumer writes,
+	// and tail the number pushed, which only the producer writes. They're
+	// kept on separate cache lines, and first to be 64-bit aligned.
+	head uint64
+	_    [56]byte
+	tail uint64
+	_    [56]byte
+
+	slots []Frame
+	mask  uint64
+
+	// closed is set by Close. The producer and consumer set waitingFull
+	// and waitingEmpty before blocking on notFull and notEmpty, so that
+	// they're only signaled when they might be waiting.
+	closed       int32
+	waitingFull  int32
+	waitingEmpty int32
+	notFull      chan struct{}
+	notEmpty     chan struct{}
+}
+
+// NewFrameQueue returns a FrameQueue that holds up to size Frames, rounded up
+// to a power of two.
+func NewFrameQueue(size int) *FrameQueue {
+	n := 1
+	for n < size {
+		n *= 2
+	}
+	return &FrameQueue{
+		slots:    make([]Frame, n),
+		mask:     uint64(n - 1),
+		notFull:  make(chan struct{}, 1),
+		notEmpty: make(chan struct{}, 1),
+	}
+}
+
+// Push adds f to the queue, waiting for room if it's full. It returns false
+// if the queue is closed, in which case f isn't added.
+func (q *FrameQueue) Push(f Frame) bool {
+	for {
+		if q.isClosed() {
+			return false
+		}
+		if q.TryPush(f) {
+			return true
+		}
+		atomic.StoreInt32(&q.waitingFull, 1)
+		// The consumer might have made room before seeing waitingFull
+		if q.TryPush(f) {
+			atomic.StoreInt32(&q.waitingFull, 0)
+			return true
+		}
+		if q.isClosed() {
+			return false
+		}
+		<-q.notFull
+	}
+}
+
+// TryPush adds f to the queue unless it's full or closed, and returns true if
+// it did.
+func (q *FrameQueue) TryPush(f Frame) bool {
+	tail := q.tail
+	if tail-atomic.LoadUint64(&q.head) == uint64(len(q.slots)) || q.isClosed() {
+		return false
+	}
+	q.slots[tail&q.mask] = f
+	atomic.StoreUint64(&q.tail, tail+1)
+	q.signal(&q.waitingEmpty, q.notEmpty)
+	return true
+}
+
+// Pop removes and returns the oldest Frame in the queue, waiting for one if
+// it's empty. It returns false once the queue is closed and empty.
+func (q *FrameQueue) Pop() (Frame, bool) {
+	for {
+		if f, ok := q.TryPop(); ok {
+			return f, true
+		}
+		atomic.StoreInt32(&q.waitingEmpty, 1)
+		// The producer might have pushed before seeing waitingEmpty
+		if f, ok := q.TryPop(); ok {
+			atomic.StoreInt32(&q.waitingEmpty, 0)
+			return f, true
+		}
+		if q.isClosed() {
+			// Frames pushed before Close are still popped
+			if f, ok := q.TryPop(); ok {
+				return f, true
+			}
+			return nil, false
+		}
+		<-q.notEmpty
+	}
+}
+
+// TryPop removes and returns the oldest Frame in the queue, if there's one.
+func (q *FrameQueue) TryPop() (Frame, bool) {
+	head := q.head
+	if head == atomic.LoadUint64(&q.tail) {
+		return nil, false
+	}
+	i := head & q.mask
+	f := q.slots[i]
+	q.slots[i] = nil
+	atomic.StoreUint64(&q.head, head+1)
+	q.signal(&q.waitingFull, q.notFull)
+	return f, true
+}
+
+// Len returns the number of Frames in the queue.
+func (q *FrameQueue) Len() int {
+	return int(atomic.LoadUint64(&q.tail) - atomic.LoadUint64(&q.head))
+}
+
+// Close closes the queue. It may be called by the producer once it's done,
+// after which the consumer pops the remaining Frames, or by the consumer to
+// stop the producer, in which case the remaining Frames are dropped without
+// being released.
+func (q *FrameQueue) Close() {
+	atomic.StoreInt32(&q.closed, 1)
+	wake(q.notFull)
+	wake(q.notEmpty)
+}
+
+// ReadFrom pushes the Frames read from r until reading fails or the queue is
+// closed, and then closes the queue. It returns the error reading failed with,
+// or nil if the queue was closed. It's meant to be run by the producer.
+func (q *FrameQueue) ReadFrom(r *Reader) error {
+	defer q.Close()
+	for {
+		f, err := r.ReadFrame()
+		if err != nil {
+			f.Release()
+			return err
+		}
+		if !q.Push(f) {
+			f.Release()
+			return nil
+		}
+	}
+}
+
+func (q *FrameQueue) isClosed() bool {
+	return atomic.LoadInt32(&q.closed) == 1
+}
+
+// signal wakes the other side of the queue if it set waiting before
+// blocking on c.
+func (q *FrameQueue) signal(waiting *int32, c chan struct{}) {
+	if atomic.LoadInt32(waiting) == 1 && atomic.CompareAndSwapInt32(waiting, 1, 0) {
+		wake(c)
+	}
+}
+
+// wake wakes the goroutine blocked on c, or the next one to block on it.
+func wake(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,89 @@
+package resp
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrameQueue(t *testing.T) {
+	q := NewFrameQueue(3)
+	for i := 0; i < 4; i++ {
+		if !q.TryPush(Frame(NewInteger(int64(i)))) {
+			t.Fatalf("expected room for frames[%d]", i)
+		}
+	}
+	if q.TryPush(Frame(OK)) || q.Len() != 4 {
+		t.Errorf("expected the queue to be full, got %d frames", q.Len())
+	}
+
+	// Frames are handed over in order, with the producer waiting for room
+	const n = 10000
+	go func() {
+		for i := 4; i < n; i++ {
+			q.Push(Frame(NewInteger(int64(i))))
+		}
+		q.Close()
+	}()
+	for i := 0; i < n; i++ {
+		f, ok := q.Pop()
+		if expected := ":" + strconv.Itoa(i) + "\r\n"; !ok || string(f) != expected {
+			t.Fatalf("expected %q, got %q, %v", expected, f, ok)
+		}
+	}
+	if f, ok := q.Pop(); ok {
+		t.Errorf("expected the queue to be closed, got %q", f)
+	}
+	if q.Push(Frame(OK)) {
+		t.Errorf("expected pushing to a closed queue to fail")
+	}
+}
+
+func TestFrameQueue_CloseByConsumer(t *testing.T) {
+	q := NewFrameQueue(1)
+	q.Push(Frame(OK))
+	pushed := make(chan bool)
+	go func() { pushed <- q.Push(Frame(OK)) }()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	if <-pushed {
+		t.Errorf("expected the blocked push to fail")
+	}
+}
+
+func TestFrameQueue_ReadFrom(t *testing.T) {
+	q := NewFrameQueue(2)
+	r := NewReader(strings.NewReader(strings.Repeat("+OK\r\n", 100)))
+	done := make(chan error)
+	go func() { done <- q.ReadFrom(r) }()
+	for i := 0; i < 100; i++ {
+		f, ok := q.Pop()
+		if !ok || string(f) != "+OK\r\n" {
+			t.Fatalf("frames[%d]: unexpected frame: %q, %v", i, f, ok)
+		}
+		f.Release()
+	}
+	if _, ok := q.Pop(); ok {
+		t.Errorf("expected the queue to be closed")
+	}
+	if err := <-done; err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func BenchmarkFrameQueue(b *testing.B) {
+	q := NewFrameQueue(1024)
+	go func() {
+		for i := 0; i < b.N; i++ {
+			q.Push(Frame(OK))
+		}
+		q.Close()
+	}()
+	for {
+		if _, ok := q.Pop(); !ok {
+			break
+		}
+	}
+}
@@ -0,0 +1,192 @@
+//go:build ignore
+
+// gen_command_table writes command_table.go from the COMMAND reply of a live
+// Redis server:
+//
+//	go run gen_command_table.go -addr localhost:6379 -o command_table.go
+//
+// Only the flags that have a CommandFlags constant are kept.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/stvp/resp"
+)
+
+// flagNames maps the flags in COMMAND replies to CommandFlags constants, in
+// the order the constants are declared.
+var flagNames = []struct {
+	flag     string
+	constant string
+}{
+	{"write", "FLAG_WRITE"},
+	{"readonly", "FLAG_READONLY"},
+	{"denyoom", "FLAG_DENYOOM"},
+	{"admin", "FLAG_ADMIN"},
+	{"pubsub", "FLAG_PUBSUB"},
+	{"noscript", "FLAG_NOSCRIPT"},
+	{"blocking", "FLAG_BLOCKING"},
+	{"fast", "FLAG_FAST"},
+	{"movablekeys", "FLAG_MOVABLEKEYS"},
+}
+
+// transactionCommands are marked with FLAG_TRANSACTION, which isn't a Redis
+// flag.
+var transactionCommands = map[string]bool{
+	"DISCARD": true,
+	"EXEC":    true,
+	"MULTI":   true,
+	"UNWATCH": true,
+	"WATCH":   true,
+}
+
+var errUnexpectedReply = errors.New("unexpected COMMAND reply")
+
+type command struct {
+	name                    string
+	arity                   int64
+	flags                   []string
+	firstKey, lastKey, step int64
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:6379", "address of the Redis server")
+	out := flag.String("o", "command_table.go", "file to write")
+	flag.Parse()
+
+	conn, err := resp.Dial("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	reply, err := conn.Do("INFO", "server")
+	if err != nil {
+		log.Fatal(err)
+	}
+	info, err := resp.ParseInfo(reply)
+	if err != nil {
+		log.Fatal(err)
+	}
+	version := info["server"]["redis_version"]
+
+	reply, err = conn.Do("COMMAND")
+	if err != nil {
+		log.Fatal(err)
+	}
+	commands, err := parseCommands(reply)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].name < commands[j].name })
+
+	src, err := format.Source(render(version, commands))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parseCommands(reply resp.Object) ([]command, error) {
+	array, ok := reply.(resp.Array)
+	if !ok {
+		return nil, errUnexpectedReply
+	}
+	objects, err := array.Objects()
+	if err != nil {
+		return nil, err
+	}
+
+	commands := make([]command, len(objects))
+	for i, obj := range objects {
+		fields, ok := obj.(resp.Array)
+		if !ok {
+			return nil, errUnexpectedReply
+		}
+		items, err := fields.Objects()
+		if err != nil || len(items) < 6 {
+			return nil, errUnexpectedReply
+		}
+		name, ok1 := items[0].(resp.String)
+		flags, ok2 := items[2].(resp.Array)
+		if !ok1 || !ok2 {
+			return nil, errUnexpectedReply
+		}
+		// Arity, first key, last key, and step
+		var ints [4]int64
+		for j, k := range []int{1, 3, 4, 5} {
+			integer, ok := items[k].(resp.Integer)
+			if !ok {
+				return nil, errUnexpectedReply
+			}
+			if ints[j], err = integer.Int64(); err != nil {
+				return nil, err
+			}
+		}
+		c := &commands[i]
+		*c = command{strings.ToUpper(name.String()), ints[0], nil, ints[1], ints[2], ints[3]}
+		flagObjects, err := flags.Objects()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range flagObjects {
+			if s, ok := f.(resp.String); ok {
+				c.flags = append(c.flags, s.String())
+			}
+		}
+	}
+	return commands, nil
+}
+
+func render(version string, commands []command) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen_command_table.go from Redis %s; DO NOT EDIT.\n\n", version)
+	buf.WriteString("package resp\n\n")
+	buf.WriteString(header)
+	buf.WriteString("var commandTable = []CommandInfo{\n")
+	for _, c := range commands {
+		fmt.Fprintf(&buf, "\t{%q, %d, %s, %d, %d, %d},\n", c.name, c.arity, flagsExpr(c), c.firstKey, c.lastKey, c.step)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// flagsExpr returns the CommandFlags expression for the flags of c.
+func flagsExpr(c command) string {
+	has := map[string]bool{}
+	for _, f := range c.flags {
+		has[f] = true
+	}
+	var constants []string
+	for _, f := range flagNames {
+		if has[f.flag] {
+			constants = append(constants, f.constant)
+		}
+	}
+	if transactionCommands[c.name] {
+		constants = append(constants, "FLAG_TRANSACTION")
+	}
+	if len(constants) == 0 {
+		return "0"
+	}
+	return strings.Join(constants, " | ")
+}
+
+const header = `// commandTable mirrors the output of Redis 7's COMMAND INFO for every
+// top-level command: name, arity, flags, and the legacy first key, last key,
+// and key step. Container commands (CLIENT, CONFIG, ...) are listed without
+// flags because their flags depend on the subcommand. FLAG_TRANSACTION isn't a
+// Redis flag; it's added here to mark the commands that make up transactions.
+// The table is generated from a live server by gen_command_table.go.
+`
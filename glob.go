@@ -0,0 +1,84 @@
+package resp
+
+// matchPattern returns true if s matches the glob-style pattern as Redis
+// matches PSUBSCRIBE patterns and KEYS patterns: '*' matches any sequence of
+// bytes, '?' matches any byte, "[abc]", "[^abc]", and "[a-c]" match one byte
+// of, not of, or in a range of bytes, and '\' escapes the next byte.
+func matchPattern(pattern, s []byte) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchPattern(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			var matched bool
+			matched, pattern = matchClass(pattern[1:], s[0])
+			if !matched {
+				return false
+			}
+			s = s[1:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+			s = s[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass returns true if b matches the character class at the start of
+// pattern, just after its '[', and returns the rest of the pattern after the
+// class's ']'.
+func matchClass(pattern []byte, b byte) (bool, []byte) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			matched = matched || pattern[1] == b
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			start, end := pattern[0], pattern[2]
+			if start > end {
+				start, end = end, start
+			}
+			matched = matched || (b >= start && b <= end)
+			pattern = pattern[3:]
+		default:
+			matched = matched || pattern[0] == b
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		// Skip the ']'
+		pattern = pattern[1:]
+	}
+	return matched != negate, pattern
+}
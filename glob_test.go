@@ -0,0 +1,38 @@
+package resp
+
+import (
+	"testing"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"news.*", "news.sports", true},
+		{"news.*", "news.", true},
+		{"news.*", "weather", false},
+		{"*", "", true},
+		{"a**b", "axxb", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[c-a]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{"h\\*llo", "h*llo", true},
+		{"h\\*llo", "hello", false},
+		{"[\\]]", "]", true},
+		{"a/b", "a/b", true},
+		{"*/*", "a/b/c", true},
+	}
+	for i, test := range tests {
+		if match := matchPattern([]byte(test.pattern), []byte(test.s)); match != test.match {
+			t.Errorf("tests[%d]: %q against %q: expected %t, got %t", i, test.pattern, test.s, test.match, match)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package resp
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"hash/crc32"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// ErrCrossNode is returned by HashRing.Route for commands whose keys map to
+// different nodes.
+var ErrCrossNode = errors.New("resp: keys in request map to different nodes")
+
+// DEFAULT_VIRTUAL_NODES is the number of points each node gets on a HashRing
+// if all nodes have the same weight, as in twemproxy and libketama.
+const DEFAULT_VIRTUAL_NODES = 160
+
+// A HashFunc hashes a key to a point on a HashRing.
+type HashFunc func([]byte) uint32
+
+// Hash functions that match twemproxy's hash functions of the same names.
+var (
+	HashMD5 HashFunc = func(key []byte) uint32 {
+		digest := md5.Sum(key)
+		return ketamaPoint(digest, 0)
+	}
+
+	HashCRC32 HashFunc = crc32.ChecksumIEEE
+
+	HashFNV1a32 HashFunc = func(key []byte) uint32 {
+		hash := uint32(2166136261)
+		for _, c := range key {
+			hash ^= uint32(c)
+			hash *= 16777619
+		}
+		return hash
+	}
+
+	// HashFNV1a64 is the 64-bit FNV-1a hash computed with 32-bit arithmetic,
+	// as twemproxy does.
+	HashFNV1a64 HashFunc = func(key []byte) uint32 {
+		hash := uint32(0xcbf29ce484222325 & math.MaxUint32)
+		for _, c := range key {
+			hash ^= uint32(c)
+			hash *= uint32(0x100000001b3 & math.MaxUint32)
+		}
+		return hash
+	}
+)
+
+// A HashNode is a node on a HashRing. Name is hashed to place the node's
+// points on the ring, so renaming a node moves its keys; it defaults to Addr.
+// Weight defaults to 1.
+type HashNode struct {
+	Name   string
+	Addr   string
+	Weight int
+}
+
+// HashRingConfig configures a HashRing. The zero value makes a ring that
+// distributes keys like twemproxy's default ketama configuration.
+type HashRingConfig struct {
+	// Hash hashes keys. It defaults to HashFNV1a64. Node points are always
+	// placed with MD5, as in ketama.
+	Hash HashFunc
+	// HashTag, if set, is a two character string such as "{}". When a key
+	// contains both characters, only the part between them is hashed, so that
+	// related keys map to the same node.
+	HashTag string
+	// VirtualNodes is the average number of points per node. It defaults to
+	// DEFAULT_VIRTUAL_NODES.
+	VirtualNodes int
+}
+
+// A HashRing maps keys to nodes with ketama consistent hashing, for sharded
+// deployments that don't use Redis Cluster. When all nodes have the same
+// weight, adding or removing a node only moves the keys that the node gains or
+// loses. A HashRing is immutable and safe for concurrent use.
+type HashRing struct {
+	hash    HashFunc
+	hashTag string
+	points  []ringPoint
+}
+
+type ringPoint struct {
+	value uint32
+	addr  string
+}
+
+// NewHashRing returns a HashRing of nodes. Nodes with a Weight less than 1
+// are given a weight of 1.
+func NewHashRing(nodes []HashNode, config HashRingConfig) *HashRing {
+	r := &HashRing{hash: config.Hash, hashTag: config.HashTag}
+	if r.hash == nil {
+		r.hash = HashFNV1a64
+	}
+	virtualNodes := config.VirtualNodes
+	if virtualNodes < 1 {
+		virtualNodes = DEFAULT_VIRTUAL_NODES
+	}
+
+	totalWeight := 0
+	for _, node := range nodes {
+		totalWeight += weight(node)
+	}
+	for _, node := range nodes {
+		name := node.Name
+		if name == "" {
+			name = node.Addr
+		}
+		// Points are added four at a time, one per 32 bits of an MD5 digest.
+		share := float64(weight(node)) / float64(totalWeight)
+		groups := int(math.Floor(share*float64(virtualNodes)/4*float64(len(nodes)) + 0.0000000001))
+		for i := 0; i < groups; i++ {
+			digest := md5.Sum([]byte(name + "-" + strconv.Itoa(i)))
+			for j := 0; j < 4; j++ {
+				r.points = append(r.points, ringPoint{ketamaPoint(digest, j), node.Addr})
+			}
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i].value < r.points[j].value
+	})
+	return r
+}
+
+func weight(node HashNode) int {
+	if node.Weight < 1 {
+		return 1
+	}
+	return node.Weight
+}
+
+// ketamaPoint returns the i'th little endian 32-bit word of digest.
+func ketamaPoint(digest [md5.Size]byte, i int) uint32 {
+	return uint32(digest[3+i*4])<<24 | uint32(digest[2+i*4])<<16 | uint32(digest[1+i*4])<<8 | uint32(digest[i*4])
+}
+
+// Lookup returns the address of the node key maps to, or "" if the ring has
+// no nodes.
+func (r *HashRing) Lookup(key []byte) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	if len(r.hashTag) == 2 {
+		if start := bytes.IndexByte(key, r.hashTag[0]); start >= 0 {
+			if end := bytes.IndexByte(key[start+1:], r.hashTag[1]); end > 0 {
+				key = key[start+1 : start+1+end]
+			}
+		}
+	}
+
+	value := r.hash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].value >= value
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].addr
+}
+
+// Route returns the address of the node that should serve a command. It
+// returns ErrNoKeys for commands without keys, ErrCrossNode for commands
+// whose keys map to different nodes, and the errors of ExtractKeys for
+// invalid commands.
+func (r *HashRing) Route(name string, args [][]byte) (string, error) {
+	keys, err := ExtractKeys(name, args)
+	if err != nil {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", ErrNoKeys
+	}
+	addr := r.Lookup(keys[0])
+	for _, key := range keys[1:] {
+		if r.Lookup(key) != addr {
+			return "", ErrCrossNode
+		}
+	}
+	return addr, nil
+}
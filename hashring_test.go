@@ -0,0 +1,74 @@
+package resp
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHashFuncs(t *testing.T) {
+	tests := []struct {
+		hash     HashFunc
+		key      string
+		expected uint32
+	}{
+		{HashFNV1a32, "", 0x811c9dc5},
+		{HashFNV1a32, "a", 0xe40c292c},
+		{HashFNV1a64, "", 0x84222325},
+		{HashCRC32, "123456789", 0xcbf43926},
+		{HashMD5, "", 0xd98c1dd4},
+	}
+	for i, test := range tests {
+		if hash := test.hash([]byte(test.key)); hash != test.expected {
+			t.Errorf("tests[%d]: expected %#x, got %#x", i, test.expected, hash)
+		}
+	}
+}
+
+func TestHashRing(t *testing.T) {
+	nodes := []HashNode{
+		{Addr: "10.0.0.1:6379"},
+		{Addr: "10.0.0.2:6379"},
+		{Addr: "10.0.0.3:6379", Weight: 2},
+	}
+	ring := NewHashRing(nodes, HashRingConfig{HashTag: "{}"})
+	// 160 points per node on average, split by weight
+	if len(ring.points) != 480 {
+		t.Errorf("expected 480 points, got %d", len(ring.points))
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[ring.Lookup([]byte("key:"+strconv.Itoa(i)))]++
+	}
+	if len(counts) != 3 || counts["10.0.0.3:6379"] < counts["10.0.0.1:6379"] {
+		t.Errorf("unexpected distribution: %v", counts)
+	}
+
+	// With equal weights, adding a node only moves keys to the new node
+	equal := NewHashRing(nodes[:2], HashRingConfig{})
+	grown := NewHashRing(append(nodes[:2:2], HashNode{Addr: "10.0.0.4:6379"}), HashRingConfig{})
+	for i := 0; i < 10000; i++ {
+		key := []byte("key:" + strconv.Itoa(i))
+		if before, after := equal.Lookup(key), grown.Lookup(key); before != after && after != "10.0.0.4:6379" {
+			t.Fatalf("%s moved from %s to %s", key, before, after)
+		}
+	}
+
+	if addr, err := ring.Route("MGET", byteArgs("{user1}.a", "{user1}.b")); err != nil || addr != ring.Lookup([]byte("user1")) {
+		t.Errorf("unexpected route: %s, %v", addr, err)
+	}
+	if _, err := ring.Route("PING", nil); err != ErrNoKeys {
+		t.Errorf("expected ErrNoKeys, got %v", err)
+	}
+	other := 1
+	for ring.Lookup([]byte("key:"+strconv.Itoa(other))) == ring.Lookup([]byte("key:0")) {
+		other++
+	}
+	if _, err := ring.Route("MGET", byteArgs("key:0", "key:"+strconv.Itoa(other))); err != ErrCrossNode {
+		t.Errorf("expected ErrCrossNode, got %v", err)
+	}
+
+	if addr := NewHashRing(nil, HashRingConfig{}).Lookup([]byte("a")); addr != "" {
+		t.Errorf("expected an empty ring, got %q", addr)
+	}
+}
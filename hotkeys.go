@@ -0,0 +1,158 @@
+package resp
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A HotKey is one of the most requested keys reported by HotKeys.Top.
+type HotKey struct {
+	Key string
+	// Count is an estimate of the number of requests for the key. It may be
+	// too high by at most Error.
+	Count uint64
+	Error uint64
+	// Rate is Count divided by the time since the HotKeys was created or
+	// reset, in requests per second.
+	Rate float64
+}
+
+// HotKeys finds the most requested keys in a stream of commands with the
+// space-saving algorithm, which uses memory proportional to its capacity no
+// matter how many distinct keys there are. Any key requested more than
+// 1/capacity of the time is guaranteed to be tracked. HotKeys is safe for
+// concurrent use.
+type HotKeys struct {
+	mu       sync.Mutex
+	capacity int
+	counters map[string]*hotKeyCounter
+	heap     hotKeyHeap
+	since    time.Time
+}
+
+type hotKeyCounter struct {
+	key   string
+	count uint64
+	error uint64
+	index int
+}
+
+// NewHotKeys returns a HotKeys that tracks up to capacity keys.
+func NewHotKeys(capacity int) *HotKeys {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &HotKeys{
+		capacity: capacity,
+		counters: make(map[string]*hotKeyCounter, capacity),
+		since:    time.Now(),
+	}
+}
+
+// ObserveCommand records a request for each of a command's keys. Commands
+// whose keys can't be extracted are ignored.
+func (h *HotKeys) ObserveCommand(name string, args [][]byte) {
+	keys, err := ExtractKeys(name, args)
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		h.Observe(key)
+	}
+}
+
+// Observe records a request for key.
+func (h *HotKeys) Observe(key []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The conversion doesn't allocate when used as a map index.
+	if c, ok := h.counters[string(key)]; ok {
+		c.count++
+		heap.Fix(&h.heap, c.index)
+		return
+	}
+
+	if len(h.heap) < h.capacity {
+		c := &hotKeyCounter{key: string(key), count: 1}
+		h.counters[c.key] = c
+		heap.Push(&h.heap, c)
+		return
+	}
+
+	// Replace the least requested key. The new key may have been requested
+	// as often as the key it replaces.
+	c := h.heap[0]
+	delete(h.counters, c.key)
+	c.key = string(key)
+	c.error = c.count
+	c.count++
+	h.counters[c.key] = c
+	heap.Fix(&h.heap, 0)
+}
+
+// Top returns up to n of the most requested keys, most requested first. It
+// returns no keys if n <= 0.
+func (h *HotKeys) Top(n int) []HotKey {
+	if n <= 0 {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	elapsed := time.Since(h.since).Seconds()
+	keys := make([]HotKey, len(h.heap))
+	for i, c := range h.heap {
+		keys[i] = HotKey{c.key, c.count, c.error, 0}
+		if elapsed > 0 {
+			keys[i].Rate = float64(c.count) / elapsed
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if n < len(keys) {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+// Reset forgets all keys and restarts the rate window.
+func (h *HotKeys) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.counters = make(map[string]*hotKeyCounter, h.capacity)
+	h.heap = nil
+	h.since = time.Now()
+}
+
+// hotKeyHeap is a min-heap of counters by count.
+type hotKeyHeap []*hotKeyCounter
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotKeyHeap) Push(x interface{}) {
+	c := x.(*hotKeyCounter)
+	c.index = len(*h)
+	*h = append(*h, c)
+}
+
+func (h *hotKeyHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
@@ -0,0 +1,50 @@
+package resp
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestHotKeys(t *testing.T) {
+	h := NewHotKeys(10)
+
+	// Three hot keys hidden in a long tail of keys requested once
+	for i := 0; i < 1000; i++ {
+		h.ObserveCommand("GET", byteArgs("hot1"))
+		if i%2 == 0 {
+			h.ObserveCommand("MGET", byteArgs("hot2", "cold:"+strconv.Itoa(i)))
+		}
+		if i%4 == 0 {
+			h.Observe([]byte("hot3"))
+		}
+		h.Observe([]byte("cold:" + strconv.Itoa(i+1000)))
+	}
+	h.ObserveCommand("NOPE", byteArgs("ignored"))
+
+	top := h.Top(3)
+	if len(top) != 3 {
+		t.Fatalf("expected 3 keys, got %v", top)
+	}
+	for i, key := range []string{"hot1", "hot2", "hot3"} {
+		if top[i].Key != key {
+			t.Errorf("top[%d]: expected %s, got %+v", i, key, top[i])
+		}
+	}
+	if top[0].Count < 1000 || top[0].Count-top[0].Error > 1000 {
+		t.Errorf("unexpected count bounds for hot1: %+v", top[0])
+	}
+	if top[0].Rate <= 0 {
+		t.Errorf("expected a rate, got %+v", top[0])
+	}
+	if none := h.Top(-1); len(none) != 0 {
+		t.Errorf("expected no keys, got %v", none)
+	}
+	if all := h.Top(100); len(all) != 10 {
+		t.Errorf("expected capacity to limit keys, got %d", len(all))
+	}
+
+	h.Reset()
+	if top := h.Top(3); len(top) != 0 {
+		t.Errorf("expected no keys after Reset, got %v", top)
+	}
+}
@@ -0,0 +1,154 @@
+package resp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// An Info is an INFO reply parsed into sections of fields, keyed by the
+// section's name in lower case, e.g. "server", "memory", or "keyspace".
+type Info map[string]map[string]string
+
+// A DBStats holds the statistics of a database from the keyspace section of
+// an INFO reply.
+type DBStats struct {
+	Keys    int64
+	Expires int64
+	// AvgTTL is the average TTL of keys with an expiry, in milliseconds.
+	AvgTTL int64
+}
+
+// ParseInfo parses an INFO reply, a bulk string or, from RESP3 servers, a
+// verbatim string. An Error reply is returned as an error, and other replies
+// as ErrUnexpectedReply.
+func ParseInfo(obj Object) (Info, error) {
+	text, err := objectText(obj)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInfoString(text), nil
+}
+
+// ParseInfoString parses the text of an INFO reply, such as the output of
+// redis-cli info. Sections start with a "# Name" line and hold "field:value"
+// lines. Lines that are neither are ignored, as are fields before the first
+// section, which are put in a section named "".
+func ParseInfoString(s string) Info {
+	info := Info{}
+	section := map[string]string{}
+	info[""] = section
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "#") {
+			name := strings.ToLower(strings.TrimSpace(line[1:]))
+			if section = info[name]; section == nil {
+				section = map[string]string{}
+				info[name] = section
+			}
+			continue
+		}
+		if colon := strings.IndexByte(line, ':'); colon > 0 {
+			section[line[:colon]] = line[colon+1:]
+		}
+	}
+	if len(info[""]) == 0 {
+		delete(info, "")
+	}
+	return info
+}
+
+// Get returns the value of a field from any section.
+func (i Info) Get(field string) (string, bool) {
+	for _, section := range i {
+		if value, ok := section[field]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// Int returns the value of a field from any section as an integer. It returns
+// false if the field is missing or isn't an integer.
+func (i Info) Int(field string) (int64, bool) {
+	value, ok := i.Get(field)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	return n, err == nil
+}
+
+// Float returns the value of a field from any section as a float, such as
+// mem_fragmentation_ratio. Integer fields are converted too. It returns false
+// if the field is missing or isn't a number.
+func (i Info) Float(field string) (float64, bool) {
+	value, ok := i.Get(field)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	return f, err == nil
+}
+
+// Values returns the value of a field made of comma separated "name=value"
+// pairs, such as the db0 field of the keyspace section or the cmdstat_get
+// field of the commandstats section, as a map. It returns nil if the field is
+// missing.
+func (i Info) Values(field string) map[string]string {
+	value, ok := i.Get(field)
+	if !ok {
+		return nil
+	}
+	return parseInfoValues(value)
+}
+
+// Role returns the role field: "master" or "slave" for servers, "sentinel"
+// for sentinels, or "" if the reply doesn't include the replication section.
+func (i Info) Role() string {
+	role, _ := i.Get("role")
+	return role
+}
+
+// ConnectedClients returns the connected_clients field.
+func (i Info) ConnectedClients() int64 {
+	n, _ := i.Int("connected_clients")
+	return n
+}
+
+// UsedMemory returns the used_memory field, in bytes.
+func (i Info) UsedMemory() int64 {
+	n, _ := i.Int("used_memory")
+	return n
+}
+
+// Keyspace returns the statistics of each database in the keyspace section,
+// keyed by database number. Databases without keys aren't listed by Redis.
+func (i Info) Keyspace() map[int]DBStats {
+	keyspace := map[int]DBStats{}
+	for field, value := range i["keyspace"] {
+		if !strings.HasPrefix(field, "db") {
+			continue
+		}
+		db, err := strconv.Atoi(field[2:])
+		if err != nil {
+			continue
+		}
+		values := parseInfoValues(value)
+		var stats DBStats
+		stats.Keys, _ = strconv.ParseInt(values["keys"], 10, 64)
+		stats.Expires, _ = strconv.ParseInt(values["expires"], 10, 64)
+		stats.AvgTTL, _ = strconv.ParseInt(values["avg_ttl"], 10, 64)
+		keyspace[db] = stats
+	}
+	return keyspace
+}
+
+func parseInfoValues(s string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if eq := strings.IndexByte(pair, '='); eq >= 0 {
+			values[pair[:eq]] = pair[eq+1:]
+		}
+	}
+	return values
+}
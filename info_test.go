@@ -0,0 +1,85 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testInfo = "# Server\r\n" +
+	"redis_version:7.2.4\r\n" +
+	"uptime_in_seconds:3600\r\n" +
+	"\r\n" +
+	"# Clients\r\n" +
+	"connected_clients:12\r\n" +
+	"\r\n" +
+	"# Memory\r\n" +
+	"used_memory:1048576\r\n" +
+	"mem_fragmentation_ratio:1.25\r\n" +
+	"\r\n" +
+	"# Replication\r\n" +
+	"role:master\r\n" +
+	"\r\n" +
+	"# Commandstats\r\n" +
+	"cmdstat_get:calls=2,usec=10,usec_per_call=5.00\r\n" +
+	"\r\n" +
+	"# Keyspace\r\n" +
+	"db0:keys=100,expires=3,avg_ttl=5000\r\n" +
+	"db12:keys=1,expires=0,avg_ttl=0,subexpiry=0\r\n"
+
+func TestParseInfo(t *testing.T) {
+	for _, obj := range []Object{
+		NewBulkString(testInfo),
+		VerbatimString(appendBlob(nil, VERBATIM_STRING_PREFIX, []byte("txt:"+testInfo))),
+	} {
+		info, err := ParseInfo(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(info) != 6 || info["server"]["redis_version"] != "7.2.4" {
+			t.Errorf("unexpected info %v", info)
+		}
+		if role := info.Role(); role != "master" {
+			t.Errorf("expected master, got %q", role)
+		}
+		if n := info.ConnectedClients(); n != 12 {
+			t.Errorf("expected 12, got %d", n)
+		}
+		if n := info.UsedMemory(); n != 1048576 {
+			t.Errorf("expected 1048576, got %d", n)
+		}
+		if f, ok := info.Float("mem_fragmentation_ratio"); !ok || f != 1.25 {
+			t.Errorf("expected 1.25, got %v, %v", f, ok)
+		}
+		if _, ok := info.Int("mem_fragmentation_ratio"); ok {
+			t.Errorf("expected a float not to be an integer")
+		}
+		if _, ok := info.Int("missing"); ok {
+			t.Errorf("expected a missing field not to be found")
+		}
+		expected := map[string]string{"calls": "2", "usec": "10", "usec_per_call": "5.00"}
+		if values := info.Values("cmdstat_get"); !reflect.DeepEqual(expected, values) {
+			t.Errorf("expected %v, got %v", expected, values)
+		}
+		expectedKeyspace := map[int]DBStats{0: {Keys: 100, Expires: 3, AvgTTL: 5000}, 12: {Keys: 1}}
+		if keyspace := info.Keyspace(); !reflect.DeepEqual(expectedKeyspace, keyspace) {
+			t.Errorf("expected %v, got %v", expectedKeyspace, keyspace)
+		}
+	}
+
+	if _, err := ParseInfo(NewError("ERR unknown section")); err == nil || err.Error() != "ERR unknown section" {
+		t.Errorf("expected the error reply, got %v", err)
+	}
+	for _, obj := range []Object{NewInteger(1), String("$-1\r\n")} {
+		if _, err := ParseInfo(obj); err != ErrUnexpectedReply {
+			t.Errorf("expected ErrUnexpectedReply for %q, got %v", obj.Raw(), err)
+		}
+	}
+}
+
+func TestParseInfoString(t *testing.T) {
+	info := ParseInfoString("loose:1\n# A\nk:v:w\nnot a field\n")
+	expected := Info{"": {"loose": "1"}, "a": {"k": "v:w"}}
+	if !reflect.DeepEqual(expected, info) {
+		t.Errorf("expected %v, got %v", expected, info)
+	}
+}
@@ -0,0 +1,123 @@
+package resp
+
+import (
+	"errors"
+)
+
+var (
+	// ErrUnbalancedQuotes is returned for inline command lines with an
+	// unterminated quoted argument.
+	ErrUnbalancedQuotes = errors.New("resp: unbalanced quotes in request")
+)
+
+// SplitInline splits an inline command line into arguments using the same
+// rules as Redis (and redis-cli): arguments are separated by whitespace and
+// may be quoted. Double quoted arguments support \n, \r, \t, \b, \a, and \xHH
+// escapes, and a backslash before any other character yields that character.
+// Single quoted arguments only support the \' escape. A closing quote must be
+// followed by whitespace or the end of the line. Arguments are binary safe.
+// It returns ErrUnbalancedQuotes if the line is invalid.
+func SplitInline(line []byte) ([][]byte, error) {
+	args := [][]byte{}
+	p := 0
+
+	for {
+		for p < len(line) && isSpace(line[p]) {
+			p++
+		}
+		if p == len(line) {
+			return args, nil
+		}
+
+		var (
+			arg      = []byte{}
+			inDouble bool
+			inSingle bool
+			done     bool
+		)
+		for !done {
+			if inDouble {
+				switch {
+				case p == len(line):
+					return nil, ErrUnbalancedQuotes
+				case line[p] == '\\' && p+3 < len(line) && line[p+1] == 'x' && isHexDigit(line[p+2]) && isHexDigit(line[p+3]):
+					arg = append(arg, hexValue(line[p+2])<<4|hexValue(line[p+3]))
+					p += 3
+				case line[p] == '\\' && p+1 < len(line):
+					p++
+					switch line[p] {
+					case 'n':
+						arg = append(arg, '\n')
+					case 'r':
+						arg = append(arg, '\r')
+					case 't':
+						arg = append(arg, '\t')
+					case 'b':
+						arg = append(arg, '\b')
+					case 'a':
+						arg = append(arg, '\a')
+					default:
+						arg = append(arg, line[p])
+					}
+				case line[p] == '"':
+					// The closing quote must be followed by a space or nothing
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					arg = append(arg, line[p])
+				}
+			} else if inSingle {
+				switch {
+				case p == len(line):
+					return nil, ErrUnbalancedQuotes
+				case line[p] == '\\' && p+1 < len(line) && line[p+1] == '\'':
+					p++
+					arg = append(arg, '\'')
+				case line[p] == '\'':
+					if p+1 < len(line) && !isSpace(line[p+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					arg = append(arg, line[p])
+				}
+			} else {
+				switch {
+				case p == len(line) || isSpace(line[p]):
+					done = true
+				case line[p] == '"':
+					inDouble = true
+				case line[p] == '\'':
+					inSingle = true
+				default:
+					arg = append(arg, line[p])
+				}
+			}
+			if p < len(line) {
+				p++
+			}
+		}
+		args = append(args, arg)
+	}
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\r' || b == '\t' || b == '\v' || b == '\f'
+}
+
+func isHexDigit(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+func hexValue(b byte) byte {
+	switch {
+	case b >= '0' && b <= '9':
+		return b - '0'
+	case b >= 'a' && b <= 'f':
+		return b - 'a' + 10
+	default:
+		return b - 'A' + 10
+	}
+}
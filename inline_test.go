@@ -0,0 +1,66 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+type splitInlineTest struct {
+	given    string
+	expected []string
+}
+
+func TestSplitInline_Valid(t *testing.T) {
+	tests := []splitInlineTest{
+		{"", []string{}},
+		{"   ", []string{}},
+		{"PING", []string{"PING"}},
+		{"  SET  foo   bar \r\n", []string{"SET", "foo", "bar"}},
+		{`SET "hello world" 'it''s'`, nil},
+		{`SET "hello world" 'it\'s'`, []string{"SET", "hello world", "it's"}},
+		{`SET k "a\nb\tc\x41\x7a\"\\"`, []string{"SET", "k", "a\nb\tcAz\"\\"}},
+		{`SET k 'a\nb'`, []string{"SET", "k", `a\nb`}},
+		{`SET k ""`, []string{"SET", "k", ""}},
+		{`SET k foo"bar baz"`, []string{"SET", "k", "foobar baz"}},
+		{`SET k "\x00\xff"`, []string{"SET", "k", "\x00\xff"}},
+		{`SET k "\xZZ"`, []string{"SET", "k", "xZZ"}},
+	}
+
+	for i, test := range tests {
+		args, err := SplitInline([]byte(test.given))
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("tests[%d]: expected an error but didn't get one", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err.Error())
+			continue
+		}
+		got := make([]string, len(args))
+		for j, arg := range args {
+			got[j] = string(arg)
+		}
+		if !reflect.DeepEqual(test.expected, got) {
+			t.Errorf("tests[%d]:\nexpected: %q\ngot: %q", i, test.expected, got)
+		}
+	}
+}
+
+func TestSplitInline_Invalid(t *testing.T) {
+	tests := []string{
+		`SET "foo`,
+		`SET 'foo`,
+		`SET "foo"bar`,
+		`SET 'foo'bar`,
+		`SET "foo\"`,
+	}
+
+	for i, test := range tests {
+		_, err := SplitInline([]byte(test))
+		if err != ErrUnbalancedQuotes {
+			t.Errorf("tests[%d]: expected ErrUnbalancedQuotes, got %v", i, err)
+		}
+	}
+}
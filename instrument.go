@@ -0,0 +1,84 @@
+package resp
+
+import (
+	"context"
+	"strings"
+)
+
+// An Exchange describes a command and its reply for an Instrumenter.
+type Exchange struct {
+	// Command is the command's upper case name, or empty if the command
+	// is invalid.
+	Command string
+	// Keys is the number of keys the command accesses, or 0 if they can't
+	// be determined.
+	Keys         int
+	RequestBytes int
+
+	// The remaining fields are set when the exchange ends.
+
+	// ReplyType is the type of the reply, e.g. "bulk string", "error", or
+	// "map", or empty if no reply arrived.
+	ReplyType  string
+	ReplyBytes int
+	// Err is the error that kept the reply from arriving, if any. Error
+	// replies aren't errors here; they're told apart by ReplyType.
+	Err error
+}
+
+// An Instrumenter is notified of the start and end of each exchange of a
+// command and its reply by a Conn or Session, e.g. to trace them with
+// OpenTelemetry spans without this package depending on it: StartExchange
+// can start a span and return a context holding it, and EndExchange, which is
+// given that context, can set the span's attributes and end it. The methods
+// may be called concurrently for different exchanges.
+type Instrumenter interface {
+	// StartExchange is called before the command is sent, with the
+	// context of the call that sends it. The context it returns is passed
+	// to EndExchange.
+	StartExchange(ctx context.Context, e *Exchange) context.Context
+	// EndExchange is called once the reply has been read, or has failed
+	// to arrive.
+	EndExchange(ctx context.Context, e *Exchange)
+}
+
+// newExchange returns the Exchange for cmd.
+func newExchange(cmd Command) *Exchange {
+	e := &Exchange{RequestBytes: len(cmd)}
+	name, args, err := ParseCommand(cmd)
+	if err != nil {
+		return e
+	}
+	if canonical, ok := LookupCommand([]byte(name)); ok {
+		e.Command = canonical
+	} else {
+		e.Command = strings.ToUpper(name)
+	}
+	if keys, err := ExtractKeys(name, args); err == nil {
+		e.Keys = len(keys)
+	}
+	return e
+}
+
+// end records the outcome of the exchange.
+func (e *Exchange) end(reply []byte, err error) {
+	if err != nil {
+		e.Err = err
+		return
+	}
+	if len(reply) > 0 {
+		e.ReplyType = prefixNames[reply[0]]
+	}
+	e.ReplyBytes = len(reply)
+}
+
+// startExchanges starts an exchange for each of commands.
+func startExchanges(inst Instrumenter, ctx context.Context, commands []Command) ([]*Exchange, []context.Context) {
+	exchanges := make([]*Exchange, len(commands))
+	contexts := make([]context.Context, len(commands))
+	for i, cmd := range commands {
+		exchanges[i] = newExchange(cmd)
+		contexts[i] = inst.StartExchange(ctx, exchanges[i])
+	}
+	return exchanges, contexts
+}
@@ -0,0 +1,117 @@
+package resp
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type spanKey struct{}
+
+// recordingInstrumenter records the exchanges it's notified of, along with
+// the value stored under spanKey in the context they were started with.
+type recordingInstrumenter struct {
+	mu      sync.Mutex
+	started int
+	ended   []Exchange
+	spans   []interface{}
+	done    chan bool
+}
+
+func (i *recordingInstrumenter) StartExchange(ctx context.Context, e *Exchange) context.Context {
+	i.mu.Lock()
+	i.started++
+	i.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, e.Command+" span")
+}
+
+func (i *recordingInstrumenter) EndExchange(ctx context.Context, e *Exchange) {
+	i.mu.Lock()
+	i.ended = append(i.ended, *e)
+	i.spans = append(i.spans, ctx.Value(spanKey{}))
+	i.mu.Unlock()
+	if i.done != nil {
+		i.done <- true
+	}
+}
+
+func TestConn_SetInstrumenter(t *testing.T) {
+	conn := fakeServer(echoHandler)
+	inst := &recordingInstrumenter{}
+	conn.SetInstrumenter(inst)
+
+	if _, err := conn.Do("ECHO", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := conn.DoPipeline([]Command{NewCommand("mget", "a", "b"), NewCommand("PING")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	conn.Do("PING")
+
+	expected := []Exchange{
+		{Command: "ECHO", RequestBytes: 25, ReplyType: "bulk string", ReplyBytes: 11},
+		{Command: "MGET", Keys: 2, RequestBytes: 28, ReplyType: "error", ReplyBytes: 29},
+		{Command: "PING", RequestBytes: 14, ReplyType: "simple string", ReplyBytes: 7},
+		{Command: "PING", RequestBytes: 14, Err: ErrConnClosed},
+	}
+	if !reflect.DeepEqual(expected, inst.ended) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, inst.ended)
+	}
+	if expected := []interface{}{"ECHO span", "MGET span", "PING span", "PING span"}; !reflect.DeepEqual(expected, inst.spans) {
+		t.Errorf("expected contexts %v, got %v", expected, inst.spans)
+	}
+	if inst.started != 4 {
+		t.Errorf("expected 4 exchanges started, got %d", inst.started)
+	}
+}
+
+func TestSession_SetInstrumenter(t *testing.T) {
+	client, proxySide := net.Pipe()
+	defer client.Close()
+	up, server := net.Pipe()
+	release := make(chan bool, 10)
+	go echoUpstream(server, release)
+	go func() {
+		r := NewReader(client)
+		for {
+			if _, err := r.ReadObject(); err != nil {
+				return
+			}
+		}
+	}()
+
+	s := NewSession(proxySide, up)
+	defer s.Close()
+	inst := &recordingInstrumenter{done: make(chan bool, 10)}
+	s.SetInstrumenter(inst)
+
+	if err := s.Forward(0, NewCommand("GET", "key")); err != nil {
+		t.Fatal(err)
+	}
+	release <- true
+	<-inst.done
+	if err := s.Forward(1, NewCommand("GET", "key")); err != ErrNoUpstream {
+		t.Errorf("expected ErrNoUpstream, got %v", err)
+	}
+	if err := s.Forward(0, NewCommand("SET", "key", "value")); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	<-inst.done
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	if len(inst.ended) != 2 {
+		t.Fatalf("expected 2 exchanges, got %+v", inst.ended)
+	}
+	if expected := (Exchange{Command: "GET", Keys: 1, RequestBytes: 22, ReplyType: "bulk string", ReplyBytes: 9}); !reflect.DeepEqual(expected, inst.ended[0]) {
+		t.Errorf("expected %+v, got %+v", expected, inst.ended[0])
+	}
+	if e := inst.ended[1]; e.Command != "SET" || e.Err == nil || e.ReplyType != "" {
+		t.Errorf("expected SET to fail, got %+v", e)
+	}
+}
@@ -1,7 +1,7 @@
 package resp
 
 import (
-	"strconv"
+	"math"
 )
 
 // Error points to the bytes for a RESP integer.
@@ -11,7 +11,7 @@ type Integer []byte
 // RESP integer.
 func NewInteger(i int64) Integer {
 	buf := []byte{INTEGER_PREFIX}
-	strconv.AppendInt(buf, i, 10)
+	buf = AppendInt(buf, i)
 	buf = append(buf, '\r', '\n')
 	return Integer(buf)
 }
@@ -20,18 +20,88 @@ func (i Integer) Raw() []byte { return i }
 
 // Int returns the value of the RESP integer as an int.
 func (i Integer) Int() (int, error) {
-	n, err := strconv.Atoi(string(i[1 : len(i)-2]))
-	if err != nil {
+	n, err := i.Int64()
+	if err != nil || int64(int(n)) != n {
 		return 0, ErrSyntaxError
 	}
-	return n, nil
+	return int(n), nil
 }
 
 // Int64 returns the value of the RESP integer as in int64.
 func (i Integer) Int64() (int64, error) {
-	n, err := strconv.ParseInt(string(i[1:len(i)-2]), 10, 64)
-	if err != nil {
+	if len(i) < MIN_OBJECT_LENGTH {
+		return 0, ErrSyntaxError
+	}
+	return ParseInt(i[1 : len(i)-2])
+}
+
+// ParseInt parses b as the decimal digits of a RESP integer or length, with
+// an optional leading '-', e.g. "-42". Unlike strconv.ParseInt, it doesn't
+// accept a leading '+' or underscores, and doesn't allocate. It returns
+// ErrSyntaxError if b isn't an integer or overflows an int64.
+func ParseInt(b []byte) (int64, error) {
+	n, end, err := parseInt(b)
+	if err != nil || end != len(b) {
 		return 0, ErrSyntaxError
 	}
 	return n, nil
 }
+
+// parseInt parses the integer at the start of b like ParseInt and returns
+// the index of the byte that follows it.
+func parseInt(b []byte) (int64, int, error) {
+	start := 0
+	if len(b) > 0 && b[0] == '-' {
+		start = 1
+	}
+	n, digits := parseDigits(b[start:])
+	// 19 digits fit in a uint64, but more might have wrapped around. The
+	// limit is one more for negative integers.
+	if digits == 0 || digits > 19 || n > math.MaxInt64+uint64(start) {
+		return 0, 0, ErrSyntaxError
+	}
+	if start == 1 {
+		return -int64(n), start + digits, nil
+	}
+	return int64(n), digits, nil
+}
+
+// parseDigits returns the value of the decimal digits at the start of b, and
+// how many there are. The value wraps around if there are more than 19.
+func parseDigits(b []byte) (uint64, int) {
+	var n uint64
+	i := 0
+	for ; i < len(b); i++ {
+		digit := b[i] - '0'
+		if digit > 9 {
+			break
+		}
+		n = n*10 + uint64(digit)
+	}
+	return n, i
+}
+
+// AppendInt appends the decimal form of n to dst and returns the extended
+// buffer, like strconv.AppendInt(dst, n, 10).
+func AppendInt(dst []byte, n int64) []byte {
+	if n >= 0 && n < 10 {
+		// Lengths of commands and small replies
+		return append(dst, byte('0'+n))
+	}
+	u := uint64(n)
+	if n < 0 {
+		dst = append(dst, '-')
+		u = -u
+	}
+	var digits [20]byte
+	i := len(digits)
+	for u >= 10 {
+		q := u / 10
+		i--
+		digits[i] = byte('0' + u - q*10)
+		u = q
+	}
+	i--
+	digits[i] = byte('0' + u)
+	return append(dst, digits[i:]...)
+}
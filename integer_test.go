@@ -0,0 +1,72 @@
+package resp
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestNewInteger(t *testing.T) {
+	i := NewInteger(-42)
+	expected := []byte(":-42\r\n")
+	if !reflect.DeepEqual(expected, []byte(i)) {
+		t.Errorf("expected: %q\ngot: %q", expected, i)
+	}
+	n, err := i.Int64()
+	if err != nil || n != -42 {
+		t.Errorf("expected -42, got %v, %v", n, err)
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		given    string
+		expected int64
+		valid    bool
+	}{
+		{"0", 0, true},
+		{"-0", 0, true},
+		{"42", 42, true},
+		{"-42", -42, true},
+		{"007", 7, true},
+		{"9223372036854775807", math.MaxInt64, true},
+		{"-9223372036854775808", math.MinInt64, true},
+		{"9223372036854775808", 0, false},
+		{"-9223372036854775809", 0, false},
+		{"99999999999999999999", 0, false},
+		{"", 0, false},
+		{"-", 0, false},
+		{"+1", 0, false},
+		{"1_000", 0, false},
+		{" 1", 0, false},
+		{"1\r\n", 0, false},
+	}
+	for _, test := range tests {
+		n, err := ParseInt([]byte(test.given))
+		if test.valid && (err != nil || n != test.expected) {
+			t.Errorf("%q: expected %d, got %d, %v", test.given, test.expected, n, err)
+		}
+		if !test.valid && err != ErrSyntaxError {
+			t.Errorf("%q: expected ErrSyntaxError, got %d, %v", test.given, n, err)
+		}
+	}
+}
+
+func TestAppendInt(t *testing.T) {
+	for _, n := range []int64{0, 7, 10, -1, -10, 1234567890, math.MaxInt64, math.MinInt64} {
+		expected := strconv.FormatInt(n, 10)
+		if s := string(AppendInt([]byte("x"), n)); s != "x"+expected {
+			t.Errorf("expected %q, got %q", "x"+expected, s)
+		}
+		if parsed, err := ParseInt([]byte(expected)); err != nil || parsed != n {
+			t.Errorf("%d: round trip returned %d, %v", n, parsed, err)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		ParseInt([]byte("x"))
+		AppendInt(make([]byte, 0, 20), math.MinInt64)
+	}); allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
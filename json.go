@@ -0,0 +1,384 @@
+package resp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"unicode/utf8"
+)
+
+// ErrInvalidJSON is returned by FromJSON for JSON that doesn't follow the
+// schema described by ToJSON.
+var ErrInvalidJSON = errors.New("resp: JSON isn't a RESP object")
+
+// The type tags of objects converted to JSON by ToJSON.
+const (
+	JSON_SIMPLE_STRING   = "simple_string"
+	JSON_ERROR           = "error"
+	JSON_INTEGER         = "integer"
+	JSON_BULK_STRING     = "bulk_string"
+	JSON_ARRAY           = "array"
+	JSON_NULL            = "null"
+	JSON_PUSH            = "push"
+	JSON_MAP             = "map"
+	JSON_SET             = "set"
+	JSON_ATTRIBUTE       = "attribute"
+	JSON_BOOLEAN         = "boolean"
+	JSON_DOUBLE          = "double"
+	JSON_BIG_NUMBER      = "big_number"
+	JSON_VERBATIM_STRING = "verbatim_string"
+	JSON_BLOB_ERROR      = "blob_error"
+)
+
+var jsonTypes = map[byte]string{
+	SIMPLE_STRING_PREFIX:   JSON_SIMPLE_STRING,
+	ERROR_PREFIX:           JSON_ERROR,
+	INTEGER_PREFIX:         JSON_INTEGER,
+	BULK_STRING_PREFIX:     JSON_BULK_STRING,
+	ARRAY_PREFIX:           JSON_ARRAY,
+	NULL_PREFIX:            JSON_NULL,
+	PUSH_PREFIX:            JSON_PUSH,
+	MAP_PREFIX:             JSON_MAP,
+	SET_PREFIX:             JSON_SET,
+	ATTRIBUTE_PREFIX:       JSON_ATTRIBUTE,
+	BOOLEAN_PREFIX:         JSON_BOOLEAN,
+	DOUBLE_PREFIX:          JSON_DOUBLE,
+	BIG_NUMBER_PREFIX:      JSON_BIG_NUMBER,
+	VERBATIM_STRING_PREFIX: JSON_VERBATIM_STRING,
+	BLOB_ERROR_PREFIX:      JSON_BLOB_ERROR,
+}
+
+var jsonPrefixes = map[string]byte{
+	JSON_ARRAY:     ARRAY_PREFIX,
+	JSON_SET:       SET_PREFIX,
+	JSON_PUSH:      PUSH_PREFIX,
+	JSON_MAP:       MAP_PREFIX,
+	JSON_ATTRIBUTE: ATTRIBUTE_PREFIX,
+}
+
+// jsonObject is the JSON form of a RESP object.
+type jsonObject struct {
+	Type     string      `json:"type"`
+	Value    interface{} `json:"value"`
+	Encoding string      `json:"encoding,omitempty"`
+	Format   string      `json:"format,omitempty"`
+}
+
+// ToJSON converts a RESP object to JSON, so that replies can be logged or
+// analyzed by tools that don't understand RESP. Each object becomes a JSON
+// object with a "type" tag, one of the JSON_* constants, and a "value":
+//
+//	simple_string, error   the string
+//	bulk_string            the string, or null for a null bulk string
+//	verbatim_string        the text, with the format in "format"
+//	blob_error             the message
+//	integer                the number
+//	double                 the number, or "inf", "-inf", or "nan"
+//	big_number             the digits, as a string
+//	boolean                true or false
+//	null                   null
+//	array, set, push       an array of objects, or null for a null array
+//	map, attribute         an array of [key, value] pairs of objects
+//
+// Strings that aren't valid UTF-8 are base64 encoded, which is marked by an
+// "encoding" of "base64". For example, the reply
+// "*2\r\n:1\r\n$3\r\nfoo\r\n" becomes
+//
+//	{"type":"array","value":[{"type":"integer","value":1},{"type":"bulk_string","value":"foo"}]}
+//
+// ToJSON returns ErrSyntaxError if obj isn't a single valid RESP object.
+func ToJSON(obj Object) ([]byte, error) {
+	b := obj.Raw()
+	end, err := objectEnd(b)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(b)-1 {
+		return nil, ErrSyntaxError
+	}
+	j, err := toJSONObject(b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(j)
+}
+
+func toJSONObject(b []byte) (*jsonObject, error) {
+	j := &jsonObject{Type: jsonTypes[b[0]]}
+	switch b[0] {
+	case SIMPLE_STRING_PREFIX, ERROR_PREFIX:
+		j.setString(b[1 : len(b)-2])
+	case INTEGER_PREFIX:
+		i, err := Integer(b).Int64()
+		if err != nil {
+			return nil, err
+		}
+		j.Value = i
+	case BULK_STRING_PREFIX, BLOB_ERROR_PREFIX:
+		length, lineEnd, err := parseLenLine(b)
+		if err != nil {
+			return nil, err
+		}
+		if length >= 0 {
+			j.setString(b[lineEnd+1 : len(b)-2])
+		}
+	case VERBATIM_STRING_PREFIX:
+		s := VerbatimString(b)
+		j.Format = s.Format()
+		j.setString(s.Slice())
+	case NULL_PREFIX:
+	case BOOLEAN_PREFIX:
+		v, err := Boolean(b).Bool()
+		if err != nil {
+			return nil, err
+		}
+		j.Value = v
+	case DOUBLE_PREFIX:
+		f, err := Double(b).Float64()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case math.IsInf(f, 1):
+			j.Value = "inf"
+		case math.IsInf(f, -1):
+			j.Value = "-inf"
+		case math.IsNaN(f):
+			j.Value = "nan"
+		default:
+			j.Value = f
+		}
+	case BIG_NUMBER_PREFIX:
+		i, err := BigNumber(b).Int()
+		if err != nil {
+			return nil, err
+		}
+		j.Value = i.String()
+	case ARRAY_PREFIX, SET_PREFIX, PUSH_PREFIX, MAP_PREFIX, ATTRIBUTE_PREFIX:
+		objects, err := aggregateObjects(b)
+		if err != nil {
+			return nil, err
+		}
+		if objects == nil {
+			break
+		}
+		values := make([]*jsonObject, len(objects))
+		for i, object := range objects {
+			if values[i], err = toJSONObject(object.Raw()); err != nil {
+				return nil, err
+			}
+		}
+		if b[0] == MAP_PREFIX || b[0] == ATTRIBUTE_PREFIX {
+			pairs := make([][]*jsonObject, len(values)/2)
+			for i := range pairs {
+				pairs[i] = values[2*i : 2*i+2]
+			}
+			j.Value = pairs
+		} else {
+			j.Value = values
+		}
+	default:
+		return nil, ErrSyntaxError
+	}
+	return j, nil
+}
+
+// setString sets the value of j to s, base64 encoded if it isn't UTF-8.
+func (j *jsonObject) setString(s []byte) {
+	if utf8.Valid(s) {
+		j.Value = string(s)
+	} else {
+		j.Value = base64.StdEncoding.EncodeToString(s)
+		j.Encoding = "base64"
+	}
+}
+
+// FromJSON converts JSON in the form returned by ToJSON back to a RESP
+// object, so that test fixtures and tools can describe RESP as JSON. It
+// returns the error from encoding/json if b isn't valid JSON and
+// ErrInvalidJSON if it doesn't follow the schema.
+func FromJSON(b []byte) (Object, error) {
+	buf, err := appendFromJSON(nil, b)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(buf), nil
+}
+
+// appendFromJSON appends the RESP object described by the JSON object b to
+// buf.
+func appendFromJSON(buf []byte, b []byte) ([]byte, error) {
+	var j struct {
+		Type     string          `json:"type"`
+		Value    json.RawMessage `json:"value"`
+		Encoding string          `json:"encoding"`
+		Format   string          `json:"format"`
+	}
+	if err := json.Unmarshal(b, &j); err != nil {
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			return nil, ErrInvalidJSON
+		}
+		return nil, err
+	}
+	null := len(j.Value) == 0 || bytes.Equal(j.Value, []byte("null"))
+
+	switch j.Type {
+	case JSON_SIMPLE_STRING, JSON_ERROR:
+		s, err := jsonString(j.Value, j.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.IndexAny(s, "\r\n") >= 0 {
+			return nil, ErrInvalidJSON
+		}
+		prefix := byte(SIMPLE_STRING_PREFIX)
+		if j.Type == JSON_ERROR {
+			prefix = ERROR_PREFIX
+		}
+		buf = append(buf, prefix)
+		buf = append(buf, s...)
+		return append(buf, lineSuffix...), nil
+	case JSON_BULK_STRING, JSON_BLOB_ERROR:
+		prefix := byte(BULK_STRING_PREFIX)
+		if j.Type == JSON_BLOB_ERROR {
+			prefix = BLOB_ERROR_PREFIX
+		} else if null {
+			return append(buf, "$-1\r\n"...), nil
+		}
+		s, err := jsonString(j.Value, j.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		return appendBlob(buf, prefix, s), nil
+	case JSON_VERBATIM_STRING:
+		s, err := jsonString(j.Value, j.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		format := j.Format
+		if format == "" {
+			format = "txt"
+		}
+		if len(format) != 3 {
+			return nil, ErrInvalidJSON
+		}
+		return appendBlob(buf, VERBATIM_STRING_PREFIX, append([]byte(format+":"), s...)), nil
+	case JSON_INTEGER:
+		var i int64
+		if err := json.Unmarshal(j.Value, &i); err != nil || null {
+			return nil, ErrInvalidJSON
+		}
+		return append(buf, NewInteger(i)...), nil
+	case JSON_NULL:
+		if !null {
+			return nil, ErrInvalidJSON
+		}
+		return append(buf, NULL...), nil
+	case JSON_BOOLEAN:
+		var v bool
+		if err := json.Unmarshal(j.Value, &v); err != nil || null {
+			return nil, ErrInvalidJSON
+		}
+		return append(buf, NewBoolean(v)...), nil
+	case JSON_DOUBLE:
+		var f float64
+		if err := json.Unmarshal(j.Value, &f); err != nil || null {
+			var s string
+			if json.Unmarshal(j.Value, &s) != nil {
+				return nil, ErrInvalidJSON
+			}
+			switch s {
+			case "inf":
+				f = math.Inf(1)
+			case "-inf":
+				f = math.Inf(-1)
+			case "nan":
+				f = math.NaN()
+			default:
+				return nil, ErrInvalidJSON
+			}
+		}
+		return append(buf, NewDouble(f)...), nil
+	case JSON_BIG_NUMBER:
+		var s string
+		if err := json.Unmarshal(j.Value, &s); err != nil {
+			return nil, ErrInvalidJSON
+		}
+		if _, ok := new(big.Int).SetString(s, 10); !ok {
+			return nil, ErrInvalidJSON
+		}
+		buf = append(buf, BIG_NUMBER_PREFIX)
+		buf = append(buf, s...)
+		return append(buf, lineSuffix...), nil
+	case JSON_ARRAY, JSON_SET, JSON_PUSH, JSON_MAP, JSON_ATTRIBUTE:
+		prefix := jsonPrefixes[j.Type]
+		if null {
+			if prefix != ARRAY_PREFIX {
+				return nil, ErrInvalidJSON
+			}
+			return append(buf, "*-1\r\n"...), nil
+		}
+		var values []json.RawMessage
+		if err := json.Unmarshal(j.Value, &values); err != nil {
+			return nil, ErrInvalidJSON
+		}
+		length := len(values)
+		if prefix == MAP_PREFIX || prefix == ATTRIBUTE_PREFIX {
+			pairs := values
+			values = make([]json.RawMessage, 0, 2*len(pairs))
+			for _, pair := range pairs {
+				var kv []json.RawMessage
+				if err := json.Unmarshal(pair, &kv); err != nil || len(kv) != 2 {
+					return nil, ErrInvalidJSON
+				}
+				values = append(values, kv...)
+			}
+		}
+		buf = append(buf, prefix)
+		buf = AppendInt(buf, int64(length))
+		buf = append(buf, lineSuffix...)
+		for _, value := range values {
+			var err error
+			if buf, err = appendFromJSON(buf, value); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, ErrInvalidJSON
+	}
+}
+
+// jsonString decodes a JSON string value with the given encoding, "" or
+// "base64".
+func jsonString(value json.RawMessage, encoding string) ([]byte, error) {
+	var s *string
+	if err := json.Unmarshal(value, &s); err != nil || s == nil {
+		return nil, ErrInvalidJSON
+	}
+	switch encoding {
+	case "":
+		return []byte(*s), nil
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(*s)
+		if err != nil {
+			return nil, ErrInvalidJSON
+		}
+		return b, nil
+	default:
+		return nil, ErrInvalidJSON
+	}
+}
+
+// appendBlob appends a bulk string, verbatim string, or blob error with the
+// given prefix and contents to buf.
+func appendBlob(buf []byte, prefix byte, s []byte) []byte {
+	buf = append(buf, prefix)
+	buf = AppendInt(buf, int64(len(s)))
+	buf = append(buf, lineSuffix...)
+	buf = append(buf, s...)
+	return append(buf, lineSuffix...)
+}
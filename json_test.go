@@ -0,0 +1,114 @@
+package resp
+
+import (
+	"testing"
+)
+
+var jsonTests = []struct {
+	resp string
+	json string
+}{
+	{"+OK\r\n", `{"type":"simple_string","value":"OK"}`},
+	{"-ERR bad\r\n", `{"type":"error","value":"ERR bad"}`},
+	{":-12\r\n", `{"type":"integer","value":-12}`},
+	{"$3\r\nfoo\r\n", `{"type":"bulk_string","value":"foo"}`},
+	{"$0\r\n\r\n", `{"type":"bulk_string","value":""}`},
+	{"$-1\r\n", `{"type":"bulk_string","value":null}`},
+	{"$2\r\n\xff\x00\r\n", `{"type":"bulk_string","value":"/wA=","encoding":"base64"}`},
+	{"*-1\r\n", `{"type":"array","value":null}`},
+	{"*0\r\n", `{"type":"array","value":[]}`},
+	{"*2\r\n:1\r\n$3\r\nfoo\r\n", `{"type":"array","value":[{"type":"integer","value":1},{"type":"bulk_string","value":"foo"}]}`},
+	{"_\r\n", `{"type":"null","value":null}`},
+	{"#t\r\n", `{"type":"boolean","value":true}`},
+	{"#f\r\n", `{"type":"boolean","value":false}`},
+	{",1.5\r\n", `{"type":"double","value":1.5}`},
+	{",-inf\r\n", `{"type":"double","value":"-inf"}`},
+	{",nan\r\n", `{"type":"double","value":"nan"}`},
+	{"(12345678901234567890123\r\n", `{"type":"big_number","value":"12345678901234567890123"}`},
+	{"=8\r\nmkd:# hi\r\n", `{"type":"verbatim_string","value":"# hi","format":"mkd"}`},
+	{"!5\r\nERR x\r\n", `{"type":"blob_error","value":"ERR x"}`},
+	{"%1\r\n+a\r\n~1\r\n:1\r\n", `{"type":"map","value":[[{"type":"simple_string","value":"a"},{"type":"set","value":[{"type":"integer","value":1}]}]]}`},
+	{"|1\r\n+ttl\r\n:3\r\n", `{"type":"attribute","value":[[{"type":"simple_string","value":"ttl"},{"type":"integer","value":3}]]}`},
+	{">1\r\n+message\r\n", `{"type":"push","value":[{"type":"simple_string","value":"message"}]}`},
+}
+
+func TestToJSON(t *testing.T) {
+	for i, test := range jsonTests {
+		b, err := ToJSON(Parse([]byte(test.resp)))
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err)
+			continue
+		}
+		if string(b) != test.json {
+			t.Errorf("tests[%d]: expected %s, got %s", i, test.json, b)
+		}
+	}
+}
+
+func TestToJSON_Invalid(t *testing.T) {
+	for _, resp := range []string{"+OK\r\n+OK\r\n", "$3\r\nfo", ":x\r\n", "#x\r\n", "(1.5\r\n", "*2\r\n:1\r\n"} {
+		if b, err := ToJSON(InvalidObject(resp)); err == nil {
+			t.Errorf("expected an error for %q, got %s", resp, b)
+		}
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	for i, test := range jsonTests {
+		obj, err := FromJSON([]byte(test.json))
+		if err != nil {
+			t.Errorf("jsonTests[%d]: %s", i, err)
+			continue
+		}
+		if string(obj.Raw()) != test.resp {
+			t.Errorf("jsonTests[%d]: expected %q, got %q", i, test.resp, obj.Raw())
+		}
+	}
+
+	obj, err := FromJSON([]byte(`{"type":"verbatim_string","value":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := obj.(VerbatimString); !ok || string(s) != "=6\r\ntxt:hi\r\n" {
+		t.Errorf("expected a txt verbatim string, got %#v", obj)
+	}
+	obj, err = FromJSON([]byte(`{"type":"double","value":"inf"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(obj.Raw()) != ",inf\r\n" {
+		t.Errorf("expected inf, got %q", obj.Raw())
+	}
+}
+
+func TestFromJSON_Invalid(t *testing.T) {
+	for _, s := range []string{
+		`{"type":"simple_string","value":"a\r\nb"}`,
+		`{"type":"simple_string","value":null}`,
+		`{"type":"simple_string","value":1}`,
+		`{"type":"bulk_string","value":"!","encoding":"base64"}`,
+		`{"type":"bulk_string","value":"a","encoding":"hex"}`,
+		`{"type":"blob_error","value":null}`,
+		`{"type":"verbatim_string","value":"a","format":"text"}`,
+		`{"type":"integer","value":1.5}`,
+		`{"type":"integer","value":null}`,
+		`{"type":"null","value":1}`,
+		`{"type":"boolean","value":"true"}`,
+		`{"type":"double","value":"1.5"}`,
+		`{"type":"big_number","value":"1.5"}`,
+		`{"type":"set","value":null}`,
+		`{"type":"array","value":{}}`,
+		`{"type":"array","value":[{"type":"nope"}]}`,
+		`{"type":"map","value":[[{"type":"null"}]]}`,
+		`{"type":"string","value":"a"}`,
+		`[1]`,
+	} {
+		if obj, err := FromJSON([]byte(s)); err != ErrInvalidJSON {
+			t.Errorf("expected ErrInvalidJSON for %s, got %v, %q", s, err, obj)
+		}
+	}
+
+	if _, err := FromJSON([]byte(`{"type":`)); err == nil || err == ErrInvalidJSON {
+		t.Errorf("expected a JSON syntax error, got %v", err)
+	}
+}
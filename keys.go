@@ -0,0 +1,189 @@
+package resp
+
+import (
+	"strconv"
+)
+
+// ExtractKeys returns the keys of the named command given its arguments (not
+// including the command name itself). The keys point into args. It returns
+// ErrUnknownCommand for commands that aren't in the command table and
+// ErrInvalidArguments if the arguments don't make sense for the command.
+func ExtractKeys(name string, args [][]byte) ([][]byte, error) {
+	indexes, err := keyIndexes(name, args)
+	if err != nil || indexes == nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, len(indexes))
+	for i, index := range indexes {
+		keys[i] = args[index]
+	}
+	return keys, nil
+}
+
+// keyIndexes returns the indexes in args of the named command's keys.
+func keyIndexes(name string, args [][]byte) ([]int, error) {
+	info := lookupCommandInfo([]byte(name))
+	if info == nil {
+		return nil, ErrUnknownCommand
+	}
+	if !info.validArity(len(args) + 1) {
+		return nil, ErrInvalidArguments
+	}
+
+	switch info.Name {
+	case "EVAL", "EVALSHA", "EVAL_RO", "EVALSHA_RO", "FCALL", "FCALL_RO":
+		// EVAL script numkeys key [key ...] arg [arg ...]
+		return numKeysIndexes(args, 1)
+	case "ZUNIONSTORE", "ZINTERSTORE", "ZDIFFSTORE":
+		// ZUNIONSTORE destination numkeys key [key ...] ...
+		indexes, err := numKeysIndexes(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return append([]int{0}, indexes...), nil
+	case "ZUNION", "ZINTER", "ZDIFF", "ZINTERCARD", "SINTERCARD", "LMPOP", "ZMPOP":
+		// ZUNION numkeys key [key ...] ...
+		return numKeysIndexes(args, 0)
+	case "BLMPOP", "BZMPOP":
+		// BLMPOP timeout numkeys key [key ...] ...
+		return numKeysIndexes(args, 1)
+	case "GEORADIUS", "GEORADIUSBYMEMBER":
+		return storeKeyIndexes(args, 4, "STORE", "STOREDIST"), nil
+	case "SORT", "SORT_RO":
+		return sortKeyIndexes(args), nil
+	case "XREAD", "XREADGROUP":
+		return streamsKeyIndexes(args)
+	case "MIGRATE":
+		return migrateKeyIndexes(args), nil
+	case "MEMORY":
+		// MEMORY USAGE key [SAMPLES count]
+		if len(args) > 1 && CommandEquals(args[0], "USAGE") {
+			return []int{1}, nil
+		}
+		return nil, nil
+	case "OBJECT", "XINFO", "XGROUP":
+		// OBJECT ENCODING key, XINFO STREAM key, XGROUP CREATE key ...
+		if len(args) > 1 && !CommandEquals(args[0], "HELP") {
+			return []int{1}, nil
+		}
+		return nil, nil
+	}
+
+	if info.FirstKey == 0 {
+		return nil, nil
+	}
+
+	last := info.LastKey
+	if last < 0 {
+		last += len(args) + 1
+	}
+	var indexes []int
+	for i := info.FirstKey; i <= last; i += info.Step {
+		indexes = append(indexes, i-1)
+	}
+	return indexes, nil
+}
+
+// numKeysIndexes returns the indexes of the keys that follow the numkeys
+// argument at args[at].
+func numKeysIndexes(args [][]byte, at int) ([]int, error) {
+	if at >= len(args) {
+		return nil, ErrInvalidArguments
+	}
+	n, err := strconv.Atoi(string(args[at]))
+	if err != nil || n < 0 || at+1+n > len(args) {
+		return nil, ErrInvalidArguments
+	}
+
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = at + 1 + i
+	}
+	return indexes, nil
+}
+
+// storeKeyIndexes returns the index of args[0] plus the index of the argument
+// that follows the last of the given options found at or after args[from].
+func storeKeyIndexes(args [][]byte, from int, options ...string) []int {
+	indexes := []int{0}
+	store := -1
+	for i := from; i < len(args)-1; i++ {
+		for _, option := range options {
+			if CommandEquals(args[i], option) {
+				store = i + 1
+			}
+		}
+	}
+	if store > 0 {
+		indexes = append(indexes, store)
+	}
+	return indexes
+}
+
+// sortKeyIndexes handles SORT key [BY pattern] [LIMIT offset count]
+// [GET pattern ...] [ASC|DESC] [ALPHA] [STORE destination].
+func sortKeyIndexes(args [][]byte) []int {
+	indexes := []int{0}
+	for i := 1; i < len(args); i++ {
+		switch {
+		case CommandEquals(args[i], "LIMIT"):
+			i += 2
+		case CommandEquals(args[i], "BY"), CommandEquals(args[i], "GET"):
+			i++
+		case CommandEquals(args[i], "STORE") && i+1 < len(args):
+			indexes = append(indexes, i+1)
+			i++
+		}
+	}
+	return indexes
+}
+
+// streamsKeyIndexes handles XREAD [COUNT count] [BLOCK ms] STREAMS key
+// [key ...] id [id ...] and XREADGROUP GROUP group consumer [COUNT count]
+// [BLOCK ms] [NOACK] STREAMS key [key ...] id [id ...].
+func streamsKeyIndexes(args [][]byte) ([]int, error) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case CommandEquals(args[i], "GROUP"):
+			i += 2
+		case CommandEquals(args[i], "COUNT"), CommandEquals(args[i], "BLOCK"):
+			i++
+		case CommandEquals(args[i], "STREAMS"):
+			rest := len(args) - i - 1
+			if rest == 0 || rest%2 != 0 {
+				return nil, ErrInvalidArguments
+			}
+			indexes := make([]int, rest/2)
+			for j := range indexes {
+				indexes[j] = i + 1 + j
+			}
+			return indexes, nil
+		}
+	}
+	return nil, ErrInvalidArguments
+}
+
+// migrateKeyIndexes handles MIGRATE host port key|"" destination-db timeout
+// [COPY] [REPLACE] [AUTH password] [AUTH2 username password]
+// [KEYS key [key ...]].
+func migrateKeyIndexes(args [][]byte) []int {
+	var indexes []int
+	if len(args[2]) > 0 {
+		indexes = append(indexes, 2)
+	}
+	for i := 5; i < len(args); i++ {
+		switch {
+		case CommandEquals(args[i], "AUTH"):
+			i++
+		case CommandEquals(args[i], "AUTH2"):
+			i += 2
+		case CommandEquals(args[i], "KEYS"):
+			for j := i + 1; j < len(args); j++ {
+				indexes = append(indexes, j)
+			}
+			return indexes
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,90 @@
+package resp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type extractKeysTest struct {
+	command  string
+	expected []string
+}
+
+func TestExtractKeys_Valid(t *testing.T) {
+	tests := []extractKeysTest{
+		{"GET foo", []string{"foo"}},
+		{"PING", nil},
+		{"MSET a 1 b 2", []string{"a", "b"}},
+		{"BLPOP a b 0", []string{"a", "b"}},
+		{"BITOP AND dest a b", []string{"dest", "a", "b"}},
+		{"ZADD z 1 m", []string{"z"}},
+		{"EVAL script 2 a b arg", []string{"a", "b"}},
+		{"evalsha sha 0 arg", []string{}},
+		{"ZUNIONSTORE dest 2 a b WEIGHTS 1 2", []string{"dest", "a", "b"}},
+		{"ZINTER 2 a b", []string{"a", "b"}},
+		{"BLMPOP 0 2 a b LEFT", []string{"a", "b"}},
+		{"GEORADIUS g 0 0 10 km STORE dest", []string{"g", "dest"}},
+		{"GEORADIUSBYMEMBER g m 10 km STOREDIST dest", []string{"g", "dest"}},
+		{"GEORADIUS g 0 0 10 km", []string{"g"}},
+		{"SORT list BY weight_* LIMIT 0 10 GET store STORE dest", []string{"list", "dest"}},
+		{"XREAD COUNT 2 STREAMS s1 s2 0 0", []string{"s1", "s2"}},
+		{"XREADGROUP GROUP streams c BLOCK 0 STREAMS s1 >", []string{"s1"}},
+		{"MIGRATE host 6379 key 0 5000", []string{"key"}},
+		{"MIGRATE host 6379 \"\" 0 5000 COPY AUTH keys KEYS a b", []string{"a", "b"}},
+		{"MEMORY USAGE foo SAMPLES 5", []string{"foo"}},
+		{"MEMORY STATS", nil},
+		{"OBJECT ENCODING foo", []string{"foo"}},
+	}
+
+	for i, test := range tests {
+		parts := strings.Split(test.command, " ")
+		args := make([][]byte, len(parts)-1)
+		for j, part := range parts[1:] {
+			if part == "\"\"" {
+				part = ""
+			}
+			args[j] = []byte(part)
+		}
+
+		keys, err := ExtractKeys(parts[0], args)
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err.Error())
+			continue
+		}
+		var got []string
+		if keys != nil {
+			got = make([]string, len(keys))
+			for j, key := range keys {
+				got[j] = string(key)
+			}
+		}
+		if !reflect.DeepEqual(test.expected, got) {
+			t.Errorf("tests[%d]: %s\nexpected: %q\ngot: %q", i, test.command, test.expected, got)
+		}
+	}
+}
+
+func TestExtractKeys_Invalid(t *testing.T) {
+	tests := []string{
+		"NOPE foo",
+		"GET",
+		"GET a b",
+		"EVAL script 3 a b",
+		"EVAL script x a",
+		"XREAD STREAMS s1 s2 0",
+		"XREAD COUNT 1",
+	}
+
+	for i, test := range tests {
+		parts := strings.Split(test, " ")
+		args := make([][]byte, len(parts)-1)
+		for j, part := range parts[1:] {
+			args[j] = []byte(part)
+		}
+		_, err := ExtractKeys(parts[0], args)
+		if err == nil {
+			t.Errorf("tests[%d]: expected an error but didn't get one", i)
+		}
+	}
+}
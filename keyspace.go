@@ -0,0 +1,176 @@
+package resp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotKeyspaceEvent is returned by ParseKeyspaceEvent for messages that
+// weren't published on a keyspace or keyevent channel.
+var ErrNotKeyspaceEvent = errors.New("resp: not a keyspace notification")
+
+// The flags that "A" stands for in notify-keyspace-events.
+const notifyAllFlags = "g$lshzxetd"
+
+// A KeyspaceEvent is a keyspace notification, e.g. the event "del" for the key
+// "user:1" in database 0. It's the same whether it was received on the
+// keyspace channel (__keyspace@0__:user:1) or the keyevent channel
+// (__keyevent@0__:del).
+type KeyspaceEvent struct {
+	DB    int
+	Event string
+	Key   string
+}
+
+// ParseKeyspaceEvent decodes a message received on a keyspace or keyevent
+// channel.
+func ParseKeyspaceEvent(m Message) (KeyspaceEvent, error) {
+	var event KeyspaceEvent
+	var keyspace bool
+	rest := m.Channel
+	switch {
+	case strings.HasPrefix(rest, "__keyspace@"):
+		keyspace = true
+		rest = rest[len("__keyspace@"):]
+	case strings.HasPrefix(rest, "__keyevent@"):
+		rest = rest[len("__keyevent@"):]
+	default:
+		return event, ErrNotKeyspaceEvent
+	}
+	end := strings.Index(rest, "__:")
+	if end < 0 {
+		return event, ErrNotKeyspaceEvent
+	}
+	db, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return event, ErrNotKeyspaceEvent
+	}
+	event.DB = db
+	if keyspace {
+		event.Key, event.Event = rest[end+3:], string(m.Payload)
+	} else {
+		event.Event, event.Key = rest[end+3:], string(m.Payload)
+	}
+	return event, nil
+}
+
+// EnableKeyspaceEvents makes sure the server's notify-keyspace-events setting
+// includes flags, e.g. "KEA", adding the missing ones with CONFIG SET.
+func EnableKeyspaceEvents(conn *Conn, flags string) error {
+	reply, err := conn.Do("CONFIG", "GET", "notify-keyspace-events")
+	if err != nil {
+		return err
+	}
+	if e, ok := reply.(Error); ok {
+		return e
+	}
+	pairs, ok := objectPairs(reply)
+	if !ok || len(pairs) != 2 {
+		return ErrUnexpectedReply
+	}
+	current, ok := objectString(pairs[1])
+	if !ok {
+		return ErrUnexpectedReply
+	}
+
+	missing := ""
+	for _, flag := range flags {
+		switch {
+		case strings.ContainsRune(current+missing, flag):
+		case strings.ContainsRune(notifyAllFlags, flag) && strings.ContainsRune(current+missing, 'A'):
+		default:
+			missing += string(flag)
+		}
+	}
+	if missing == "" {
+		return nil
+	}
+	reply, err = conn.Do("CONFIG", "SET", "notify-keyspace-events", current+missing)
+	if err != nil {
+		return err
+	}
+	if e, ok := reply.(Error); ok {
+		return e
+	}
+	return nil
+}
+
+// A KeyspaceSubscriber receives keyspace notifications. Events are delivered
+// on the channel returned by Events, which is closed when the connection
+// fails or the subscriber is closed.
+type KeyspaceSubscriber struct {
+	pubsub    *PubSub
+	events    chan KeyspaceEvent
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewKeyspaceSubscriber returns a KeyspaceSubscriber that uses conn, which
+// must not be used for anything else afterwards. If flags isn't empty, the
+// server's notify-keyspace-events setting is first updated to include them,
+// as by EnableKeyspaceEvents.
+func NewKeyspaceSubscriber(conn *Conn, flags string) (*KeyspaceSubscriber, error) {
+	if flags != "" {
+		if err := EnableKeyspaceEvents(conn, flags); err != nil {
+			return nil, err
+		}
+	}
+	s := &KeyspaceSubscriber{
+		pubsub: NewPubSub(conn),
+		events: make(chan KeyspaceEvent, 100),
+		done:   make(chan struct{}),
+	}
+	go s.receive(s.pubsub.Messages())
+	return s, nil
+}
+
+func (s *KeyspaceSubscriber) receive(messages <-chan Message) {
+	defer close(s.events)
+	for m := range messages {
+		if event, err := ParseKeyspaceEvent(m); err == nil {
+			select {
+			case s.events <- event:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// Events returns the channel events are delivered on.
+func (s *KeyspaceSubscriber) Events() <-chan KeyspaceEvent {
+	return s.events
+}
+
+// SubscribeKeys subscribes to events for keys matching pattern in database
+// db, or in all databases if db is negative. It requires the K flag.
+func (s *KeyspaceSubscriber) SubscribeKeys(db int, pattern string) error {
+	return s.pubsub.PSubscribe("__keyspace@" + dbPattern(db) + "__:" + pattern)
+}
+
+// SubscribeEvents subscribes to events matching pattern, e.g. "del" or
+// "*", in database db, or in all databases if db is negative. It requires the
+// E flag.
+func (s *KeyspaceSubscriber) SubscribeEvents(db int, pattern string) error {
+	return s.pubsub.PSubscribe("__keyevent@" + dbPattern(db) + "__:" + pattern)
+}
+
+func dbPattern(db int) string {
+	if db < 0 {
+		return "*"
+	}
+	return strconv.Itoa(db)
+}
+
+// Err returns the error that ended the event channel, if any.
+func (s *KeyspaceSubscriber) Err() error {
+	return s.pubsub.Err()
+}
+
+// Close closes the connection and the event channel.
+func (s *KeyspaceSubscriber) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	return s.pubsub.Close()
+}
@@ -0,0 +1,96 @@
+package resp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeyspaceEvent(t *testing.T) {
+	tests := []struct {
+		given    Message
+		expected KeyspaceEvent
+	}{
+		{Message{Channel: "__keyspace@0__:user:1", Payload: []byte("del")}, KeyspaceEvent{0, "del", "user:1"}},
+		{Message{Channel: "__keyevent@12__:expired", Payload: []byte("session:__x__:y")}, KeyspaceEvent{12, "expired", "session:__x__:y"}},
+	}
+	for i, test := range tests {
+		event, err := ParseKeyspaceEvent(test.given)
+		if err != nil || event != test.expected {
+			t.Errorf("tests[%d]: expected %+v, got %+v, %v", i, test.expected, event, err)
+		}
+	}
+	for _, channel := range []string{"news", "__keyspace@x__:a", "__keyevent@0:del"} {
+		if _, err := ParseKeyspaceEvent(Message{Channel: channel}); err != ErrNotKeyspaceEvent {
+			t.Errorf("%s: expected ErrNotKeyspaceEvent, got %v", channel, err)
+		}
+	}
+}
+
+func TestEnableKeyspaceEvents(t *testing.T) {
+	tests := []struct {
+		current string
+		flags   string
+		set     string
+	}{
+		{"", "KEA", "KEA"},
+		{"Ex", "KEx", "ExK"},
+		{"AKE", "Kg$", ""},
+		{"gE", "E", ""},
+	}
+	for i, test := range tests {
+		set := ""
+		conn := fakeServer(func(name string, args [][]byte) Object {
+			if strings.ToUpper(string(args[0])) == "SET" {
+				set = string(args[2])
+				return OK
+			}
+			return NewArray(NewBulkString("notify-keyspace-events"), NewBulkString(test.current))
+		})
+		if err := EnableKeyspaceEvents(conn, test.flags); err != nil {
+			t.Errorf("tests[%d]: %v", i, err)
+		}
+		if set != test.set {
+			t.Errorf("tests[%d]: expected to set %q, set %q", i, test.set, set)
+		}
+		conn.Close()
+	}
+}
+
+func TestKeyspaceSubscriber(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		r := NewReader(server)
+		for {
+			cmd, err := r.ReadCommand()
+			if err != nil {
+				return
+			}
+			_, args, _ := ParseCommand(cmd)
+			pattern := string(args[0])
+			server.Write(NewArray(NewBulkString("psubscribe"), NewBulkString(pattern), NewInteger(1)))
+			server.Write(NewArray(NewBulkString("pmessage"), NewBulkString(pattern), NewBulkString("news"), NewBulkString("ignored")))
+			channel := strings.Replace(pattern, "*", "3", 1)
+			server.Write(NewArray(NewBulkString("pmessage"), NewBulkString(pattern), NewBulkString(channel), NewBulkString("k")))
+		}
+	}()
+
+	s, err := NewKeyspaceSubscriber(NewConn(client), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.SubscribeEvents(-1, "del"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-s.Events():
+		if event != (KeyspaceEvent{3, "del", "k"}) {
+			t.Errorf("unexpected event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+}
@@ -0,0 +1,292 @@
+package resp
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// latencySubBits sets the precision of a LatencyHistogram: each power
+	// of two is split into 1<<(latencySubBits-1) buckets, so values are
+	// within 1/32 of the bucket's bounds.
+	latencySubBits = 6
+	latencySub     = 1 << latencySubBits
+	latencyHalf    = latencySub / 2
+	// latencyBuckets covers every non-negative int64.
+	latencyBuckets = latencySub + (63-latencySubBits)*latencyHalf
+)
+
+// A LatencyHistogram records durations in log-linear buckets, like an HDR
+// histogram: durations are recorded exactly up to 64ns, and to within about
+// 3% beyond that, in constant memory. It's not safe for concurrent use.
+type LatencyHistogram struct {
+	counts   [latencyBuckets]uint64
+	count    uint64
+	sum      time.Duration
+	min, max time.Duration
+}
+
+// Record records a duration. Negative durations are recorded as 0.
+func (h *LatencyHistogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[latencyBucket(uint64(d))]++
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+}
+
+// Count returns the number of durations recorded.
+func (h *LatencyHistogram) Count() uint64 {
+	return h.count
+}
+
+// Min, Max, and Mean return the smallest, largest, and mean durations
+// recorded, or 0 if there are none. They're exact.
+func (h *LatencyHistogram) Min() time.Duration {
+	return h.min
+}
+
+func (h *LatencyHistogram) Max() time.Duration {
+	return h.max
+}
+
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+// Percentile returns the duration that p percent of the durations recorded
+// are at most, rounded up to the end of its bucket, but never more than Max.
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range h.counts {
+		seen += n
+		if seen >= rank {
+			if d := time.Duration(latencyBucketMax(i)); d < h.max {
+				return d
+			}
+			break
+		}
+	}
+	return h.max
+}
+
+// Merge adds the durations recorded by other to h.
+func (h *LatencyHistogram) Merge(other *LatencyHistogram) {
+	if other.count == 0 {
+		return
+	}
+	for i, n := range other.counts {
+		h.counts[i] += n
+	}
+	if h.count == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	h.count += other.count
+	h.sum += other.sum
+}
+
+// latencyBucket returns the index of the bucket v is counted in.
+func latencyBucket(v uint64) int {
+	if v < latencySub {
+		return int(v)
+	}
+	shift := bits.Len64(v) - latencySubBits
+	return latencySub + (shift-1)*latencyHalf + int(v>>uint(shift)) - latencyHalf
+}
+
+// latencyBucketMax returns the largest value counted in bucket i.
+func latencyBucketMax(i int) uint64 {
+	if i < latencySub {
+		return uint64(i)
+	}
+	shift := uint((i-latencySub)/latencyHalf + 1)
+	top := uint64((i-latencySub)%latencyHalf + latencyHalf)
+	return (top+1)<<shift - 1
+}
+
+// LatencyStats collects a LatencyHistogram of round trip times for each
+// command, from when a command is written to a connection to when its reply
+// is read. Times are recorded with Record, by the Traces returned by Trace,
+// or from the requests of a Correlator with RecordRequest. LatencyStats is
+// safe for concurrent use. The zero value is ready to use.
+type LatencyStats struct {
+	mu    sync.Mutex
+	stats map[string]*LatencyHistogram
+}
+
+// Record records the round trip time of a call to the named command.
+func (s *LatencyStats) Record(name string, d time.Duration) {
+	if canonical, ok := LookupCommand([]byte(name)); ok {
+		name = canonical
+	} else {
+		name = strings.ToUpper(name)
+	}
+
+	s.mu.Lock()
+	if s.stats == nil {
+		s.stats = map[string]*LatencyHistogram{}
+	}
+	h := s.stats[name]
+	if h == nil {
+		h = &LatencyHistogram{}
+		s.stats[name] = h
+	}
+	h.Record(d)
+	s.mu.Unlock()
+}
+
+// RecordRequest records the round trip time of a request just matched with
+// its reply by a Correlator, from its Sent time to now. Requests without a
+// Sent time are ignored.
+func (s *LatencyStats) RecordRequest(req *Request) {
+	if req.Sent.IsZero() {
+		return
+	}
+	if name, _, err := ParseCommand(req.Command); err == nil {
+		s.Record(name, time.Since(req.Sent))
+	}
+}
+
+// Trace returns a Trace that records the round trip times of the commands
+// written and replies read by a client connection's Writer and Reader, such
+// as those of a Conn, with Conn.SetTrace. Each command is timed from when
+// it's flushed. The Trace pairs replies with commands in order, so it can't
+// be used on connections in subscriber mode, or on several connections.
+func (s *LatencyStats) Trace() *Trace {
+	t := &latencyTrace{stats: s}
+	return &Trace{
+		ObjectWritten:    t.written,
+		FlushEnd:         t.flushed,
+		ObjectRead:       t.read,
+		ErrorEncountered: t.failed,
+	}
+}
+
+// Snapshot returns copies of the histograms, keyed by upper case command
+// name.
+func (s *LatencyStats) Snapshot() map[string]*LatencyHistogram {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]*LatencyHistogram, len(s.stats))
+	for name, h := range s.stats {
+		copied := *h
+		snapshot[name] = &copied
+	}
+	return snapshot
+}
+
+// Names returns the names of the commands recorded, sorted.
+func (s *LatencyStats) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.stats))
+	for name := range s.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reset clears all histograms.
+func (s *LatencyStats) Reset() {
+	s.mu.Lock()
+	s.stats = nil
+	s.mu.Unlock()
+}
+
+// latencyTrace pairs the commands written to a connection with the replies
+// read from it.
+type latencyTrace struct {
+	stats *LatencyStats
+	// mu guards queue, since the Reader and Writer may be used by
+	// different goroutines.
+	mu    sync.Mutex
+	queue []pendingCommand
+	// sent is the number of commands at the front of queue that have been
+	// flushed.
+	sent int
+}
+
+type pendingCommand struct {
+	// name is empty if the command couldn't be parsed.
+	name string
+	sent time.Time
+}
+
+func (t *latencyTrace) written(b []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for len(b) > 0 {
+		end, err := objectEnd(b)
+		if end < 0 || err != nil {
+			return
+		}
+		name, _, _ := ParseCommand(b[:end+1])
+		t.queue = append(t.queue, pendingCommand{name: name})
+		b = b[end+1:]
+	}
+}
+
+func (t *latencyTrace) flushed(n int, err error) {
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	for ; t.sent < len(t.queue); t.sent++ {
+		t.queue[t.sent].sent = now
+	}
+	t.mu.Unlock()
+}
+
+func (t *latencyTrace) read(obj []byte) {
+	if obj[0] == PUSH_PREFIX {
+		return
+	}
+	t.mu.Lock()
+	if t.sent == 0 {
+		t.mu.Unlock()
+		return
+	}
+	cmd := t.queue[0]
+	t.queue = t.queue[1:]
+	t.sent--
+	t.mu.Unlock()
+
+	if cmd.name != "" {
+		t.stats.Record(cmd.name, time.Since(cmd.sent))
+	}
+}
+
+func (t *latencyTrace) failed(err error) {
+	t.mu.Lock()
+	t.queue = nil
+	t.sent = 0
+	t.mu.Unlock()
+}
@@ -0,0 +1,125 @@
+package resp
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	values := []uint64{0, 1, 63, 64, 65, 127, 128, 1000, 1 << 40, 1<<63 - 1}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		values = append(values, uint64(r.Int63())>>uint(r.Intn(63)))
+	}
+	for _, v := range values {
+		i := latencyBucket(v)
+		if i < 0 || i >= latencyBuckets {
+			t.Fatalf("%d: bucket %d out of range", v, i)
+		}
+		max := latencyBucketMax(i)
+		if max < v || i > 0 && latencyBucketMax(i-1) >= v {
+			t.Fatalf("%d: not in bucket %d", v, i)
+		}
+		if float64(max-v) > float64(v)/32 {
+			t.Fatalf("%d: bucket %d ends at %d", v, i, max)
+		}
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h LatencyHistogram
+	if h.Percentile(50) != 0 || h.Mean() != 0 {
+		t.Errorf("expected zeros from an empty histogram")
+	}
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	if h.Count() != 1000 || h.Min() != time.Microsecond || h.Max() != time.Millisecond {
+		t.Errorf("unexpected count %d, min %v, and max %v", h.Count(), h.Min(), h.Max())
+	}
+	if h.Mean() != 500500*time.Nanosecond {
+		t.Errorf("expected a mean of 500.5µs, got %v", h.Mean())
+	}
+	for _, p := range []float64{1, 50, 90, 99, 99.9} {
+		expected := time.Duration(p * 10 * float64(time.Microsecond))
+		if got := h.Percentile(p); got < expected || got > expected+expected/32 {
+			t.Errorf("expected p%v of %v, got %v", p, expected, got)
+		}
+	}
+	if h.Percentile(100) != time.Millisecond {
+		t.Errorf("expected p100 to be the max, got %v", h.Percentile(100))
+	}
+
+	var other LatencyHistogram
+	other.Record(-time.Second)
+	other.Record(2 * time.Second)
+	h.Merge(&other)
+	if h.Count() != 1002 || h.Min() != 0 || h.Max() != 2*time.Second {
+		t.Errorf("unexpected count %d, min %v, and max %v", h.Count(), h.Min(), h.Max())
+	}
+}
+
+func TestLatencyStats_Conn(t *testing.T) {
+	conn := fakeServer(func(name string, args [][]byte) Object {
+		if name == "DEBUG" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return OK
+	})
+	defer conn.Close()
+
+	var stats LatencyStats
+	conn.SetTrace(stats.Trace())
+	if _, err := conn.Do("get", "a"); err != nil {
+		t.Fatal(err)
+	}
+	conn.Send("DEBUG", "SLEEP", "0.02")
+	conn.Send("SET", "a", "b")
+	if _, err := conn.Receive(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Receive(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"DEBUG", "GET", "SET"}; !reflect.DeepEqual(expected, stats.Names()) {
+		t.Errorf("expected %v, got %v", expected, stats.Names())
+	}
+	snapshot := stats.Snapshot()
+	if d := snapshot["DEBUG"].Max(); d < 20*time.Millisecond {
+		t.Errorf("expected DEBUG to take at least 20ms, got %v", d)
+	}
+	if snapshot["GET"].Count() != 1 || snapshot["SET"].Count() != 1 {
+		t.Errorf("expected a GET and a SET, got %d and %d", snapshot["GET"].Count(), snapshot["SET"].Count())
+	}
+
+	// Snapshots are copies
+	snapshot["GET"].Record(time.Hour)
+	if stats.Snapshot()["GET"].Count() != 1 {
+		t.Errorf("expected the snapshot to be a copy")
+	}
+	stats.Reset()
+	if len(stats.Snapshot()) != 0 {
+		t.Errorf("expected no stats after Reset")
+	}
+}
+
+func TestLatencyStats_RecordRequest(t *testing.T) {
+	var stats LatencyStats
+	var c Correlator
+	c.Record(&Request{Command: NewCommand("GET", "a"), Sent: time.Now().Add(-time.Second)})
+	c.Record(&Request{Command: NewCommand("GET", "b")})
+	for i := 0; i < 2; i++ {
+		req, _, err := c.Match(OK)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats.RecordRequest(req)
+	}
+	h := stats.Snapshot()["GET"]
+	if h == nil || h.Count() != 1 || h.Min() < time.Second {
+		t.Errorf("expected one GET taking a second")
+	}
+}
@@ -0,0 +1,283 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MAX_INLINE_LENGTH is the longest inline command Redis accepts.
+const MAX_INLINE_LENGTH = 64 << 10
+
+// The rules Lint checks.
+const (
+	// LINT_LF_TERMINATOR is a line ended by "\n" rather than "\r\n",
+	// which Redis accepts from inline commands but not in RESP.
+	LINT_LF_TERMINATOR = "lf-terminator"
+	// LINT_DEPRECATED_ENCODING is a RESP2 null bulk string or array,
+	// "$-1\r\n" or "*-1\r\n", on a RESP3 stream, which has its own null.
+	LINT_DEPRECATED_ENCODING = "deprecated-encoding"
+	// LINT_INLINE_TOO_LONG is an inline command longer than
+	// MAX_INLINE_LENGTH, which Redis rejects.
+	LINT_INLINE_TOO_LONG = "inline-too-long"
+	// LINT_INCONSISTENT_NULL is a stream that encodes nulls both as "_"
+	// and as RESP2 nulls.
+	LINT_INCONSISTENT_NULL = "inconsistent-null"
+	// LINT_RESP3_ON_RESP2 is a RESP3 type on a stream using RESP2, which
+	// RESP2 clients can't parse.
+	LINT_RESP3_ON_RESP2 = "resp3-on-resp2"
+	// LINT_INVALID is data that isn't RESP or an inline command at all.
+	LINT_INVALID = "invalid"
+)
+
+// A LintIssue is a protocol smell found by Lint or LintCapture.
+type LintIssue struct {
+	// Rule is one of the LINT_* rules.
+	Rule    string
+	Message string
+	// Offset is where the offending data starts in its stream.
+	Offset int64
+	// Conn and Direction are the stream the issue was found in, for
+	// issues found by LintCapture.
+	Conn      int64
+	Direction Direction
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%d: %s: %s", i.Offset, i.Rule, i.Message)
+}
+
+// Lint reads a stream of RESP, such as commands sent by a client or replies
+// sent by a server using the given protocol version, 2 or 3, and returns the
+// protocol smells it finds in it: problems that clients or servers may
+// tolerate but shouldn't have to. Data that can't be parsed is reported and
+// skipped to the next line. Lint only returns an error if reading fails.
+func Lint(r io.Reader, protocol int) ([]LintIssue, error) {
+	l := &linter{protocol: &protocol}
+	buf := make([]byte, DEFAULT_BUFFER)
+	for {
+		n, err := r.Read(buf)
+		l.write(buf[:n])
+		if err == io.EOF {
+			l.close()
+			return l.issues, nil
+		}
+		if err != nil {
+			return l.issues, err
+		}
+	}
+}
+
+// LintCapture lints each direction of each connection recorded in a capture
+// file, like Lint. Connections are assumed to use RESP2 until a HELLO command
+// switches their protocol.
+func LintCapture(r *ReplayReader) ([]LintIssue, error) {
+	type stream struct {
+		conn      int64
+		direction Direction
+	}
+	linters := map[stream]*linter{}
+	var order []*linter
+	protocols := map[int64]*int{}
+	var issues []LintIssue
+	for {
+		rec, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return issues, err
+		}
+
+		key := stream{rec.Conn, rec.Direction}
+		l := linters[key]
+		if l == nil {
+			protocol := protocols[rec.Conn]
+			if protocol == nil {
+				protocol = new(int)
+				*protocol = 2
+				protocols[rec.Conn] = protocol
+			}
+			l = &linter{protocol: protocol, conn: rec.Conn, direction: rec.Direction}
+			linters[key] = l
+			order = append(order, l)
+		}
+		l.write(rec.Frame)
+		issues = append(issues, l.issues...)
+		l.issues = l.issues[:0]
+	}
+	for _, l := range order {
+		l.close()
+		issues = append(issues, l.issues...)
+	}
+	return issues, nil
+}
+
+// A linter checks a stream written to it a piece at a time.
+type linter struct {
+	// protocol is shared by the linters of a connection's directions, so
+	// that a HELLO command read in one switches the protocol of the other.
+	protocol  *int
+	conn      int64
+	direction Direction
+	// buf holds the incomplete object or line at offset.
+	buf    []byte
+	offset int64
+	scan   objectScanner
+	// nulls is set to the prefix of the first null seen, '_' for RESP3
+	// nulls and '$' for RESP2 nulls, and inconsistent once reported.
+	nulls        byte
+	inconsistent bool
+	issues       []LintIssue
+}
+
+func (l *linter) report(offset int64, rule, format string, args ...interface{}) {
+	l.issues = append(l.issues, LintIssue{
+		Rule:      rule,
+		Message:   fmt.Sprintf(format, args...),
+		Offset:    offset,
+		Conn:      l.conn,
+		Direction: l.direction,
+	})
+}
+
+func (l *linter) write(p []byte) {
+	l.buf = append(l.buf, p...)
+	i := 0
+	for i < len(l.buf) {
+		rest := l.buf[i:]
+		offset := l.offset + int64(i)
+		if _, ok := prefixNames[rest[0]]; !ok {
+			// An inline command
+			end := bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				if len(rest) > MAX_INLINE_LENGTH {
+					l.report(offset, LINT_INLINE_TOO_LONG, "inline command longer than %d bytes", MAX_INLINE_LENGTH)
+					i = len(l.buf)
+				}
+				break
+			}
+			l.lintInline(offset, rest[:end+1])
+			i += end + 1
+			continue
+		}
+
+		end, err := l.scan.scan(rest)
+		if err != nil {
+			l.scan.reset()
+			end = bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				break
+			}
+			if end == 0 || rest[end-1] != '\r' {
+				l.report(offset, LINT_LF_TERMINATOR, "%s line ended by \\n: %q", prefixNames[rest[0]], rest[:end+1])
+			} else {
+				l.report(offset, LINT_INVALID, "invalid %s: %q", prefixNames[rest[0]], rest[:end+1])
+			}
+			i += end + 1
+			continue
+		}
+		if end < 0 {
+			break
+		}
+		l.scan.reset()
+		l.lintObject(offset, rest[:end+1])
+		l.lintHello(rest[:end+1])
+		i += end + 1
+	}
+	l.offset += int64(i)
+	l.buf = append(l.buf[:0], l.buf[i:]...)
+}
+
+// close reports an incomplete object or line at the end of the stream.
+func (l *linter) close() {
+	if len(l.buf) > 0 {
+		l.report(l.offset, LINT_INVALID, "stream ends with %d bytes of an incomplete object or line", len(l.buf))
+		l.buf = nil
+	}
+}
+
+func (l *linter) lintInline(offset int64, line []byte) {
+	if len(line) > MAX_INLINE_LENGTH {
+		l.report(offset, LINT_INLINE_TOO_LONG, "inline command of %d bytes is longer than %d", len(line), MAX_INLINE_LENGTH)
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		l.report(offset, LINT_LF_TERMINATOR, "inline command ended by \\n")
+	}
+}
+
+// lintObject checks obj, which starts at offset, and the objects nested in
+// it.
+func (l *linter) lintObject(offset int64, obj []byte) {
+	switch obj[0] {
+	case SIMPLE_STRING_PREFIX, ERROR_PREFIX:
+		if bytes.IndexByte(obj[:len(obj)-2], '\n') >= 0 {
+			l.report(offset, LINT_LF_TERMINATOR, "%s contains \\n, as if a line was ended by \\n: %q", prefixNames[obj[0]], obj)
+		}
+		return
+	case NULL_PREFIX:
+		l.lintNull(offset, obj)
+	case BULK_STRING_PREFIX, ARRAY_PREFIX:
+		if bytes.HasPrefix(obj[1:], []byte("-1\r\n")) {
+			l.lintNull(offset, obj)
+		}
+	}
+	if *l.protocol < 3 {
+		switch obj[0] {
+		case SIMPLE_STRING_PREFIX, ERROR_PREFIX, INTEGER_PREFIX, BULK_STRING_PREFIX, ARRAY_PREFIX:
+		default:
+			l.report(offset, LINT_RESP3_ON_RESP2, "%s on a RESP2 stream", prefixNames[obj[0]])
+		}
+	}
+
+	switch obj[0] {
+	case ARRAY_PREFIX, PUSH_PREFIX, SET_PREFIX, MAP_PREFIX, ATTRIBUTE_PREFIX:
+		length, lineEnd, err := parseLenLine(obj)
+		if err != nil {
+			return
+		}
+		pos := lineEnd + 1
+		for n := aggregateLength(obj[0], length); n > 0; n-- {
+			end, err := objectEnd(obj[pos:])
+			if end < 0 || err != nil {
+				return
+			}
+			l.lintObject(offset+int64(pos), obj[pos:pos+end+1])
+			pos += end + 1
+		}
+	}
+}
+
+func (l *linter) lintNull(offset int64, obj []byte) {
+	prefix := byte(NULL_PREFIX)
+	if obj[0] != NULL_PREFIX {
+		prefix = BULK_STRING_PREFIX
+		if *l.protocol >= 3 {
+			l.report(offset, LINT_DEPRECATED_ENCODING, "RESP2 null %q on a RESP3 stream", obj)
+		}
+	}
+	if l.nulls == 0 {
+		l.nulls = prefix
+	} else if l.nulls != prefix && !l.inconsistent {
+		l.inconsistent = true
+		l.report(offset, LINT_INCONSISTENT_NULL, "null %q after nulls encoded as %q", obj, map[byte]string{NULL_PREFIX: "_", BULK_STRING_PREFIX: "$-1 or *-1"}[l.nulls])
+	}
+}
+
+// lintHello switches the protocol after a HELLO command.
+func (l *linter) lintHello(obj []byte) {
+	if obj[0] != ARRAY_PREFIX {
+		return
+	}
+	name, args, err := ParseCommand(obj)
+	if err != nil || len(args) == 0 || !strings.EqualFold(name, "HELLO") {
+		return
+	}
+	switch string(args[0]) {
+	case "2":
+		*l.protocol = 2
+	case "3":
+		*l.protocol = 3
+	}
+}
@@ -0,0 +1,91 @@
+package resp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func lintRules(issues []LintIssue) []string {
+	rules := []string{}
+	for _, issue := range issues {
+		rules = append(rules, issue.Rule)
+	}
+	return rules
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		protocol int
+		input    string
+		rules    string
+	}{
+		{2, "+OK\r\n$3\r\nfoo\r\n*2\r\n:1\r\n$-1\r\n", ""},
+		{3, "%1\r\n+a\r\n_\r\n>2\r\n+a\r\n#t\r\n", ""},
+		{2, "PING\r\nSET a b\r\n", ""},
+
+		{2, "PING\n", "lf-terminator"},
+		{2, "+OK\n+OK\r\n", "lf-terminator"},
+		{2, "$3\nfoo\r\n", "lf-terminator"},
+		{2, "*1\r\n+a\nb\r\n", "lf-terminator"},
+		{2, "PING " + strings.Repeat("x", MAX_INLINE_LENGTH) + "\r\n", "inline-too-long"},
+		{3, "$-1\r\n", "deprecated-encoding"},
+		{3, "*1\r\n*-1\r\n", "deprecated-encoding"},
+		{2, "$-1\r\n*-1\r\n", ""},
+		{2, "$-1\r\n_\r\n_\r\n", "inconsistent-null resp3-on-resp2 resp3-on-resp2"},
+		{3, "_\r\n$-1\r\n$-1\r\n", "deprecated-encoding inconsistent-null deprecated-encoding"},
+		{2, "%1\r\n+a\r\n,1.5\r\n", "resp3-on-resp2 resp3-on-resp2"},
+		{2, "$x\r\n+OK\r\n", "invalid"},
+		{2, "+OK\r\n$3\r\nfo", "invalid"},
+	}
+
+	for i, test := range tests {
+		issues, err := Lint(iotest.OneByteReader(strings.NewReader(test.input)), test.protocol)
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err)
+			continue
+		}
+		if rules := strings.Join(lintRules(issues), " "); rules != test.rules {
+			t.Errorf("tests[%d]: expected %q, got %q (%v)", i, test.rules, rules, issues)
+		}
+	}
+}
+
+func TestLint_Offset(t *testing.T) {
+	issues, err := Lint(strings.NewReader("+OK\r\n*2\r\n:1\r\n~0\r\n"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Offset != 13 {
+		t.Errorf("expected one issue at offset 13, got %v", issues)
+	}
+}
+
+func TestLintCapture(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCaptureWriter(&buf)
+	c.WriteRecord(CaptureRecord{Conn: 1, Direction: DIRECTION_READ, Frame: NewCommand("HELLO", "3")})
+	c.WriteRecord(CaptureRecord{Conn: 1, Direction: DIRECTION_WRITTEN, Frame: []byte("%1\r\n+proto\r\n:3\r\n")})
+	c.WriteRecord(CaptureRecord{Conn: 1, Direction: DIRECTION_WRITTEN, Frame: []byte("$-1\r\n")})
+	c.WriteRecord(CaptureRecord{Conn: 2, Direction: DIRECTION_WRITTEN, Frame: []byte("#t\r")})
+	c.WriteRecord(CaptureRecord{Conn: 2, Direction: DIRECTION_WRITTEN, Frame: []byte("\n$-1")})
+	c.Flush()
+
+	issues, err := LintCapture(NewReplayReader(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := strings.Join(lintRules(issues), " "); rules != "deprecated-encoding resp3-on-resp2 invalid" {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	if issues[0].Conn != 1 || issues[0].Direction != DIRECTION_WRITTEN || issues[0].Offset != 16 {
+		t.Errorf("unexpected issue %+v", issues[0])
+	}
+	if issues[1].Conn != 2 || issues[1].Offset != 0 {
+		t.Errorf("unexpected issue %+v", issues[1])
+	}
+	if issues[2].Conn != 2 || issues[2].Offset != 4 {
+		t.Errorf("unexpected issue %+v", issues[2])
+	}
+}
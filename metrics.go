@@ -0,0 +1,200 @@
+package resp
+
+import (
+	"io"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// objectSizeBounds are the upper bounds, in bytes, of the object size
+	// histogram's buckets.
+	objectSizeBounds = []float64{16, 64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+	// fillLatencyBounds are the upper bounds, in seconds, of the fill
+	// latency histogram's buckets.
+	fillLatencyBounds = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5}
+)
+
+// A Histogram counts observed values in buckets with fixed upper bounds, like
+// a Prometheus histogram. It's safe for concurrent use.
+type Histogram struct {
+	// sum holds the bits of a float64.
+	sum    uint64
+	counts []uint64
+	bounds []float64
+}
+
+// NewHistogram returns a Histogram with the given bucket upper bounds, which
+// must be sorted. Values above the last bound are counted in an implicit
+// +Inf bucket.
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		counts: make([]uint64, len(bounds)+1),
+		bounds: append([]float64(nil), bounds...),
+	}
+}
+
+// Observe records a value.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	atomic.AddUint64(&h.counts[i], 1)
+	for {
+		old := atomic.LoadUint64(&h.sum)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sum, old, sum) {
+			return
+		}
+	}
+}
+
+// Snapshot returns the current state of the histogram.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: make([]uint64, len(h.counts)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadUint64(&h.counts[i])
+		s.Count += s.Counts[i]
+	}
+	s.Sum = math.Float64frombits(atomic.LoadUint64(&h.sum))
+	return s
+}
+
+// A HistogramSnapshot is the state of a Histogram at some point in time.
+type HistogramSnapshot struct {
+	// Bounds are the upper bounds of the buckets, excluding +Inf. They
+	// mustn't be modified.
+	Bounds []float64
+	// Counts are the number of values in each bucket, which aren't
+	// cumulative. The last is the +Inf bucket.
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+// Buckets returns the cumulative counts of values less than or equal to each
+// bound, excluding +Inf, as expected by prometheus.NewConstHistogram.
+func (s HistogramSnapshot) Buckets() map[float64]uint64 {
+	buckets := make(map[float64]uint64, len(s.Bounds))
+	var total uint64
+	for i, bound := range s.Bounds {
+		total += s.Counts[i]
+		buckets[bound] = total
+	}
+	return buckets
+}
+
+// Metrics aggregates counters and histograms for any number of Readers and
+// Writers, which record into it through the Traces it returns. It's safe for
+// concurrent use. The package doesn't depend on any metrics library, but a
+// MetricsSnapshot maps directly onto Prometheus counters and const
+// histograms, so a prometheus.Collector only has to copy its fields.
+type Metrics struct {
+	objectsRead    uint64
+	objectsWritten uint64
+	bytesRead      uint64
+	bytesWritten   uint64
+	fills          uint64
+	flushes        uint64
+	errors         uint64
+
+	objectSize  *Histogram
+	fillLatency *Histogram
+}
+
+// A MetricsSnapshot holds the values of a Metrics at some point in time.
+type MetricsSnapshot struct {
+	ObjectsRead    uint64
+	ObjectsWritten uint64
+	BytesRead      uint64
+	BytesWritten   uint64
+	Fills          uint64
+	Flushes        uint64
+	// Errors counts the errors returned by Readers and Writers, not
+	// counting io.EOF.
+	Errors uint64
+
+	// ObjectSize holds the sizes of the objects read, in bytes.
+	ObjectSize HistogramSnapshot
+	// FillLatency holds the durations of the reads from Readers'
+	// underlying io.Readers, in seconds.
+	FillLatency HistogramSnapshot
+}
+
+// NewMetrics returns a new Metrics with all values zero.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		objectSize:  NewHistogram(objectSizeBounds),
+		fillLatency: NewHistogram(fillLatencyBounds),
+	}
+}
+
+// Trace returns a new Trace that records into m. Each Trace times fills
+// itself, so it should only be set on one Reader (and any number of
+// Writers).
+func (m *Metrics) Trace() *Trace {
+	var fillStart time.Time
+	return &Trace{
+		ObjectRead: func(obj []byte) {
+			atomic.AddUint64(&m.objectsRead, 1)
+			m.objectSize.Observe(float64(len(obj)))
+		},
+		ObjectWritten: func(b []byte) {
+			atomic.AddUint64(&m.objectsWritten, countObjects(b))
+		},
+		FillStart: func() {
+			fillStart = time.Now()
+		},
+		FillEnd: func(n int, err error) {
+			m.fillLatency.Observe(time.Since(fillStart).Seconds())
+			atomic.AddUint64(&m.fills, 1)
+			atomic.AddUint64(&m.bytesRead, uint64(n))
+		},
+		FlushEnd: func(n int, err error) {
+			atomic.AddUint64(&m.flushes, 1)
+			atomic.AddUint64(&m.bytesWritten, uint64(n))
+		},
+		ErrorEncountered: func(err error) {
+			if err != io.EOF {
+				atomic.AddUint64(&m.errors, 1)
+			}
+		},
+	}
+}
+
+// Snapshot returns the current values. Each value is loaded atomically, but
+// values recorded while Snapshot runs may be included in some and not others.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		ObjectsRead:    atomic.LoadUint64(&m.objectsRead),
+		ObjectsWritten: atomic.LoadUint64(&m.objectsWritten),
+		BytesRead:      atomic.LoadUint64(&m.bytesRead),
+		BytesWritten:   atomic.LoadUint64(&m.bytesWritten),
+		Fills:          atomic.LoadUint64(&m.fills),
+		Flushes:        atomic.LoadUint64(&m.flushes),
+		Errors:         atomic.LoadUint64(&m.errors),
+		ObjectSize:     m.objectSize.Snapshot(),
+		FillLatency:    m.fillLatency.Snapshot(),
+	}
+}
+
+// countObjects returns the number of complete objects at the start of b,
+// or 1 if there are none, e.g. because b holds part of a large object.
+func countObjects(b []byte) uint64 {
+	var n uint64
+	for len(b) > 0 {
+		end, err := objectEnd(b)
+		if end < 0 || err != nil {
+			break
+		}
+		n++
+		b = b[end+1:]
+	}
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
@@ -0,0 +1,87 @@
+package resp
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 10})
+	for _, v := range []float64{0.5, 1, 2, 10, 11, 100} {
+		h.Observe(v)
+	}
+	s := h.Snapshot()
+	if expected := []uint64{2, 2, 2}; !reflect.DeepEqual(expected, s.Counts) {
+		t.Errorf("expected counts %v, got %v", expected, s.Counts)
+	}
+	if s.Count != 6 || s.Sum != 124.5 {
+		t.Errorf("expected count 6 and sum 124.5, got %d and %v", s.Count, s.Sum)
+	}
+	if expected := map[float64]uint64{1: 2, 10: 4}; !reflect.DeepEqual(expected, s.Buckets()) {
+		t.Errorf("expected buckets %v, got %v", expected, s.Buckets())
+	}
+}
+
+func TestHistogram_Concurrent(t *testing.T) {
+	h := NewHistogram([]float64{1})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				h.Observe(2)
+			}
+		}()
+	}
+	wg.Wait()
+	if s := h.Snapshot(); s.Count != 4000 || s.Sum != 8000 {
+		t.Errorf("expected count 4000 and sum 8000, got %d and %v", s.Count, s.Sum)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	stream := "+OK\r\n$300\r\n" + strings.Repeat("x", 300) + "\r\n"
+	r := NewReader(strings.NewReader(stream))
+	r.SetTrace(m.Trace())
+	for {
+		if _, err := r.ReadObjectSlice(); err != nil {
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetTrace(m.Trace())
+	w.Write([]byte("+OK\r\n:1\r\n"))
+	w.WriteObject(NewInteger(2))
+	w.Flush()
+	w = NewWriter(failingWriter{})
+	w.SetTrace(m.Trace())
+	w.WriteObject(OK)
+	w.Flush()
+
+	s := m.Snapshot()
+	if s.ObjectsRead != 2 || s.BytesRead != uint64(len(stream)) {
+		t.Errorf("expected 2 objects and %d bytes read, got %d and %d", len(stream), s.ObjectsRead, s.BytesRead)
+	}
+	if s.ObjectsWritten != 4 || s.BytesWritten != 13 || s.Flushes != 2 {
+		t.Errorf("expected 4 objects, 13 bytes, and 2 flushes written, got %d, %d, and %d", s.ObjectsWritten, s.BytesWritten, s.Flushes)
+	}
+	if s.Errors != 1 {
+		t.Errorf("expected 1 error, got %d", s.Errors)
+	}
+	if s.Fills == 0 || s.FillLatency.Count != s.Fills {
+		t.Errorf("expected a fill latency per fill, got %d fills and %d latencies", s.Fills, s.FillLatency.Count)
+	}
+	if s.ObjectSize.Count != 2 || s.ObjectSize.Sum != float64(len(stream)) {
+		t.Errorf("unexpected object sizes %+v", s.ObjectSize)
+	}
+	if buckets := s.ObjectSize.Buckets(); buckets[16] != 1 || buckets[256] != 1 || buckets[1<<10] != 2 {
+		t.Errorf("unexpected object size buckets %v", buckets)
+	}
+}
@@ -0,0 +1,252 @@
+package resp
+
+import (
+	"crypto/subtle"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// NOAUTH_ERROR is sent by RequirePass to clients that haven't
+	// authenticated.
+	NOAUTH_ERROR = NewError("NOAUTH Authentication required.")
+	// WRONGPASS_ERROR is sent by RequirePass for invalid credentials.
+	WRONGPASS_ERROR = NewError("WRONGPASS invalid username-password pair or user is disabled.")
+	// RATE_LIMIT_ERROR is sent by RateLimit for commands beyond a client's
+	// rate.
+	RATE_LIMIT_ERROR = NewError("ERR max request rate exceeded")
+	// INTERNAL_ERROR is sent by RecoverPanics for commands whose Handler
+	// panicked.
+	INTERNAL_ERROR = NewError("ERR internal error")
+)
+
+// A Middleware wraps a Handler to add behavior around it, e.g. logging.
+type Middleware func(Handler) Handler
+
+// Chain returns handler wrapped by middlewares. The first middleware is the
+// outermost one, so it sees commands first.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	var gates []authGate
+	if gate, ok := handler.(authGate); ok {
+		gates = append(gates, gate)
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+		if gate, ok := handler.(authGate); ok {
+			gates = append(gates, gate)
+		}
+	}
+	if len(gates) == 0 {
+		return handler
+	}
+	return &gatedHandler{Handler: handler, gates: gates}
+}
+
+// An authGate is a Handler that refuses the commands of clients that haven't
+// authenticated, such as RequirePass's. The Server asks it before answering
+// its built-in commands, which don't go through the Handler.
+type authGate interface {
+	Handler
+	authenticated(w *Writer) bool
+}
+
+// A gatedHandler is a chain of handlers that includes authGates.
+type gatedHandler struct {
+	Handler
+	gates []authGate
+}
+
+func (h *gatedHandler) authenticated(w *Writer) bool {
+	for _, gate := range h.gates {
+		if !gate.authenticated(w) {
+			return false
+		}
+	}
+	return true
+}
+
+// LogRequests returns a Middleware that logs each command with logf, e.g.
+// log.Printf, once it's handled: the client's address, the command with its
+// credentials redacted as by RedactCommand, and how long it took.
+func LogRequests(logf func(format string, args ...interface{})) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *Writer, cmd Command) {
+			start := time.Now()
+			next.ServeRESP(w, cmd)
+			elapsed := time.Since(start)
+			name, args, _ := ParseCommand(cmd)
+			logf("%s %s %s", remoteAddr(w), strings.Join(RedactCommand(name, args), " "), elapsed)
+		})
+	}
+}
+
+// RequirePass returns a Middleware that requires clients to authenticate
+// with AUTH password, or AUTH default password, before sending other
+// commands, like Redis's requirepass setting. AUTH is answered by the
+// Middleware itself. Commands that weren't received by a Server, and so
+// can't be associated with an authenticated connection, are refused.
+//
+// The Server passes the credentials given to HELLO to its Handler as AUTH,
+// so HELLO AUTH works too. The Server's built-in commands are refused as
+// well until the client authenticates, provided the Handler is the
+// Middleware's Handler or was built with Chain.
+func RequirePass(password string) Middleware {
+	return func(next Handler) Handler {
+		return &passHandler{next: next, password: []byte(password)}
+	}
+}
+
+// A passHandler is the Handler of a RequirePass Middleware. Connections are
+// authenticated by storing true for the passHandler on them.
+type passHandler struct {
+	next     Handler
+	password []byte
+}
+
+func (h *passHandler) ServeRESP(w *Writer, cmd Command) {
+	name, args, _ := ParseCommand(cmd)
+	switch {
+	case CommandEquals([]byte(name), "AUTH"):
+		if len(args) < 1 || len(args) > 2 {
+			w.WriteObject(wrongArityError(name))
+			return
+		}
+		valid := subtle.ConstantTimeCompare(args[len(args)-1], h.password) == 1
+		if len(args) == 2 && string(args[0]) != "default" {
+			valid = false
+		}
+		if !valid {
+			w.WriteObject(WRONGPASS_ERROR)
+			return
+		}
+		if !setConnValue(w, h, true) {
+			w.WriteObject(NOAUTH_ERROR)
+			return
+		}
+		w.WriteObject(OK)
+	case CommandEquals([]byte(name), "QUIT"):
+		h.next.ServeRESP(w, cmd)
+	case !h.authenticated(w):
+		w.WriteObject(NOAUTH_ERROR)
+	default:
+		h.next.ServeRESP(w, cmd)
+	}
+}
+
+func (h *passHandler) authenticated(w *Writer) bool {
+	return connValue(w, h, nil) == true
+}
+
+// RateLimit returns a Middleware that limits each connection to rate
+// commands per second on average, with bursts of up to burst commands.
+// Commands beyond the limit are answered with RATE_LIMIT_ERROR. Commands that
+// weren't received by a Server aren't limited.
+func RateLimit(rate float64, burst int) Middleware {
+	return func(next Handler) Handler {
+		key := new(int)
+		return HandlerFunc(func(w *Writer, cmd Command) {
+			bucket, _ := connValue(w, key, func() interface{} {
+				return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+			}).(*tokenBucket)
+			if bucket != nil && !bucket.take(time.Now()) {
+				w.WriteObject(RATE_LIMIT_ERROR)
+				return
+			}
+			next.ServeRESP(w, cmd)
+		})
+	}
+}
+
+// A tokenBucket allows burst events at once and rate events per second on
+// average.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// take consumes a token, if there's one, and returns true if it did.
+func (b *tokenBucket) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// RecoverPanics returns a Middleware that recovers from panics in Handlers
+// and answers the command with INTERNAL_ERROR instead of crashing the
+// process. report, if not nil, is called with the command and the value
+// passed to panic. Replies the Handler wrote before panicking can't be taken
+// back, so Handlers should write their replies last.
+func RecoverPanics(report func(cmd Command, v interface{})) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(w *Writer, cmd Command) {
+			defer func() {
+				if v := recover(); v != nil {
+					if report != nil {
+						report(cmd, v)
+					}
+					w.WriteObject(INTERNAL_ERROR)
+				}
+			}()
+			next.ServeRESP(w, cmd)
+		})
+	}
+}
+
+// remoteAddr returns the address of the client w writes to, or "-" if w
+// doesn't write to a Server connection.
+func remoteAddr(w *Writer) string {
+	if w.conn == nil {
+		return "-"
+	}
+	return w.conn.conn.RemoteAddr().String()
+}
+
+// connValue returns the value stored for key on the Server connection w
+// writes to. If there's none and init isn't nil, it stores and returns the
+// result of init. It returns nil if w doesn't write to a Server connection.
+func connValue(w *Writer, key interface{}, init func() interface{}) interface{} {
+	c := w.conn
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	if !ok && init != nil {
+		if c.values == nil {
+			c.values = map[interface{}]interface{}{}
+		}
+		value = init()
+		c.values[key] = value
+	}
+	return value
+}
+
+// setConnValue stores value for key on the Server connection w writes to. It
+// returns false if w doesn't write to a Server connection.
+func setConnValue(w *Writer, key, value interface{}) bool {
+	c := w.conn
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = map[interface{}]interface{}{}
+	}
+	c.values[key] = value
+	return true
+}
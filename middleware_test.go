@@ -0,0 +1,182 @@
+package resp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return HandlerFunc(func(w *Writer, cmd Command) {
+				calls = append(calls, name)
+				next.ServeRESP(w, cmd)
+			})
+		}
+	}
+	handler := Chain(echoServerHandler, trace("a"), trace("b"))
+	handler.ServeRESP(NewWriter(&strings.Builder{}), NewCommand("PING"))
+	if !reflect.DeepEqual([]string{"a", "b"}, calls) {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	logf := func(format string, args ...interface{}) {
+		mu.Lock()
+		lines = append(lines, fmt.Sprintf(format, args...))
+		mu.Unlock()
+	}
+	addr := startServer(t, &Server{Handler: Chain(echoServerHandler, LogRequests(logf))})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Do("AUTH", "user", "secret")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], conn.conn.LocalAddr().String()+" AUTH user (redacted) ") {
+		t.Errorf("unexpected log: %q", lines)
+	}
+}
+
+func TestRequirePass(t *testing.T) {
+	addr := startServer(t, &Server{Handler: Chain(echoServerHandler, RequirePass("secret"))})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies, err := conn.DoPipeline([]Command{
+		NewCommand("PING"),
+		NewCommand("AUTH", "wrong"),
+		NewCommand("AUTH", "admin", "secret"),
+		NewCommand("AUTH", "secret"),
+		NewCommand("PING"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Object{NOAUTH_ERROR, WRONGPASS_ERROR, WRONGPASS_ERROR, OK, PONG}
+	if !reflect.DeepEqual(expected, replies) {
+		t.Errorf("expected %q, got %q", expected, replies)
+	}
+
+	// HELLO authenticates through AUTH
+	conn, err = Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies, err = conn.DoPipeline([]Command{
+		NewCommand("HELLO", "3", "AUTH", "default", "wrong"),
+		NewCommand("HELLO", "3", "AUTH", "default", "secret"),
+		NewCommand("PING"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(WRONGPASS_ERROR, replies[0]) || !reflect.DeepEqual(PONG, replies[2]) {
+		t.Errorf("unexpected replies: %q", replies)
+	}
+	if _, ok := replies[1].(Map); !ok {
+		t.Errorf("expected a map, got %q", replies[1])
+	}
+
+	// Built-in commands are refused until the client authenticates
+	conn, err = Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies, err = conn.DoPipeline([]Command{
+		NewCommand("SELECT", "3"),
+		NewCommand("CLIENT", "SETNAME", "x"),
+		NewCommand("CLIENT", "ID"),
+		NewCommand("HELLO", "3"),
+		NewCommand("AUTH", "secret"),
+		NewCommand("SELECT", "3"),
+		NewCommand("CLIENT", "SETNAME", "x"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []Object{NOAUTH_ERROR, NOAUTH_ERROR, NOAUTH_ERROR, nil, OK, OK, OK}
+	for i, e := range expected {
+		if i == 3 {
+			if e, ok := replies[i].(Error); !ok || !strings.HasPrefix(string(e.Slice()), "NOAUTH ") {
+				t.Errorf("replies[%d]: expected a NOAUTH error, got %q", i, replies[i])
+			}
+			continue
+		}
+		if !reflect.DeepEqual(e, replies[i]) {
+			t.Errorf("replies[%d]: expected %q, got %q", i, e, replies[i])
+		}
+	}
+
+	// Commands outside of a Server can't be authenticated
+	var out strings.Builder
+	w := NewWriter(&out)
+	handler := Chain(echoServerHandler, RequirePass("secret"))
+	handler.ServeRESP(w, NewCommand("AUTH", "secret"))
+	handler.ServeRESP(w, NewCommand("PING"))
+	w.Flush()
+	if expected := string(NOAUTH_ERROR) + string(NOAUTH_ERROR); out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	addr := startServer(t, &Server{Handler: Chain(echoServerHandler, RateLimit(0.001, 2))})
+	for i := 0; i < 2; i++ {
+		conn, err := Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		// Each connection has its own limit
+		replies, err := conn.DoPipeline([]Command{NewCommand("PING"), NewCommand("PING"), NewCommand("PING")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []Object{PONG, PONG, RATE_LIMIT_ERROR}
+		if !reflect.DeepEqual(expected, replies) {
+			t.Errorf("conns[%d]: expected %q, got %q", i, expected, replies)
+		}
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	reported := make(chan interface{}, 1)
+	handler := HandlerFunc(func(w *Writer, cmd Command) {
+		if name, _, _ := ParseCommand(cmd); name == "BOOM" {
+			panic("boom")
+		}
+		echoServerHandler(w, cmd)
+	})
+	report := func(cmd Command, v interface{}) { reported <- v }
+	addr := startServer(t, &Server{Handler: Chain(handler, RecoverPanics(report))})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies, err := conn.DoPipeline([]Command{NewCommand("BOOM"), NewCommand("PING")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Object{INTERNAL_ERROR, PONG}; !reflect.DeepEqual(expected, replies) {
+		t.Errorf("expected %q, got %q", expected, replies)
+	}
+	if v := <-reported; v != "boom" {
+		t.Errorf("expected the panic to be reported, got %v", v)
+	}
+}
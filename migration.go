@@ -0,0 +1,196 @@
+package resp
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DEFAULT_MIGRATION_WAIT is the longest a MigrationQueue holds a
+	// request if its MaxWait is zero.
+	DEFAULT_MIGRATION_WAIT = 50 * time.Millisecond
+	// DEFAULT_MIGRATION_TTL is how long a MigrationQueue keeps a migration
+	// tracked from ASK redirects if its TTL is zero.
+	DEFAULT_MIGRATION_TTL = time.Second
+)
+
+// A MigrationQueue holds requests for slots that are being migrated between
+// cluster nodes until the migration completes, and then releases them to the
+// slot's new owner. Without it, every request for a key that has already
+// moved bounces through an ASK redirect while the migration is in progress.
+// Holding requests is only worth it for short migrations, so a request is
+// never held longer than MaxWait; after that it's released to the slot's
+// current owner, which may answer with an ASK redirect.
+//
+// Migrations can be tracked explicitly with StartMigration and
+// CompleteMigration, or by feeding redirects to ObserveRedirect. Nodes don't
+// announce the end of a migration, so migrations tracked from redirects are
+// dropped once no ASK redirect for their slot has been seen for TTL.
+type MigrationQueue struct {
+	Table *RoutingTable
+	// MaxWait is the longest a request is held.
+	MaxWait time.Duration
+	// MaxQueued limits the number of requests held per slot. Zero means no
+	// limit. Requests over the limit aren't held.
+	MaxQueued int
+	// TTL is how long a migration tracked from ASK redirects is kept after
+	// the last ASK redirect for its slot.
+	TTL time.Duration
+
+	mu         sync.Mutex
+	migrations map[uint16]*migration
+}
+
+type migration struct {
+	target  string
+	waiting int
+	// expires is when a migration tracked from redirects is dropped, or
+	// zero for migrations started with StartMigration.
+	expires time.Time
+	// done is closed when the migration ends, after which addr is the
+	// address requests should be sent to.
+	done chan struct{}
+	addr string
+}
+
+// StartMigration marks slot as being migrated to the node at target.
+// Requests for the slot are held by Wait until the migration ends with
+// CompleteMigration or AbortMigration.
+func (q *MigrationQueue) StartMigration(slot uint16, target string) {
+	q.start(slot, target, time.Time{})
+}
+
+// start tracks a migration that expires at expires, unless it's zero. A
+// migration started with StartMigration never expires.
+func (q *MigrationQueue) start(slot uint16, target string, expires time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.migrations == nil {
+		q.migrations = map[uint16]*migration{}
+	}
+	if m, ok := q.migrations[slot]; ok {
+		m.target = target
+		if expires.IsZero() || !m.expires.IsZero() {
+			m.expires = expires
+		}
+		return
+	}
+	q.migrations[slot] = &migration{target: target, expires: expires, done: make(chan struct{})}
+}
+
+// CompleteMigration assigns slot to the node it was being migrated to and
+// releases the held requests to it.
+func (q *MigrationQueue) CompleteMigration(slot uint16) {
+	q.end(slot, true)
+}
+
+// AbortMigration releases the requests held for slot to its current owner.
+func (q *MigrationQueue) AbortMigration(slot uint16) {
+	q.end(slot, false)
+}
+
+func (q *MigrationQueue) end(slot uint16, completed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	m, ok := q.migrations[slot]
+	if !ok {
+		return
+	}
+	q.endLocked(slot, m, completed)
+}
+
+// endLocked ends the migration m of slot. q.mu must be held.
+func (q *MigrationQueue) endLocked(slot uint16, m *migration, completed bool) {
+	delete(q.migrations, slot)
+	if completed {
+		q.Table.SetSlot(slot, m.target)
+	}
+	m.addr = q.Table.Lookup(slot)
+	close(m.done)
+}
+
+// Migrating returns true if slot is being migrated.
+func (q *MigrationQueue) Migrating(slot uint16) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.migrationLocked(slot) != nil
+}
+
+// migrationLocked returns the migration of slot, or nil if there's none. A
+// migration that has expired is aborted. q.mu must be held.
+func (q *MigrationQueue) migrationLocked(slot uint16) *migration {
+	m, ok := q.migrations[slot]
+	if !ok {
+		return nil
+	}
+	if !m.expires.IsZero() && time.Now().After(m.expires) {
+		q.endLocked(slot, m, false)
+		return nil
+	}
+	return m
+}
+
+// ObserveRedirect tracks migrations from the redirects received from cluster
+// nodes: an ASK redirect means its slot is being migrated to the node it
+// names, for TTL from the redirect, and a MOVED redirect for a slot that's
+// being migrated means the migration is complete.
+func (q *MigrationQueue) ObserveRedirect(r *Redirect) {
+	switch r.Kind {
+	case REDIRECT_ASK:
+		ttl := q.TTL
+		if ttl <= 0 {
+			ttl = DEFAULT_MIGRATION_TTL
+		}
+		q.start(r.Slot, r.Addr, time.Now().Add(ttl))
+	case REDIRECT_MOVED:
+		q.mu.Lock()
+		m, ok := q.migrations[r.Slot]
+		if ok {
+			m.target = r.Addr
+		}
+		q.mu.Unlock()
+		if ok {
+			q.CompleteMigration(r.Slot)
+		}
+	}
+}
+
+// Wait returns the address a request for slot should be sent to. If the slot
+// isn't being migrated, it returns the slot's owner right away. Otherwise it
+// blocks until the migration ends, expires, or MaxWait passes, and returns
+// true if the request was held.
+func (q *MigrationQueue) Wait(slot uint16) (string, bool) {
+	q.mu.Lock()
+	m := q.migrationLocked(slot)
+	if m == nil || (q.MaxQueued > 0 && m.waiting >= q.MaxQueued) {
+		q.mu.Unlock()
+		return q.Table.Lookup(slot), false
+	}
+	m.waiting++
+	// ASK redirects move expires, so it's read while holding the lock
+	expires := m.expires
+	q.mu.Unlock()
+
+	maxWait := q.MaxWait
+	if maxWait == 0 {
+		maxWait = DEFAULT_MIGRATION_WAIT
+	}
+	if !expires.IsZero() && time.Until(expires) < maxWait {
+		// Nothing is known about the migration after it expires
+		maxWait = time.Until(expires)
+	}
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case <-m.done:
+		return m.addr, true
+	case <-timer.C:
+		q.mu.Lock()
+		m.waiting--
+		q.mu.Unlock()
+		return q.Table.Lookup(slot), true
+	}
+}
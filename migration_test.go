@@ -0,0 +1,135 @@
+package resp
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMigrationQueue(t *testing.T) {
+	table := NewRoutingTable()
+	table.SetSlot(100, "10.0.0.1:7000")
+	q := &MigrationQueue{Table: table, MaxWait: time.Second}
+
+	if addr, held := q.Wait(100); addr != "10.0.0.1:7000" || held {
+		t.Errorf("unexpected result for a slot that isn't migrating: %s, %v", addr, held)
+	}
+
+	q.ObserveRedirect(&Redirect{REDIRECT_ASK, 100, "10.0.0.2:7000"})
+	if !q.Migrating(100) {
+		t.Fatal("expected an ASK redirect to start a migration")
+	}
+
+	var wg sync.WaitGroup
+	addrs := make([]string, 5)
+	for i := range addrs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			addrs[i], _ = q.Wait(100)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	q.ObserveRedirect(&Redirect{REDIRECT_MOVED, 100, "10.0.0.2:7000"})
+	wg.Wait()
+
+	for i, addr := range addrs {
+		if addr != "10.0.0.2:7000" {
+			t.Errorf("addrs[%d]: expected the new owner, got %s", i, addr)
+		}
+	}
+	if q.Migrating(100) || table.Lookup(100) != "10.0.0.2:7000" {
+		t.Errorf("expected the migration to be complete")
+	}
+}
+
+func TestMigrationQueue_Limits(t *testing.T) {
+	table := NewRoutingTable()
+	table.SetSlot(100, "10.0.0.1:7000")
+	q := &MigrationQueue{Table: table, MaxWait: 10 * time.Millisecond, MaxQueued: 1}
+	q.StartMigration(100, "10.0.0.2:7000")
+
+	// Times out to the current owner
+	start := time.Now()
+	if addr, held := q.Wait(100); addr != "10.0.0.1:7000" || !held {
+		t.Errorf("unexpected result: %s, %v", addr, held)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Errorf("expected the request to be held")
+	}
+
+	// Over MaxQueued
+	q.MaxWait = time.Second
+	done := make(chan string)
+	go func() {
+		addr, _ := q.Wait(100)
+		done <- addr
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if addr, held := q.Wait(100); addr != "10.0.0.1:7000" || held {
+		t.Errorf("expected the request not to be held: %s, %v", addr, held)
+	}
+
+	q.AbortMigration(100)
+	if addr := <-done; addr != "10.0.0.1:7000" {
+		t.Errorf("expected the current owner after an abort, got %s", addr)
+	}
+}
+
+func TestMigrationQueue_TTL(t *testing.T) {
+	table := NewRoutingTable()
+	table.SetSlot(100, "10.0.0.1:7000")
+	q := &MigrationQueue{Table: table, MaxWait: time.Second, TTL: 20 * time.Millisecond}
+
+	// Held until the migration expires
+	q.ObserveRedirect(&Redirect{REDIRECT_ASK, 100, "10.0.0.2:7000"})
+	start := time.Now()
+	if addr, held := q.Wait(100); addr != "10.0.0.1:7000" || !held {
+		t.Errorf("unexpected result: %s, %v", addr, held)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the request to be released when the migration expired, held for %v", elapsed)
+	}
+
+	// Expired migrations are dropped
+	if q.Migrating(100) {
+		t.Errorf("expected the migration to have expired")
+	}
+	if addr, held := q.Wait(100); addr != "10.0.0.1:7000" || held {
+		t.Errorf("expected the request not to be held: %s, %v", addr, held)
+	}
+
+	// Explicit migrations don't expire
+	q.StartMigration(100, "10.0.0.2:7000")
+	q.ObserveRedirect(&Redirect{REDIRECT_ASK, 100, "10.0.0.2:7000"})
+	time.Sleep(30 * time.Millisecond)
+	if !q.Migrating(100) {
+		t.Errorf("expected the migration to be kept")
+	}
+}
+
+func TestMigrationQueue_Concurrent(t *testing.T) {
+	table := NewRoutingTable()
+	table.SetSlot(100, "10.0.0.1:7000")
+	q := &MigrationQueue{Table: table, MaxWait: time.Millisecond, TTL: 5 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				q.ObserveRedirect(&Redirect{REDIRECT_ASK, 100, "10.0.0.2:7000"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if addr, _ := q.Wait(100); addr != "10.0.0.1:7000" {
+					t.Errorf("unexpected address: %s", addr)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,161 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidMonitorLine is returned for MONITOR output that can't be parsed.
+var ErrInvalidMonitorLine = errors.New("resp: invalid MONITOR line")
+
+// A MonitorRecord is a command reported by MONITOR. Addr is the client's
+// address, e.g. "127.0.0.1:60866" or "unix:/tmp/redis.sock", or "lua" for
+// commands called from scripts.
+type MonitorRecord struct {
+	Time    time.Time
+	DB      int
+	Addr    string
+	Command string
+	Args    [][]byte
+}
+
+// A Monitor is a connection that streams the commands processed by the
+// server, as reported by MONITOR.
+type Monitor struct {
+	conn *Conn
+}
+
+// NewMonitor sends MONITOR on conn, which must not be used for anything else
+// afterwards. If the server refuses, the Error reply is returned.
+func NewMonitor(conn *Conn) (*Monitor, error) {
+	reply, err := conn.Do("MONITOR")
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return &Monitor{conn: conn}, nil
+}
+
+// Next waits for the next command processed by the server. It returns
+// ErrInvalidMonitorLine for lines it can't parse, after which Next can be
+// called again. Other errors break the connection.
+func (m *Monitor) Next() (MonitorRecord, error) {
+	if m.conn.err != nil {
+		return MonitorRecord{}, m.conn.err
+	}
+	reply, err := m.conn.r.ReadObject()
+	if err != nil {
+		return MonitorRecord{}, m.conn.fatal(err)
+	}
+	line, ok := reply.(String)
+	if !ok || line[0] != SIMPLE_STRING_PREFIX {
+		return MonitorRecord{}, ErrInvalidMonitorLine
+	}
+	return ParseMonitorLine(line.Slice())
+}
+
+// Close closes the connection.
+func (m *Monitor) Close() error {
+	return m.conn.Close()
+}
+
+// ParseMonitorLine parses a line of MONITOR output, such as:
+//
+//	1339518083.107412 [0 127.0.0.1:60866] "set" "key" "va\"lue"
+//
+// The line may start with the "+" of the simple string it was sent as and end
+// with "\r\n" or "\n", so that lines from captured connections and from the
+// output of redis-cli monitor can be parsed alike. The arguments are quoted
+// as by Redis' sdscatrepr, which SplitInline undoes, so they can hold any
+// bytes; they don't point into line. ParseMonitorLine returns
+// ErrInvalidMonitorLine if the line can't be parsed.
+func ParseMonitorLine(line []byte) (MonitorRecord, error) {
+	var record MonitorRecord
+
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) > 0 && line[0] == SIMPLE_STRING_PREFIX {
+		line = line[1:]
+	}
+
+	space := bytes.IndexByte(line, ' ')
+	if space < 0 {
+		return record, ErrInvalidMonitorLine
+	}
+	ts := line[:space]
+	sec, usec := ts, []byte("0")
+	if dot := bytes.IndexByte(ts, '.'); dot >= 0 {
+		sec, usec = ts[:dot], ts[dot+1:]
+	}
+	s, err := strconv.ParseInt(string(sec), 10, 64)
+	if err != nil {
+		return record, ErrInvalidMonitorLine
+	}
+	us, err := strconv.ParseInt(string(usec), 10, 64)
+	if err != nil {
+		return record, ErrInvalidMonitorLine
+	}
+	record.Time = time.Unix(s, us*int64(time.Microsecond))
+
+	rest := line[space+1:]
+	end := bytes.Index(rest, []byte("] "))
+	if len(rest) == 0 || rest[0] != '[' || end < 0 {
+		return record, ErrInvalidMonitorLine
+	}
+	client := rest[1:end]
+	space = bytes.IndexByte(client, ' ')
+	if space < 0 {
+		return record, ErrInvalidMonitorLine
+	}
+	if record.DB, err = strconv.Atoi(string(client[:space])); err != nil {
+		return record, ErrInvalidMonitorLine
+	}
+	record.Addr = string(client[space+1:])
+
+	args, err := SplitInline(rest[end+2:])
+	if err != nil || len(args) == 0 {
+		return record, ErrInvalidMonitorLine
+	}
+	record.Command = string(args[0])
+	record.Args = args[1:]
+	return record, nil
+}
+
+// A MonitorScanner reads MONITOR output saved to a file or read from any other
+// stream, one line at a time, such as the output of redis-cli monitor or the
+// replies read from a MONITOR connection.
+type MonitorScanner struct {
+	r *bufio.Reader
+}
+
+// NewMonitorScanner returns a MonitorScanner reading from r.
+func NewMonitorScanner(r io.Reader) *MonitorScanner {
+	return &MonitorScanner{r: bufio.NewReader(r)}
+}
+
+// Next returns the next command in the stream, as parsed by ParseMonitorLine.
+// Empty lines and the OK reply to MONITOR are skipped. Like Monitor.Next, it
+// returns ErrInvalidMonitorLine for lines it can't parse, after which Next can
+// be called again. It returns io.EOF at the end of the stream.
+func (s *MonitorScanner) Next() (MonitorRecord, error) {
+	for {
+		line, err := s.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return MonitorRecord{}, err
+		}
+		switch string(bytes.TrimRight(line, "\r\n")) {
+		case "", "OK", "+OK":
+			if err != nil {
+				return MonitorRecord{}, err
+			}
+			continue
+		}
+		return ParseMonitorLine(line)
+	}
+}
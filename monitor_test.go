@@ -0,0 +1,100 @@
+package resp
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMonitorLine(t *testing.T) {
+	record, err := ParseMonitorLine([]byte(`1339518083.107412 [3 127.0.0.1:60866] "set" "k\"ey" "\x00\r\n"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := MonitorRecord{
+		Time:    time.Unix(1339518083, 107412000),
+		DB:      3,
+		Addr:    "127.0.0.1:60866",
+		Command: "set",
+		Args:    [][]byte{[]byte(`k"ey`), []byte("\x00\r\n")},
+	}
+	if !reflect.DeepEqual(expected, record) {
+		t.Errorf("expected %+v, got %+v", expected, record)
+	}
+
+	record, err = ParseMonitorLine([]byte(`1339518083.000001 [0 lua] "ping"`))
+	if err != nil || record.Addr != "lua" || record.Command != "ping" || len(record.Args) != 0 {
+		t.Errorf("unexpected record: %+v, %v", record, err)
+	}
+
+	record, err = ParseMonitorLine([]byte("+1339518083.000001 [0 lua] \"ping\"\r\n"))
+	if err != nil || record.Command != "ping" || len(record.Args) != 0 {
+		t.Errorf("unexpected record: %+v, %v", record, err)
+	}
+
+	for _, invalid := range []string{"", "OK", "x.1 [0 lua] \"ping\"", "1.1 0 lua \"ping\"", "1.1 [x lua] \"ping\"", "1.1 [0 lua] ", "1.1 [0 lua] \"ping"} {
+		if _, err := ParseMonitorLine([]byte(invalid)); err != ErrInvalidMonitorLine {
+			t.Errorf("%q: expected ErrInvalidMonitorLine, got %v", invalid, err)
+		}
+	}
+}
+
+func TestMonitorScanner(t *testing.T) {
+	s := NewMonitorScanner(strings.NewReader("OK\n" +
+		"1339518083.107412 [0 127.0.0.1:60866] \"set\" \"a\" \"\\x00\"\n" +
+		"\n" +
+		"garbage\n" +
+		"1339518083.107413 [1 lua] \"get\" \"a\""))
+
+	record, err := s.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Command != "set" || !reflect.DeepEqual(record.Args, [][]byte{[]byte("a"), []byte("\x00")}) {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if _, err := s.Next(); err != ErrInvalidMonitorLine {
+		t.Errorf("expected ErrInvalidMonitorLine, got %v", err)
+	}
+	record, err = s.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Command != "get" || record.DB != 1 || record.Addr != "lua" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+	if _, err := s.Next(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	conn := fakeServer(func(name string, args [][]byte) Object {
+		if name != "MONITOR" {
+			return NewError("ERR unexpected command")
+		}
+		return String("+OK\r\n+1339518083.107412 [0 127.0.0.1:60866] \"get\" \"a\"\r\n-ERR bogus\r\n")
+	})
+	m, err := NewMonitor(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	record, err := m.Next()
+	if err != nil || record.Command != "get" || string(record.Args[0]) != "a" {
+		t.Errorf("unexpected record: %+v, %v", record, err)
+	}
+	if _, err := m.Next(); err != ErrInvalidMonitorLine {
+		t.Errorf("expected ErrInvalidMonitorLine, got %v", err)
+	}
+
+	conn = fakeServer(func(name string, args [][]byte) Object {
+		return NewError("NOPERM this user has no permissions to run the 'monitor' command")
+	})
+	if _, err := NewMonitor(conn); err == nil || err.(Error).Code() != "NOPERM" {
+		t.Errorf("expected the error reply, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package resp
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// A PushHandler handles a RESP3 push received by a MuxConn.
+type PushHandler func(Push)
+
+// A MuxConn is a client connection that multiplexes requests from many
+// goroutines onto a single RESP3 connection. Replies are matched to requests
+// in order, while out-of-band pushes, such as client-side caching
+// invalidations, are routed to handlers by their kind. A MuxConn is safe for
+// concurrent use.
+//
+// Handlers are called from the goroutine that reads the connection, so they
+// must not block or call the MuxConn. Pub/sub commands can't be used on a
+// MuxConn because their replies are pushes; use a PubSub instead.
+type MuxConn struct {
+	conn net.Conn
+
+	// wmu serializes writes so that requests are recorded in the order they
+	// are written.
+	wmu        sync.Mutex
+	w          *Writer
+	correlator Correlator
+
+	mu       sync.Mutex
+	handlers map[string]PushHandler
+	err      error
+}
+
+type muxReply struct {
+	reply Object
+	err   error
+}
+
+// NewMuxConn returns a MuxConn that uses conn, which must not be used for
+// anything else afterwards, and starts reading from it. The connection must
+// be switched to RESP3 with HELLO 3 before pushes are received.
+func NewMuxConn(conn net.Conn) *MuxConn {
+	m := &MuxConn{
+		conn:     conn,
+		w:        NewWriter(conn),
+		handlers: map[string]PushHandler{},
+	}
+	r := NewReader(conn)
+	r.SetMaxSize(DEFAULT_MAX_BUFFER)
+	go m.receive(r)
+	return m
+}
+
+// HandlePush registers handler for pushes of the given kind, the first
+// element of the push, e.g. "invalidate". The handler for the kind "" handles
+// pushes that don't have a handler of their own. Pushes without a handler
+// are dropped. A nil handler removes the handler for kind.
+func (m *MuxConn) HandlePush(kind string, handler PushHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if handler == nil {
+		delete(m.handlers, kind)
+	} else {
+		m.handlers[kind] = handler
+	}
+}
+
+// Do sends a command and waits for its reply. The arguments are formatted as
+// by FormatCommand. As with Conn, error replies are returned as replies.
+func (m *MuxConn) Do(name string, args ...interface{}) (Object, error) {
+	return m.DoCommandContext(context.Background(), FormatCommand(name, args...))
+}
+
+// DoContext is the same as Do except that it stops waiting for the reply
+// when ctx ends. Since the connection is shared, the command may still be
+// executed, and its reply is discarded when it arrives.
+func (m *MuxConn) DoContext(ctx context.Context, name string, args ...interface{}) (Object, error) {
+	return m.DoCommandContext(ctx, FormatCommand(name, args...))
+}
+
+// DoCommand is the same as Do except that it takes a Command.
+func (m *MuxConn) DoCommand(cmd Command) (Object, error) {
+	return m.DoCommandContext(context.Background(), cmd)
+}
+
+// DoCommandContext is the same as DoContext except that it takes a Command.
+func (m *MuxConn) DoCommandContext(ctx context.Context, cmd Command) (Object, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// done is buffered so that the reply to an abandoned request doesn't
+	// block the receiving goroutine.
+	done := make(chan muxReply, 1)
+
+	m.wmu.Lock()
+	if err := m.Err(); err != nil {
+		m.wmu.Unlock()
+		return nil, err
+	}
+	m.correlator.Record(&Request{Command: cmd, Client: done})
+	_, err := m.w.Write(cmd)
+	if err == nil {
+		err = m.w.Flush()
+	}
+	m.wmu.Unlock()
+	if err != nil {
+		m.fail(err)
+	}
+
+	select {
+	case result := <-done:
+		return result.reply, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *MuxConn) receive(r *Reader) {
+	for {
+		reply, err := r.ReadObject()
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		if _, ok := reply.(Attribute); ok {
+			continue
+		}
+
+		req, reply, err := m.correlator.Match(reply)
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		if req == nil {
+			m.dispatch(reply.(Push))
+			continue
+		}
+		req.Client.(chan muxReply) <- muxReply{reply, nil}
+	}
+}
+
+func (m *MuxConn) dispatch(push Push) {
+	var kind string
+	if objects, err := push.Objects(); err == nil && len(objects) > 0 {
+		kind, _ = objectString(objects[0])
+	}
+
+	m.mu.Lock()
+	handler, ok := m.handlers[kind]
+	if !ok {
+		handler = m.handlers[""]
+	}
+	m.mu.Unlock()
+	if handler != nil {
+		handler(push)
+	}
+}
+
+// fail breaks the connection after err and fails all outstanding requests.
+func (m *MuxConn) fail(err error) {
+	m.mu.Lock()
+	if m.err == nil {
+		m.err = err
+		m.conn.Close()
+	}
+	err = m.err
+	m.mu.Unlock()
+
+	// Requests are recorded while holding wmu after checking for an error, so
+	// once wmu is acquired here no more requests can be recorded. Closing the
+	// connection first unblocks any write holding wmu.
+	m.wmu.Lock()
+	defer m.wmu.Unlock()
+	if missing, ok := m.correlator.Fail(err).(*MissingReplyError); ok {
+		for _, req := range missing.Requests {
+			req.Client.(chan muxReply) <- muxReply{nil, err}
+		}
+	}
+}
+
+// Err returns the error that broke the connection, if any.
+func (m *MuxConn) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Close closes the connection. Outstanding and later requests fail with
+// ErrConnClosed.
+func (m *MuxConn) Close() error {
+	m.fail(ErrConnClosed)
+	return nil
+}
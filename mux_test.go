@@ -0,0 +1,109 @@
+package resp
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRESP3Server answers ECHO with its argument, preceded by an
+// invalidation push and an attribute, and hangs up on QUIT.
+func fakeRESP3Server() net.Conn {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		r := NewReader(server)
+		for {
+			cmd, err := r.ReadCommand()
+			if err != nil {
+				return
+			}
+			name, args, _ := ParseCommand(cmd)
+			if name == "QUIT" {
+				return
+			}
+			push := NewPush(NewBulkString("invalidate"), NewArray(NewBulkString(string(args[0]))))
+			server.Write(append(push, "|1\r\n+ttl\r\n:3\r\n"...))
+			server.Write(NewBulkString(string(args[0])))
+		}
+	}()
+	return client
+}
+
+func TestMuxConn(t *testing.T) {
+	m := NewMuxConn(fakeRESP3Server())
+	defer m.Close()
+
+	var mu sync.Mutex
+	invalidated := map[string]bool{}
+	m.HandlePush("invalidate", func(p Push) {
+		inv, err := ParseInvalidation(p)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		invalidated[string(inv.Keys[0])] = true
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			arg := strconv.Itoa(i)
+			reply, err := m.Do("ECHO", arg)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if s, ok := reply.(String); !ok || s.String() != arg {
+				t.Errorf("expected %s, got %q", arg, reply)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	if len(invalidated) != 20 {
+		t.Errorf("expected 20 invalidations, got %v", invalidated)
+	}
+	mu.Unlock()
+}
+
+func TestMuxConn_Failure(t *testing.T) {
+	m := NewMuxConn(fakeRESP3Server())
+
+	// The server hangs up without replying
+	done := make(chan error)
+	go func() {
+		_, err := m.Do("QUIT")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("expected an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the request to fail")
+	}
+	if _, err := m.Do("ECHO", "a"); err == nil || err != m.Err() {
+		t.Errorf("expected the connection error, got %v", err)
+	}
+
+	m = NewMuxConn(fakeRESP3Server())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.DoContext(ctx, "ECHO", "a"); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	m.Close()
+	if _, err := m.Do("ECHO", "a"); err != ErrConnClosed {
+		t.Errorf("expected ErrConnClosed, got %v", err)
+	}
+}
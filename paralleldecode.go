@@ -0,0 +1,128 @@
+package resp
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrDecoderClosed is returned by ParallelDecoder.Next once it's closed.
+var ErrDecoderClosed = errors.New("resp: decoder closed")
+
+// A DecodeFunc decodes an object, e.g. with ParseClusterSlots. The object
+// is only valid until the DecodeFunc returns, so the result must not point
+// into it.
+type DecodeFunc func(obj Object) (interface{}, error)
+
+// A ParallelDecoder reads objects from a Reader, such as the replies to a
+// long pipeline, and decodes them in a pool of goroutines, for when decoding
+// objects takes much longer than reading them. The results are returned by
+// Next in the order the objects were read.
+type ParallelDecoder struct {
+	jobs    chan *decodeJob
+	results chan *decodeJob
+	done    chan struct{}
+	once    sync.Once
+	// err is the error the Reader failed with, returned by Next once
+	// the results before it have been.
+	err error
+}
+
+// A decodeJob is the decoding of one object.
+type decodeJob struct {
+	frame   Frame
+	decode  DecodeFunc
+	result  interface{}
+	err     error
+	decoded chan struct{}
+}
+
+// NewParallelDecoder starts reading objects from r and decoding them with
+// decode in workers goroutines. At most twice as many objects as workers are
+// read ahead of the results Next has returned. r must not be used by anything
+// else until the ParallelDecoder is closed.
+func NewParallelDecoder(r *Reader, workers int, decode DecodeFunc) *ParallelDecoder {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &ParallelDecoder{
+		jobs:    make(chan *decodeJob, workers),
+		results: make(chan *decodeJob, 2*workers),
+		done:    make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	go d.read(r, decode)
+	return d
+}
+
+// Next returns the result of decoding the next object. Once reading fails, it
+// returns the error reading failed with, e.g. io.EOF. It returns
+// ErrDecoderClosed once the ParallelDecoder is closed.
+func (d *ParallelDecoder) Next() (interface{}, error) {
+	select {
+	case <-d.done:
+		return nil, ErrDecoderClosed
+	default:
+	}
+	select {
+	case job, ok := <-d.results:
+		if !ok {
+			return nil, d.err
+		}
+		select {
+		case <-job.decoded:
+			return job.result, job.err
+		case <-d.done:
+			return nil, ErrDecoderClosed
+		}
+	case <-d.done:
+		return nil, ErrDecoderClosed
+	}
+}
+
+// Close stops reading and decoding objects. Reading stops once the read in
+// progress, if any, is done, so closing the Reader's connection might be
+// needed to stop it promptly.
+func (d *ParallelDecoder) Close() {
+	d.once.Do(func() { close(d.done) })
+}
+
+// read frames objects and hands them to the workers, and to Next in the same
+// order, until reading fails or d is closed.
+func (d *ParallelDecoder) read(r *Reader, decode DecodeFunc) {
+	defer close(d.jobs)
+	for {
+		frame, err := r.ReadFrame()
+		if err != nil {
+			frame.Release()
+			d.err = err
+			close(d.results)
+			return
+		}
+		job := &decodeJob{frame: frame, decode: decode, decoded: make(chan struct{})}
+		// The job is queued for Next first, so that the workers never wait
+		// for Next to make room for a decoded job.
+		select {
+		case d.results <- job:
+		case <-d.done:
+			frame.Release()
+			return
+		}
+		select {
+		case d.jobs <- job:
+		case <-d.done:
+			frame.Release()
+			return
+		}
+	}
+}
+
+// work decodes the objects of jobs until there are no more.
+func (d *ParallelDecoder) work() {
+	for job := range d.jobs {
+		job.result, job.err = job.decode(job.frame.Object())
+		job.frame.Release()
+		close(job.decoded)
+	}
+}
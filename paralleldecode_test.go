@@ -0,0 +1,55 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParallelDecoder(t *testing.T) {
+	var stream bytes.Buffer
+	for i := 0; i < 100; i++ {
+		stream.Write(NewInteger(int64(i)))
+	}
+	stream.Write(NewError("ERR x"))
+	// Later objects are decoded faster, but results keep their order
+	decode := func(obj Object) (interface{}, error) {
+		if e, ok := obj.(Error); ok {
+			return nil, e
+		}
+		n, err := obj.(Integer).Int()
+		time.Sleep(time.Duration(100-n) * 10 * time.Microsecond)
+		return n, err
+	}
+	d := NewParallelDecoder(NewReader(&stream), 8, decode)
+	defer d.Close()
+	for i := 0; i < 100; i++ {
+		if n, err := d.Next(); err != nil || n != i {
+			t.Fatalf("results[%d]: unexpected result: %v, %v", i, n, err)
+		}
+	}
+	if _, err := d.Next(); err == nil || err.Error() != "ERR x" {
+		t.Errorf("expected the decoding error, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := d.Next(); err != io.EOF {
+			t.Errorf("expected io.EOF, got %v", err)
+		}
+	}
+}
+
+func TestParallelDecoder_Close(t *testing.T) {
+	stream := strings.Repeat("+OK\r\n", 100)
+	decode := func(obj Object) (interface{}, error) { return string(obj.Raw()), nil }
+	d := NewParallelDecoder(NewReader(strings.NewReader(stream)), 2, decode)
+	if s, err := d.Next(); err != nil || s != "+OK\r\n" {
+		t.Errorf("unexpected result: %q, %v", s, err)
+	}
+	d.Close()
+	d.Close()
+	if _, err := d.Next(); err != ErrDecoderClosed {
+		t.Errorf("expected ErrDecoderClosed, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package resp
+
+import (
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+// ErrReleased is returned by Readers and Writers used after Release.
+var ErrReleased = errors.New("resp: use of released Reader or Writer")
+
+// Buffers whose size is a power of two from MIN_POOLED_BUFFER to
+// MAX_POOLED_BUFFER, such as DEFAULT_BUFFER, are drawn from a pool per size by
+// NewReaderSize and NewWriterSize, and returned to it by Release. Buffers of
+// other sizes are allocated and left to the garbage collector.
+const (
+	MIN_POOLED_BUFFER = 512
+	MAX_POOLED_BUFFER = 1 << 16
+)
+
+// bufferPools holds a pool for each size, 512 bytes to 64KB.
+var bufferPools [8]sync.Pool
+
+// bufferClass returns the index of the pool for buffers of the given size,
+// or -1 if they aren't pooled.
+func bufferClass(size int) int {
+	if size < MIN_POOLED_BUFFER || size > MAX_POOLED_BUFFER || size&(size-1) != 0 {
+		return -1
+	}
+	return bits.TrailingZeros(uint(size / MIN_POOLED_BUFFER))
+}
+
+// pooledSize returns the size of the smallest pooled buffers that can hold n
+// bytes, or n if they're too large to be pooled.
+func pooledSize(n int) int {
+	if n > MAX_POOLED_BUFFER {
+		return n
+	}
+	size := MIN_POOLED_BUFFER
+	for size < n {
+		size *= 2
+	}
+	return size
+}
+
+// getBuffer returns a buffer of the given size, from its pool if there's
+// one. Pooled buffers aren't zeroed.
+func getBuffer(size int) []byte {
+	if i := bufferClass(size); i >= 0 {
+		if p, ok := bufferPools[i].Get().(*[]byte); ok {
+			return *p
+		}
+	}
+	return make([]byte, size)
+}
+
+// putBuffer returns buf to its pool, if there's one. buf must not be used
+// afterwards.
+func putBuffer(buf []byte) {
+	if i := bufferClass(cap(buf)); i >= 0 {
+		buf = buf[:cap(buf)]
+		bufferPools[i].Put(&buf)
+	}
+}
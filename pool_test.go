@@ -0,0 +1,98 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBufferClass(t *testing.T) {
+	tests := []struct {
+		size     int
+		expected int
+	}{
+		{256, -1},
+		{512, 0},
+		{1000, -1},
+		{1024, 1},
+		{DEFAULT_BUFFER, 4},
+		{MAX_POOLED_BUFFER, len(bufferPools) - 1},
+		{MAX_POOLED_BUFFER * 2, -1},
+	}
+	for _, test := range tests {
+		if class := bufferClass(test.size); class != test.expected {
+			t.Errorf("%d: expected %d, got %d", test.size, test.expected, class)
+		}
+	}
+
+	for n, expected := range map[int]int{0: 512, 513: 1024, MAX_POOLED_BUFFER: MAX_POOLED_BUFFER, MAX_POOLED_BUFFER + 1: MAX_POOLED_BUFFER + 1} {
+		if size := pooledSize(n); size != expected {
+			t.Errorf("pooledSize(%d): expected %d, got %d", n, expected, size)
+		}
+	}
+
+	// Buffers keep their size in the pool
+	putBuffer(make([]byte, 10, 1024))
+	if buf := getBuffer(1024); len(buf) != 1024 {
+		t.Errorf("expected 1024 bytes, got %d", len(buf))
+	}
+}
+
+func TestReader_Release(t *testing.T) {
+	r := NewReader(strings.NewReader("+OK\r\n+OK\r\n"))
+	if _, err := r.ReadObject(); err != nil {
+		t.Fatal(err)
+	}
+	r.Release()
+	for i := 0; i < 2; i++ {
+		if _, err := r.ReadObject(); err != ErrReleased {
+			t.Errorf("expected ErrReleased, got %v", err)
+		}
+	}
+	r.Release()
+}
+
+func TestWriter_Release(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.WriteObject(OK)
+	w.Flush()
+	w.WriteObject(OK)
+	w.Release()
+	if err := w.WriteObject(OK); err != ErrReleased {
+		t.Errorf("expected ErrReleased, got %v", err)
+	}
+	if err := w.Flush(); err != ErrReleased {
+		t.Errorf("expected ErrReleased, got %v", err)
+	}
+	if buf.String() != "+OK\r\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+	w.Release()
+}
+
+func TestReader_ReadFrame(t *testing.T) {
+	large := NewBulkString(strings.Repeat("x", MAX_POOLED_BUFFER))
+	r := NewReaderSize(strings.NewReader("+OK\r\n"+string(large)+"+O"), 2*MAX_POOLED_BUFFER)
+	frame, err := r.ReadFrame()
+	if err != nil || !bytes.Equal(OK, frame) || cap(frame) != MIN_POOLED_BUFFER {
+		t.Errorf("unexpected frame: %q (%d bytes), %v", frame, cap(frame), err)
+	}
+	if obj := frame.Object(); !reflect.DeepEqual(OK, obj) {
+		t.Errorf("expected %q, got %q", OK, obj)
+	}
+	frame.Release()
+
+	// Frames too large for the pools are allocated on their own
+	frame, err = r.ReadFrame()
+	if err != nil || !bytes.Equal(large, frame) || cap(frame) != len(large) {
+		t.Errorf("unexpected frame: %d bytes, %v", cap(frame), err)
+	}
+	frame.Release()
+
+	if frame, err := r.ReadFrame(); err != io.EOF || string(frame) != "+O" {
+		t.Errorf("expected the broken object and io.EOF, got %q, %v", frame, err)
+	}
+}
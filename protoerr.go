@@ -0,0 +1,59 @@
+package resp
+
+import (
+	"fmt"
+)
+
+// A ProtocolError is returned by Reader.ReadCommand for data that isn't a
+// valid command. Detail describes the problem in the words Redis uses in its
+// "ERR Protocol error: ..." replies, e.g. "invalid bulk length". Err is the
+// underlying error, such as ErrSyntaxError or ErrUnbalancedQuotes.
+type ProtocolError struct {
+	Detail string
+	Err    error
+}
+
+func (e *ProtocolError) Error() string {
+	return "resp: protocol error: " + e.Detail
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// Reply returns the Error reply Redis sends before closing the connection.
+func (e *ProtocolError) Reply() Error {
+	return NewError("ERR Protocol error: " + e.Detail)
+}
+
+// commandSyntaxDetail describes why the data at the start of b isn't a valid
+// command.
+func commandSyntaxDetail(b []byte) string {
+	if len(b) == 0 {
+		return "invalid request"
+	}
+	if b[0] != ARRAY_PREFIX {
+		return fmt.Sprintf("expected '*', got '%c'", b[0])
+	}
+	count, end, err := parseLenLine(b)
+	if err != nil || count < 1 {
+		return "invalid multibulk length"
+	}
+
+	cursor := end + 1
+	for i := 0; i < count && cursor < len(b); i++ {
+		if b[cursor] != BULK_STRING_PREFIX {
+			return fmt.Sprintf("expected '$', got '%c'", b[cursor])
+		}
+		length, end, err := parseLenLine(b[cursor:])
+		if err != nil || length < 0 {
+			return "invalid bulk length"
+		}
+		cursor += end + 1 + length
+		if cursor+2 <= len(b) && (b[cursor] != '\r' || b[cursor+1] != '\n') {
+			return "invalid bulk format"
+		}
+		cursor += 2
+	}
+	return "invalid request"
+}
@@ -0,0 +1,41 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestReadCommand_ProtocolError(t *testing.T) {
+	tests := []struct {
+		input  string
+		inline bool
+		detail string
+	}{
+		{"*x\r\n", false, "invalid multibulk length"},
+		{"*0\r\n", false, "invalid multibulk length"},
+		{"*1\r\n:1\r\n", false, "expected '$', got ':'"},
+		{"*2\r\n$3\r\nGET\r\n*1\r\n$1\r\na\r\n", false, "expected '$', got '*'"},
+		{"*1\r\n$-1\r\n", false, "invalid bulk length"},
+		{"*1\r\n$x\r\n", false, "invalid bulk length"},
+		{"*1\r\n$3\r\nGETX\r\n", false, "invalid bulk format"},
+		{"+PING\r\n", false, "expected '*', got '+'"},
+		{"GET 'foo\r\n", true, "unbalanced quotes in request"},
+	}
+	for i, test := range tests {
+		reader := NewReader(bytes.NewReader([]byte(test.input)))
+		reader.SetInline(test.inline)
+		_, err := reader.ReadCommand()
+		var perr *ProtocolError
+		if !errors.As(err, &perr) {
+			t.Errorf("tests[%d]: expected a ProtocolError, got %v", i, err)
+			continue
+		}
+		if perr.Detail != test.detail {
+			t.Errorf("tests[%d]: expected %q, got %q", i, test.detail, perr.Detail)
+		}
+		if reply := string(perr.Reply()); reply != "-ERR Protocol error: "+test.detail+"\r\n" {
+			t.Errorf("tests[%d]: unexpected reply %q", i, reply)
+		}
+	}
+}
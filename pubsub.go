@@ -0,0 +1,268 @@
+package resp
+
+import (
+	"sync"
+)
+
+// A Message is a message received by a PubSub. Pattern is set for messages
+// received through a pattern subscription.
+type Message struct {
+	Channel string
+	Pattern string
+	Payload []byte
+}
+
+// A PubSub is a connection in subscriber mode. Messages for its subscriptions
+// are delivered on the channel returned by Messages, which is closed when the
+// connection fails or the PubSub is closed. After a connection failure, the
+// subscriptions can be restored on a new connection with Resubscribe.
+//
+// The subscribe and unsubscribe methods only send their commands; the
+// confirmations are processed in the background and reflected by Channels
+// and Patterns.
+type PubSub struct {
+	// mu guards everything but the confirmed subscriptions, which are guarded
+	// by trackerMu so that they can be updated while a command is written.
+	mu       sync.Mutex
+	conn     *Conn
+	messages chan Message
+	done     chan struct{}
+	err      error
+	closed   bool
+	// channels and patterns are the requested subscriptions, which are
+	// restored by Resubscribe.
+	channels map[string]bool
+	patterns map[string]bool
+
+	trackerMu sync.Mutex
+	tracker   SubscriptionTracker
+}
+
+// NewPubSub returns a PubSub that uses conn, which must not be used for
+// anything else afterwards.
+func NewPubSub(conn *Conn) *PubSub {
+	p := &PubSub{
+		channels: map[string]bool{},
+		patterns: map[string]bool{},
+	}
+	p.start(conn)
+	return p
+}
+
+func (p *PubSub) start(conn *Conn) {
+	p.conn = conn
+	p.err = nil
+	p.messages = make(chan Message, 100)
+	p.done = make(chan struct{})
+	p.trackerMu.Lock()
+	p.tracker.Reset()
+	p.trackerMu.Unlock()
+	go p.receive(conn, p.messages, p.done)
+}
+
+// receive reads from conn until it fails, delivering messages on messages.
+func (p *PubSub) receive(conn *Conn, messages chan Message, done chan struct{}) {
+	defer close(messages)
+	for {
+		reply, err := conn.r.ReadObject()
+		if err != nil {
+			p.mu.Lock()
+			if p.conn == conn {
+				p.err = conn.fatal(err)
+			}
+			p.mu.Unlock()
+			return
+		}
+
+		p.trackerMu.Lock()
+		confirmation := p.tracker.ObserveReply(reply)
+		p.trackerMu.Unlock()
+		if confirmation {
+			continue
+		}
+		if message, ok := parseMessage(reply); ok {
+			select {
+			case messages <- message:
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// parseMessage decodes a message, pmessage, or smessage in RESP2 or RESP3
+// form.
+func parseMessage(obj Object) (Message, bool) {
+	var objects []Object
+	switch o := obj.(type) {
+	case Array:
+		objects, _ = o.Objects()
+	case Push:
+		objects, _ = o.Objects()
+	}
+
+	var message Message
+	var ok bool
+	switch {
+	case len(objects) == 3 && (stringEquals(objects[0], "message") || stringEquals(objects[0], "smessage")):
+		message.Channel, ok = objectString(objects[1])
+		objects = objects[2:]
+	case len(objects) == 4 && stringEquals(objects[0], "pmessage"):
+		message.Pattern, ok = objectString(objects[1])
+		if ok {
+			message.Channel, ok = objectString(objects[2])
+		}
+		objects = objects[3:]
+	}
+	if !ok {
+		return Message{}, false
+	}
+	payload, ok := objects[0].(String)
+	if !ok {
+		return Message{}, false
+	}
+	message.Payload = payload.Slice()
+	return message, true
+}
+
+// Messages returns the channel messages are delivered on.
+func (p *PubSub) Messages() <-chan Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.messages
+}
+
+// Subscribe subscribes to channels.
+func (p *PubSub) Subscribe(channels ...string) error {
+	return p.send("SUBSCRIBE", p.channels, true, channels)
+}
+
+// PSubscribe subscribes to channels matching patterns.
+func (p *PubSub) PSubscribe(patterns ...string) error {
+	return p.send("PSUBSCRIBE", p.patterns, true, patterns)
+}
+
+// Unsubscribe unsubscribes from channels, or from all channels if none are
+// given.
+func (p *PubSub) Unsubscribe(channels ...string) error {
+	return p.send("UNSUBSCRIBE", p.channels, false, channels)
+}
+
+// PUnsubscribe unsubscribes from patterns, or from all patterns if none are
+// given.
+func (p *PubSub) PUnsubscribe(patterns ...string) error {
+	return p.send("PUNSUBSCRIBE", p.patterns, false, patterns)
+}
+
+// send records a change to the requested subscriptions and sends the command
+// for it.
+func (p *PubSub) send(name string, set map[string]bool, subscribe bool, names []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrConnClosed
+	}
+	if !subscribe && len(names) == 0 {
+		for name := range set {
+			delete(set, name)
+		}
+	}
+	for _, name := range names {
+		if subscribe {
+			set[name] = true
+		} else {
+			delete(set, name)
+		}
+	}
+	if subscribe && len(names) == 0 {
+		return nil
+	}
+	return p.write(name, names)
+}
+
+// write sends a command on the current connection. p.mu must be held.
+func (p *PubSub) write(name string, names []string) error {
+	if p.err != nil {
+		return p.err
+	}
+	args := make([][]byte, len(names))
+	for i, name := range names {
+		args[i] = []byte(name)
+	}
+	cmd := newCommand(name, args)
+
+	p.trackerMu.Lock()
+	p.tracker.ObserveCommand(cmd)
+	p.trackerMu.Unlock()
+	if _, err := p.conn.w.Write(cmd); err != nil {
+		return p.conn.fatal(err)
+	}
+	if err := p.conn.w.Flush(); err != nil {
+		return p.conn.fatal(err)
+	}
+	return nil
+}
+
+// Channels returns the sorted names of the channels whose subscriptions have
+// been confirmed.
+func (p *PubSub) Channels() []string {
+	p.trackerMu.Lock()
+	defer p.trackerMu.Unlock()
+	return p.tracker.Channels()
+}
+
+// Patterns returns the sorted patterns whose subscriptions have been
+// confirmed.
+func (p *PubSub) Patterns() []string {
+	p.trackerMu.Lock()
+	defer p.trackerMu.Unlock()
+	return p.tracker.Patterns()
+}
+
+// Err returns the error that ended the message channel, if any.
+func (p *PubSub) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Resubscribe replaces the connection, e.g. after the previous one failed,
+// and restores all requested subscriptions on it. The previous connection is
+// closed. Messages must be called again to get the new message channel.
+func (p *PubSub) Resubscribe(conn *Conn) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrConnClosed
+	}
+	close(p.done)
+	p.conn.Close()
+	p.start(conn)
+
+	if len(p.channels) > 0 {
+		if err := p.write("SUBSCRIBE", sortedKeys(p.channels)); err != nil {
+			return err
+		}
+	}
+	if len(p.patterns) > 0 {
+		if err := p.write("PSUBSCRIBE", sortedKeys(p.patterns)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection and the message channel.
+func (p *PubSub) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	return p.conn.Close()
+}
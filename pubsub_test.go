@@ -0,0 +1,111 @@
+package resp
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePubSubServer returns a Conn connected to a goroutine that confirms
+// subscriptions and then publishes one message to each new subscription,
+// using RESP3 pushes for pattern subscriptions.
+func fakePubSubServer() *Conn {
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		r := NewReader(server)
+		count := 0
+		for {
+			cmd, err := r.ReadCommand()
+			if err != nil {
+				return
+			}
+			name, args, _ := ParseCommand(cmd)
+			kind := strings.ToLower(name)
+			for _, arg := range args {
+				if strings.HasPrefix(kind, "un") || strings.HasPrefix(kind, "pun") {
+					count--
+				} else {
+					count++
+				}
+				server.Write(NewArray(NewBulkString(kind), NewBulkString(string(arg)), NewInteger(int64(count))))
+				switch kind {
+				case "subscribe":
+					server.Write(NewArray(NewBulkString("message"), NewBulkString(string(arg)), NewBulkString("hello "+string(arg))))
+				case "psubscribe":
+					channel := strings.Replace(string(arg), "*", "x", 1)
+					push := NewArray(NewBulkString("pmessage"), NewBulkString(string(arg)), NewBulkString(channel), NewBulkString("hi"))
+					push[0] = PUSH_PREFIX
+					server.Write(push)
+				}
+			}
+		}
+	}()
+	return NewConn(client)
+}
+
+func receiveMessage(t *testing.T, messages <-chan Message) Message {
+	select {
+	case message, ok := <-messages:
+		if !ok {
+			t.Fatal("message channel closed")
+		}
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a message")
+	}
+	return Message{}
+}
+
+func TestPubSub(t *testing.T) {
+	p := NewPubSub(fakePubSubServer())
+	defer p.Close()
+
+	if err := p.Subscribe("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.PSubscribe("news.*"); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Message{
+		{Channel: "a", Payload: []byte("hello a")},
+		{Channel: "b", Payload: []byte("hello b")},
+		{Channel: "news.x", Pattern: "news.*", Payload: []byte("hi")},
+	}
+	for i, e := range expected {
+		if message := receiveMessage(t, p.Messages()); !reflect.DeepEqual(e, message) {
+			t.Errorf("messages[%d]: expected %+v, got %+v", i, e, message)
+		}
+	}
+	if !reflect.DeepEqual([]string{"a", "b"}, p.Channels()) || !reflect.DeepEqual([]string{"news.*"}, p.Patterns()) {
+		t.Errorf("unexpected subscriptions: %v, %v", p.Channels(), p.Patterns())
+	}
+
+	// Subscriptions are restored on a new connection, minus the ones
+	// unsubscribed from.
+	if err := p.Unsubscribe("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Resubscribe(fakePubSubServer()); err != nil {
+		t.Fatal(err)
+	}
+	expected = []Message{
+		{Channel: "b", Payload: []byte("hello b")},
+		{Channel: "news.x", Pattern: "news.*", Payload: []byte("hi")},
+	}
+	for i, e := range expected {
+		if message := receiveMessage(t, p.Messages()); !reflect.DeepEqual(e, message) {
+			t.Errorf("resubscribed messages[%d]: expected %+v, got %+v", i, e, message)
+		}
+	}
+
+	p.Close()
+	if _, ok := <-p.Messages(); ok {
+		t.Errorf("expected the message channel to be closed")
+	}
+	if err := p.Subscribe("c"); err != ErrConnClosed {
+		t.Errorf("expected ErrConnClosed, got %v", err)
+	}
+}
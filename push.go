@@ -0,0 +1,82 @@
+package resp
+
+import (
+	"errors"
+)
+
+var (
+	// ErrNotInvalidation is returned by ParseInvalidation for objects that
+	// aren't invalidation messages.
+	ErrNotInvalidation = errors.New("resp: not an invalidation message")
+)
+
+// A Push is a RESP3 push object: an array of RESP objects sent by the server
+// out of band, independently of replies to commands.
+type Push []byte
+
+func (p Push) Raw() []byte { return p }
+
+// Objects returns the RESP objects contained in this Push. The objects point
+// into the Push's bytes.
+func (p Push) Objects() ([]Object, error) {
+	return aggregateObjects(p)
+}
+
+// An Invalidation is a decoded client-side caching "invalidate" message. Keys
+// point into the bytes of the message they were decoded from. Keys is nil when
+// the server invalidated every key, e.g. after a FLUSHALL.
+type Invalidation struct {
+	Keys [][]byte
+}
+
+// ParseInvalidation decodes a client-side caching invalidation message. It
+// accepts both the RESP3 push form (`>2 invalidate <keys>`) and the RESP2
+// pub/sub message published to __redis__:invalidate in redirect mode. It
+// returns ErrNotInvalidation if obj is some other kind of object.
+func ParseInvalidation(obj Object) (Invalidation, error) {
+	var keysObject Object
+
+	switch o := obj.(type) {
+	case Push:
+		objects, err := o.Objects()
+		if err != nil {
+			return Invalidation{}, err
+		}
+		if len(objects) != 2 || !stringEquals(objects[0], "invalidate") {
+			return Invalidation{}, ErrNotInvalidation
+		}
+		keysObject = objects[1]
+	case Array:
+		objects, err := o.Objects()
+		if err != nil {
+			return Invalidation{}, err
+		}
+		if len(objects) != 3 || !stringEquals(objects[0], "message") || !stringEquals(objects[1], "__redis__:invalidate") {
+			return Invalidation{}, ErrNotInvalidation
+		}
+		keysObject = objects[2]
+	default:
+		return Invalidation{}, ErrNotInvalidation
+	}
+
+	switch k := keysObject.(type) {
+	case Null:
+		return Invalidation{}, nil
+	case Array:
+		objects, err := k.Objects()
+		if err != nil || objects == nil {
+			return Invalidation{}, err
+		}
+		keys := make([][]byte, len(objects))
+		for i, object := range objects {
+			key, ok := object.(String)
+			if !ok {
+				return Invalidation{}, ErrSyntaxError
+			}
+			keys[i] = key.Slice()
+		}
+		return Invalidation{keys}, nil
+	default:
+		return Invalidation{}, ErrSyntaxError
+	}
+}
@@ -0,0 +1,56 @@
+package resp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+type invalidationTest struct {
+	given    []byte
+	expected [][]byte
+}
+
+func TestParseInvalidation_Valid(t *testing.T) {
+	tests := []invalidationTest{
+		// RESP3 push with keys
+		{[]byte(">2\r\n$10\r\ninvalidate\r\n*2\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"), [][]byte{[]byte("foo"), []byte("bar")}},
+		// RESP3 push for flushall
+		{[]byte(">2\r\n$10\r\ninvalidate\r\n_\r\n"), nil},
+		// RESP2 redirect mode message
+		{[]byte("*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*1\r\n$3\r\nfoo\r\n"), [][]byte{[]byte("foo")}},
+		// RESP2 redirect mode message for flushall
+		{[]byte("*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*-1\r\n"), nil},
+	}
+
+	for i, test := range tests {
+		reader := NewReader(bytes.NewReader(test.given))
+		object, err := reader.ReadObject()
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err.Error())
+			continue
+		}
+		invalidation, err := ParseInvalidation(object)
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err.Error())
+		} else if !reflect.DeepEqual(test.expected, invalidation.Keys) {
+			t.Errorf("tests[%d]:\nexpected: %v\ngot: %v", i, test.expected, invalidation.Keys)
+		}
+	}
+}
+
+func TestParseInvalidation_Invalid(t *testing.T) {
+	tests := []Object{
+		Push(">2\r\n$7\r\nmessage\r\n$3\r\nfoo\r\n"),
+		Array("*3\r\n$7\r\nmessage\r\n$3\r\nfoo\r\n$3\r\nbar\r\n"),
+		Push(">2\r\n$10\r\ninvalidate\r\n:1\r\n"),
+		NewSimpleString("OK"),
+	}
+
+	for i, test := range tests {
+		_, err := ParseInvalidation(test)
+		if err == nil {
+			t.Errorf("tests[%d]: expected an error but didn't get one", i)
+		}
+	}
+}
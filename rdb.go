@@ -0,0 +1,162 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// RDB_EOF_MARK_LENGTH is the length of the random delimiter that ends RDB
+// payloads sent by masters using diskless replication.
+const RDB_EOF_MARK_LENGTH = 40
+
+// ErrBadRDBHeader is returned when an RDB payload doesn't start with a
+// "$<length>\r\n" or "$EOF:<delimiter>\r\n" line.
+var ErrBadRDBHeader = errors.New("resp: invalid RDB payload header")
+
+// skipNewlines discards the newlines masters send to keep the connection
+// alive while they prepare a payload.
+func (r *Reader) skipNewlines() error {
+	for {
+		for r.r < r.w && r.buf[r.r] == '\n' {
+			r.r++
+		}
+		if r.r < r.w {
+			return nil
+		}
+		if r.err != nil {
+			return r.readErr()
+		}
+		r.fill()
+	}
+}
+
+// readLine reads a line ending in "\r\n" and returns it without the line
+// ending. The line points into the buffer.
+func (r *Reader) readLine() ([]byte, error) {
+	for {
+		if i := bytes.IndexByte(r.buf[r.r:r.w], '\n'); i >= 0 {
+			line := r.buf[r.r : r.r+i+1]
+			r.r += i + 1
+			if len(line) < 2 || line[len(line)-2] != '\r' {
+				return nil, ErrSyntaxError
+			}
+			return line[:len(line)-2], nil
+		}
+		if r.err != nil {
+			return nil, r.readErr()
+		}
+		r.fill()
+	}
+}
+
+// ReadRDB reads the header of an RDB payload, as sent by masters to replicas
+// and in reply to SYNC, and returns the payload. Unlike bulk strings, RDB
+// payloads aren't followed by "\r\n", and may have an EOF delimiter instead
+// of a length: "$<length>\r\n<payload>" or "$EOF:<delimiter>\r\n<payload>
+// <delimiter>", where the delimiter is RDB_EOF_MARK_LENGTH random bytes.
+// Newlines before the header, which masters send to keep the connection alive
+// while they generate the payload, are skipped. The payload is read from the
+// Reader's buffer and then its io.Reader, and must be read to its end before
+// the Reader is used again. ReadRDB returns ErrBadRDBHeader if the header is
+// invalid.
+func (r *Reader) ReadRDB() (*RDBPayload, error) {
+	if err := r.skipNewlines(); err != nil {
+		return nil, err
+	}
+	line, err := r.readLine()
+	if err != nil {
+		if err == ErrSyntaxError {
+			err = ErrBadRDBHeader
+		}
+		return nil, err
+	}
+	if len(line) < 2 || line[0] != BULK_STRING_PREFIX {
+		return nil, ErrBadRDBHeader
+	}
+	if bytes.HasPrefix(line[1:], []byte("EOF:")) {
+		mark := line[5:]
+		if len(mark) != RDB_EOF_MARK_LENGTH {
+			return nil, ErrBadRDBHeader
+		}
+		return &RDBPayload{r: r, length: -1, remaining: -1, mark: append([]byte(nil), mark...)}, nil
+	}
+	length, err := strconv.ParseInt(string(line[1:]), 10, 64)
+	if err != nil || length < 0 {
+		return nil, ErrBadRDBHeader
+	}
+	return &RDBPayload{r: r, length: length, remaining: length}, nil
+}
+
+// An RDBPayload is an io.Reader of an RDB payload read by Reader.ReadRDB. Read
+// returns io.EOF at the end of the payload and io.ErrUnexpectedEOF if the
+// stream ends before it.
+type RDBPayload struct {
+	r      *Reader
+	length int64
+	// remaining is the number of bytes left of a payload with a length, or
+	// -1 for a payload ended by mark.
+	remaining int64
+	mark      []byte
+	done      bool
+}
+
+// Length returns the length of the payload, or -1 if it's ended by a
+// delimiter.
+func (p *RDBPayload) Length() int64 {
+	return p.length
+}
+
+func (p *RDBPayload) Read(b []byte) (int, error) {
+	if p.done {
+		return 0, io.EOF
+	}
+	r := p.r
+	for {
+		buffered := r.buf[r.r:r.w]
+		if p.mark == nil {
+			if p.remaining == 0 {
+				p.done = true
+				return 0, io.EOF
+			}
+			if len(buffered) > 0 {
+				if int64(len(buffered)) > p.remaining {
+					buffered = buffered[:p.remaining]
+				}
+				n := copy(b, buffered)
+				r.r += n
+				p.remaining -= int64(n)
+				return n, nil
+			}
+		} else {
+			if i := bytes.Index(buffered, p.mark); i >= 0 {
+				n := copy(b, buffered[:i])
+				r.r += n
+				if n == i {
+					r.r += len(p.mark)
+					p.done = true
+					if n == 0 {
+						return 0, io.EOF
+					}
+				}
+				return n, nil
+			}
+			// The end of the buffer may be the start of the mark
+			if safe := len(buffered) - len(p.mark) + 1; safe > 0 {
+				n := copy(b, buffered[:safe])
+				r.r += n
+				return n, nil
+			}
+		}
+
+		if r.err != nil {
+			err := r.readErr()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		r.fill()
+	}
+}
@@ -0,0 +1,56 @@
+package resp
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReader_ReadRDB(t *testing.T) {
+	mark := strings.Repeat("0123456789", 4)
+	tests := []struct {
+		input  string
+		length int64
+		rdb    string
+	}{
+		{"$9\r\nREDIS0011", 9, "REDIS0011"},
+		{"\n\n\n$9\r\nREDIS0011", 9, "REDIS0011"},
+		{"$EOF:" + mark + "\r\nREDIS0011" + mark, -1, "REDIS0011"},
+		{"$EOF:" + mark + "\r\n" + strings.Repeat("x", 1000) + mark, -1, strings.Repeat("x", 1000)},
+	}
+
+	for i, test := range tests {
+		r := NewReaderSize(iotest.HalfReader(strings.NewReader(test.input+"+OK\r\n")), 64)
+		payload, err := r.ReadRDB()
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if payload.Length() != test.length {
+			t.Errorf("tests[%d]: expected length %d, got %d", i, test.length, payload.Length())
+		}
+		b, err := io.ReadAll(payload)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if string(b) != test.rdb {
+			t.Errorf("tests[%d]: expected %q, got %q", i, test.rdb, b)
+		}
+		// The Reader carries on after the payload
+		obj, err := r.ReadObject()
+		if err != nil || string(obj.Raw()) != "+OK\r\n" {
+			t.Errorf("tests[%d]: expected +OK, got %q, %v", i, obj.Raw(), err)
+		}
+	}
+}
+
+func TestReader_ReadRDB_Errors(t *testing.T) {
+	for _, input := range []string{"+OK\r\n", "$\r\n", "$-1\r\n", "$x\r\n", "$EOF:abc\r\n", "$3\nabc"} {
+		if _, err := NewReader(strings.NewReader(input)).ReadRDB(); err != ErrBadRDBHeader {
+			t.Errorf("expected ErrBadRDBHeader for %q, got %v", input, err)
+		}
+	}
+	if _, err := NewReader(strings.NewReader("\n\n")).ReadRDB(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
@@ -7,10 +7,19 @@ import (
 
 // Reader implements a buffered RESP object reader for an io.Reader object.
 type Reader struct {
-	rd   io.Reader
-	buf  []byte
-	r, w int
-	err  error
+	rd       io.Reader
+	buf      []byte
+	r, w     int
+	err      error
+	rewriter Rewriter
+	inline   bool
+	// max is the size the buffer may grow to.
+	max int
+	// scan is the progress made scanning the object being read.
+	scan objectScanner
+	// arena, if set, holds the copies of objects.
+	arena *Arena
+	trace *Trace
 }
 
 // NewReader returns a new Reader with the default buffer size.
@@ -19,7 +28,9 @@ func NewReader(r io.Reader) *Reader {
 }
 
 // NewReaderSize returns a new Reader with the given buffer size. If the buffer
-// size is less than 1, the default buffer size will be used.
+// size is less than 1, the default buffer size will be used. Buffers of the
+// default size, and other powers of two up to MAX_POOLED_BUFFER, are pooled;
+// see Release.
 func NewReaderSize(r io.Reader, size int) *Reader {
 	if size < 1 {
 		size = DEFAULT_BUFFER
@@ -27,10 +38,21 @@ func NewReaderSize(r io.Reader, size int) *Reader {
 
 	return &Reader{
 		rd:  r,
-		buf: make([]byte, size),
+		buf: getBuffer(size),
 	}
 }
 
+// Release returns the Reader's buffer to the pool it was drawn from, so that
+// it can be reused by new Readers and Writers. Slices returned by
+// ReadObjectSlice become invalid, and reading afterwards fails with
+// ErrReleased. Release is optional: buffers of unreleased Readers are
+// garbage collected as usual.
+func (r *Reader) Release() {
+	putBuffer(r.buf)
+	r.buf = nil
+	r.r, r.w = 0, 0
+}
+
 func (r *Reader) ReadObject() (Object, error) {
 	bytes, err := r.ReadObjectBytes()
 	if err != nil {
@@ -39,6 +61,130 @@ func (r *Reader) ReadObject() (Object, error) {
 	return Parse(bytes), nil
 }
 
+// SetRewriter sets a Rewriter that ReadCommand applies to every command it
+// reads. A nil Rewriter disables rewriting.
+func (r *Reader) SetRewriter(rw Rewriter) {
+	r.rewriter = rw
+}
+
+// SetArena sets an Arena that ReadObject and ReadObjectBytes allocate the
+// objects they return from, which are then valid until the Arena is reset. A
+// nil Arena, the default, has each object allocated on its own.
+func (r *Reader) SetArena(a *Arena) {
+	r.arena = a
+}
+
+// SetMaxSize lets the buffer grow up to size bytes to fit objects larger than
+// the buffer. Objects larger than that still fail with ErrBufferFull. A size
+// less than the buffer's current size disables growing.
+func (r *Reader) SetMaxSize(size int) {
+	r.max = size
+}
+
+// SetInline sets whether ReadCommand accepts inline commands, which are
+// plain lines of space-separated arguments, as sent by telnet or redis-cli in
+// raw mode. Redis accepts them from clients.
+func (r *Reader) SetInline(enabled bool) {
+	r.inline = enabled
+}
+
+// ReadCommand reads one RESP object, validates that it's a command (an array
+// of bulk strings), and returns a copy of it, rewritten by the Reader's
+// Rewriter if one is set. An invalid command is consumed and a *ProtocolError
+// wrapping ErrSyntaxError is returned. Errors returned by the Rewriter are
+// returned as-is, also after consuming the command.
+//
+// If inline commands are enabled with SetInline, a line that doesn't start
+// with '*' is split into arguments as by SplitInline and returned as the
+// equivalent RESP command. Empty lines are skipped, and a line with
+// unbalanced quotes returns a *ProtocolError wrapping ErrUnbalancedQuotes.
+func (r *Reader) ReadCommand() (Command, error) {
+	if r.inline {
+		for {
+			if r.Buffered() == 0 {
+				r.fill()
+				if r.Buffered() == 0 {
+					return nil, r.readErr()
+				}
+			}
+			if r.buf[r.r] == ARRAY_PREFIX {
+				break
+			}
+			args, err := r.readInline()
+			if err == ErrUnbalancedQuotes {
+				return nil, &ProtocolError{Detail: "unbalanced quotes in request", Err: err}
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(args) > 0 {
+				return r.rewrite(string(args[0]), args[1:])
+			}
+		}
+	}
+
+	slice, err := r.ReadObjectSlice()
+	if err == ErrSyntaxError {
+		return nil, &ProtocolError{Detail: commandSyntaxDetail(slice), Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name, args, err := ParseCommand(slice)
+	if err != nil {
+		return nil, &ProtocolError{Detail: commandSyntaxDetail(slice), Err: err}
+	}
+
+	if r.rewriter == nil {
+		command := make(Command, len(slice))
+		copy(command, slice)
+		return command, nil
+	}
+	if UNSAFE_STRINGS {
+		// name points into the buffer, and Rewriters may keep it
+		name = string([]byte(name))
+	}
+	return r.rewrite(name, args)
+}
+
+// rewrite returns the command made of name and args, rewritten by the
+// Reader's Rewriter if one is set.
+func (r *Reader) rewrite(name string, args [][]byte) (Command, error) {
+	if r.rewriter == nil {
+		return newCommand(name, args), nil
+	}
+
+	name, args, err := r.rewriter.Rewrite(name, args)
+	if err != nil {
+		return nil, err
+	}
+	return newCommand(name, args), nil
+}
+
+// readInline reads an inline command line and splits it into arguments. The
+// arguments don't point into the buffer.
+func (r *Reader) readInline() ([][]byte, error) {
+	for {
+		if i := bytes.IndexByte(r.buf[r.r:r.w], '\n'); i >= 0 {
+			line := r.buf[r.r : r.r+i]
+			r.r += i + 1
+			if len(line) > 0 && line[len(line)-1] == '\r' {
+				line = line[:len(line)-1]
+			}
+			return SplitInline(line)
+		}
+		if r.err != nil {
+			r.r = 0
+			r.w = 0
+			err := r.readErr()
+			r.trace.errorEncountered(err)
+			return nil, err
+		}
+		r.fill()
+	}
+}
+
 // ReadObjectSlice reads until the buffer contains one full valid RESP object
 // and returns a slice pointing at the slice of the buffer that contains the
 // object. The byte slice stops being valid after the next read on this Reader.
@@ -49,10 +195,12 @@ func (r *Reader) ReadObject() (Object, error) {
 // because there's no easy way to recover from them when processing a stream of
 // RESP objects.
 func (r *Reader) ReadObjectSlice() ([]byte, error) {
+	r.scan.reset()
 	i := r.indexObjectEnd(r.r)
 	if i > r.r {
 		object := r.buf[r.r : i+1]
 		r.r = i + 1
+		r.trace.objectRead(object)
 		return object, nil
 	}
 
@@ -63,6 +211,7 @@ func (r *Reader) ReadObjectSlice() ([]byte, error) {
 		if i > r.r {
 			object := r.buf[r.r : i+1]
 			r.r = i + 1
+			r.trace.objectRead(object)
 			return object, nil
 		}
 
@@ -70,20 +219,79 @@ func (r *Reader) ReadObjectSlice() ([]byte, error) {
 			brokenObject := r.buf[r.r:r.w]
 			r.r = 0
 			r.w = 0
-			return brokenObject, r.readErr()
+			err := r.readErr()
+			r.trace.errorEncountered(err)
+			return brokenObject, err
+		}
+	}
+}
+
+// ReadObjects reads the objects buffered by the Reader, such as the replies to
+// a pipeline, into dst, overwriting its contents, and returns the resulting
+// slice. It blocks until one object is read, but not to read more, and reads
+// at most max objects if max is positive. Reusing the slice across calls
+// saves allocating one per batch. If an error occurs, the objects read before
+// it are returned along with it.
+func (r *Reader) ReadObjects(dst []Object, max int) ([]Object, error) {
+	obj, err := r.ReadObject()
+	if err != nil {
+		return dst[:0], err
+	}
+	dst = append(dst[:0], obj)
+	for max <= 0 || len(dst) < max {
+		// Invalid objects are left for the next read to report
+		end, _ := objectEnd(r.buf[r.r:r.w])
+		if end < 0 {
+			break
 		}
+		copied := r.arena.alloc(end + 1)
+		copy(copied, r.buf[r.r:])
+		r.r += end + 1
+		r.trace.objectRead(copied)
+		dst = append(dst, Parse(copied))
 	}
+	return dst, nil
 }
 
 // ReadObjectBytes behaves similarly to ReadObjectSlice except that it returns
-// a copied slice of bytes that remains valid after the next read.
+// a copied slice of bytes that remains valid after the next read. The copy is
+// allocated from the Reader's Arena, if it has one.
 func (r *Reader) ReadObjectBytes() ([]byte, error) {
 	bytes, err := r.ReadObjectSlice()
-	copied := make([]byte, len(bytes))
+	copied := r.arena.alloc(len(bytes))
 	copy(copied, bytes)
 	return copied, err
 }
 
+// A Frame is a copy of an object read by Reader.ReadFrame, in a pooled
+// buffer.
+type Frame []byte
+
+// ReadFrame behaves like ReadObjectBytes except that the copy is made in a
+// buffer drawn from the same pools as the buffers of Readers and Writers,
+// which is returned to its pool by the Frame's Release method. This saves
+// proxies that hold on to each object briefly, e.g. to pass it to another
+// goroutine, from allocating a copy of every object.
+func (r *Reader) ReadFrame() (Frame, error) {
+	bytes, err := r.ReadObjectSlice()
+	frame := getBuffer(pooledSize(len(bytes)))[:len(bytes)]
+	copy(frame, bytes)
+	return Frame(frame), err
+}
+
+// Object returns the object in the Frame, which points into the Frame. A
+// Frame returned with an error doesn't hold a valid object.
+func (f Frame) Object() Object {
+	return Parse(f)
+}
+
+// Release returns the Frame's buffer to its pool. Neither the Frame nor the
+// objects pointing into it can be used afterwards, and the Frame must not be
+// released again.
+func (f Frame) Release() {
+	putBuffer(f)
+}
+
 // Buffered returns the number of bytes currently buffered.
 func (r *Reader) Buffered() int {
 	return r.w - r.r
@@ -91,89 +299,76 @@ func (r *Reader) Buffered() int {
 
 // indexObjectEnd returns the buffer index of the final character of the object
 // beginning at the given position. It returns -1 if a valid object can't be
-// found.
+// found. The parts of the object found by previous calls since r.scan was
+// reset aren't scanned again.
 func (r *Reader) indexObjectEnd(start int) int {
-	if r.Buffered()-start < MIN_OBJECT_LENGTH {
-		return -1
+	end, err := r.scan.scan(r.buf[start:r.w])
+	if err != nil {
+		r.err = err
 	}
-
-	switch r.buf[start] {
-	case '+', '-', ':':
-		lineEnd := bytes.Index(r.buf[start:], lineSuffix)
-		if lineEnd < 0 {
-			return -1
-		}
-		if lineEnd+2 < MIN_OBJECT_LENGTH {
-			r.err = ErrSyntaxError
-			return -1
-		}
-		return start + lineEnd + 1
-	case '$':
-		length, lineEnd, err := parseLenLine(r.buf[start:])
-		if err != nil {
-			r.err = err
-			return -1
-		}
-
-		lineEnd += start
-		if length == -1 {
-			return lineEnd
-		} else {
-			bulkStringEnd := lineEnd + length + 2
-			if bulkStringEnd >= r.w {
-				return -1
-			}
-			return bulkStringEnd
-		}
-	case '*':
-		length, lineEnd, err := parseLenLine(r.buf[start:])
-		if err != nil {
-			r.err = err
-			return -1
-		}
-
-		lineEnd += start
-		if length == -1 {
-			return lineEnd
-		} else {
-			end := lineEnd
-			for i := 0; i < length; i++ {
-				end = r.indexObjectEnd(end + 1)
-				if end < 0 {
-					return -1
-				}
-			}
-			return end
-		}
-	default:
-		r.err = ErrSyntaxError
+	if end < 0 {
 		return -1
 	}
+	return start + end
 }
 
+// maxConsecutiveEmptyReads is how many reads returning no data and no error
+// fill makes before giving up with io.ErrNoProgress.
+const maxConsecutiveEmptyReads = 100
+
 // fill reads new data into the buffer, if possible. If the io.Reader returns
 // an error, it is set on this Reader for future returning.
 func (r *Reader) fill() {
-	if r.Buffered() >= len(r.buf)-1 {
-		r.err = ErrBufferFull
+	if r.buf == nil {
+		r.err = ErrReleased
 		return
 	}
+	if r.Buffered() >= len(r.buf)-1 {
+		if len(r.buf) >= r.max {
+			r.err = ErrBufferFull
+			return
+		}
+		size := len(r.buf) * 2
+		if size > r.max {
+			size = r.max
+		}
+		buf := make([]byte, size)
+		r.w = copy(buf, r.buf[r.r:r.w])
+		r.r = 0
+		r.buf = buf
+	}
 
-	if r.r > 0 {
+	if r.r == r.w {
+		// Nothing's buffered, so reading can start over at the front
+		r.r = 0
+		r.w = 0
+	} else if r.r > 0 && len(r.buf)-r.w <= len(r.buf)/4 {
+		// The unread bytes are only moved to the front once there's little
+		// room left after them, rather than before every read, which would
+		// copy large objects arriving in many reads over and over.
 		copy(r.buf, r.buf[r.r:r.w])
 		r.w -= r.r
 		r.r = 0
 	}
 
-	// Add new data
-	n, err := r.rd.Read(r.buf[r.w:])
-	if n < 0 {
-		panic("read negative bytes")
-	}
-	r.w += n
-	if err != nil {
-		r.err = err
+	// Add new data, retrying reads that return nothing as bufio does
+	for i := 0; i < maxConsecutiveEmptyReads; i++ {
+		r.trace.fillStart()
+		n, err := r.rd.Read(r.buf[r.w:])
+		r.trace.fillEnd(n, err)
+		if n < 0 {
+			panic("read negative bytes")
+		}
+		r.w += n
+		if err != nil {
+			r.err = err
+			return
+		}
+		if n > 0 {
+			return
+		}
 	}
+	r.err = io.ErrNoProgress
 }
 
 func (r *Reader) readErr() error {
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type respTest struct {
@@ -42,6 +44,10 @@ func TestReadObjectSlice_Valid(t *testing.T) {
 		{[]byte("*2\r\n*1\r\n-OK\r\n*1\r\n-OK\r\n"), []byte("*2\r\n*1\r\n-OK\r\n*1\r\n-OK\r\n")},
 		// array with null bulk string
 		{[]byte("*3\r\n$3\r\nfoo\r\n$-1\r\n$3\r\nbar\r\n"), []byte("*3\r\n$3\r\nfoo\r\n$-1\r\n$3\r\nbar\r\n")},
+		// RESP3 null
+		{[]byte("_\r\n"), []byte("_\r\n")},
+		// RESP3 push
+		{[]byte(">2\r\n$10\r\ninvalidate\r\n_\r\n"), []byte(">2\r\n$10\r\ninvalidate\r\n_\r\n")},
 		// array with 1 byte length integer
 		{[]byte("*3\r\n*4\r\n:5462\r\n:10922\r\n*2\r\n$9\r\n127.0.0.1\r\n:7932\r\n*2\r\n$9\r\n127.0.0.1\r\n:8032\r\n*4\r\n:0\r\n:5461\r\n*2\r\n$9\r\n127.0.0.1\r\n:7931\r\n*2\r\n$9\r\n127.0.0.1\r\n:8031\r\n*3\r\n:10923\r\n:16383\r\n*2\r\n$9\r\n127.0.0.1\r\n:7933\r\n"), []byte("*3\r\n*4\r\n:5462\r\n:10922\r\n*2\r\n$9\r\n127.0.0.1\r\n:7932\r\n*2\r\n$9\r\n127.0.0.1\r\n:8032\r\n*4\r\n:0\r\n:5461\r\n*2\r\n$9\r\n127.0.0.1\r\n:7931\r\n*2\r\n$9\r\n127.0.0.1\r\n:8031\r\n*3\r\n:10923\r\n:16383\r\n*2\r\n$9\r\n127.0.0.1\r\n:7933\r\n")},
 	}
@@ -95,6 +101,24 @@ func TestReadObjectSlice_BufferErrors(t *testing.T) {
 	}
 }
 
+func TestReader_SetMaxSize(t *testing.T) {
+	cmd := NewCommand("SET", "k", string(make([]byte, 100)))
+	reader := NewReaderSize(bytes.NewReader(append(cmd, cmd...)), 16)
+	reader.SetMaxSize(len(cmd) + 1)
+	for i := 0; i < 2; i++ {
+		object, err := reader.ReadObjectSlice()
+		if err != nil || !bytes.Equal(cmd, object) {
+			t.Errorf("reads[%d]: unexpected object: %q, %v", i, object, err)
+		}
+	}
+
+	reader = NewReaderSize(bytes.NewReader(cmd), 16)
+	reader.SetMaxSize(64)
+	if _, err := reader.ReadObjectSlice(); err != ErrBufferFull {
+		t.Errorf("expected ErrBufferFull but got %#v", err)
+	}
+}
+
 type multipleReadTest struct {
 	reads    [][]byte
 	expected []byte
@@ -114,6 +138,10 @@ func TestReadObjectSlice_MultipleReads_Valid(t *testing.T) {
 			[][]byte{[]byte("*2\r\n"), []byte("-OK\r"), []byte("\n-O"), []byte("K\r\n")},
 			[]byte("*2\r\n-OK\r\n-OK\r\n"),
 		},
+		{
+			[][]byte{[]byte("$10\r"), []byte("\n0123456789\r\n")},
+			[]byte("$10\r\n0123456789\r\n"),
+		},
 		{
 			[][]byte{[]byte("*2\r\n*"), []byte("1\r\n-OK\r"), []byte("\n-O"), []byte("K\r\n")},
 			[]byte("*2\r\n*1\r\n-OK\r\n-OK\r\n"),
@@ -135,6 +163,69 @@ func TestReadObjectSlice_MultipleReads_Valid(t *testing.T) {
 	}
 }
 
+func TestReadObjectSlice_ManyReads(t *testing.T) {
+	frame := "*3\r\n*2\r\n$3\r\nfoo\r\n%1\r\n+a\r\n:1\r\n*0\r\n$3\r\nbar\r\n"
+	reader := NewReader(iotest.OneByteReader(strings.NewReader(frame + "+OK\r\n")))
+	for _, expected := range []string{frame, "+OK\r\n"} {
+		object, err := reader.ReadObjectSlice()
+		if err != nil || string(object) != expected {
+			t.Errorf("expected %q, got %q, %v", expected, object, err)
+		}
+	}
+}
+
+func TestReader_ReadObjects(t *testing.T) {
+	r := NewReader(io.MultiReader(
+		strings.NewReader("+OK\r\n:1\r\n*1\r\n$1\r\na\r\n$3\r\nfo"),
+		strings.NewReader("o\r\n-ERR x\r\n?\r\n"),
+	))
+	dst := make([]Object, 0, 4)
+	expected := [][]Object{
+		// Only the objects buffered after the first are read
+		{OK, NewInteger(1)},
+		{NewArray(NewBulkString("a"))},
+		{NewBulkString("foo"), NewError("ERR x")},
+	}
+	for i, e := range expected {
+		objects, err := r.ReadObjects(dst, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(e, objects) {
+			t.Errorf("reads[%d]: expected %q, got %q", i, e, objects)
+		}
+		if &objects[0] != &dst[:1][0] {
+			t.Errorf("reads[%d]: expected dst to be reused", i)
+		}
+	}
+	if objects, err := r.ReadObjects(dst, 0); err != ErrSyntaxError || len(objects) != 0 {
+		t.Errorf("expected ErrSyntaxError, got %q, %v", objects, err)
+	}
+}
+
+func TestReadObjectSlice_Streaming(t *testing.T) {
+	var objects []string
+	var stream []byte
+	for i := 0; i < 50; i++ {
+		object := string(NewBulkString(strings.Repeat("x", i)))
+		objects = append(objects, object)
+		stream = append(stream, object...)
+	}
+	// Objects end up at every position in buffers of every size
+	for size := 16; size <= 128; size += 7 {
+		for chunk := 1; chunk <= 50; chunk += 7 {
+			reader := NewReaderSize(&chunkReader{stream, chunk}, size)
+			reader.SetMaxSize(128)
+			for i, expected := range objects {
+				object, err := reader.ReadObjectSlice()
+				if err != nil || string(object) != expected {
+					t.Fatalf("size %d, chunk %d, objects[%d]: expected %q, got %q, %v", size, chunk, i, expected, object, err)
+				}
+			}
+		}
+	}
+}
+
 func TestReadObjectSlice_MultipleReads_Invalid(t *testing.T) {
 	tests := []multipleReadTest{
 		{
@@ -163,6 +254,84 @@ func TestReadObjectSlice_MultipleReads_Invalid(t *testing.T) {
 	}
 }
 
+func BenchmarkReaderReadObjectSliceFragmented(b *testing.B) {
+	// A 20000 element array received 1KB at a time
+	frame := []byte("*20000\r\n" + strings.Repeat("$3\r\nfoo\r\n", 20000))
+	b.SetBytes(int64(len(frame)))
+	for i := 0; i < b.N; i++ {
+		reader := NewReaderSize(&chunkReader{frame, 1024}, 1<<18)
+		if _, err := reader.ReadObjectSlice(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReaderReadObjectSliceStreaming(b *testing.B) {
+	// 4KB objects received a TCP segment at a time
+	frame := []byte(NewBulkString(strings.Repeat("x", 4096)))
+	stream := bytes.Repeat(frame, 64)
+	b.SetBytes(int64(len(stream)))
+	for i := 0; i < b.N; i++ {
+		reader := NewReaderSize(&chunkReader{stream, 1460}, 1<<16)
+		for j := 0; j < 64; j++ {
+			if _, err := reader.ReadObjectSlice(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// A chunkReader reads b at most n bytes at a time.
+type chunkReader struct {
+	b []byte
+	n int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	}
+	if len(p) > r.n {
+		p = p[:r.n]
+	}
+	n := copy(p, r.b)
+	r.b = r.b[n:]
+	return n, nil
+}
+
+// An emptyReader returns no data and no error from its first n reads, and
+// reads from r afterwards.
+type emptyReader struct {
+	r io.Reader
+	n int
+}
+
+func (r *emptyReader) Read(p []byte) (int, error) {
+	if r.n > 0 {
+		r.n--
+		return 0, nil
+	}
+	return r.r.Read(p)
+}
+
+func TestReader_EmptyReads(t *testing.T) {
+	reader := NewReader(&emptyReader{strings.NewReader("PING\r\n"), 10})
+	reader.SetInline(true)
+	if command, err := reader.ReadCommand(); err != nil || string(command) != "*1\r\n$4\r\nPING\r\n" {
+		t.Errorf("unexpected command: %q, %v", command, err)
+	}
+
+	reader = NewReader(&emptyReader{strings.NewReader("PING\r\n"), 1000})
+	reader.SetInline(true)
+	if command, err := reader.ReadCommand(); err != io.ErrNoProgress {
+		t.Errorf("expected io.ErrNoProgress, got %q, %v", command, err)
+	}
+	reader = NewReader(&emptyReader{strings.NewReader("+OK\r\n"), 1000})
+	if object, err := reader.ReadObjectSlice(); err != io.ErrNoProgress {
+		t.Errorf("expected io.ErrNoProgress, got %q, %v", object, err)
+	}
+}
+
 type LoopReader struct {
 	bytes []byte
 	i     int
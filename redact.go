@@ -0,0 +1,74 @@
+package resp
+
+// REDACTED replaces sensitive arguments in the output of RedactCommand.
+const REDACTED = "(redacted)"
+
+// RedactCommand returns a loggable form of a command: the command name
+// followed by its arguments, with passwords and other credentials replaced by
+// REDACTED. It masks AUTH passwords, HELLO AUTH credentials, the values of
+// CONFIG SET requirepass and masterauth, MIGRATE AUTH and AUTH2 passwords, and
+// ACL SETUSER password rules.
+func RedactCommand(name string, args [][]byte) []string {
+	argv := make([]string, len(args)+1)
+	argv[0] = name
+	for i, arg := range args {
+		argv[i+1] = string(arg)
+	}
+	redact := func(i int) {
+		if i < len(args) {
+			argv[i+1] = REDACTED
+		}
+	}
+
+	switch {
+	case CommandEquals([]byte(name), "AUTH"):
+		// AUTH [username] password
+		if len(args) > 0 {
+			redact(len(args) - 1)
+		}
+	case CommandEquals([]byte(name), "HELLO"):
+		// HELLO [protover [AUTH username password] [SETNAME clientname]]
+		for i := 1; i < len(args); i++ {
+			if CommandEquals(args[i], "AUTH") {
+				redact(i + 2)
+				i += 2
+			} else if CommandEquals(args[i], "SETNAME") {
+				i++
+			}
+		}
+	case CommandEquals([]byte(name), "CONFIG"):
+		// CONFIG SET parameter value [parameter value ...]
+		if len(args) > 0 && CommandEquals(args[0], "SET") {
+			for i := 1; i < len(args); i += 2 {
+				if CommandEquals(args[i], "REQUIREPASS") || CommandEquals(args[i], "MASTERAUTH") {
+					redact(i + 1)
+				}
+			}
+		}
+	case CommandEquals([]byte(name), "MIGRATE"):
+		// MIGRATE ... [AUTH password] [AUTH2 username password] [KEYS ...]
+		for i := 5; i < len(args); i++ {
+			if CommandEquals(args[i], "AUTH") {
+				redact(i + 1)
+				i++
+			} else if CommandEquals(args[i], "AUTH2") {
+				redact(i + 2)
+				i += 2
+			} else if CommandEquals(args[i], "KEYS") {
+				break
+			}
+		}
+	case CommandEquals([]byte(name), "ACL"):
+		// ACL SETUSER username [rule ...], where >password, <password,
+		// #hash, and !hash rules contain credentials
+		if len(args) > 0 && CommandEquals(args[0], "SETUSER") {
+			for i := 2; i < len(args); i++ {
+				if len(args[i]) > 0 && (args[i][0] == '>' || args[i][0] == '<' || args[i][0] == '#' || args[i][0] == '!') {
+					redact(i)
+				}
+			}
+		}
+	}
+
+	return argv
+}
@@ -0,0 +1,40 @@
+package resp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type redactTest struct {
+	command  string
+	expected string
+}
+
+func TestRedactCommand(t *testing.T) {
+	tests := []redactTest{
+		{"GET foo", "GET foo"},
+		{"AUTH secret", "AUTH (redacted)"},
+		{"AUTH", "AUTH"},
+		{"auth user secret", "auth user (redacted)"},
+		{"HELLO 3 AUTH user secret SETNAME app", "HELLO 3 AUTH user (redacted) SETNAME app"},
+		{"CONFIG SET requirepass secret maxmemory 10mb", "CONFIG SET requirepass (redacted) maxmemory 10mb"},
+		{"CONFIG SET masterauth secret", "CONFIG SET masterauth (redacted)"},
+		{"CONFIG GET requirepass", "CONFIG GET requirepass"},
+		{"MIGRATE h 6379 k 0 1000 AUTH secret", "MIGRATE h 6379 k 0 1000 AUTH (redacted)"},
+		{"MIGRATE h 6379 k 0 1000 AUTH2 user secret KEYS a", "MIGRATE h 6379 k 0 1000 AUTH2 user (redacted) KEYS a"},
+		{"ACL SETUSER bob on >secret ~*", "ACL SETUSER bob on (redacted) ~*"},
+	}
+
+	for i, test := range tests {
+		parts := strings.Split(test.command, " ")
+		args := make([][]byte, len(parts)-1)
+		for j, part := range parts[1:] {
+			args[j] = []byte(part)
+		}
+		got := RedactCommand(parts[0], args)
+		if !reflect.DeepEqual(strings.Split(test.expected, " "), got) {
+			t.Errorf("tests[%d]:\nexpected: %v\ngot: %v", i, test.expected, got)
+		}
+	}
+}
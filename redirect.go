@@ -0,0 +1,104 @@
+package resp
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// ASKING is the ASKING command sent before a command redirected by an ASK
+// redirect.
+var ASKING = NewCommand("ASKING")
+
+// AskingCommand returns ASKING followed by cmd, ready to be written to the
+// node named by an ASK redirect. The replies should be read with
+// ReadAskingReply.
+func AskingCommand(cmd Command) []byte {
+	train := make([]byte, 0, len(ASKING)+len(cmd))
+	train = append(train, ASKING...)
+	return append(train, cmd...)
+}
+
+// ReadAskingReply reads the two replies to a command written with
+// AskingCommand. It discards the +OK reply to ASKING and returns the reply to
+// the command. If ASKING fails, both replies are still read and ASKING's
+// reply is returned.
+func ReadAskingReply(r *Reader) (Object, error) {
+	asking, err := r.ReadObject()
+	if err != nil {
+		return asking, err
+	}
+	reply, err := r.ReadObject()
+	if err != nil {
+		return reply, err
+	}
+	if !stringEquals(asking, "OK") {
+		return asking, nil
+	}
+	return reply, nil
+}
+
+// A RedirectKind is the kind of a cluster redirect.
+type RedirectKind int
+
+const (
+	// REDIRECT_MOVED means the slot has moved to another node for good.
+	REDIRECT_MOVED RedirectKind = iota + 1
+	// REDIRECT_ASK means the slot is being migrated and only the
+	// redirected command should be sent to the other node, after ASKING.
+	REDIRECT_ASK
+)
+
+func (k RedirectKind) String() string {
+	switch k {
+	case REDIRECT_MOVED:
+		return "MOVED"
+	case REDIRECT_ASK:
+		return "ASK"
+	default:
+		return "RedirectKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// A Redirect is a parsed -MOVED or -ASK error reply from a cluster node. Addr
+// is the address of the node the request should be sent to, as given by the
+// node (e.g. "127.0.0.1:7001"). A *Redirect is an error, and errors.As can
+// extract one from an Error reply.
+type Redirect struct {
+	Kind RedirectKind
+	Slot uint16
+	Addr string
+}
+
+// Error returns the redirect formatted as the original error message.
+func (r *Redirect) Error() string {
+	return r.Kind.String() + " " + strconv.Itoa(int(r.Slot)) + " " + r.Addr
+}
+
+// ParseRedirect parses an error reply of the form "MOVED <slot> <host:port>"
+// or "ASK <slot> <host:port>". It returns false if e isn't a valid redirect.
+func ParseRedirect(e Error) (*Redirect, bool) {
+	if len(e) < 3 || e[0] != ERROR_PREFIX {
+		return nil, false
+	}
+	fields := bytes.Fields(e.Slice())
+	if len(fields) != 3 {
+		return nil, false
+	}
+
+	var kind RedirectKind
+	switch string(fields[0]) {
+	case "MOVED":
+		kind = REDIRECT_MOVED
+	case "ASK":
+		kind = REDIRECT_ASK
+	default:
+		return nil, false
+	}
+
+	slot, err := strconv.ParseUint(string(fields[1]), 10, 16)
+	if err != nil || slot >= SLOT_COUNT {
+		return nil, false
+	}
+
+	return &Redirect{kind, uint16(slot), string(fields[2])}, true
+}
@@ -0,0 +1,73 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type redirectTest struct {
+	given    Error
+	expected *Redirect
+}
+
+func TestParseRedirect(t *testing.T) {
+	tests := []redirectTest{
+		{NewError("MOVED 3999 127.0.0.1:6381"), &Redirect{REDIRECT_MOVED, 3999, "127.0.0.1:6381"}},
+		{NewError("ASK 0 :7000"), &Redirect{REDIRECT_ASK, 0, ":7000"}},
+		{NewError("MOVED 16384 127.0.0.1:6381"), nil},
+		{NewError("MOVED x 127.0.0.1:6381"), nil},
+		{NewError("MOVED 1"), nil},
+		{NewError("ERR oops"), nil},
+	}
+
+	for i, test := range tests {
+		redirect, ok := ParseRedirect(test.given)
+		if ok != (test.expected != nil) || !reflect.DeepEqual(test.expected, redirect) {
+			t.Errorf("tests[%d]:\nexpected: %v\ngot: %v", i, test.expected, redirect)
+		}
+	}
+
+	if s := tests[0].expected.Error(); s != "MOVED 3999 127.0.0.1:6381" {
+		t.Errorf("unexpected error string: %s", s)
+	}
+}
+
+func TestRedirectErrorsAs(t *testing.T) {
+	var err error = fmt.Errorf("request failed: %w", NewError("ASK 12 10.0.0.1:7002"))
+	var redirect *Redirect
+	if !errors.As(err, &redirect) {
+		t.Fatal("expected errors.As to find a redirect")
+	}
+	expected := &Redirect{REDIRECT_ASK, 12, "10.0.0.1:7002"}
+	if !reflect.DeepEqual(expected, redirect) {
+		t.Errorf("expected: %v\ngot: %v", expected, redirect)
+	}
+
+	if errors.As(NewError("ERR oops"), &redirect) {
+		t.Errorf("expected errors.As to fail for a non-redirect error")
+	}
+}
+
+func TestAsking(t *testing.T) {
+	train := AskingCommand(NewCommand("GET", "foo"))
+	expected := []byte("*1\r\n$6\r\nASKING\r\n*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n")
+	if !reflect.DeepEqual(expected, train) {
+		t.Errorf("expected: %q\ngot: %q", expected, train)
+	}
+
+	reader := NewReader(bytes.NewReader([]byte("+OK\r\n$3\r\nbar\r\n-ERR nope\r\n-MOVED 1 a:1\r\n")))
+	reply, err := ReadAskingReply(reader)
+	if err != nil || !reflect.DeepEqual(NewBulkString("bar"), reply) {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+	reply, err = ReadAskingReply(reader)
+	if err != nil || !reflect.DeepEqual(NewError("ERR nope"), reply) {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+	if reader.Buffered() != 0 {
+		t.Errorf("expected both replies to be consumed")
+	}
+}
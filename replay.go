@@ -0,0 +1,119 @@
+package resp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrBadCapture is returned by ReplayReader for files that don't start with
+// CAPTURE_MAGIC.
+var ErrBadCapture = errors.New("resp: not a capture file")
+
+// A ReplayReader reads a capture file written by a CaptureWriter. Its records
+// can be read one at a time with ReadRecord, or their frames can be read in
+// order as a stream with Read, e.g. to push recorded traffic through a Reader,
+// proxy, or mock server.
+type ReplayReader struct {
+	// Filter, if set, selects the records whose frames Read returns, e.g.
+	// those of one direction of one connection. Records it returns false
+	// for are skipped.
+	Filter func(rec CaptureRecord) bool
+	// Timing, if set, makes Read wait before returning each frame until as
+	// much time has passed since the first frame was returned as had when
+	// it was recorded.
+	Timing bool
+
+	r       *bufio.Reader
+	header  [captureHeaderSize]byte
+	buf     []byte
+	pending []byte
+	started bool
+	err     error
+	// first is the time the first frame Read returned was recorded, and
+	// start is the time it was returned.
+	first time.Time
+	start time.Time
+}
+
+// NewReplayReader returns a ReplayReader that reads a capture file from r.
+func NewReplayReader(r io.Reader) *ReplayReader {
+	return &ReplayReader{r: bufio.NewReader(r)}
+}
+
+// ReadRecord returns the next record. Its frame is only valid until the next
+// call to ReadRecord or Read. At the end of the file, it returns io.EOF. If
+// the file doesn't start with CAPTURE_MAGIC, it returns ErrBadCapture, and if
+// it ends in the middle of a record, io.ErrUnexpectedEOF.
+func (r *ReplayReader) ReadRecord() (CaptureRecord, error) {
+	if r.err != nil {
+		return CaptureRecord{}, r.err
+	}
+	if !r.started {
+		r.started = true
+		magic := make([]byte, len(CAPTURE_MAGIC))
+		if _, err := io.ReadFull(r.r, magic); err != nil || string(magic) != CAPTURE_MAGIC {
+			r.err = ErrBadCapture
+			return CaptureRecord{}, r.err
+		}
+	}
+
+	if _, err := io.ReadFull(r.r, r.header[:]); err != nil {
+		r.err = err
+		return CaptureRecord{}, err
+	}
+	n := int(binary.BigEndian.Uint32(r.header[17:]))
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	r.buf = r.buf[:n]
+	if _, err := io.ReadFull(r.r, r.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		r.err = err
+		return CaptureRecord{}, err
+	}
+
+	return CaptureRecord{
+		Time:      time.Unix(0, int64(binary.BigEndian.Uint64(r.header[0:]))),
+		Conn:      int64(binary.BigEndian.Uint64(r.header[8:])),
+		Direction: Direction(r.header[16]),
+		Frame:     r.buf,
+	}, nil
+}
+
+// Read reads the frames of the records selected by Filter, concatenated.
+func (r *ReplayReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		rec, err := r.ReadRecord()
+		if err != nil {
+			return 0, err
+		}
+		if r.Filter != nil && !r.Filter(rec) {
+			continue
+		}
+		if r.Timing {
+			r.wait(rec.Time)
+		}
+		r.pending = rec.Frame
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// wait sleeps until a frame recorded at t should be replayed.
+func (r *ReplayReader) wait(t time.Time) {
+	if r.start.IsZero() {
+		r.first = t
+		r.start = time.Now()
+		return
+	}
+	if d := t.Sub(r.first) - time.Since(r.start); d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,103 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayReader(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCaptureWriter(&buf)
+	now := time.Unix(1700000000, 0)
+	c.WriteRecord(CaptureRecord{Time: now, Conn: 1, Direction: DIRECTION_READ, Frame: NewCommand("GET", "a")})
+	c.WriteRecord(CaptureRecord{Time: now, Conn: 1, Direction: DIRECTION_WRITTEN, Frame: NewBulkString("1")})
+	c.WriteRecord(CaptureRecord{Time: now, Conn: 2, Direction: DIRECTION_READ, Frame: NewCommand("SET", "b", "2")})
+	c.Flush()
+	capture := buf.Bytes()
+
+	r := NewReplayReader(bytes.NewReader(capture))
+	var conns []int64
+	for {
+		rec, err := r.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rec.Time.Equal(now) {
+			t.Errorf("expected %v, got %v", now, rec.Time)
+		}
+		conns = append(conns, rec.Conn)
+	}
+	if expected := []int64{1, 1, 2}; !reflect.DeepEqual(expected, conns) {
+		t.Errorf("expected %v, got %v", expected, conns)
+	}
+
+	// Commands read by the server can be read back as a stream
+	r = NewReplayReader(bytes.NewReader(capture))
+	r.Filter = func(rec CaptureRecord) bool { return rec.Direction == DIRECTION_READ }
+	reader := NewReader(r)
+	var names []string
+	for {
+		cmd, err := reader.ReadCommand()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		name, _, _ := ParseCommand(cmd)
+		names = append(names, name)
+	}
+	if expected := []string{"GET", "SET"}; !reflect.DeepEqual(expected, names) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestReplayReader_Timing(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCaptureWriter(&buf)
+	now := time.Now()
+	c.WriteRecord(CaptureRecord{Time: now, Frame: []byte("+a\r\n")})
+	c.WriteRecord(CaptureRecord{Time: now.Add(time.Hour), Direction: DIRECTION_WRITTEN, Frame: []byte("+b\r\n")})
+	c.WriteRecord(CaptureRecord{Time: now.Add(50 * time.Millisecond), Frame: []byte("+c\r\n")})
+	c.Flush()
+
+	r := NewReplayReader(&buf)
+	r.Timing = true
+	r.Filter = func(rec CaptureRecord) bool { return rec.Direction != DIRECTION_WRITTEN }
+	start := time.Now()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "+a\r\n+c\r\n" {
+		t.Errorf("unexpected frames %q", b)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("expected the replay to take 50ms, took %v", elapsed)
+	}
+}
+
+func TestReplayReader_Errors(t *testing.T) {
+	r := NewReplayReader(strings.NewReader("*1\r\n$4\r\nPING\r\n"))
+	if _, err := r.ReadRecord(); err != ErrBadCapture {
+		t.Errorf("expected ErrBadCapture, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	c := NewCaptureWriter(&buf)
+	c.WriteRecord(CaptureRecord{Frame: []byte("+OK\r\n")})
+	c.Flush()
+	for _, n := range []int{1, captureHeaderSize + 1} {
+		r = NewReplayReader(bytes.NewReader(buf.Bytes()[:buf.Len()-n]))
+		if _, err := io.ReadAll(r); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+var (
+	// READONLY is the command that allows a connection to a cluster replica to
+	// serve reads.
+	READONLY = NewCommand("READONLY")
+
+	// ErrNoKeys is returned by ReplicaRouter.Route for commands without keys,
+	// which can't be routed by slot.
+	ErrNoKeys = errors.New("resp: command has no keys to route by")
+
+	// ErrNoReplica is returned by ReplicaRouter.Route when a read can't be
+	// served by a replica and the router's fallback is FALLBACK_FAIL.
+	ErrNoReplica = errors.New("resp: no replica available")
+
+	// ErrNoNode is returned by ReplicaRouter.Route when a command's slot isn't
+	// assigned to any node.
+	ErrNoNode = errors.New("resp: slot is not served by any node")
+)
+
+// A ReplicaFallback decides what a ReplicaRouter does with a read when none of
+// the slot's replicas are available.
+type ReplicaFallback int
+
+const (
+	// FALLBACK_MASTER sends the read to the master, trading the load
+	// balancing of replica reads for availability.
+	FALLBACK_MASTER ReplicaFallback = iota
+	// FALLBACK_FAIL returns ErrNoReplica so that reads never land on masters.
+	FALLBACK_FAIL
+)
+
+// A ReplicaRouter routes commands to cluster nodes using a RoutingTable,
+// sending read-only commands to replicas and everything else to masters.
+// Replicas may lag behind their masters, so reads routed to them can return
+// stale data. Connections to replicas must be put into read-only mode with
+// ReadOnlyHandshake before they're used, otherwise replicas answer with
+// MOVED redirects to their masters.
+type ReplicaRouter struct {
+	Table *RoutingTable
+	// Fallback is used for reads when none of the slot's replicas are
+	// available.
+	Fallback ReplicaFallback
+	// Available, if set, reports whether a replica can currently serve reads.
+	// Replicas for which it returns false are skipped.
+	Available func(addr string) bool
+
+	next uint32
+}
+
+// Route returns the address of the node that should serve a command and
+// whether it's a replica. Reads are spread over the available replicas of the
+// command's slot in turn. Route returns ErrNoKeys for commands without keys,
+// CROSSSLOT for commands whose keys hash to different slots, and the errors
+// of ExtractKeys for invalid commands.
+func (r *ReplicaRouter) Route(name string, args [][]byte) (addr string, replica bool, err error) {
+	keys, err := ExtractKeys(name, args)
+	if err != nil {
+		return "", false, err
+	}
+	if len(keys) == 0 {
+		return "", false, ErrNoKeys
+	}
+	slot := Slot(keys[0])
+	for _, key := range keys[1:] {
+		if Slot(key) != slot {
+			return "", false, CROSSSLOT
+		}
+	}
+
+	master := r.Table.Lookup(slot)
+	if master == "" {
+		return "", false, ErrNoNode
+	}
+	if !IsReadOnly(name) {
+		return master, false, nil
+	}
+
+	replicas := r.Table.Replicas(slot)
+	if len(replicas) > 0 {
+		// Unsigned so that the index stays positive when the counter wraps,
+		// and where int is 32 bits
+		start := atomic.AddUint32(&r.next, 1)
+		for i := range replicas {
+			candidate := replicas[(start+uint32(i))%uint32(len(replicas))]
+			if r.Available == nil || r.Available(candidate) {
+				return candidate, true, nil
+			}
+		}
+	}
+	if r.Fallback == FALLBACK_FAIL {
+		return "", false, ErrNoReplica
+	}
+	return master, false, nil
+}
+
+// ReadOnlyHandshake sends READONLY on a new connection to a replica and
+// checks that it was accepted. The reply is returned as an error if it isn't
+// +OK.
+func ReadOnlyHandshake(conn io.ReadWriter) error {
+	if _, err := conn.Write(READONLY); err != nil {
+		return err
+	}
+	reply, err := NewReaderSize(conn, 256).ReadObject()
+	if err != nil {
+		return err
+	}
+	if !stringEquals(reply, "OK") {
+		if e, ok := reply.(Error); ok {
+			return e
+		}
+		return ErrUnexpectedReply
+	}
+	return nil
+}
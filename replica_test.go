@@ -0,0 +1,97 @@
+package resp
+
+import (
+	"math"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestReplicaRouter(t *testing.T) {
+	table := NewRoutingTable()
+	table.ReplaceSlots([]SlotRange{
+		{0, 8191, SlotNode{IP: "10.0.0.1", Port: 7000}, []SlotNode{{IP: "10.0.0.2", Port: 7000}, {IP: "10.0.0.3", Port: 7000}}},
+		{8192, 16383, SlotNode{IP: "10.0.0.4", Port: 7000}, nil},
+	})
+	// Slot("a") is 15495 and Slot("b") is 3300
+	down := map[string]bool{}
+	router := &ReplicaRouter{
+		Table:     table,
+		Available: func(addr string) bool { return !down[addr] },
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		addr, replica, err := router.Route("GET", byteArgs("b"))
+		if err != nil || !replica {
+			t.Fatalf("unexpected route: %s, %v, %v", addr, replica, err)
+		}
+		seen[addr] = true
+	}
+	if !reflect.DeepEqual(map[string]bool{"10.0.0.2:7000": true, "10.0.0.3:7000": true}, seen) {
+		t.Errorf("expected reads to be spread over replicas, got %v", seen)
+	}
+
+	if addr, replica, err := router.Route("SET", byteArgs("b", "1")); addr != "10.0.0.1:7000" || replica || err != nil {
+		t.Errorf("expected a write to go to the master, got %s, %v, %v", addr, replica, err)
+	}
+
+	// The counter wraps around
+	router.next = math.MaxUint32 - 1
+	for i := 0; i < 3; i++ {
+		if _, replica, err := router.Route("GET", byteArgs("b")); err != nil || !replica {
+			t.Errorf("unexpected route after %d wrapping reads: %v, %v", i, replica, err)
+		}
+	}
+
+	down["10.0.0.2:7000"] = true
+	if addr, _, _ := router.Route("GET", byteArgs("b")); addr != "10.0.0.3:7000" {
+		t.Errorf("expected the available replica, got %s", addr)
+	}
+
+	// No replicas
+	if addr, replica, err := router.Route("GET", byteArgs("a")); addr != "10.0.0.4:7000" || replica || err != nil {
+		t.Errorf("expected a fallback to the master, got %s, %v, %v", addr, replica, err)
+	}
+	router.Fallback = FALLBACK_FAIL
+	if _, _, err := router.Route("GET", byteArgs("a")); err != ErrNoReplica {
+		t.Errorf("expected ErrNoReplica, got %v", err)
+	}
+
+	if _, _, err := router.Route("PING", nil); err != ErrNoKeys {
+		t.Errorf("expected ErrNoKeys, got %v", err)
+	}
+	if _, _, err := router.Route("MGET", byteArgs("a", "b")); !reflect.DeepEqual(CROSSSLOT, err) {
+		t.Errorf("expected CROSSSLOT, got %v", err)
+	}
+
+	empty := &ReplicaRouter{Table: NewRoutingTable()}
+	if _, _, err := empty.Route("GET", byteArgs("a")); err != ErrNoNode {
+		t.Errorf("expected ErrNoNode, got %v", err)
+	}
+}
+
+func TestReadOnlyHandshake(t *testing.T) {
+	handshake := func(reply Object) error {
+		client, server := net.Pipe()
+		defer client.Close()
+		go func() {
+			defer server.Close()
+			if cmd, err := NewReader(server).ReadCommand(); err == nil && string(cmd) == string(READONLY) {
+				server.Write(reply.Raw())
+			}
+		}()
+		return ReadOnlyHandshake(client)
+	}
+
+	if err := handshake(OK); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := handshake(NewError("ERR This instance has cluster support disabled"))
+	if e, ok := err.(Error); !ok || e.Code() != "ERR" {
+		t.Errorf("expected the error reply, got %v", err)
+	}
+	if err := handshake(NewInteger(1)); err != ErrUnexpectedReply {
+		t.Errorf("expected ErrUnexpectedReply, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// A ReplicationStream consumes the replication stream a master sends a
+// replica: the reply to PSYNC, the RDB snapshot of a full resynchronization,
+// and then the feed of write commands, tracking the replication offset as it
+// goes. The connection should already have been through the rest of the
+// replica handshake, e.g. REPLCONF capa eof psync2, which lets the master send
+// RDB payloads framed with an EOF delimiter.
+type ReplicationStream struct {
+	// ReplID is the master's replication ID and Offset is the replication
+	// offset of the data read so far. They are set by PSYNC replies and
+	// Offset advances with each command read, so that a replica can use
+	// them to partially resynchronize after reconnecting.
+	ReplID string
+	Offset int64
+
+	r   *Reader
+	rdb *RDBPayload
+	// full is set by a +FULLRESYNC reply until the feed of commands is read,
+	// while the RDB payload is still ahead of it.
+	full bool
+}
+
+// NewReplicationStream returns a ReplicationStream reading from r. ReplID and
+// Offset start out unknown, as "?" and -1, which makes PSync request a full
+// resynchronization.
+func NewReplicationStream(r *Reader) *ReplicationStream {
+	return &ReplicationStream{ReplID: "?", Offset: -1, r: r}
+}
+
+// PSync writes a PSYNC command asking to continue from ReplID and Offset to
+// w, which is usually the connection r reads from, and reads the master's
+// reply with ReadPSyncReply.
+func (s *ReplicationStream) PSync(w io.Writer) (full bool, err error) {
+	offset := s.Offset
+	if offset >= 0 {
+		// PSYNC takes the offset of the first byte wanted
+		offset++
+	}
+	if _, err := w.Write(FormatCommand("PSYNC", s.ReplID, offset)); err != nil {
+		return false, err
+	}
+	return s.ReadPSyncReply()
+}
+
+// ReadPSyncReply reads the master's reply to PSYNC. +FULLRESYNC <replid>
+// <offset> sets ReplID and Offset and returns true, after which the RDB
+// payload can be read with RDB; ReadCommand skips it otherwise. +CONTINUE
+// [<replid>] returns false, after which the feed of commands follows. Error
+// replies are returned as errors, and other replies as ErrUnexpectedReply.
+func (s *ReplicationStream) ReadPSyncReply() (full bool, err error) {
+	if err := s.r.skipNewlines(); err != nil {
+		return false, err
+	}
+	obj, err := s.r.ReadObject()
+	if err != nil {
+		return false, err
+	}
+	str, ok := obj.(String)
+	if !ok || str[0] != SIMPLE_STRING_PREFIX {
+		if e, ok := obj.(Error); ok {
+			return false, e
+		}
+		return false, ErrUnexpectedReply
+	}
+
+	fields := bytes.Fields(str.Slice())
+	switch {
+	case len(fields) == 3 && string(fields[0]) == "FULLRESYNC":
+		offset, err := strconv.ParseInt(string(fields[2]), 10, 64)
+		if err != nil {
+			return false, ErrUnexpectedReply
+		}
+		s.ReplID = string(fields[1])
+		s.Offset = offset
+		s.rdb = nil
+		s.full = true
+		return true, nil
+	case len(fields) >= 1 && len(fields) <= 2 && string(fields[0]) == "CONTINUE":
+		if len(fields) == 2 {
+			s.ReplID = string(fields[1])
+		}
+		return false, nil
+	default:
+		return false, ErrUnexpectedReply
+	}
+}
+
+// RDB returns the RDB payload sent after a +FULLRESYNC reply, as read by
+// Reader.ReadRDB. The payload must be read before the feed of commands, and
+// whatever wasn't read is discarded by ReadCommand.
+func (s *ReplicationStream) RDB() (*RDBPayload, error) {
+	if s.rdb == nil {
+		rdb, err := s.r.ReadRDB()
+		if err != nil {
+			return nil, err
+		}
+		s.rdb = rdb
+	}
+	return s.rdb, nil
+}
+
+// ReadCommand reads the next command from the feed and advances Offset by its
+// length. The feed includes PINGs and REPLCONF GETACKs from the master as
+// well as writes. After a full resynchronization, the RDB payload, or any
+// unread part of it, is discarded first.
+func (s *ReplicationStream) ReadCommand() (Command, error) {
+	if s.full {
+		rdb, err := s.RDB()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.Copy(io.Discard, rdb); err != nil {
+			return nil, err
+		}
+		s.full = false
+	}
+
+	slice, err := s.r.ReadObjectSlice()
+	if err == ErrSyntaxError {
+		return nil, &ProtocolError{Detail: commandSyntaxDetail(slice), Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := ParseCommand(slice); err != nil {
+		return nil, &ProtocolError{Detail: commandSyntaxDetail(slice), Err: err}
+	}
+	s.Offset += int64(len(slice))
+	command := make(Command, len(slice))
+	copy(command, slice)
+	return command, nil
+}
@@ -0,0 +1,153 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReplicationStream(t *testing.T) {
+	mark := strings.Repeat("m", RDB_EOF_MARK_LENGTH)
+	tests := []struct {
+		stream string
+		rdb    string
+	}{
+		{"$7\r\nREDIS01", "REDIS01"},
+		{"\n\n$7\r\nREDIS01", "REDIS01"},
+		{"$0\r\n", ""},
+		{"$EOF:" + mark + "\r\nREDIS01" + mark, "REDIS01"},
+		{"$EOF:" + mark + "\r\nREDIS" + mark[1:] + "x" + mark, "REDIS" + mark[1:] + "x"},
+		{"$EOF:" + mark + "\r\n" + mark, ""},
+	}
+
+	for i, test := range tests {
+		input := "+FULLRESYNC 8de1787ba490483314a4d30f1c628bc5025eb761 100\r\n" + test.stream +
+			"*1\r\n$4\r\nPING\r\n*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+		s := NewReplicationStream(NewReaderSize(iotest.OneByteReader(strings.NewReader(input)), 64))
+		var w bytes.Buffer
+		full, err := s.PSync(&w)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if expected := "*3\r\n$5\r\nPSYNC\r\n$1\r\n?\r\n$2\r\n-1\r\n"; w.String() != expected {
+			t.Errorf("tests[%d]: expected %q, got %q", i, expected, w.String())
+		}
+		if !full || s.ReplID != "8de1787ba490483314a4d30f1c628bc5025eb761" || s.Offset != 100 {
+			t.Errorf("tests[%d]: unexpected state %v %q %d", i, full, s.ReplID, s.Offset)
+		}
+
+		rdb, err := s.RDB()
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		b, err := io.ReadAll(rdb)
+		if err != nil {
+			t.Fatalf("tests[%d]: %s", i, err)
+		}
+		if string(b) != test.rdb {
+			t.Errorf("tests[%d]: expected %q, got %q", i, test.rdb, b)
+		}
+
+		for _, expected := range []string{"*1\r\n$4\r\nPING\r\n", "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"} {
+			cmd, err := s.ReadCommand()
+			if err != nil {
+				t.Fatalf("tests[%d]: %s", i, err)
+			}
+			if string(cmd) != expected {
+				t.Errorf("tests[%d]: expected %q, got %q", i, expected, cmd)
+			}
+		}
+		if s.Offset != 100+14+27 {
+			t.Errorf("tests[%d]: expected offset %d, got %d", i, 100+14+27, s.Offset)
+		}
+		if _, err := s.ReadCommand(); err != io.EOF {
+			t.Errorf("tests[%d]: expected EOF, got %v", i, err)
+		}
+	}
+}
+
+func TestReplicationStream_UnreadRDB(t *testing.T) {
+	s := NewReplicationStream(NewReader(strings.NewReader("+FULLRESYNC abc 0\r\n$5\r\nREDIS*1\r\n$4\r\nPING\r\n")))
+	if _, err := s.ReadPSyncReply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RDB(); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err := s.ReadCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cmd) != "*1\r\n$4\r\nPING\r\n" {
+		t.Errorf("unexpected command %q", cmd)
+	}
+
+	// The payload is skipped if RDB isn't called at all
+	s = NewReplicationStream(NewReader(strings.NewReader("+FULLRESYNC abc 0\r\n$5\r\nREDIS*1\r\n$4\r\nPING\r\n")))
+	if _, err := s.ReadPSyncReply(); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err = s.ReadCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(cmd) != "*1\r\n$4\r\nPING\r\n" {
+		t.Errorf("unexpected command %q", cmd)
+	}
+}
+
+func TestReplicationStream_Continue(t *testing.T) {
+	s := NewReplicationStream(NewReader(strings.NewReader("+CONTINUE newid\r\n*1\r\n$4\r\nPING\r\n")))
+	s.ReplID = "oldid"
+	s.Offset = 41
+	var w bytes.Buffer
+	full, err := s.PSync(&w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "*3\r\n$5\r\nPSYNC\r\n$5\r\noldid\r\n$2\r\n42\r\n"; w.String() != expected {
+		t.Errorf("expected %q, got %q", expected, w.String())
+	}
+	if full || s.ReplID != "newid" || s.Offset != 41 {
+		t.Errorf("unexpected state %v %q %d", full, s.ReplID, s.Offset)
+	}
+	if _, err := s.ReadCommand(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Offset != 55 {
+		t.Errorf("expected offset 55, got %d", s.Offset)
+	}
+}
+
+func TestReplicationStream_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"-NOMASTERLINK Can't SYNC while not connected with my master\r\n", "NOMASTERLINK Can't SYNC while not connected with my master"},
+		{"+OK\r\n", ErrUnexpectedReply.Error()},
+		{"+FULLRESYNC abc x\r\n", ErrUnexpectedReply.Error()},
+		{"+FULLRESYNC abc 0\r\n*1\r\n", ErrBadRDBHeader.Error()},
+		{"+FULLRESYNC abc 0\r\n$EOF:short\r\n", ErrBadRDBHeader.Error()},
+		{"+FULLRESYNC abc 0\r\n$5\nREDIS", ErrBadRDBHeader.Error()},
+		{"+FULLRESYNC abc 0\r\n$5\r\nRED", io.ErrUnexpectedEOF.Error()},
+		{"+FULLRESYNC abc 0\r\n$EOF:" + strings.Repeat("m", RDB_EOF_MARK_LENGTH) + "\r\nREDIS", io.ErrUnexpectedEOF.Error()},
+	}
+
+	for i, test := range tests {
+		s := NewReplicationStream(NewReader(strings.NewReader(test.input)))
+		_, err := s.ReadPSyncReply()
+		if err == nil {
+			var rdb io.Reader
+			rdb, err = s.RDB()
+			if err == nil {
+				_, err = io.ReadAll(rdb)
+			}
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("tests[%d]: expected %q, got %v", i, test.err, err)
+		}
+	}
+}
@@ -8,6 +8,11 @@ const (
 	// A large INFO ALL response can be over 4kb, so we set the default to 8kb.
 	DEFAULT_BUFFER = 8192
 
+	// The size the read buffers of client and server connections may grow to
+	// in order to hold large objects, the same as Redis'
+	// client-query-buffer-limit.
+	DEFAULT_MAX_BUFFER = 1 << 30
+
 	// Smallest valid RESP object is ":0\r\n".
 	MIN_OBJECT_LENGTH = 4
 
@@ -20,17 +25,35 @@ const (
 	INTEGER_PREFIX       = ':'
 	BULK_STRING_PREFIX   = '$'
 	ARRAY_PREFIX         = '*'
+
+	// RESP3 object prefixes
+	NULL_PREFIX            = '_'
+	PUSH_PREFIX            = '>'
+	MAP_PREFIX             = '%'
+	SET_PREFIX             = '~'
+	ATTRIBUTE_PREFIX       = '|'
+	BOOLEAN_PREFIX         = '#'
+	DOUBLE_PREFIX          = ','
+	BIG_NUMBER_PREFIX      = '('
+	VERBATIM_STRING_PREFIX = '='
+	BLOB_ERROR_PREFIX      = '!'
 )
 
 var (
 	// Common responses
 	OK   = NewSimpleString("OK")
 	PONG = NewSimpleString("PONG")
+	NULL = Null("_\r\n")
 
 	// Errors
 	ErrSyntaxError = errors.New("resp: syntax error")
 	ErrBufferFull  = errors.New("resp: object is larger than buffer")
 
+	ErrUnknownCommand   = errors.New("resp: unknown command")
+	ErrInvalidArguments = errors.New("resp: invalid command arguments")
+	ErrUnexpectedReply  = errors.New("resp: unexpected reply")
+	ErrConnClosed       = errors.New("resp: connection closed")
+
 	lineSuffix = []byte("\r\n")
 )
 
@@ -42,6 +65,11 @@ type InvalidObject []byte
 
 func (o InvalidObject) Raw() []byte { return o }
 
+// A Null is a RESP3 null ("_\r\n").
+type Null []byte
+
+func (n Null) Raw() []byte { return n }
+
 // Parse takes a slice pointing to valid a valid RESP object and returns the
 // RESP as the corresponding type.
 func Parse(resp []byte) Object {
@@ -56,6 +84,26 @@ func Parse(resp []byte) Object {
 		return String(resp)
 	case ARRAY_PREFIX:
 		return Array(resp)
+	case NULL_PREFIX:
+		return Null(resp)
+	case PUSH_PREFIX:
+		return Push(resp)
+	case MAP_PREFIX:
+		return Map(resp)
+	case SET_PREFIX:
+		return Set(resp)
+	case ATTRIBUTE_PREFIX:
+		return Attribute(resp)
+	case BOOLEAN_PREFIX:
+		return Boolean(resp)
+	case DOUBLE_PREFIX:
+		return Double(resp)
+	case BIG_NUMBER_PREFIX:
+		return BigNumber(resp)
+	case VERBATIM_STRING_PREFIX:
+		return VerbatimString(resp)
+	case BLOB_ERROR_PREFIX:
+		return BlobError(resp)
 	default:
 		// This will never happen when being used with Reader
 		return InvalidObject(resp)
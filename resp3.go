@@ -0,0 +1,254 @@
+package resp
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// A Map is a RESP3 map.
+type Map []byte
+
+// NewMap returns a Map of the given alternating keys and values. It panics if
+// it's given an odd number of objects.
+func NewMap(pairs ...Object) Map {
+	if len(pairs)%2 != 0 {
+		panic("resp: odd number of map objects")
+	}
+	return Map(newAggregate(MAP_PREFIX, len(pairs)/2, pairs))
+}
+
+func (m Map) Raw() []byte { return m }
+
+// Objects returns the keys and values of this Map, alternating. The objects
+// point into the Map's bytes.
+func (m Map) Objects() ([]Object, error) {
+	return aggregateObjects(m)
+}
+
+// A Set is a RESP3 set.
+type Set []byte
+
+// NewSet returns a Set containing the given objects.
+func NewSet(objects ...Object) Set {
+	return Set(newAggregate(SET_PREFIX, len(objects), objects))
+}
+
+func (s Set) Raw() []byte { return s }
+
+// Objects returns the RESP objects contained in this Set. The objects point
+// into the Set's bytes.
+func (s Set) Objects() ([]Object, error) {
+	return aggregateObjects(s)
+}
+
+// An Attribute is a RESP3 attribute: a map of auxiliary data sent before a
+// reply, which most clients ignore.
+type Attribute []byte
+
+func (a Attribute) Raw() []byte { return a }
+
+// Objects returns the keys and values of this Attribute, alternating.
+func (a Attribute) Objects() ([]Object, error) {
+	return aggregateObjects(a)
+}
+
+// NewPush returns a Push containing the given objects.
+func NewPush(objects ...Object) Push {
+	return Push(newAggregate(PUSH_PREFIX, len(objects), objects))
+}
+
+func newAggregate(prefix byte, length int, objects []Object) []byte {
+	buf := []byte{prefix}
+	buf = AppendInt(buf, int64(length))
+	buf = append(buf, lineSuffix...)
+	for _, object := range objects {
+		buf = append(buf, object.Raw()...)
+	}
+	return buf
+}
+
+// A Boolean is a RESP3 boolean.
+type Boolean []byte
+
+var (
+	TRUE  = Boolean("#t\r\n")
+	FALSE = Boolean("#f\r\n")
+)
+
+// NewBoolean returns TRUE or FALSE.
+func NewBoolean(b bool) Boolean {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
+
+func (b Boolean) Raw() []byte { return b }
+
+// Bool returns the value of the Boolean. It returns ErrSyntaxError if the
+// Boolean is invalid.
+func (b Boolean) Bool() (bool, error) {
+	switch string(b) {
+	case "#t\r\n":
+		return true, nil
+	case "#f\r\n":
+		return false, nil
+	default:
+		return false, ErrSyntaxError
+	}
+}
+
+// A Double is a RESP3 double.
+type Double []byte
+
+// NewDouble returns a Double with the given value.
+func NewDouble(f float64) Double {
+	buf := []byte{DOUBLE_PREFIX}
+	switch {
+	case math.IsInf(f, 1):
+		buf = append(buf, "inf"...)
+	case math.IsInf(f, -1):
+		buf = append(buf, "-inf"...)
+	case math.IsNaN(f):
+		buf = append(buf, "nan"...)
+	default:
+		buf = strconv.AppendFloat(buf, f, 'g', -1, 64)
+	}
+	return Double(append(buf, lineSuffix...))
+}
+
+func (d Double) Raw() []byte { return d }
+
+// Float64 returns the value of the Double. It returns ErrSyntaxError if the
+// Double is invalid.
+func (d Double) Float64() (float64, error) {
+	f, err := strconv.ParseFloat(string(d[1:len(d)-2]), 64)
+	if err != nil {
+		return 0, ErrSyntaxError
+	}
+	return f, nil
+}
+
+// A BigNumber is a RESP3 big number.
+type BigNumber []byte
+
+func (n BigNumber) Raw() []byte { return n }
+
+// Int returns the value of the BigNumber. It returns ErrSyntaxError if the
+// BigNumber is invalid.
+func (n BigNumber) Int() (*big.Int, error) {
+	i, ok := new(big.Int).SetString(string(n[1:len(n)-2]), 10)
+	if !ok {
+		return nil, ErrSyntaxError
+	}
+	return i, nil
+}
+
+// A VerbatimString is a RESP3 verbatim string: a bulk string with a three
+// letter format, such as "txt" or "mkd".
+type VerbatimString []byte
+
+func (s VerbatimString) Raw() []byte { return s }
+
+// Format returns the format of the string, or "" if the string is invalid.
+func (s VerbatimString) Format() string {
+	contents := blobContents(s)
+	if len(contents) < 4 || contents[3] != ':' {
+		return ""
+	}
+	return string(contents[:3])
+}
+
+// Slice returns a slice pointing to the text of the string, without the
+// format.
+func (s VerbatimString) Slice() []byte {
+	contents := blobContents(s)
+	if len(contents) < 4 || contents[3] != ':' {
+		return contents
+	}
+	return contents[4:]
+}
+
+// A BlobError is a RESP3 blob error: an error whose message can contain any
+// bytes.
+type BlobError []byte
+
+func (e BlobError) Raw() []byte { return e }
+
+// Slice returns a slice pointing to the error message.
+func (e BlobError) Slice() []byte {
+	return blobContents(e)
+}
+
+// Error returns the error message. This allows BlobError to satisfy the error
+// interface.
+func (e BlobError) Error() string {
+	return string(e.Slice())
+}
+
+// blobContents returns the contents of a bulk string, verbatim string, or
+// blob error.
+func blobContents(b []byte) []byte {
+	length, lineEnd, err := parseLenLine(b)
+	if err != nil || length < 0 {
+		return nil
+	}
+	return b[lineEnd+1 : len(b)-2]
+}
+
+// appendRESP2 appends the RESP2 equivalent of the object b, which may contain
+// RESP3 types, to buf, as Redis answers RESP2 clients: maps become arrays of
+// alternating keys and values, sets and pushes become arrays, nulls become
+// null bulk strings, booleans become the integers 1 and 0, doubles, big
+// numbers, and verbatim strings become bulk strings, and blob errors become
+// simple errors. Attributes are dropped.
+func appendRESP2(buf []byte, b []byte) []byte {
+	if len(b) == 0 {
+		return buf
+	}
+	switch b[0] {
+	case ARRAY_PREFIX, MAP_PREFIX, SET_PREFIX, PUSH_PREFIX:
+		length, cursor, err := parseLenLine(b)
+		if err != nil {
+			return append(buf, b...)
+		}
+		if length < 0 {
+			return append(buf, "*-1\r\n"...)
+		}
+		length = aggregateLength(b[0], length)
+		buf = append(buf, ARRAY_PREFIX)
+		buf = AppendInt(buf, int64(length))
+		buf = append(buf, lineSuffix...)
+		for i := 0; i < length; i++ {
+			cursor++
+			end, err := objectEnd(b[cursor:])
+			if end < 0 || err != nil {
+				break
+			}
+			buf = appendRESP2(buf, b[cursor:cursor+end+1])
+			cursor += end
+		}
+		return buf
+	case ATTRIBUTE_PREFIX:
+		return buf
+	case NULL_PREFIX:
+		return append(buf, "$-1\r\n"...)
+	case BOOLEAN_PREFIX:
+		if len(b) > 1 && b[1] == 't' {
+			return append(buf, ":1\r\n"...)
+		}
+		return append(buf, ":0\r\n"...)
+	case DOUBLE_PREFIX, BIG_NUMBER_PREFIX:
+		return appendBulkBytes(buf, bytes.TrimSuffix(b[1:], lineSuffix))
+	case VERBATIM_STRING_PREFIX:
+		return appendBulkBytes(buf, VerbatimString(b).Slice())
+	case BLOB_ERROR_PREFIX:
+		buf = append(buf, ERROR_PREFIX)
+		buf = append(buf, blobContents(b)...)
+		return append(buf, lineSuffix...)
+	default:
+		return append(buf, b...)
+	}
+}
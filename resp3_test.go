@@ -0,0 +1,86 @@
+package resp
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReadObject_RESP3(t *testing.T) {
+	tests := []struct {
+		given    string
+		expected Object
+	}{
+		{"%2\r\n+a\r\n:1\r\n+b\r\n_\r\n", Map("%2\r\n+a\r\n:1\r\n+b\r\n_\r\n")},
+		{"~2\r\n+a\r\n#t\r\n", Set("~2\r\n+a\r\n#t\r\n")},
+		{"|1\r\n+ttl\r\n:3\r\n", Attribute("|1\r\n+ttl\r\n:3\r\n")},
+		{"#f\r\n", Boolean("#f\r\n")},
+		{",3.14\r\n", Double(",3.14\r\n")},
+		{"(3492890328409238509324850943850943825024385\r\n", BigNumber("(3492890328409238509324850943850943825024385\r\n")},
+		{"=15\r\ntxt:Some string\r\n", VerbatimString("=15\r\ntxt:Some string\r\n")},
+		{"!21\r\nSYNTAX invalid syntax\r\n", BlobError("!21\r\nSYNTAX invalid syntax\r\n")},
+		{"%1\r\n+nested\r\n%1\r\n+k\r\n~0\r\n", Map("%1\r\n+nested\r\n%1\r\n+k\r\n~0\r\n")},
+	}
+	for i, test := range tests {
+		obj, err := NewReader(bytes.NewBufferString(test.given + "+next\r\n")).ReadObject()
+		if err != nil {
+			t.Errorf("tests[%d]: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.expected, obj) {
+			t.Errorf("tests[%d]: expected %#v, got %#v", i, test.expected, obj)
+		}
+	}
+
+	// Incomplete
+	for _, partial := range []string{"%2\r\n+a\r\n:1\r\n+b\r\n", "=15\r\ntxt:Some"} {
+		_, err := NewReader(bytes.NewBufferString(partial)).ReadObject()
+		if err == nil {
+			t.Errorf("%q: expected an error but didn't get one", partial)
+		}
+	}
+}
+
+func TestRESP3Values(t *testing.T) {
+	m := NewMap(NewSimpleString("a"), NewInteger(1))
+	if string(m) != "%1\r\n+a\r\n:1\r\n" {
+		t.Errorf("unexpected map: %q", m)
+	}
+	objects, err := m.Objects()
+	if err != nil || len(objects) != 2 {
+		t.Errorf("unexpected objects: %q, %v", objects, err)
+	}
+	if pairs, ok := objectPairs(m); !ok || len(pairs) != 2 {
+		t.Errorf("expected a map to have pairs, got %q", pairs)
+	}
+	if s := NewSet(NewBoolean(true)); string(s) != "~1\r\n#t\r\n" {
+		t.Errorf("unexpected set: %q", s)
+	}
+	if p := NewPush(NewBulkString("invalidate"), Null("_\r\n")); string(p) != ">2\r\n$10\r\ninvalidate\r\n_\r\n" {
+		t.Errorf("unexpected push: %q", p)
+	}
+
+	if b, err := FALSE.Bool(); b || err != nil {
+		t.Errorf("unexpected bool: %v, %v", b, err)
+	}
+	for _, f := range []float64{1.5, -2, math.Inf(1), math.Inf(-1)} {
+		if got, err := NewDouble(f).Float64(); got != f || err != nil {
+			t.Errorf("%v: unexpected double: %v, %v", f, got, err)
+		}
+	}
+	if got, _ := NewDouble(math.NaN()).Float64(); !math.IsNaN(got) {
+		t.Errorf("expected NaN, got %v", got)
+	}
+	if n, err := BigNumber("(-12345678901234567890\r\n").Int(); err != nil || n.String() != "-12345678901234567890" {
+		t.Errorf("unexpected big number: %v, %v", n, err)
+	}
+
+	v := VerbatimString("=15\r\ntxt:Some string\r\n")
+	if v.Format() != "txt" || string(v.Slice()) != "Some string" {
+		t.Errorf("unexpected verbatim string: %q, %q", v.Format(), v.Slice())
+	}
+	if e := BlobError("!21\r\nSYNTAX invalid syntax\r\n"); e.Error() != "SYNTAX invalid syntax" {
+		t.Errorf("unexpected blob error: %q", e.Error())
+	}
+}
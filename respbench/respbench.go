@@ -0,0 +1,308 @@
+// Package respbench is a load generator for RESP servers, in the style of
+// redis-benchmark: it sends a mix of commands from many connections at once,
+// optionally pipelined, and reports the throughput and latencies.
+package respbench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/stvp/resp"
+)
+
+// ErrNoOps is returned by Run if the Config has no Ops.
+var ErrNoOps = errors.New("respbench: no commands to send")
+
+const (
+	// DEFAULT_CLIENTS is the number of connections used if Config.Clients
+	// is 0, as in redis-benchmark.
+	DEFAULT_CLIENTS = 50
+	// DEFAULT_REQUESTS is the number of commands sent if neither
+	// Config.Requests nor Config.Duration is set, as in redis-benchmark.
+	DEFAULT_REQUESTS = 100000
+)
+
+// An Op is a command of a benchmark's mix.
+type Op struct {
+	Command resp.Command
+	// Weight is the share of the commands sent that are this Op's,
+	// relative to the other Ops' weights. 0 is the same as 1.
+	Weight int
+}
+
+// A Config describes a benchmark.
+type Config struct {
+	// Network and Addr are the server's address, as for net.Dial. Network
+	// is "tcp" if it's empty.
+	Network string
+	Addr    string
+	// Ops are the commands to send. Each connection cycles through them,
+	// starting at a different point, in proportion to their weights.
+	Ops []Op
+	// Clients is the number of connections to send commands from at once,
+	// DEFAULT_CLIENTS if it's 0.
+	Clients int
+	// Pipeline is the number of commands each connection sends before
+	// reading their replies. It's 1 if it's 0.
+	Pipeline int
+	// Requests is the number of commands to send, and Duration how long
+	// to send them for. The benchmark stops when either is reached. If
+	// neither is set, DEFAULT_REQUESTS commands are sent.
+	Requests int
+	Duration time.Duration
+}
+
+// A Result is the outcome of a benchmark.
+type Result struct {
+	// Requests is the number of commands answered, and Errors the number
+	// answered with errors.
+	Requests int
+	Errors   int
+	// Duration is how long the benchmark took.
+	Duration time.Duration
+	// Latencies holds how long each command took to be answered, sorted.
+	// With pipelining, that's from writing the pipeline to reading the
+	// command's reply.
+	Latencies []time.Duration
+}
+
+// Throughput returns the number of commands answered per second.
+func (r *Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Duration.Seconds()
+}
+
+// Percentile returns the latency that p percent of commands were answered
+// within, e.g. 99 for the 99th percentile.
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(r.Latencies)))
+	if i >= len(r.Latencies) {
+		i = len(r.Latencies) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return r.Latencies[i]
+}
+
+// String summarizes the result, e.g.:
+//
+//	100000 requests in 1.2s, 83333.33 requests/s, 0 errors
+//	latency p50 0.52ms, p90 0.81ms, p99 1.30ms, p99.9 2.03ms, max 4.11ms
+func (r *Result) String() string {
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond)) }
+	max := time.Duration(0)
+	if len(r.Latencies) > 0 {
+		max = r.Latencies[len(r.Latencies)-1]
+	}
+	return fmt.Sprintf("%d requests in %s, %.2f requests/s, %d errors\nlatency p50 %s, p90 %s, p99 %s, p99.9 %s, max %s",
+		r.Requests, r.Duration.Round(time.Millisecond), r.Throughput(), r.Errors,
+		ms(r.Percentile(50)), ms(r.Percentile(90)), ms(r.Percentile(99)), ms(r.Percentile(99.9)), ms(max))
+}
+
+// Run runs the benchmark described by config until it's done or ctx is
+// canceled. It fails if a connection can't be made or fails, e.g. because
+// the server closed it.
+func Run(ctx context.Context, config Config) (*Result, error) {
+	if len(config.Ops) == 0 {
+		return nil, ErrNoOps
+	}
+	network := config.Network
+	if network == "" {
+		network = "tcp"
+	}
+	clients := config.Clients
+	if clients <= 0 {
+		clients = DEFAULT_CLIENTS
+	}
+	pipeline := config.Pipeline
+	if pipeline <= 0 {
+		pipeline = 1
+	}
+	requests := config.Requests
+	if requests <= 0 && config.Duration <= 0 {
+		requests = DEFAULT_REQUESTS
+	}
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+
+	conns := make([]net.Conn, clients)
+	for i := range conns {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, network, config.Addr)
+		if err != nil {
+			for _, conn := range conns[:i] {
+				conn.Close()
+			}
+			return nil, err
+		}
+		conns[i] = conn
+	}
+
+	b := &benchmark{
+		ctx:      ctx,
+		schedule: schedule(config.Ops),
+		pipeline: pipeline,
+		limited:  requests > 0,
+		requests: int64(requests),
+	}
+	start := time.Now()
+	errs := make(chan error, clients)
+	for i, conn := range conns {
+		go func(i int, conn net.Conn) {
+			errs <- b.client(i, conn)
+		}(i, conn)
+	}
+	var err error
+	for range conns {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Requests:  len(b.latencies),
+		Errors:    b.errors,
+		Duration:  time.Since(start),
+		Latencies: b.latencies,
+	}
+	sort.Slice(result.Latencies, func(i, j int) bool { return result.Latencies[i] < result.Latencies[j] })
+	return result, nil
+}
+
+// schedule returns the order in which to send ops: each op appears as many
+// times as its weight, spread out among the others.
+func schedule(ops []Op) []resp.Command {
+	var total int
+	weights := make([]int, len(ops))
+	for i, op := range ops {
+		weights[i] = op.Weight
+		if weights[i] <= 0 {
+			weights[i] = 1
+		}
+		total += weights[i]
+	}
+	// Smooth weighted round-robin, as used by nginx
+	commands := make([]resp.Command, 0, total)
+	current := make([]int, len(ops))
+	for len(commands) < total {
+		best := 0
+		for i := range ops {
+			current[i] += weights[i]
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		commands = append(commands, ops[best].Command)
+	}
+	return commands
+}
+
+// A benchmark is the state shared by the connections of a running benchmark.
+type benchmark struct {
+	ctx      context.Context
+	schedule []resp.Command
+	pipeline int
+	// requests is the number of commands left to send, if limited is
+	// true.
+	limited  bool
+	requests int64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	errors    int
+}
+
+// take reserves up to n commands to send, and returns how many it got.
+func (b *benchmark) take(n int) int {
+	if !b.limited {
+		return n
+	}
+	left := atomic.AddInt64(&b.requests, -int64(n))
+	if left < 0 {
+		n += int(left)
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// client sends commands from connection i until the benchmark is done.
+func (b *benchmark) client(i int, conn net.Conn) error {
+	// Reading is interrupted when the benchmark is stopped
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-b.ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	w := resp.NewWriter(conn)
+	r := resp.NewReader(conn)
+	r.SetMaxSize(resp.DEFAULT_MAX_BUFFER)
+	defer w.Release()
+	defer r.Release()
+	next := i
+	latencies := make([]time.Duration, 0, b.pipeline)
+	for b.ctx.Err() == nil {
+		n := b.take(b.pipeline)
+		if n == 0 {
+			return nil
+		}
+		start := time.Now()
+		for j := 0; j < n; j++ {
+			w.Write(b.schedule[next%len(b.schedule)])
+			next++
+		}
+		if err := w.Flush(); err != nil {
+			return b.connErr(err)
+		}
+		latencies = latencies[:0]
+		failed := 0
+		for j := 0; j < n; j++ {
+			reply, err := r.ReadObjectSlice()
+			if err != nil {
+				return b.connErr(err)
+			}
+			latencies = append(latencies, time.Since(start))
+			if reply[0] == resp.ERROR_PREFIX || reply[0] == resp.BLOB_ERROR_PREFIX {
+				failed++
+			}
+		}
+		b.mu.Lock()
+		b.latencies = append(b.latencies, latencies...)
+		b.errors += failed
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// connErr returns err, unless it's due to the benchmark being stopped.
+func (b *benchmark) connErr(err error) error {
+	if b.ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
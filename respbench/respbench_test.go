@@ -0,0 +1,137 @@
+package respbench
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stvp/resp"
+	"github.com/stvp/resp/resptest"
+)
+
+func TestSchedule(t *testing.T) {
+	set, get := resp.NewCommand("SET", "k", "v"), resp.NewCommand("GET", "k")
+	commands := schedule([]Op{{Command: set, Weight: 3}, {Command: get}})
+	if expected := []resp.Command{set, set, get, set}; !reflect.DeepEqual(expected, commands) {
+		t.Errorf("expected %q, got %q", expected, commands)
+	}
+}
+
+func TestRun(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+	s.Reply("SET", resp.OK)
+
+	result, err := Run(context.Background(), Config{
+		Addr: s.Addr,
+		Ops: []Op{
+			{Command: resp.NewCommand("SET", "k", "v"), Weight: 3},
+			{Command: resp.NewCommand("NOPE")},
+		},
+		Clients:  4,
+		Pipeline: 8,
+		Requests: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Requests != 1000 || len(s.Commands()) != 1000 || len(result.Latencies) != 1000 {
+		t.Errorf("expected 1000 requests, got %d, %d commands received", result.Requests, len(s.Commands()))
+	}
+	// About a quarter of the commands are unknown
+	if result.Errors < 200 || result.Errors > 300 {
+		t.Errorf("expected about 250 errors, got %d", result.Errors)
+	}
+	if !sort.SliceIsSorted(result.Latencies, func(i, j int) bool { return result.Latencies[i] < result.Latencies[j] }) {
+		t.Errorf("expected the latencies to be sorted")
+	}
+	if p50, p99 := result.Percentile(50), result.Percentile(99); p50 <= 0 || p50 > p99 || result.Percentile(100) != result.Latencies[999] {
+		t.Errorf("unexpected percentiles: %v, %v", p50, p99)
+	}
+	if summary := result.String(); !strings.HasPrefix(summary, "1000 requests in ") || result.Throughput() <= 0 {
+		t.Errorf("unexpected summary: %q", summary)
+	}
+}
+
+func TestRun_LargeReply(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+	s.Reply("GET", resp.NewBulkString(strings.Repeat("x", 20<<10)))
+
+	result, err := Run(context.Background(), Config{
+		Addr:     s.Addr,
+		Ops:      []Op{{Command: resp.NewCommand("GET", "k")}},
+		Clients:  2,
+		Requests: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Requests != 10 || result.Errors != 0 {
+		t.Errorf("expected 10 requests without errors, got %d with %d errors", result.Requests, result.Errors)
+	}
+}
+
+func TestRun_Duration(t *testing.T) {
+	s := resptest.NewServer()
+	defer s.Close()
+	s.Reply("PING", resp.PONG)
+
+	start := time.Now()
+	result, err := Run(context.Background(), Config{
+		Addr:     s.Addr,
+		Ops:      []Op{{Command: resp.NewCommand("PING")}},
+		Clients:  2,
+		Duration: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || result.Requests == 0 || result.Errors != 0 {
+		t.Errorf("unexpected result after %v: %v", elapsed, result)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	if _, err := Run(context.Background(), Config{Addr: "127.0.0.1:1"}); err != ErrNoOps {
+		t.Errorf("expected ErrNoOps, got %v", err)
+	}
+
+	s := resptest.NewServer()
+	defer s.Close()
+	s.Inject("", resptest.Fault{Close: true})
+	ops := []Op{{Command: resp.NewCommand("PING")}}
+	if _, err := Run(context.Background(), Config{Addr: s.Addr, Ops: ops, Clients: 1}); err == nil {
+		t.Errorf("expected the closed connection to fail the benchmark")
+	}
+}
+
+// BenchmarkServer measures the throughput of a resp.Server answering PING.
+func BenchmarkServer(b *testing.B) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	server := &resp.Server{Handler: resp.HandlerFunc(func(w *resp.Writer, cmd resp.Command) {
+		w.WriteObject(resp.PONG)
+	})}
+	go server.Serve(l)
+	defer server.Close()
+
+	b.ResetTimer()
+	result, err := Run(context.Background(), Config{
+		Addr:     l.Addr().String(),
+		Ops:      []Op{{Command: resp.NewCommand("PING")}},
+		Clients:  8,
+		Pipeline: 16,
+		Requests: b.N,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(float64(result.Percentile(99).Microseconds()), "p99-us")
+}
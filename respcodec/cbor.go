@@ -0,0 +1,353 @@
+package respcodec
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"unicode/utf8"
+
+	"github.com/stvp/resp"
+)
+
+// CBOR tags of RESP types without a native equivalent.
+const (
+	// CBOR_ERROR_TAG tags a text or byte string holding an error's
+	// message. It's "RESP" in ASCII, from the first come first served
+	// range, but isn't registered.
+	CBOR_ERROR_TAG = 0x52455350
+	// CBOR_SET_TAG tags an array holding a set's members, as registered
+	// for sets.
+	CBOR_SET_TAG = 258
+
+	cborPositiveBignumTag = 2
+	cborNegativeBignumTag = 3
+)
+
+// CBOR major types
+const (
+	cborUnsigned = 0 << 5
+	cborNegative = 1 << 5
+	cborBytes    = 2 << 5
+	cborText     = 3 << 5
+	cborArray    = 4 << 5
+	cborMap      = 5 << 5
+	cborTag      = 6 << 5
+	cborSimple   = 7 << 5
+)
+
+// ToCBOR converts a RESP object to CBOR. It returns resp.ErrSyntaxError if
+// obj is invalid.
+func ToCBOR(obj resp.Object) ([]byte, error) {
+	return appendCBOR(nil, obj)
+}
+
+func appendCBOR(buf []byte, obj resp.Object) ([]byte, error) {
+	switch o := obj.(type) {
+	case resp.String:
+		s := o.Slice()
+		if s == nil && o.Raw()[0] == resp.BULK_STRING_PREFIX {
+			return append(buf, cborSimple|22), nil
+		}
+		return appendCBORString(buf, s), nil
+	case resp.VerbatimString:
+		return appendCBORString(buf, o.Slice()), nil
+	case resp.Error:
+		buf = appendCBORHead(buf, cborTag, CBOR_ERROR_TAG)
+		return appendCBORString(buf, o.Slice()), nil
+	case resp.BlobError:
+		buf = appendCBORHead(buf, cborTag, CBOR_ERROR_TAG)
+		return appendCBORString(buf, o.Slice()), nil
+	case resp.Integer:
+		n, err := o.Int64()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		if n < 0 {
+			return appendCBORHead(buf, cborNegative, uint64(-1-n)), nil
+		}
+		return appendCBORHead(buf, cborUnsigned, uint64(n)), nil
+	case resp.Double:
+		f, err := o.Float64()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		buf = append(buf, cborSimple|27)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(f)), nil
+	case resp.BigNumber:
+		i, err := o.Int()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		if i.Sign() < 0 {
+			// Negative bignums hold -1-n
+			buf = appendCBORHead(buf, cborTag, cborNegativeBignumTag)
+			i.Neg(i).Sub(i, big.NewInt(1))
+		} else {
+			buf = appendCBORHead(buf, cborTag, cborPositiveBignumTag)
+		}
+		magnitude := i.Bytes()
+		buf = appendCBORHead(buf, cborBytes, uint64(len(magnitude)))
+		return append(buf, magnitude...), nil
+	case resp.Boolean:
+		v, err := o.Bool()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		if v {
+			return append(buf, cborSimple|21), nil
+		}
+		return append(buf, cborSimple|20), nil
+	case resp.Null:
+		return append(buf, cborSimple|22), nil
+	case resp.Array, resp.Command, resp.Set, resp.Push, resp.Map, resp.Attribute:
+		objects, err := objects(obj)
+		if err != nil {
+			return nil, err
+		}
+		if objects == nil {
+			return append(buf, cborSimple|22), nil
+		}
+		switch obj.(type) {
+		case resp.Map, resp.Attribute:
+			buf = appendCBORHead(buf, cborMap, uint64(len(objects)/2))
+		case resp.Set:
+			buf = appendCBORHead(buf, cborTag, CBOR_SET_TAG)
+			buf = appendCBORHead(buf, cborArray, uint64(len(objects)))
+		default:
+			buf = appendCBORHead(buf, cborArray, uint64(len(objects)))
+		}
+		for _, object := range objects {
+			if buf, err = appendCBOR(buf, object); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, resp.ErrSyntaxError
+	}
+}
+
+// appendCBORHead appends the head of an item of the given major type and
+// argument, in its shortest form.
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+	}
+}
+
+func appendCBORString(buf []byte, s []byte) []byte {
+	major := byte(cborText)
+	if !utf8.Valid(s) {
+		major = cborBytes
+	}
+	buf = appendCBORHead(buf, major, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// FromCBOR converts a CBOR item to a RESP object, as described in the package
+// documentation. Tags other than the ones ToCBOR uses are ignored. It returns
+// ErrInvalidCBOR if b isn't a single CBOR item and ErrUnsupported if it has
+// no RESP equivalent.
+func FromCBOR(b []byte) (resp.Object, error) {
+	d := &cborDecoder{b: b}
+	obj, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if len(d.b) > 0 {
+		return nil, ErrInvalidCBOR
+	}
+	return obj, nil
+}
+
+type cborDecoder struct {
+	b []byte
+}
+
+// next consumes and returns the next n bytes.
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if uint64(len(d.b)) < n {
+		return nil, ErrInvalidCBOR
+	}
+	next := d.b[:n]
+	d.b = d.b[n:]
+	return next, nil
+}
+
+// head consumes the head of an item and returns its major type, additional
+// information, and argument.
+func (d *cborDecoder) head() (major, info byte, arg uint64, err error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]&0xe0, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		b, err := d.next(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, c := range b {
+			arg = arg<<8 | uint64(c)
+		}
+		return major, info, arg, nil
+	case info == 31:
+		return 0, 0, 0, ErrUnsupported
+	default:
+		return 0, 0, 0, ErrInvalidCBOR
+	}
+}
+
+func (d *cborDecoder) decode() (resp.Object, error) {
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUnsigned:
+		if arg > math.MaxInt64 {
+			return newBigNumber(new(big.Int).SetUint64(arg).String()), nil
+		}
+		return resp.NewInteger(int64(arg)), nil
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			i := new(big.Int).SetUint64(arg)
+			return newBigNumber(i.Neg(i).Sub(i, big.NewInt(1)).String()), nil
+		}
+		return resp.NewInteger(-1 - int64(arg)), nil
+	case cborBytes, cborText:
+		s, err := d.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		return resp.String(appendBlob(nil, resp.BULK_STRING_PREFIX, s)), nil
+	case cborArray, cborMap:
+		n := arg
+		if major == cborMap {
+			n *= 2
+		}
+		var objects []resp.Object
+		for i := uint64(0); i < n; i++ {
+			obj, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, obj)
+		}
+		if major == cborMap {
+			return resp.NewMap(objects...), nil
+		}
+		return resp.NewArray(objects...), nil
+	case cborTag:
+		return d.tagged(arg)
+	default:
+		return d.simple(info, arg)
+	}
+}
+
+// tagged decodes the item with the given tag.
+func (d *cborDecoder) tagged(tag uint64) (resp.Object, error) {
+	switch tag {
+	case cborPositiveBignumTag, cborNegativeBignumTag:
+		major, _, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborBytes {
+			return nil, ErrInvalidCBOR
+		}
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		i := new(big.Int).SetBytes(b)
+		if tag == cborNegativeBignumTag {
+			i.Neg(i).Sub(i, big.NewInt(1))
+		}
+		if i.IsInt64() {
+			return resp.NewInteger(i.Int64()), nil
+		}
+		return newBigNumber(i.String()), nil
+	case CBOR_ERROR_TAG:
+		major, _, n, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if major != cborText && major != cborBytes {
+			return nil, ErrInvalidCBOR
+		}
+		msg, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return newError(msg), nil
+	case CBOR_SET_TAG:
+		obj, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		array, ok := obj.(resp.Array)
+		if !ok {
+			return nil, ErrInvalidCBOR
+		}
+		return resp.Set(append([]byte{resp.SET_PREFIX}, array[1:]...)), nil
+	default:
+		return d.decode()
+	}
+}
+
+// simple decodes a floating point number or simple value.
+func (d *cborDecoder) simple(info byte, arg uint64) (resp.Object, error) {
+	switch info {
+	case 20:
+		return resp.FALSE, nil
+	case 21:
+		return resp.TRUE, nil
+	case 22, 23:
+		// null and undefined
+		return resp.NULL, nil
+	case 25:
+		return resp.NewDouble(halfToFloat(uint16(arg))), nil
+	case 26:
+		return resp.NewDouble(float64(math.Float32frombits(uint32(arg)))), nil
+	case 27:
+		return resp.NewDouble(math.Float64frombits(arg)), nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// halfToFloat converts an IEEE 754 half precision float.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(frac+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,364 @@
+package respcodec
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"unicode/utf8"
+
+	"github.com/stvp/resp"
+)
+
+// The MessagePack extension types of RESP types without a native equivalent.
+const (
+	// MSGPACK_ERROR_EXT holds an error's message.
+	MSGPACK_ERROR_EXT = 1
+	// MSGPACK_BIG_NUMBER_EXT holds a big number's decimal digits.
+	MSGPACK_BIG_NUMBER_EXT = 2
+)
+
+// ToMsgPack converts a RESP object to MessagePack. It returns
+// resp.ErrSyntaxError if obj is invalid.
+func ToMsgPack(obj resp.Object) ([]byte, error) {
+	return appendMsgPack(nil, obj)
+}
+
+func appendMsgPack(buf []byte, obj resp.Object) ([]byte, error) {
+	switch o := obj.(type) {
+	case resp.String:
+		s := o.Slice()
+		if s == nil && o.Raw()[0] == resp.BULK_STRING_PREFIX {
+			return append(buf, 0xc0), nil
+		}
+		return appendMsgPackString(buf, s), nil
+	case resp.VerbatimString:
+		return appendMsgPackString(buf, o.Slice()), nil
+	case resp.Error:
+		return appendMsgPackExt(buf, MSGPACK_ERROR_EXT, o.Slice()), nil
+	case resp.BlobError:
+		return appendMsgPackExt(buf, MSGPACK_ERROR_EXT, o.Slice()), nil
+	case resp.Integer:
+		n, err := o.Int64()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		return appendMsgPackInt(buf, n), nil
+	case resp.Double:
+		f, err := o.Float64()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(f)), nil
+	case resp.BigNumber:
+		i, err := o.Int()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		return appendMsgPackExt(buf, MSGPACK_BIG_NUMBER_EXT, []byte(i.String())), nil
+	case resp.Boolean:
+		v, err := o.Bool()
+		if err != nil {
+			return nil, resp.ErrSyntaxError
+		}
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case resp.Null:
+		return append(buf, 0xc0), nil
+	case resp.Array, resp.Command, resp.Set, resp.Push, resp.Map, resp.Attribute:
+		objects, err := objects(obj)
+		if err != nil {
+			return nil, err
+		}
+		if objects == nil {
+			return append(buf, 0xc0), nil
+		}
+		switch obj.(type) {
+		case resp.Map, resp.Attribute:
+			buf = appendMsgPackLength(buf, len(objects)/2, 0x80, 0xde)
+		default:
+			buf = appendMsgPackLength(buf, len(objects), 0x90, 0xdc)
+		}
+		for _, object := range objects {
+			if buf, err = appendMsgPack(buf, object); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, resp.ErrSyntaxError
+	}
+}
+
+func appendMsgPackInt(buf []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= 0x7f, n < 0 && n >= -32:
+		return append(buf, byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(buf, 0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(n))
+	}
+}
+
+func appendMsgPackString(buf []byte, s []byte) []byte {
+	if !utf8.Valid(s) {
+		switch {
+		case len(s) <= math.MaxUint8:
+			buf = append(buf, 0xc4, byte(len(s)))
+		case len(s) <= math.MaxUint16:
+			buf = binary.BigEndian.AppendUint16(append(buf, 0xc5), uint16(len(s)))
+		default:
+			buf = binary.BigEndian.AppendUint32(append(buf, 0xc6), uint32(len(s)))
+		}
+		return append(buf, s...)
+	}
+	switch {
+	case len(s) < 32:
+		buf = append(buf, 0xa0|byte(len(s)))
+	case len(s) <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(len(s)))
+	case len(s) <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(len(s)))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(len(s)))
+	}
+	return append(buf, s...)
+}
+
+func appendMsgPackExt(buf []byte, typ int8, data []byte) []byte {
+	switch len(data) {
+	case 1:
+		buf = append(buf, 0xd4)
+	case 2:
+		buf = append(buf, 0xd5)
+	case 4:
+		buf = append(buf, 0xd6)
+	case 8:
+		buf = append(buf, 0xd7)
+	case 16:
+		buf = append(buf, 0xd8)
+	default:
+		switch {
+		case len(data) <= math.MaxUint8:
+			buf = append(buf, 0xc7, byte(len(data)))
+		case len(data) <= math.MaxUint16:
+			buf = binary.BigEndian.AppendUint16(append(buf, 0xc8), uint16(len(data)))
+		default:
+			buf = binary.BigEndian.AppendUint32(append(buf, 0xc9), uint32(len(data)))
+		}
+	}
+	buf = append(buf, byte(typ))
+	return append(buf, data...)
+}
+
+// appendMsgPackLength appends the header of an array or map of n elements,
+// fix being the fixarray or fixmap prefix and wide the array 16 or map 16
+// prefix, which is followed by the 32-bit prefix.
+func appendMsgPackLength(buf []byte, n int, fix, wide byte) []byte {
+	switch {
+	case n < 16:
+		return append(buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, wide), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, wide+1), uint32(n))
+	}
+}
+
+// FromMsgPack converts a MessagePack value to a RESP object, as described in
+// the package documentation. It returns ErrInvalidMsgPack if b isn't a
+// single MessagePack value and ErrUnsupported if it has no RESP equivalent.
+func FromMsgPack(b []byte) (resp.Object, error) {
+	d := &msgPackDecoder{b: b}
+	obj, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+	if len(d.b) > 0 {
+		return nil, ErrInvalidMsgPack
+	}
+	return obj, nil
+}
+
+type msgPackDecoder struct {
+	b []byte
+}
+
+// next consumes and returns the next n bytes.
+func (d *msgPackDecoder) next(n uint64) ([]byte, error) {
+	if uint64(len(d.b)) < n {
+		return nil, ErrInvalidMsgPack
+	}
+	next := d.b[:n]
+	d.b = d.b[n:]
+	return next, nil
+}
+
+// uint consumes and returns a big-endian integer of n bytes.
+func (d *msgPackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(uint64(n))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *msgPackDecoder) decode() (resp.Object, error) {
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+	switch {
+	case c <= 0x7f:
+		return resp.NewInteger(int64(c)), nil
+	case c >= 0xe0:
+		return resp.NewInteger(int64(int8(c))), nil
+	case c&0xe0 == 0xa0:
+		return d.str(uint64(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.array(uint64(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return d.dict(uint64(c & 0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return resp.NULL, nil
+	case 0xc2:
+		return resp.FALSE, nil
+	case 0xc3:
+		return resp.TRUE, nil
+	case 0xc4, 0xc5, 0xc6:
+		// bin 8, 16, and 32
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xd9, 0xda, 0xdb:
+		// str 8, 16, and 32
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(n)
+	case 0xca:
+		bits, err := d.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return resp.NewDouble(float64(math.Float32frombits(uint32(bits)))), nil
+	case 0xcb:
+		bits, err := d.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return resp.NewDouble(math.Float64frombits(bits)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n > math.MaxInt64 {
+			return newBigNumber(new(big.Int).SetUint64(n).String()), nil
+		}
+		return resp.NewInteger(int64(n)), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		width := 1 << (c - 0xd0)
+		n, err := d.uint(width)
+		if err != nil {
+			return nil, err
+		}
+		// Sign extend
+		shift := 64 - 8*uint(width)
+		return resp.NewInteger(int64(n<<shift) >> shift), nil
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(n)
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.dict(n)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(1 << (c - 0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(n)
+	default:
+		return nil, ErrInvalidMsgPack
+	}
+}
+
+func (d *msgPackDecoder) str(n uint64) (resp.Object, error) {
+	s, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return resp.String(appendBlob(nil, resp.BULK_STRING_PREFIX, s)), nil
+}
+
+func (d *msgPackDecoder) array(n uint64) (resp.Object, error) {
+	var objects []resp.Object
+	for i := uint64(0); i < n; i++ {
+		obj, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	return resp.NewArray(objects...), nil
+}
+
+func (d *msgPackDecoder) dict(n uint64) (resp.Object, error) {
+	var objects []resp.Object
+	for i := uint64(0); i < 2*n; i++ {
+		obj, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	return resp.NewMap(objects...), nil
+}
+
+func (d *msgPackDecoder) ext(n uint64) (resp.Object, error) {
+	typ, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	switch int8(typ[0]) {
+	case MSGPACK_ERROR_EXT:
+		return newError(data), nil
+	case MSGPACK_BIG_NUMBER_EXT:
+		if _, ok := new(big.Int).SetString(string(data), 10); !ok {
+			return nil, ErrInvalidMsgPack
+		}
+		return newBigNumber(string(data)), nil
+	default:
+		return nil, ErrUnsupported
+	}
+}
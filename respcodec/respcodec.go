@@ -0,0 +1,85 @@
+// Package respcodec converts RESP objects to and from MessagePack and CBOR,
+// for archiving Redis traffic in compact binary formats. It's a separate
+// package so that programs that don't need it don't carry it.
+//
+// RESP types are converted to the nearest native type of each format:
+//
+//	simple, bulk, and verbatim strings   strings, or binary strings if they aren't UTF-8
+//	integers                             integers
+//	doubles                              64-bit floats
+//	booleans                             booleans
+//	nulls, null bulk strings and arrays  nil
+//	arrays and pushes                    arrays
+//	sets                                 arrays, tagged 258 in CBOR
+//	maps and attributes                  maps
+//	big numbers                          extension MSGPACK_BIG_NUMBER_EXT, or CBOR bignums
+//	errors and blob errors               extension MSGPACK_ERROR_EXT, or tag CBOR_ERROR_TAG
+//
+// Converting back yields RESP3 objects: strings become bulk strings, nils
+// become RESP3 nulls, and integers that don't fit in 64 bits become big
+// numbers. Writers set to RESP2 convert them for RESP2 clients.
+package respcodec
+
+import (
+	"errors"
+
+	"github.com/stvp/resp"
+)
+
+var (
+	// ErrInvalidMsgPack is returned by FromMsgPack if its input isn't a
+	// single well-formed MessagePack value.
+	ErrInvalidMsgPack = errors.New("respcodec: invalid MessagePack")
+	// ErrInvalidCBOR is returned by FromCBOR if its input isn't a single
+	// well-formed CBOR item.
+	ErrInvalidCBOR = errors.New("respcodec: invalid CBOR")
+	// ErrUnsupported is returned for values that have no RESP equivalent,
+	// such as MessagePack timestamps, and for CBOR items of indefinite
+	// length.
+	ErrUnsupported = errors.New("respcodec: unsupported value")
+)
+
+// An aggregate is a RESP object containing other objects.
+type aggregate interface {
+	Objects() ([]resp.Object, error)
+}
+
+// objects returns the objects in an aggregate, or nil for a null array. It
+// returns resp.ErrSyntaxError if the aggregate is invalid.
+func objects(obj resp.Object) ([]resp.Object, error) {
+	if cmd, ok := obj.(resp.Command); ok {
+		obj = resp.Array(cmd)
+	}
+	objects, err := obj.(aggregate).Objects()
+	if err != nil {
+		return nil, resp.ErrSyntaxError
+	}
+	return objects, nil
+}
+
+// newError returns a RESP error with the given message, as a blob error if
+// it can't be sent as a simple error.
+func newError(msg []byte) resp.Object {
+	for _, b := range msg {
+		if b == '\r' || b == '\n' {
+			return resp.Parse(appendBlob(nil, resp.BLOB_ERROR_PREFIX, msg))
+		}
+	}
+	return resp.NewError(string(msg))
+}
+
+// newBigNumber returns a RESP big number with the given decimal digits.
+func newBigNumber(digits string) resp.Object {
+	buf := append([]byte{resp.BIG_NUMBER_PREFIX}, digits...)
+	return resp.BigNumber(append(buf, "\r\n"...))
+}
+
+// appendBlob appends a length-prefixed RESP string with the given prefix to
+// buf.
+func appendBlob(buf []byte, prefix byte, s []byte) []byte {
+	buf = append(buf, prefix)
+	buf = resp.AppendInt(buf, int64(len(s)))
+	buf = append(buf, "\r\n"...)
+	buf = append(buf, s...)
+	return append(buf, "\r\n"...)
+}
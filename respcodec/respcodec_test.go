@@ -0,0 +1,187 @@
+package respcodec
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stvp/resp"
+)
+
+type codecTest struct {
+	obj     resp.Object
+	encoded string
+	// decoded is the RESP that encoded converts back to, if it isn't obj.
+	decoded string
+}
+
+func bigNumber(digits string) resp.Object {
+	return resp.BigNumber("(" + digits + "\r\n")
+}
+
+func checkCodec(t *testing.T, name string, tests []codecTest, to func(resp.Object) ([]byte, error), from func([]byte) (resp.Object, error)) {
+	for i, test := range tests {
+		b, err := to(test.obj)
+		if err != nil {
+			t.Errorf("%s[%d]: %s", name, i, err)
+			continue
+		}
+		if string(b) != test.encoded {
+			t.Errorf("%s[%d]: expected %x, got %x", name, i, test.encoded, b)
+		}
+
+		obj, err := from(b)
+		if err != nil {
+			t.Errorf("%s[%d]: %s", name, i, err)
+			continue
+		}
+		expected := test.decoded
+		if expected == "" {
+			expected = string(test.obj.Raw())
+		}
+		if string(obj.Raw()) != expected {
+			t.Errorf("%s[%d]: expected %q, got %q", name, i, expected, obj.Raw())
+		}
+		if resp.Parse(obj.Raw()) == nil || !bytes.Equal(resp.Parse(obj.Raw()).Raw(), obj.Raw()) {
+			t.Errorf("%s[%d]: invalid object %q", name, i, obj.Raw())
+		}
+	}
+}
+
+func TestMsgPack(t *testing.T) {
+	checkCodec(t, "msgpack", []codecTest{
+		{resp.NewBulkString("foo"), "\xa3foo", ""},
+		{resp.OK, "\xa2OK", "$2\r\nOK\r\n"},
+		{resp.NewBulkString("\xff\x00"), "\xc4\x02\xff\x00", ""},
+		{resp.NewBulkString(string(make([]byte, 40))), "\xd9\x28" + string(make([]byte, 40)), ""},
+		{resp.String("$-1\r\n"), "\xc0", "_\r\n"},
+		{resp.NULL, "\xc0", ""},
+		{resp.NewInteger(5), "\x05", ""},
+		{resp.NewInteger(-5), "\xfb", ""},
+		{resp.NewInteger(-100), "\xd0\x9c", ""},
+		{resp.NewInteger(1000), "\xd1\x03\xe8", ""},
+		{resp.NewInteger(-100000), "\xd2\xff\xfe\x79\x60", ""},
+		{resp.NewInteger(math.MinInt64), "\xd3\x80\x00\x00\x00\x00\x00\x00\x00", ""},
+		{resp.NewDouble(1.5), "\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00", ""},
+		{resp.TRUE, "\xc3", ""},
+		{resp.FALSE, "\xc2", ""},
+		{resp.NewError("ERR bad"), "\xc7\x07\x01ERR bad", ""},
+		{resp.BlobError("!4\r\na\r\nb\r\n"), "\xd6\x01a\r\nb", ""},
+		{bigNumber("123456789012345678901234567890"), "\xc7\x1e\x02123456789012345678901234567890", ""},
+		{resp.NewArray(resp.NewInteger(1), resp.NewBulkString("a")), "\x92\x01\xa1a", ""},
+		{resp.NewCommand("GET", "k"), "\x92\xa3GET\xa1k", "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"},
+		{resp.Array("*-1\r\n"), "\xc0", "_\r\n"},
+		{resp.NewSet(resp.NewInteger(1)), "\x91\x01", "*1\r\n:1\r\n"},
+		{resp.NewPush(resp.NewInteger(1)), "\x91\x01", "*1\r\n:1\r\n"},
+		{resp.NewMap(resp.NewBulkString("a"), resp.NewInteger(1)), "\x81\xa1a\x01", ""},
+	}, ToMsgPack, FromMsgPack)
+
+	// Forms ToMsgPack doesn't produce
+	for encoded, expected := range map[string]string{
+		"\xcf\xff\xff\xff\xff\xff\xff\xff\xff": "(18446744073709551615\r\n",
+		"\xcc\xff":                             ":255\r\n",
+		"\xca\x3f\xc0\x00\x00":                 ",1.5\r\n",
+		"\xdc\x00\x01\x01":                     "*1\r\n:1\r\n",
+		"\xde\x00\x01\x01\x02":                 "%1\r\n:1\r\n:2\r\n",
+		"\xd4\x01x":                            "-x\r\n",
+	} {
+		obj, err := FromMsgPack([]byte(encoded))
+		if err != nil || string(obj.Raw()) != expected {
+			t.Errorf("%x: expected %q, got %q, %v", encoded, expected, obj.Raw(), err)
+		}
+	}
+
+	for encoded, expected := range map[string]error{
+		"":             ErrInvalidMsgPack,
+		"\xa3fo":       ErrInvalidMsgPack,
+		"\x92\x01":     ErrInvalidMsgPack,
+		"\x01\x02":     ErrInvalidMsgPack,
+		"\xc1":         ErrInvalidMsgPack,
+		"\xd6\xff1234": ErrUnsupported,
+		"\xd4\x02x":    ErrInvalidMsgPack,
+	} {
+		if _, err := FromMsgPack([]byte(encoded)); err != expected {
+			t.Errorf("%x: expected %v, got %v", encoded, expected, err)
+		}
+	}
+	if _, err := ToMsgPack(resp.InvalidObject("x")); err != resp.ErrSyntaxError {
+		t.Errorf("expected ErrSyntaxError, got %v", err)
+	}
+}
+
+func TestCBOR(t *testing.T) {
+	checkCodec(t, "cbor", []codecTest{
+		{resp.NewBulkString("foo"), "\x63foo", ""},
+		{resp.OK, "\x62OK", "$2\r\nOK\r\n"},
+		{resp.NewBulkString("\xff\x00"), "\x42\xff\x00", ""},
+		{resp.String("$-1\r\n"), "\xf6", "_\r\n"},
+		{resp.NULL, "\xf6", ""},
+		{resp.NewInteger(10), "\x0a", ""},
+		{resp.NewInteger(-10), "\x29", ""},
+		{resp.NewInteger(500), "\x19\x01\xf4", ""},
+		{resp.NewInteger(math.MinInt64), "\x3b\x7f\xff\xff\xff\xff\xff\xff\xff", ""},
+		{resp.NewDouble(1.5), "\xfb\x3f\xf8\x00\x00\x00\x00\x00\x00", ""},
+		{resp.TRUE, "\xf5", ""},
+		{resp.FALSE, "\xf4", ""},
+		{resp.NewError("ERR bad"), "\xda\x52\x45\x53\x50\x67ERR bad", ""},
+		{bigNumber("18446744073709551616"), "\xc2\x49\x01\x00\x00\x00\x00\x00\x00\x00\x00", ""},
+		{bigNumber("-18446744073709551617"), "\xc3\x49\x01\x00\x00\x00\x00\x00\x00\x00\x00", ""},
+		{resp.NewArray(resp.NewInteger(1), resp.NewBulkString("a")), "\x82\x01\x61a", ""},
+		{resp.NewSet(resp.NewInteger(1)), "\xd9\x01\x02\x81\x01", ""},
+		{resp.NewPush(resp.NewInteger(1)), "\x81\x01", "*1\r\n:1\r\n"},
+		{resp.NewMap(resp.NewBulkString("a"), resp.NewInteger(1)), "\xa1\x61a\x01", ""},
+	}, ToCBOR, FromCBOR)
+
+	for encoded, expected := range map[string]string{
+		"\x1b\xff\xff\xff\xff\xff\xff\xff\xff": "(18446744073709551615\r\n",
+		"\x3b\xff\xff\xff\xff\xff\xff\xff\xff": "(-18446744073709551616\r\n",
+		"\xc2\x41\x05":                         ":5\r\n",
+		"\xf9\x3e\x00":                         ",1.5\r\n",
+		"\xfa\x3f\xc0\x00\x00":                 ",1.5\r\n",
+		"\xf7":                                 "_\r\n",
+		"\xc1\x1a\x51\x4b\x67\xb0":             ":1363896240\r\n",
+		"\xda\x52\x45\x53\x50\x63a\r\n":        "!3\r\na\r\n\r\n",
+	} {
+		obj, err := FromCBOR([]byte(encoded))
+		if err != nil || string(obj.Raw()) != expected {
+			t.Errorf("%x: expected %q, got %q, %v", encoded, expected, obj.Raw(), err)
+		}
+	}
+
+	for encoded, expected := range map[string]error{
+		"":                 ErrInvalidCBOR,
+		"\x63fo":           ErrInvalidCBOR,
+		"\x82\x01":         ErrInvalidCBOR,
+		"\x01\x02":         ErrInvalidCBOR,
+		"\x1c":             ErrInvalidCBOR,
+		"\x9f\x01\xff":     ErrUnsupported,
+		"\xf0":             ErrUnsupported,
+		"\xd9\x01\x02\xa0": ErrInvalidCBOR,
+		"\xc2\x01":         ErrInvalidCBOR,
+	} {
+		if _, err := FromCBOR([]byte(encoded)); err != expected {
+			t.Errorf("%x: expected %v, got %v", encoded, expected, err)
+		}
+	}
+	if _, err := ToCBOR(resp.InvalidObject("x")); err != resp.ErrSyntaxError {
+		t.Errorf("expected ErrSyntaxError, got %v", err)
+	}
+}
+
+func TestHalfToFloat(t *testing.T) {
+	for h, expected := range map[uint16]float64{
+		0x0000: 0,
+		0x3c00: 1,
+		0xc000: -2,
+		0x7bff: 65504,
+		0x0001: 5.960464477539063e-08,
+		0x7c00: math.Inf(1),
+	} {
+		if f := halfToFloat(h); f != expected {
+			t.Errorf("%#x: expected %v, got %v", h, expected, f)
+		}
+	}
+	if f := halfToFloat(0x7e00); !math.IsNaN(f) {
+		t.Errorf("expected NaN, got %v", f)
+	}
+}
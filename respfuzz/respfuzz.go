@@ -0,0 +1,348 @@
+// Package respfuzz helps fuzz code that reads RESP: it has harnesses for
+// native Go fuzz targets that check the invariants of the resp package's
+// Reader, a Generator of structurally valid and near-valid RESP to seed their
+// corpora, and checks that Codecs converting between RESP and Go values round
+// trip. The Generator and Seed can be used to fuzz any parser, proxy, or
+// server built on the resp package:
+//
+//	func FuzzProxy(f *testing.F) {
+//		respfuzz.Seed(f, 1, 200)
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			...
+//		})
+//	}
+package respfuzz
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strconv"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stvp/resp"
+)
+
+const (
+	// DEFAULT_MAX_DEPTH is the deepest a Generator nests aggregates if
+	// its MaxDepth is 0.
+	DEFAULT_MAX_DEPTH = 3
+	// DEFAULT_MAX_LENGTH is the largest number of elements or bytes a
+	// Generator puts in an object if its MaxLength is 0.
+	DEFAULT_MAX_LENGTH = 8
+)
+
+// prefixes are the types of objects a Generator generates. The RESP2 types
+// come first.
+var prefixes = []byte{
+	resp.SIMPLE_STRING_PREFIX, resp.ERROR_PREFIX, resp.INTEGER_PREFIX, resp.BULK_STRING_PREFIX, resp.ARRAY_PREFIX,
+	resp.NULL_PREFIX, resp.BOOLEAN_PREFIX, resp.DOUBLE_PREFIX, resp.BIG_NUMBER_PREFIX, resp.VERBATIM_STRING_PREFIX,
+	resp.BLOB_ERROR_PREFIX, resp.MAP_PREFIX, resp.SET_PREFIX, resp.PUSH_PREFIX, resp.ATTRIBUTE_PREFIX,
+}
+
+const resp2Prefixes = 5
+
+// A Generator generates random RESP. Its output is determined by its seed.
+// It's not safe for concurrent use.
+type Generator struct {
+	// MaxDepth is the deepest aggregates are nested, DEFAULT_MAX_DEPTH if
+	// it's 0.
+	MaxDepth int
+	// MaxLength is the largest number of elements in an aggregate, and of
+	// bytes in a string, DEFAULT_MAX_LENGTH if it's 0.
+	MaxLength int
+	// RESP2, if set, limits objects to the RESP2 types.
+	RESP2 bool
+
+	rand *rand.Rand
+}
+
+// NewGenerator returns a Generator seeded with seed.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Valid returns a valid RESP object.
+func (g *Generator) Valid() []byte {
+	return g.appendObject(nil, 0)
+}
+
+// Command returns a valid command: an array of bulk strings, the first a name
+// of four or more characters, which resp.ParseCommand requires of commands
+// without arguments.
+func (g *Generator) Command() []byte {
+	args := make([]string, 1+g.rand.Intn(g.maxLength()))
+	args[0] = string(append([]byte("cmd_"), g.bytes(false)...))
+	for i := 1; i < len(args); i++ {
+		args[i] = string(g.bytes(true))
+	}
+	return resp.NewCommand(args...)
+}
+
+// NearValid returns a valid object or command with a single mutation that
+// usually, but not always, makes it invalid or incomplete: a byte changed,
+// removed, or inserted, a "\r\n" replaced with "\n", a length made wrong, or
+// the end cut off.
+func (g *Generator) NearValid() []byte {
+	var b []byte
+	if g.rand.Intn(4) == 0 {
+		b = g.Command()
+	} else {
+		b = g.Valid()
+	}
+
+	i := g.rand.Intn(len(b))
+	switch g.rand.Intn(6) {
+	case 0:
+		b[i] = byte(g.rand.Intn(256))
+	case 1:
+		b = append(b[:i], b[i+1:]...)
+	case 2:
+		b = append(b[:i], append([]byte{"\r\n:$*%0-"[g.rand.Intn(8)]}, b[i:]...)...)
+	case 3:
+		if j := bytes.Index(b[i:], []byte("\r\n")); j >= 0 {
+			b = append(b[:i+j], b[i+j+1:]...)
+		}
+	case 4:
+		// Lengths follow the prefix of aggregates and bulk strings
+		for j := i; j < len(b); j++ {
+			if b[j] >= '0' && b[j] <= '9' && j > 0 && bytes.IndexByte([]byte("$*%~>|=!"), b[j-1]) >= 0 {
+				b[j] = "0123456789"[g.rand.Intn(10)]
+				break
+			}
+		}
+	default:
+		b = b[:i]
+	}
+	return b
+}
+
+// Corpus returns n inputs: valid objects, commands, near-valid inputs, and
+// pipelines of several of them.
+func (g *Generator) Corpus(n int) [][]byte {
+	corpus := make([][]byte, n)
+	for i := range corpus {
+		switch i % 4 {
+		case 0:
+			corpus[i] = g.Valid()
+		case 1:
+			corpus[i] = g.Command()
+		case 2:
+			corpus[i] = g.NearValid()
+		default:
+			corpus[i] = append(g.Valid(), g.Valid()...)
+			corpus[i] = append(corpus[i], g.NearValid()...)
+		}
+	}
+	return corpus
+}
+
+// Seed adds n inputs generated by a Generator seeded with seed to f's seed
+// corpus.
+func Seed(f *testing.F, seed int64, n int) {
+	for _, input := range NewGenerator(seed).Corpus(n) {
+		f.Add(input)
+	}
+}
+
+func (g *Generator) maxLength() int {
+	if g.MaxLength > 0 {
+		return g.MaxLength
+	}
+	return DEFAULT_MAX_LENGTH
+}
+
+func (g *Generator) maxDepth() int {
+	if g.MaxDepth > 0 {
+		return g.MaxDepth
+	}
+	return DEFAULT_MAX_DEPTH
+}
+
+// bytes returns a random string, which may hold any byte if binary is set,
+// and otherwise no '\r' or '\n'.
+func (g *Generator) bytes(binary bool) []byte {
+	b := make([]byte, g.rand.Intn(g.maxLength()+1))
+	for i := range b {
+		if binary && g.rand.Intn(4) == 0 {
+			b[i] = "\r\n\x00\xff"[g.rand.Intn(4)]
+		} else {
+			b[i] = byte('a' + g.rand.Intn(26))
+		}
+	}
+	return b
+}
+
+func (g *Generator) appendObject(b []byte, depth int) []byte {
+	n := len(prefixes)
+	if g.RESP2 {
+		n = resp2Prefixes
+	}
+	prefix := prefixes[g.rand.Intn(n)]
+	if depth >= g.maxDepth() {
+		// Only simple types
+		for isAggregate(prefix) {
+			prefix = prefixes[g.rand.Intn(n)]
+		}
+	}
+
+	b = append(b, prefix)
+	switch prefix {
+	case resp.SIMPLE_STRING_PREFIX, resp.ERROR_PREFIX:
+		// The resp package rejects empty simple strings
+		b = append(b, byte('A'+g.rand.Intn(26)))
+		b = append(b, g.bytes(false)...)
+	case resp.INTEGER_PREFIX:
+		b = resp.AppendInt(b, g.rand.Int63()-g.rand.Int63())
+	case resp.BIG_NUMBER_PREFIX:
+		if g.rand.Intn(2) == 0 {
+			b = append(b, '-')
+		}
+		b = append(b, '1')
+		for i := g.rand.Intn(40); i > 0; i-- {
+			b = append(b, byte('0'+g.rand.Intn(10)))
+		}
+	case resp.NULL_PREFIX:
+	case resp.BOOLEAN_PREFIX:
+		b = append(b, "tf"[g.rand.Intn(2)])
+	case resp.DOUBLE_PREFIX:
+		switch g.rand.Intn(4) {
+		case 0:
+			b = append(b, [...]string{"inf", "-inf", "nan"}[g.rand.Intn(3)]...)
+		case 1:
+			b = strconv.AppendInt(b, g.rand.Int63n(1000), 10)
+		default:
+			b = strconv.AppendFloat(b, g.rand.NormFloat64()*1e6, 'g', -1, 64)
+		}
+	case resp.BULK_STRING_PREFIX, resp.VERBATIM_STRING_PREFIX, resp.BLOB_ERROR_PREFIX:
+		if prefix == resp.BULK_STRING_PREFIX && g.rand.Intn(8) == 0 {
+			return append(b, "-1\r\n"...)
+		}
+		s := g.bytes(true)
+		if prefix == resp.VERBATIM_STRING_PREFIX {
+			s = append([]byte("txt:"), s...)
+		}
+		b = strconv.AppendInt(b, int64(len(s)), 10)
+		b = append(b, "\r\n"...)
+		b = append(b, s...)
+	default:
+		if prefix == resp.ARRAY_PREFIX && g.rand.Intn(8) == 0 {
+			return append(b, "-1\r\n"...)
+		}
+		n := g.rand.Intn(g.maxLength() + 1)
+		b = strconv.AppendInt(b, int64(n), 10)
+		b = append(b, "\r\n"...)
+		if prefix == resp.MAP_PREFIX || prefix == resp.ATTRIBUTE_PREFIX {
+			n *= 2
+		}
+		for i := 0; i < n; i++ {
+			b = g.appendObject(b, depth+1)
+		}
+		return b
+	}
+	return append(b, "\r\n"...)
+}
+
+func isAggregate(prefix byte) bool {
+	switch prefix {
+	case resp.ARRAY_PREFIX, resp.MAP_PREFIX, resp.SET_PREFIX, resp.PUSH_PREFIX, resp.ATTRIBUTE_PREFIX:
+		return true
+	}
+	return false
+}
+
+// CheckReader is a fuzz target for resp.Reader.ReadObjectSlice. It reads data
+// whole and one byte at a time and fails t unless the same objects are read
+// up to the first error, and each of them is read alone again, intact, and,
+// if it's an aggregate, splits into its elements.
+func CheckReader(t *testing.T, data []byte) {
+	whole := readObjects(newReader(bytes.NewReader(data), 0))
+	bytewise := readObjects(newReader(iotest.OneByteReader(bytes.NewReader(data)), 16))
+	compare(t, whole, bytewise)
+
+	for _, obj := range whole.objects {
+		again, err := newReader(bytes.NewReader(obj), 0).ReadObjectSlice()
+		if err != nil || !bytes.Equal(obj, again) {
+			t.Fatalf("%q read again as %q, %v", obj, again, err)
+		}
+		if aggregate, ok := resp.Parse(obj).(interface {
+			Objects() ([]resp.Object, error)
+		}); ok {
+			if _, err := aggregate.Objects(); err != nil {
+				t.Fatalf("%q: %v", obj, err)
+			}
+		}
+	}
+}
+
+// CheckCommandReader is a fuzz target for resp.Reader.ReadCommand, with
+// inline commands allowed. It reads data whole and one byte at a time and
+// fails t unless the same commands are read up to the first error, and each
+// of them, inline commands included, is read back as a valid array.
+func CheckCommandReader(t *testing.T, data []byte) {
+	whole := readCommands(newReader(bytes.NewReader(data), 0))
+	bytewise := readCommands(newReader(iotest.OneByteReader(bytes.NewReader(data)), 16))
+	compare(t, whole, bytewise)
+
+	for _, cmd := range whole.objects {
+		obj, err := newReader(bytes.NewReader(cmd), 0).ReadObject()
+		if _, ok := obj.(resp.Array); !ok || err != nil || !bytes.Equal(cmd, obj.Raw()) {
+			t.Fatalf("%q read back as %q, %v", cmd, obj, err)
+		}
+	}
+}
+
+// newReader returns a Reader with a buffer of the given size, or the default
+// size if it's 0, that grows to fit objects of up to 1MB.
+func newReader(rd io.Reader, size int) *resp.Reader {
+	r := resp.NewReaderSize(rd, size)
+	r.SetMaxSize(1 << 20)
+	return r
+}
+
+// A result is what was read from an input: the objects up to the first
+// error, and the error.
+type result struct {
+	objects [][]byte
+	err     error
+}
+
+func readObjects(r *resp.Reader) result {
+	var res result
+	for {
+		obj, err := r.ReadObjectSlice()
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.objects = append(res.objects, append([]byte(nil), obj...))
+	}
+}
+
+func readCommands(r *resp.Reader) result {
+	r.SetInline(true)
+	var res result
+	for {
+		cmd, err := r.ReadCommand()
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.objects = append(res.objects, append([]byte(nil), cmd...))
+	}
+}
+
+func compare(t *testing.T, whole, bytewise result) {
+	if len(whole.objects) != len(bytewise.objects) {
+		t.Fatalf("read %d objects whole and %d one byte at a time: %q and %q", len(whole.objects), len(bytewise.objects), whole.objects, bytewise.objects)
+	}
+	for i := range whole.objects {
+		if !bytes.Equal(whole.objects[i], bytewise.objects[i]) {
+			t.Fatalf("read %q whole and %q one byte at a time", whole.objects[i], bytewise.objects[i])
+		}
+	}
+	if (whole.err == io.EOF) != (bytewise.err == io.EOF) {
+		t.Fatalf("got %v reading whole and %v one byte at a time", whole.err, bytewise.err)
+	}
+}
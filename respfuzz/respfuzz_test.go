@@ -0,0 +1,60 @@
+package respfuzz
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stvp/resp"
+)
+
+func TestGenerator(t *testing.T) {
+	g := NewGenerator(1)
+	for i := 0; i < 1000; i++ {
+		b := g.Valid()
+		obj, err := resp.NewReader(bytes.NewReader(b)).ReadObjectSlice()
+		if err != nil || !bytes.Equal(b, obj) {
+			t.Fatalf("expected %q to be valid, read %q, %v", b, obj, err)
+		}
+
+		cmd := g.Command()
+		if _, _, err := resp.ParseCommand(resp.Command(cmd)); err != nil {
+			t.Fatalf("expected %q to be a valid command: %v", cmd, err)
+		}
+	}
+
+	g = NewGenerator(1)
+	g.RESP2 = true
+	g.MaxDepth = 1
+	for i := 0; i < 100; i++ {
+		b := g.Valid()
+		if bytes.IndexByte([]byte("+-:$*"), b[0]) < 0 {
+			t.Fatalf("expected a RESP2 object, got %q", b)
+		}
+	}
+
+	invalid := 0
+	for i := 0; i < 1000; i++ {
+		b := g.NearValid()
+		if _, err := resp.NewReader(bytes.NewReader(b)).ReadObjectSlice(); err != nil {
+			invalid++
+		}
+	}
+	if invalid < 500 {
+		t.Errorf("expected most near-valid inputs to be invalid, got %d of 1000", invalid)
+	}
+
+	if a, b := NewGenerator(2).Corpus(20), NewGenerator(2).Corpus(20); !reflect.DeepEqual(a, b) {
+		t.Errorf("expected the same seed to generate the same corpus")
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	Seed(f, 1, 200)
+	f.Fuzz(CheckReader)
+}
+
+func FuzzCommandReader(f *testing.F) {
+	Seed(f, 2, 200)
+	f.Fuzz(CheckCommandReader)
+}
@@ -0,0 +1,125 @@
+package respfuzz
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/stvp/resp"
+)
+
+// A Codec converts between RESP and the Go values some code represents it
+// with, e.g. a custom marshaler, or a layer transcoding between RESP and
+// another format. CheckEncodeDecode and CheckDecodeEncode test that the
+// conversions are inverses.
+type Codec struct {
+	// Encode returns the RESP encoding of v.
+	Encode func(v interface{}) ([]byte, error)
+	// Decode returns the value of the RESP object b.
+	Decode func(b []byte) (interface{}, error)
+}
+
+// ObjectCodec represents RESP objects as resp.Objects. It's the identity on
+// valid RESP, and is mostly useful for comparison with other Codecs.
+var ObjectCodec = Codec{
+	Encode: func(v interface{}) ([]byte, error) {
+		return v.(resp.Object).Raw(), nil
+	},
+	Decode: func(b []byte) (interface{}, error) {
+		return resp.NewReader(bytes.NewReader(b)).ReadObject()
+	},
+}
+
+// CheckEncodeDecode fails t unless encoding the decoded value of each input
+// reproduces the input exactly.
+func CheckEncodeDecode(t testing.TB, c Codec, inputs [][]byte) {
+	t.Helper()
+	for _, input := range inputs {
+		v, err := c.Decode(input)
+		if err != nil {
+			t.Errorf("decoding %q: %v", input, err)
+			continue
+		}
+		b, err := c.Encode(v)
+		if err != nil {
+			t.Errorf("encoding %#v, decoded from %q: %v", v, input, err)
+			continue
+		}
+		if !bytes.Equal(input, b) {
+			t.Errorf("%q was decoded to %#v and encoded as %q", input, v, b)
+		}
+	}
+}
+
+// CheckDecodeEncode fails t unless decoding the encoding of each value
+// returns a value deeply equal to it.
+func CheckDecodeEncode(t testing.TB, c Codec, values []interface{}) {
+	t.Helper()
+	for _, v := range values {
+		b, err := c.Encode(v)
+		if err != nil {
+			t.Errorf("encoding %#v: %v", v, err)
+			continue
+		}
+		decoded, err := c.Decode(b)
+		if err != nil {
+			t.Errorf("decoding %q, encoded from %#v: %v", b, v, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, decoded) {
+			t.Errorf("%#v was encoded as %q and decoded to %#v", v, b, decoded)
+		}
+	}
+}
+
+// Value returns a random Go value with a natural RESP representation: nil,
+// a bool, an int64, a float64 other than NaN, a string of any bytes, or a
+// []interface{} of such values. If the Generator isn't limited to RESP2, it
+// may also be a map[string]interface{}.
+func (g *Generator) Value() interface{} {
+	return g.value(0)
+}
+
+// Values returns n random values from Value.
+func (g *Generator) Values(n int) []interface{} {
+	values := make([]interface{}, n)
+	for i := range values {
+		values[i] = g.Value()
+	}
+	return values
+}
+
+func (g *Generator) value(depth int) interface{} {
+	kinds := 7
+	if g.RESP2 {
+		kinds = 6
+	}
+	if depth >= g.maxDepth() {
+		kinds = 5
+	}
+
+	switch g.rand.Intn(kinds) {
+	case 0:
+		return nil
+	case 1:
+		return g.rand.Intn(2) == 0
+	case 2:
+		return g.rand.Int63() - g.rand.Int63()
+	case 3:
+		return g.rand.NormFloat64() * 1e6
+	case 4:
+		return string(g.bytes(true))
+	case 5:
+		values := make([]interface{}, g.rand.Intn(g.maxLength()+1))
+		for i := range values {
+			values[i] = g.value(depth + 1)
+		}
+		return values
+	default:
+		values := make(map[string]interface{})
+		for i := g.rand.Intn(g.maxLength() + 1); i > 0; i-- {
+			values[string(g.bytes(true))] = g.value(depth + 1)
+		}
+		return values
+	}
+}
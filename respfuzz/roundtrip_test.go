@@ -0,0 +1,154 @@
+package respfuzz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stvp/resp"
+)
+
+// valueCodec encodes the values generated by Generator.Value as RESP3.
+var valueCodec = Codec{Encode: encodeValue, Decode: decodeValue}
+
+func encodeValue(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return []byte("_\r\n"), nil
+	case bool:
+		return resp.NewBoolean(v), nil
+	case int64:
+		return resp.NewInteger(v), nil
+	case float64:
+		return resp.NewDouble(v), nil
+	case string:
+		return resp.NewBulkString(v), nil
+	case []interface{}:
+		objects := make([]resp.Object, len(v))
+		for i, value := range v {
+			b, err := encodeValue(value)
+			if err != nil {
+				return nil, err
+			}
+			objects[i] = resp.Parse(b)
+		}
+		return resp.NewArray(objects...), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var pairs []resp.Object
+		for _, key := range keys {
+			b, err := encodeValue(v[key])
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, resp.NewBulkString(key), resp.Parse(b))
+		}
+		return resp.NewMap(pairs...), nil
+	}
+	return nil, fmt.Errorf("can't encode %T", v)
+}
+
+func decodeValue(b []byte) (interface{}, error) {
+	obj, err := resp.NewReader(bytes.NewReader(b)).ReadObject()
+	if err != nil {
+		return nil, err
+	}
+	return decodeObject(obj)
+}
+
+func decodeObject(obj resp.Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case resp.Null:
+		return nil, nil
+	case resp.Boolean:
+		return obj.Bool()
+	case resp.Integer:
+		return obj.Int64()
+	case resp.Double:
+		return obj.Float64()
+	case resp.String:
+		return string(obj.Bytes()), nil
+	case resp.Array:
+		objects, err := obj.Objects()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, len(objects))
+		for i, o := range objects {
+			if values[i], err = decodeObject(o); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case resp.Map:
+		objects, err := obj.Objects()
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{})
+		for i := 0; i < len(objects); i += 2 {
+			key, ok := objects[i].(resp.String)
+			if !ok {
+				return nil, errors.New("map key isn't a string")
+			}
+			if values[string(key.Bytes())], err = decodeObject(objects[i+1]); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("can't decode %q", obj.Raw())
+}
+
+// recorder records the failures of a check instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestCheckEncodeDecode(t *testing.T) {
+	g := NewGenerator(1)
+	inputs := make([][]byte, 1000)
+	for i := range inputs {
+		inputs[i] = g.Valid()
+	}
+	CheckEncodeDecode(t, ObjectCodec, inputs)
+
+	// Not every value has a single encoding
+	r := &recorder{TB: t}
+	CheckEncodeDecode(r, valueCodec, [][]byte{[]byte(":1\r\n"), []byte("+OK\r\n"), []byte(",1.50\r\n")})
+	if len(r.errors) != 2 || !strings.Contains(r.errors[0], `"+OK\r\n" was decoded to "OK" and encoded as "$2\r\nOK\r\n"`) {
+		t.Errorf("expected 2 failures, got %q", r.errors)
+	}
+}
+
+func TestCheckDecodeEncode(t *testing.T) {
+	CheckDecodeEncode(t, valueCodec, NewGenerator(1).Values(1000))
+
+	r := &recorder{TB: t}
+	CheckDecodeEncode(r, valueCodec, []interface{}{int64(1), 1, []interface{}{uint8(2)}})
+	if len(r.errors) != 2 || !strings.Contains(r.errors[0], "can't encode int") {
+		t.Errorf("expected 2 failures, got %q", r.errors)
+	}
+
+	g := NewGenerator(2)
+	g.RESP2 = true
+	for _, v := range g.Values(1000) {
+		if _, ok := v.(map[string]interface{}); ok {
+			t.Fatalf("expected no maps in RESP2 values, got %#v", v)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package resptest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A Fixture is a named protocol test case, such as a command and the reply
+// expected for it, loaded from a file with LoadFixtures. Fixture files are
+// meant to be written and read by people, unlike Go string literals full of
+// "\r\n". They look like this:
+//
+//	# Comments start with "# "
+//	=== get
+//	--- command
+//	*2
+//	$3
+//	GET
+//	$3
+//	foo
+//	--- reply
+//	$11
+//	hello\x00world
+//
+// Each "=== name" line starts a fixture and each "--- name" line a section of
+// it. The lines of a section are its data, each followed by "\r\n", unless it
+// ends with a lone backslash, which is removed. Lines may contain the escapes
+// \r, \n, \t, \\, and \xHH, for any byte.
+type Fixture struct {
+	Name     string
+	Sections []Section
+	// Line is the line of the file the fixture starts on.
+	Line int
+}
+
+// A Section is some data of a Fixture.
+type Section struct {
+	Name string
+	Data []byte
+}
+
+// Get returns the data of the named section, or nil if there's none.
+func (f *Fixture) Get(section string) []byte {
+	for _, s := range f.Sections {
+		if s.Name == section {
+			return s.Data
+		}
+	}
+	return nil
+}
+
+// LoadFixtures reads the fixtures in the file at path.
+func LoadFixtures(path string) ([]Fixture, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fixtures, err := ParseFixtures(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%v", path, err)
+	}
+	return fixtures, nil
+}
+
+// ParseFixtures parses fixtures in the format described by Fixture.
+func ParseFixtures(b []byte) ([]Fixture, error) {
+	var fixtures []Fixture
+	var section *Section
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, len(b)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "=== "):
+			fixtures = append(fixtures, Fixture{Name: line[4:], Line: n})
+			section = nil
+		case strings.HasPrefix(line, "--- "):
+			if len(fixtures) == 0 {
+				return nil, fmt.Errorf("%d: section outside of a fixture", n)
+			}
+			f := &fixtures[len(fixtures)-1]
+			f.Sections = append(f.Sections, Section{Name: line[4:], Data: []byte{}})
+			section = &f.Sections[len(f.Sections)-1]
+		case strings.HasPrefix(line, "# ") || line == "#":
+		case section == nil:
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("%d: data outside of a section", n)
+			}
+		default:
+			data, err := unescapeLine(section.Data, line)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %v", n, err)
+			}
+			section.Data = data
+		}
+	}
+	return fixtures, scanner.Err()
+}
+
+// unescapeLine appends the data of a section's line to dst.
+func unescapeLine(dst []byte, line string) ([]byte, error) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c != '\\' {
+			dst = append(dst, c)
+			continue
+		}
+		i++
+		if i == len(line) {
+			// A lone backslash at the end of the line
+			return dst, nil
+		}
+		switch line[i] {
+		case 'r':
+			dst = append(dst, '\r')
+		case 'n':
+			dst = append(dst, '\n')
+		case 't':
+			dst = append(dst, '\t')
+		case '\\':
+			dst = append(dst, '\\')
+		case 'x':
+			if i+3 > len(line) {
+				return nil, fmt.Errorf("invalid escape %q", line[i-1:])
+			}
+			b, err := strconv.ParseUint(line[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape %q", line[i-1:i+3])
+			}
+			dst = append(dst, byte(b))
+			i += 2
+		default:
+			return nil, fmt.Errorf("invalid escape %q", line[i-1:i+1])
+		}
+	}
+	return append(dst, "\r\n"...), nil
+}
+
+// WriteFixtures writes fixtures to w in the format ParseFixtures reads.
+func WriteFixtures(w io.Writer, fixtures []Fixture) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range fixtures {
+		fmt.Fprintf(bw, "=== %s\n", f.Name)
+		for _, s := range f.Sections {
+			fmt.Fprintf(bw, "--- %s\n", s.Name)
+			writeSectionData(bw, s.Data)
+		}
+	}
+	return bw.Flush()
+}
+
+// writeSectionData writes data as lines, split after each "\r\n".
+func writeSectionData(w *bufio.Writer, data []byte) {
+	for len(data) > 0 {
+		line := data
+		terminated := false
+		if i := bytes.Index(data, []byte("\r\n")); i >= 0 {
+			line = data[:i]
+			terminated = true
+		}
+
+		for i, c := range line {
+			switch {
+			case c == '\\':
+				w.WriteString(`\\`)
+			case c == '\r':
+				w.WriteString(`\r`)
+			case c == '\n':
+				w.WriteString(`\n`)
+			case c == '\t':
+				w.WriteString(`\t`)
+			case c < ' ' || c > '~' || i == 0 && isMarker(line):
+				fmt.Fprintf(w, `\x%02x`, c)
+			default:
+				w.WriteByte(c)
+			}
+		}
+		if terminated {
+			data = data[len(line)+2:]
+		} else {
+			w.WriteByte('\\')
+			data = nil
+		}
+		w.WriteByte('\n')
+	}
+}
+
+// isMarker returns true if a line of data would be read as a marker or a
+// comment unless its first byte is escaped.
+func isMarker(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("=== ")) || bytes.HasPrefix(line, []byte("--- ")) ||
+		bytes.HasPrefix(line, []byte("# ")) || string(line) == "#"
+}
@@ -0,0 +1,109 @@
+package resptest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stvp/resp"
+)
+
+const fixtureFile = `# Comments start with "# "
+=== get
+--- command
+*2
+$3
+GET
+$3
+foo
+--- reply
+$11
+hello\x00world
+
+=== partial
+# A reply cut off mid-line
+--- reply
+$5
+he\
+`
+
+func TestParseFixtures(t *testing.T) {
+	fixtures, err := ParseFixtures([]byte(fixtureFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Fixture{
+		{Name: "get", Line: 2, Sections: []Section{
+			{"command", resp.NewCommand("GET", "foo")},
+			{"reply", []byte("$11\r\nhello\x00world\r\n\r\n")},
+		}},
+		{Name: "partial", Line: 13, Sections: []Section{
+			{"reply", []byte("$5\r\nhe")},
+		}},
+	}
+	if !reflect.DeepEqual(expected, fixtures) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", expected, fixtures)
+	}
+	if reply := fixtures[1].Get("reply"); string(reply) != "$5\r\nhe" {
+		t.Errorf("unexpected reply %q", reply)
+	}
+	if command := fixtures[1].Get("command"); command != nil {
+		t.Errorf("expected no command, got %q", command)
+	}
+}
+
+func TestParseFixtures_Errors(t *testing.T) {
+	tests := map[string]string{
+		"--- reply\n":             "1: section outside of a fixture",
+		"=== a\n+OK\n":            "2: data outside of a section",
+		"=== a\n--- b\n\\q\n":     `3: invalid escape "\\q"`,
+		"=== a\n--- b\nab\\x4\n":  `3: invalid escape "\\x4"`,
+		"=== a\n--- b\nab\\xzz\n": `3: invalid escape "\\xzz"`,
+	}
+	for input, expected := range tests {
+		if _, err := ParseFixtures([]byte(input)); err == nil || err.Error() != expected {
+			t.Errorf("%q: expected %q, got %v", input, expected, err)
+		}
+	}
+}
+
+func TestWriteFixtures(t *testing.T) {
+	fixtures := []Fixture{
+		{Name: "escapes", Sections: []Section{
+			{"empty", []byte{}},
+			{"binary", []byte("$6\r\n\x00\xff\\\t\r\n\r\n")},
+			{"markers", []byte("--- a\r\n=== b\r\n# c\r\n#\r\n#t\r\n")},
+			{"bare", []byte("a\rb\nc\r")},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := WriteFixtures(&buf, fixtures); err != nil {
+		t.Fatal(err)
+	}
+	expected := "=== escapes\n--- empty\n--- binary\n$6\n\\x00\\xff\\\\\\t\n\n" +
+		"--- markers\n\\x2d-- a\n\\x3d== b\n\\x23 c\n\\x23\n#t\n--- bare\na\\rb\\nc\\r\\\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "fixtures.txt")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures[0].Line = 1
+	if !reflect.DeepEqual(fixtures, loaded) {
+		t.Errorf("expected:\n%+v\ngot:\n%+v", fixtures, loaded)
+	}
+
+	os.WriteFile(path, []byte("+OK\n"), 0644)
+	if _, err := LoadFixtures(path); err == nil || !strings.HasSuffix(err.Error(), "fixtures.txt:1: data outside of a section") {
+		t.Errorf("expected the path and line in the error, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+// Package resptest provides a scriptable mock Redis server for testing RESP
+// clients, and a readable file format for protocol test fixtures.
+package resptest
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stvp/resp"
+)
+
+// A Fault disrupts the reply to a command. See Server.Inject.
+type Fault struct {
+	// Delay is how long to wait before replying.
+	Delay time.Duration
+	// Garbage, if set, is written instead of the reply, e.g. "?\r\n" to
+	// cause a protocol error in the client.
+	Garbage []byte
+	// Partial, if positive, is the number of bytes of the reply to write
+	// before closing the connection.
+	Partial int
+	// Close closes the connection instead of replying.
+	Close bool
+}
+
+// A Server is a mock Redis server listening on a local port. Commands are
+// answered with the replies registered for them with Reply, or by the
+// Handlers registered with Handle, and other commands are answered with the
+// unknown command error Redis sends. Every command received is recorded, and
+// replies can be disrupted with Inject. Inline commands are accepted too.
+type Server struct {
+	// Addr is the address the Server listens on, e.g. "127.0.0.1:50000".
+	Addr string
+
+	listener net.Listener
+
+	mu       sync.Mutex
+	replies  map[string][]resp.Object
+	handlers map[string]resp.Handler
+	faults   map[string][]Fault
+	commands []resp.Command
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a Server on a random local port. It panics if it can't
+// listen. The Server should be closed with Close.
+func NewServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("resptest: failed to listen: %v", err))
+	}
+	s := &Server{
+		Addr:     l.Addr().String(),
+		listener: l,
+		replies:  map[string][]resp.Object{},
+		handlers: map[string]resp.Handler{},
+		faults:   map[string][]Fault{},
+		conns:    map[net.Conn]struct{}{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Reply registers replies for the named command. Each command received
+// consumes one reply, and the last one is repeated. Reply replaces the
+// replies and Handler already registered for the command.
+func (s *Server) Reply(name string, replies ...resp.Object) {
+	name = strings.ToUpper(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.handlers, name)
+	s.replies[name] = replies
+}
+
+// Handle registers handler for the named command, replacing the replies and
+// Handler already registered for it. The handler can write any number of
+// replies.
+func (s *Server) Handle(name string, handler resp.Handler) {
+	name = strings.ToUpper(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.replies, name)
+	s.handlers[name] = handler
+}
+
+// HandleFunc registers handler for the named command.
+func (s *Server) HandleFunc(name string, handler func(w *resp.Writer, cmd resp.Command)) {
+	s.Handle(name, resp.HandlerFunc(handler))
+}
+
+// Inject disrupts the reply to the next command with the given name, or to
+// the next command of any name if name is "". Faults injected for the same
+// name are applied to successive commands.
+func (s *Server) Inject(name string, fault Fault) {
+	name = strings.ToUpper(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[name] = append(s.faults[name], fault)
+}
+
+// Commands returns the commands received so far, in order.
+func (s *Server) Commands() []resp.Command {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	commands := make([]resp.Command, len(s.commands))
+	copy(commands, s.commands)
+	return commands
+}
+
+// Reset forgets the commands received so far, the registered replies and
+// Handlers, and the pending faults.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = map[string][]resp.Object{}
+	s.handlers = map[string]resp.Handler{}
+	s.faults = map[string][]Fault{}
+	s.commands = nil
+}
+
+// CloseClientConnections closes the connections of all clients, which can
+// then reconnect.
+func (s *Server) CloseClientConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the Server, closes all connections, and waits for them to be
+// done.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.listener.Close()
+	s.CloseClientConnections()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	r := resp.NewReader(conn)
+	r.SetMaxSize(resp.DEFAULT_MAX_BUFFER)
+	r.SetInline(true)
+	for {
+		cmd, err := r.ReadCommand()
+		if err != nil {
+			return
+		}
+		reply, fault, faulted := s.handle(cmd)
+
+		if !faulted {
+			if _, err := conn.Write(reply); err != nil {
+				return
+			}
+			continue
+		}
+		time.Sleep(fault.Delay)
+		switch {
+		case fault.Close:
+			return
+		case fault.Garbage != nil:
+			reply = fault.Garbage
+		case fault.Partial > 0:
+			if fault.Partial < len(reply) {
+				conn.Write(reply[:fault.Partial])
+				return
+			}
+		}
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// handle records cmd and returns the reply to it and the fault to apply, if
+// any.
+func (s *Server) handle(cmd resp.Command) ([]byte, Fault, bool) {
+	name, args, _ := resp.ParseCommand(cmd)
+	upper := strings.ToUpper(name)
+
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	fault, faulted := s.nextFault(upper)
+	handler := s.handlers[upper]
+	replies := s.replies[upper]
+	var reply resp.Object
+	if len(replies) > 0 {
+		reply = replies[0]
+		if len(replies) > 1 {
+			s.replies[upper] = replies[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	if handler != nil {
+		var buf bytes.Buffer
+		w := resp.NewWriter(&buf)
+		handler.ServeRESP(w, cmd)
+		w.Flush()
+		return buf.Bytes(), fault, faulted
+	}
+	if reply == nil {
+		reply = unknownCommandError(name, args)
+	}
+	return reply.Raw(), fault, faulted
+}
+
+// nextFault removes and returns the next fault for the named command. s.mu
+// must be held.
+func (s *Server) nextFault(name string) (Fault, bool) {
+	for _, key := range []string{name, ""} {
+		if faults := s.faults[key]; len(faults) > 0 {
+			s.faults[key] = faults[1:]
+			return faults[0], true
+		}
+	}
+	return Fault{}, false
+}
+
+// unknownCommandError returns the error Redis sends for unknown commands.
+func unknownCommandError(name string, args [][]byte) resp.Error {
+	msg := fmt.Sprintf("ERR unknown command '%s', with args beginning with: ", name)
+	for _, arg := range args {
+		msg += fmt.Sprintf("'%s' ", arg)
+	}
+	return resp.NewError(msg)
+}
@@ -0,0 +1,109 @@
+package resptest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stvp/resp"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Reply("get", resp.NewBulkString("a"), resp.NewBulkString("b"))
+	s.HandleFunc("MGET", func(w *resp.Writer, cmd resp.Command) {
+		_, args, _ := resp.ParseCommand(cmd)
+		w.WriteObject(resp.NewInteger(int64(len(args))))
+	})
+
+	conn, err := resp.Dial("tcp", s.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	replies, err := conn.DoPipeline([]resp.Command{
+		resp.NewCommand("GET", "k"),
+		resp.NewCommand("GET", "k"),
+		resp.NewCommand("GET", "k"),
+		resp.NewCommand("MGET", "k1", "k2"),
+		resp.NewCommand("NOPE", "x"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []resp.Object{
+		resp.NewBulkString("a"),
+		resp.NewBulkString("b"),
+		resp.NewBulkString("b"),
+		resp.NewInteger(2),
+		resp.NewError("ERR unknown command 'NOPE', with args beginning with: 'x' "),
+	}
+	if !reflect.DeepEqual(expected, replies) {
+		t.Errorf("expected %q, got %q", expected, replies)
+	}
+	if commands := s.Commands(); len(commands) != 5 || !reflect.DeepEqual(resp.NewCommand("MGET", "k1", "k2"), commands[3]) {
+		t.Errorf("unexpected commands: %q", commands)
+	}
+
+	// Commands larger than the default buffer
+	reply, err := conn.Do("MGET", strings.Repeat("x", 20<<10))
+	if err != nil || !reflect.DeepEqual(resp.NewInteger(1), reply) {
+		t.Errorf("unexpected reply to a large command: %q, %v", reply, err)
+	}
+
+	s.Reset()
+	if commands := s.Commands(); len(commands) != 0 {
+		t.Errorf("expected no commands, got %q", commands)
+	}
+}
+
+func TestServer_Inject(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Reply("PING", resp.PONG)
+
+	dial := func() *resp.Conn {
+		conn, err := resp.Dial("tcp", s.Addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn
+	}
+
+	// Delays
+	conn := dial()
+	s.Inject("ping", Fault{Delay: 50 * time.Millisecond})
+	start := time.Now()
+	if reply, err := conn.Do("PING"); err != nil || string(reply.Raw()) != "+PONG\r\n" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected a delay, got %s", elapsed)
+	}
+
+	// Protocol errors
+	s.Inject("", Fault{Garbage: []byte("?\r\n")})
+	if _, err := conn.Do("PING"); err == nil {
+		t.Errorf("expected an error")
+	}
+	conn.Close()
+
+	// Partial writes and closed connections
+	for _, fault := range []Fault{{Partial: 2}, {Close: true}} {
+		conn = dial()
+		s.Inject("PING", fault)
+		if _, err := conn.Do("PING"); err == nil {
+			t.Errorf("%+v: expected an error", fault)
+		}
+		conn.Close()
+	}
+
+	// Faults are used up
+	conn = dial()
+	defer conn.Close()
+	if reply, err := conn.Do("PING"); err != nil || string(reply.Raw()) != "+PONG\r\n" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+}
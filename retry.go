@@ -0,0 +1,115 @@
+package resp
+
+import (
+	"time"
+)
+
+// A RetryDecision is a RetryPolicy's answer to a failed attempt.
+type RetryDecision struct {
+	Retry bool
+	// Backoff is the time to wait before the next attempt.
+	Backoff time.Duration
+	// Reselect asks for the next attempt to pick its upstream again, e.g.
+	// after refreshing the topology, rather than reuse the one that failed.
+	Reselect bool
+}
+
+// A RetryPolicy decides whether and how to retry a request after an attempt
+// failed with err, which is either an error from reading or writing the
+// upstream connection or a retryable Error reply (see IsRetryable). attempt is
+// 1 for the first attempt.
+type RetryPolicy interface {
+	Retry(attempt int, err error) RetryDecision
+}
+
+// The RetryPolicyFunc type is an adapter to allow the use of ordinary
+// functions as RetryPolicies.
+type RetryPolicyFunc func(attempt int, err error) RetryDecision
+
+// Retry calls f(attempt, err).
+func (f RetryPolicyFunc) Retry(attempt int, err error) RetryDecision {
+	return f(attempt, err)
+}
+
+// NoRetry is a RetryPolicy that never retries.
+var NoRetry RetryPolicy = RetryPolicyFunc(func(int, error) RetryDecision {
+	return RetryDecision{}
+})
+
+// IsRetryable returns true if err is an Error reply that means the request
+// can succeed if it's sent again later: -LOADING, -CLUSTERDOWN, -TRYAGAIN,
+// -MASTERDOWN, or -BUSY.
+func IsRetryable(err error) bool {
+	e, ok := err.(Error)
+	if !ok {
+		return false
+	}
+	switch e.Code() {
+	case "LOADING", "CLUSTERDOWN", "TRYAGAIN", "MASTERDOWN", "BUSY":
+		return true
+	}
+	return false
+}
+
+// An ExponentialBackoff is a RetryPolicy that retries up to MaxAttempts times
+// in total, doubling the backoff from Base up to Max after every attempt.
+// Upstreams are reselected after connection errors and -LOADING,
+// -CLUSTERDOWN, and -MASTERDOWN replies, since those mean the upstream can't
+// serve the request for a while.
+type ExponentialBackoff struct {
+	MaxAttempts int
+	Base        time.Duration
+	Max         time.Duration
+}
+
+// Retry implements RetryPolicy.
+func (b ExponentialBackoff) Retry(attempt int, err error) RetryDecision {
+	if attempt >= b.MaxAttempts {
+		return RetryDecision{}
+	}
+
+	backoff := b.Base
+	for i := 1; i < attempt && (b.Max <= 0 || backoff < b.Max); i++ {
+		backoff *= 2
+	}
+	if b.Max > 0 && backoff > b.Max {
+		backoff = b.Max
+	}
+
+	reselect := true
+	if e, ok := err.(Error); ok {
+		code := e.Code()
+		reselect = code == "LOADING" || code == "CLUSTERDOWN" || code == "MASTERDOWN"
+	}
+	return RetryDecision{true, backoff, reselect}
+}
+
+// DoWithRetry calls do until it succeeds or policy gives up, and returns the
+// result of the last call. do is passed the attempt number and whether it
+// should reselect its upstream. An attempt fails if do returns an error or a
+// retryable Error reply; other replies, including other Error replies, are
+// returned as-is.
+func DoWithRetry(policy RetryPolicy, do func(attempt int, reselect bool) (Object, error)) (Object, error) {
+	reselect := false
+	for attempt := 1; ; attempt++ {
+		reply, err := do(attempt, reselect)
+		failure := err
+		if failure == nil {
+			if e, ok := reply.(Error); ok && IsRetryable(e) {
+				failure = e
+			}
+		}
+		if failure == nil {
+			return reply, nil
+		}
+
+		decision := policy.Retry(attempt, failure)
+		if !decision.Retry {
+			return reply, err
+		}
+		if decision.Backoff > 0 {
+			time.Sleep(decision.Backoff)
+		}
+		reselect = decision.Reselect
+	}
+}
@@ -0,0 +1,92 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{NewError("LOADING Redis is loading the dataset in memory"), true},
+		{NewError("CLUSTERDOWN The cluster is down"), true},
+		{NewError("TRYAGAIN Multiple keys request during rehashing"), true},
+		{NewError("ERR unknown command"), false},
+		{NewError("MOVED 1 127.0.0.1:7000"), false},
+		{io.EOF, false},
+	}
+	for _, test := range tests {
+		if IsRetryable(test.err) != test.expected {
+			t.Errorf("%v: expected %v", test.err, test.expected)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	policy := ExponentialBackoff{MaxAttempts: 5, Base: 10 * time.Millisecond, Max: 30 * time.Millisecond}
+	expected := []RetryDecision{
+		{true, 10 * time.Millisecond, true},
+		{true, 20 * time.Millisecond, false},
+		{true, 30 * time.Millisecond, true},
+		{true, 30 * time.Millisecond, true},
+		{},
+	}
+	errs := []error{io.EOF, NewError("TRYAGAIN"), NewError("LOADING"), NewError("CLUSTERDOWN"), io.EOF}
+	for i, err := range errs {
+		if decision := policy.Retry(i+1, err); !reflect.DeepEqual(expected[i], decision) {
+			t.Errorf("attempt %d: expected %v, got %v", i+1, expected[i], decision)
+		}
+	}
+}
+
+func TestDoWithRetry(t *testing.T) {
+	policy := ExponentialBackoff{MaxAttempts: 3}
+
+	var reselects []bool
+	reply, err := DoWithRetry(policy, func(attempt int, reselect bool) (Object, error) {
+		reselects = append(reselects, reselect)
+		switch attempt {
+		case 1:
+			return nil, io.EOF
+		case 2:
+			return NewError("TRYAGAIN"), nil
+		default:
+			return OK, nil
+		}
+	})
+	if err != nil || string(reply.Raw()) != "+OK\r\n" {
+		t.Errorf("unexpected result: %q, %v", reply, err)
+	}
+	if !reflect.DeepEqual([]bool{false, true, false}, reselects) {
+		t.Errorf("unexpected reselects: %v", reselects)
+	}
+
+	// Non-retryable error replies are returned immediately
+	attempts := 0
+	reply, err = DoWithRetry(policy, func(int, bool) (Object, error) {
+		attempts++
+		return NewError("ERR nope"), nil
+	})
+	if attempts != 1 || err != nil || string(reply.Raw()) != "-ERR nope\r\n" {
+		t.Errorf("unexpected result after %d attempts: %q, %v", attempts, reply, err)
+	}
+
+	// The last failure is returned when the policy gives up
+	broken := errors.New("broken")
+	attempts = 0
+	_, err = DoWithRetry(policy, func(int, bool) (Object, error) {
+		attempts++
+		return nil, broken
+	})
+	if attempts != 3 || err != broken {
+		t.Errorf("unexpected result after %d attempts: %v", attempts, err)
+	}
+	if _, err := DoWithRetry(NoRetry, func(int, bool) (Object, error) { return nil, broken }); err != broken {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package resp
+
+import (
+	"strings"
+)
+
+// A Rewriter rewrites commands on the read path, e.g. to prefix keys, rename
+// commands, or inject arguments. Rewrite is given a command's name and its
+// arguments (not including the name) and returns the command that should be
+// used instead. Rewrite must not modify the given arguments in place; they may
+// point into a Reader's buffer. Errors returned by Rewrite are returned to the
+// reader, so returning an Error gives a reply that can be sent to the client.
+type Rewriter interface {
+	Rewrite(name string, args [][]byte) (string, [][]byte, error)
+}
+
+// The RewriterFunc type is an adapter to allow the use of ordinary functions
+// as Rewriters.
+type RewriterFunc func(name string, args [][]byte) (string, [][]byte, error)
+
+// Rewrite calls f(name, args).
+func (f RewriterFunc) Rewrite(name string, args [][]byte) (string, [][]byte, error) {
+	return f(name, args)
+}
+
+// KeyPrefixRewriter returns a Rewriter that prefixes every key of every
+// command with prefix, using ExtractKeys' rules to find the keys. Unknown
+// commands and commands with invalid arguments are passed through unchanged.
+func KeyPrefixRewriter(prefix []byte) Rewriter {
+	return RewriterFunc(func(name string, args [][]byte) (string, [][]byte, error) {
+		indexes, err := keyIndexes(name, args)
+		if err != nil || len(indexes) == 0 {
+			return name, args, nil
+		}
+
+		rewritten := make([][]byte, len(args))
+		copy(rewritten, args)
+		for _, i := range indexes {
+			key := make([]byte, 0, len(prefix)+len(args[i]))
+			key = append(key, prefix...)
+			rewritten[i] = append(key, args[i]...)
+		}
+		return name, rewritten, nil
+	})
+}
+
+// RenameRewriter returns a Rewriter that emulates Redis' rename-command
+// configuration. renames maps original command names to the names clients
+// must use instead. Commands sent with a new name are rewritten to their
+// original name and commands sent with a renamed original name are rejected
+// as unknown commands. Renaming a command to "" disables it.
+func RenameRewriter(renames map[string]string) Rewriter {
+	originals := map[string]string{}
+	renamed := map[string]bool{}
+	for original, name := range renames {
+		renamed[strings.ToUpper(original)] = true
+		if name != "" {
+			originals[strings.ToUpper(name)] = original
+		}
+	}
+
+	return RewriterFunc(func(name string, args [][]byte) (string, [][]byte, error) {
+		upper := strings.ToUpper(name)
+		if original, ok := originals[upper]; ok {
+			return original, args, nil
+		}
+		if renamed[upper] {
+			return "", nil, unknownCommandError(name, args)
+		}
+		return name, args, nil
+	})
+}
+
+// ChainRewriters returns a Rewriter that applies each of the given Rewriters
+// in turn, stopping at the first error.
+func ChainRewriters(rewriters ...Rewriter) Rewriter {
+	return RewriterFunc(func(name string, args [][]byte) (string, [][]byte, error) {
+		var err error
+		for _, rewriter := range rewriters {
+			name, args, err = rewriter.Rewrite(name, args)
+			if err != nil {
+				return name, args, err
+			}
+		}
+		return name, args, nil
+	})
+}
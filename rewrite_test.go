@@ -0,0 +1,97 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+	reader := NewReader(bytes.NewReader([]byte("*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n+OK\r\n")))
+	cmd, err := reader.ReadCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(NewCommand("GET", "foo"), cmd) {
+		t.Errorf("unexpected command: %q", cmd)
+	}
+
+	_, err = reader.ReadCommand()
+	if !errors.Is(err, ErrSyntaxError) {
+		t.Errorf("expected ErrSyntaxError, got %v", err)
+	}
+}
+
+func TestReadCommand_Inline(t *testing.T) {
+	input := "GET foo\r\n\r\n  \n*1\r\n$4\r\nPING\r\nSET k \"a b\"\nECHO 'unbalanced\r\n"
+	reader := NewReader(bytes.NewReader([]byte(input)))
+	reader.SetInline(true)
+	expected := []Command{NewCommand("GET", "foo"), NewCommand("PING"), NewCommand("SET", "k", "a b")}
+	for i, e := range expected {
+		cmd, err := reader.ReadCommand()
+		if err != nil || !reflect.DeepEqual(e, cmd) {
+			t.Errorf("commands[%d]: expected %q, got %q, %v", i, e, cmd, err)
+		}
+	}
+	if _, err := reader.ReadCommand(); !errors.Is(err, ErrUnbalancedQuotes) {
+		t.Errorf("expected ErrUnbalancedQuotes, got %v", err)
+	}
+	if _, err := reader.ReadCommand(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+
+	// Inline commands are rejected by default
+	reader = NewReader(bytes.NewReader([]byte("GET foo\r\n")))
+	if _, err := reader.ReadCommand(); !errors.Is(err, ErrSyntaxError) {
+		t.Errorf("expected ErrSyntaxError, got %v", err)
+	}
+}
+
+type rewriteTest struct {
+	given    Command
+	expected Command
+}
+
+func TestKeyPrefixRewriter(t *testing.T) {
+	tests := []rewriteTest{
+		{NewCommand("GET", "foo"), NewCommand("GET", "app:foo")},
+		{NewCommand("MSET", "a", "1", "b", "2"), NewCommand("MSET", "app:a", "1", "app:b", "2")},
+		{NewCommand("EVAL", "s", "1", "k", "arg"), NewCommand("EVAL", "s", "1", "app:k", "arg")},
+		{NewCommand("PING"), NewCommand("PING")},
+		{NewCommand("NOPE", "x"), NewCommand("NOPE", "x")},
+	}
+
+	for i, test := range tests {
+		reader := NewReader(bytes.NewReader(test.given))
+		reader.SetRewriter(KeyPrefixRewriter([]byte("app:")))
+		cmd, err := reader.ReadCommand()
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err.Error())
+		} else if !reflect.DeepEqual(test.expected, cmd) {
+			t.Errorf("tests[%d]:\nexpected: %q\ngot: %q", i, test.expected, cmd)
+		}
+	}
+}
+
+func TestRenameRewriter(t *testing.T) {
+	rewriter := RenameRewriter(map[string]string{"config": "secretconfig", "FLUSHALL": ""})
+
+	name, _, err := rewriter.Rewrite("SECRETCONFIG", [][]byte{[]byte("GET")})
+	if err != nil || name != "config" {
+		t.Errorf("expected rewrite to config, got %q, %v", name, err)
+	}
+
+	for _, disabled := range []string{"CONFIG", "flushall"} {
+		_, _, err = rewriter.Rewrite(disabled, nil)
+		if _, ok := err.(Error); !ok {
+			t.Errorf("expected an Error reply for %s, got %v", disabled, err)
+		}
+	}
+
+	name, _, err = rewriter.Rewrite("GET", [][]byte{[]byte("foo")})
+	if err != nil || name != "GET" {
+		t.Errorf("expected GET to pass through, got %q, %v", name, err)
+	}
+}
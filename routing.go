@@ -0,0 +1,244 @@
+package resp
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A RoutingTable maps cluster hash slots to the addresses of the nodes that
+// serve them. Lookups are lock-free and can run concurrently with updates,
+// which replace an immutable snapshot of the table. The zero value is an
+// empty table that's ready to use.
+type RoutingTable struct {
+	mu       sync.Mutex // serializes updates
+	snapshot atomic.Value
+}
+
+// routingSnapshot is an immutable version of a RoutingTable.
+type routingSnapshot struct {
+	// slots holds an index into owners for every slot, or -1 if the slot is
+	// unassigned.
+	slots     [SLOT_COUNT]int32
+	owners    []slotOwner
+	overrides map[uint16]string
+}
+
+type slotOwner struct {
+	master   string
+	replicas []string
+}
+
+// NewRoutingTable returns an empty RoutingTable.
+func NewRoutingTable() *RoutingTable {
+	return &RoutingTable{}
+}
+
+func (t *RoutingTable) load() *routingSnapshot {
+	snapshot, _ := t.snapshot.Load().(*routingSnapshot)
+	return snapshot
+}
+
+// Lookup returns the address of the node that should serve requests for slot:
+// its override if it has one and its master otherwise. It returns "" if the
+// slot isn't assigned to a node.
+func (t *RoutingTable) Lookup(slot uint16) string {
+	snapshot := t.load()
+	if snapshot == nil || slot >= SLOT_COUNT {
+		return ""
+	}
+	if len(snapshot.overrides) > 0 {
+		if addr, ok := snapshot.overrides[slot]; ok {
+			return addr
+		}
+	}
+	if i := snapshot.slots[slot]; i >= 0 {
+		return snapshot.owners[i].master
+	}
+	return ""
+}
+
+// LookupKey is the same as Lookup except that it takes a key.
+func (t *RoutingTable) LookupKey(key []byte) string {
+	return t.Lookup(Slot(key))
+}
+
+// Replicas returns the addresses of the replicas of the master serving slot.
+// The returned slice must not be modified.
+func (t *RoutingTable) Replicas(slot uint16) []string {
+	snapshot := t.load()
+	if snapshot == nil || slot >= SLOT_COUNT {
+		return nil
+	}
+	if i := snapshot.slots[slot]; i >= 0 {
+		return snapshot.owners[i].replicas
+	}
+	return nil
+}
+
+// Masters returns the addresses of all masters in the table.
+func (t *RoutingTable) Masters() []string {
+	snapshot := t.load()
+	if snapshot == nil {
+		return nil
+	}
+	masters := make([]string, len(snapshot.owners))
+	for i, owner := range snapshot.owners {
+		masters[i] = owner.master
+	}
+	return masters
+}
+
+// ReplaceSlots replaces the table's slot assignments with the ones from a
+// parsed CLUSTER SLOTS reply. Overrides are kept.
+func (t *RoutingTable) ReplaceSlots(ranges []SlotRange) {
+	t.replace(func(s *routingSnapshot) {
+		owners := map[string]int32{}
+		for _, r := range ranges {
+			master := r.Master.Addr()
+			i, ok := owners[master]
+			if !ok {
+				i = int32(len(s.owners))
+				owners[master] = i
+				replicas := make([]string, len(r.Replicas))
+				for j, replica := range r.Replicas {
+					replicas[j] = replica.Addr()
+				}
+				s.owners = append(s.owners, slotOwner{master, replicas})
+			}
+			for slot := int(r.Start); slot <= int(r.End); slot++ {
+				s.slots[slot] = i
+			}
+		}
+	})
+}
+
+// ReplaceShards replaces the table's slot assignments with the ones from a
+// parsed CLUSTER SHARDS reply. Replicas that aren't online are left out and
+// shards without a master are ignored. Overrides are kept.
+func (t *RoutingTable) ReplaceShards(shards []Shard) {
+	t.replace(func(s *routingSnapshot) {
+		for _, shard := range shards {
+			master, ok := shard.Master()
+			if !ok {
+				continue
+			}
+			owner := slotOwner{master: master.Addr()}
+			for _, node := range shard.Nodes {
+				if node.Role != "master" && (node.Health == "" || node.Health == "online") {
+					owner.replicas = append(owner.replicas, node.Addr())
+				}
+			}
+			i := int32(len(s.owners))
+			s.owners = append(s.owners, owner)
+			for _, span := range shard.Slots {
+				for slot := int(span.Start); slot <= int(span.End); slot++ {
+					s.slots[slot] = i
+				}
+			}
+		}
+	})
+}
+
+// replace builds a new snapshot with no slot assignments, the current
+// overrides, and whatever assignments build makes.
+func (t *RoutingTable) replace(build func(*routingSnapshot)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := &routingSnapshot{}
+	for i := range s.slots {
+		s.slots[i] = -1
+	}
+	if current := t.load(); current != nil {
+		s.overrides = current.overrides
+	}
+	build(s)
+	t.snapshot.Store(s)
+}
+
+// SetSlot assigns a single slot to the master at addr, e.g. after a MOVED
+// redirect. A master left without slots is removed from the table. Slots out
+// of range are ignored.
+func (t *RoutingTable) SetSlot(slot uint16, addr string) {
+	if slot >= SLOT_COUNT {
+		return
+	}
+	t.update(func(s *routingSnapshot) {
+		previous := s.slots[slot]
+		s.slots[slot] = -1
+		for i, owner := range s.owners {
+			if owner.master == addr {
+				s.slots[slot] = int32(i)
+				break
+			}
+		}
+		if s.slots[slot] < 0 {
+			s.owners = append(s.owners, slotOwner{master: addr})
+			s.slots[slot] = int32(len(s.owners) - 1)
+		}
+		if previous >= 0 && previous != s.slots[slot] {
+			s.prune(previous)
+		}
+	})
+}
+
+// prune removes the owner at index i if it has no slots left, keeping the
+// order of the others.
+func (s *routingSnapshot) prune(i int32) {
+	for _, j := range s.slots {
+		if j == i {
+			return
+		}
+	}
+	s.owners = append(s.owners[:i], s.owners[i+1:]...)
+	for slot, j := range s.slots {
+		if j > i {
+			s.slots[slot] = j - 1
+		}
+	}
+}
+
+// SetOverride makes Lookup return addr for slot until the override is
+// cleared, regardless of the slot's assignment. Overrides are meant for slots
+// that are being migrated.
+func (t *RoutingTable) SetOverride(slot uint16, addr string) {
+	t.update(func(s *routingSnapshot) {
+		overrides := make(map[uint16]string, len(s.overrides)+1)
+		for k, v := range s.overrides {
+			overrides[k] = v
+		}
+		overrides[slot] = addr
+		s.overrides = overrides
+	})
+}
+
+// ClearOverride removes the override for slot, if there is one.
+func (t *RoutingTable) ClearOverride(slot uint16) {
+	t.update(func(s *routingSnapshot) {
+		overrides := make(map[uint16]string, len(s.overrides))
+		for k, v := range s.overrides {
+			if k != slot {
+				overrides[k] = v
+			}
+		}
+		s.overrides = overrides
+	})
+}
+
+// update applies change to a copy of the current snapshot.
+func (t *RoutingTable) update(change func(*routingSnapshot)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := &routingSnapshot{}
+	if current := t.load(); current != nil {
+		*s = *current
+		s.owners = append([]slotOwner(nil), current.owners...)
+	} else {
+		for i := range s.slots {
+			s.slots[i] = -1
+		}
+	}
+	change(s)
+	t.snapshot.Store(s)
+}
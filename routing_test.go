@@ -0,0 +1,116 @@
+package resp
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRoutingTable(t *testing.T) {
+	var table RoutingTable
+	if addr := table.Lookup(0); addr != "" {
+		t.Errorf("expected an empty table, got %q", addr)
+	}
+
+	table.ReplaceSlots([]SlotRange{
+		{0, 8191, SlotNode{IP: "10.0.0.1", Port: 7000}, []SlotNode{{IP: "10.0.0.2", Port: 7000}}},
+		{8192, 16383, SlotNode{IP: "10.0.0.3", Port: 7000}, nil},
+	})
+	if addr := table.Lookup(100); addr != "10.0.0.1:7000" {
+		t.Errorf("unexpected address: %q", addr)
+	}
+	if addr := table.LookupKey([]byte("foo")); addr != "10.0.0.3:7000" {
+		t.Errorf("unexpected address: %q", addr)
+	}
+	if replicas := table.Replicas(100); !reflect.DeepEqual([]string{"10.0.0.2:7000"}, replicas) {
+		t.Errorf("unexpected replicas: %v", replicas)
+	}
+	if masters := table.Masters(); !reflect.DeepEqual([]string{"10.0.0.1:7000", "10.0.0.3:7000"}, masters) {
+		t.Errorf("unexpected masters: %v", masters)
+	}
+
+	table.SetOverride(100, "10.0.0.9:7000")
+	table.SetSlot(101, "10.0.0.3:7000")
+	table.SetSlot(20000, "10.0.0.3:7000")
+	if addr := table.Lookup(100); addr != "10.0.0.9:7000" {
+		t.Errorf("expected override, got %q", addr)
+	}
+	if addr := table.Lookup(101); addr != "10.0.0.3:7000" {
+		t.Errorf("expected moved slot, got %q", addr)
+	}
+
+	table.ReplaceShards([]Shard{{
+		Slots: []SlotSpan{{0, 16383}},
+		Nodes: []ShardNode{
+			{IP: "10.0.0.5", Port: 7000, Role: "master", Health: "online"},
+			{IP: "10.0.0.6", Port: 7000, Role: "replica", Health: "loading"},
+		},
+	}})
+	if addr := table.Lookup(100); addr != "10.0.0.9:7000" {
+		t.Errorf("expected override to be kept, got %q", addr)
+	}
+	if addr := table.Lookup(101); addr != "10.0.0.5:7000" {
+		t.Errorf("unexpected address: %q", addr)
+	}
+	if replicas := table.Replicas(101); len(replicas) != 0 {
+		t.Errorf("expected loading replica to be left out, got %v", replicas)
+	}
+
+	table.ClearOverride(100)
+	if addr := table.Lookup(100); addr != "10.0.0.5:7000" {
+		t.Errorf("expected override to be cleared, got %q", addr)
+	}
+}
+
+func TestRoutingTable_Concurrent(t *testing.T) {
+	table := NewRoutingTable()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				table.Lookup(uint16(j % SLOT_COUNT))
+			}
+		}()
+	}
+	for j := 0; j < 10; j++ {
+		table.SetOverride(uint16(j), "a:1")
+	}
+	wg.Wait()
+}
+
+func BenchmarkRoutingTableLookupKey(b *testing.B) {
+	var table RoutingTable
+	table.ReplaceSlots([]SlotRange{{0, 16383, SlotNode{IP: "10.0.0.1", Port: 7000}, nil}})
+	key := []byte("{user1000}.following")
+	for i := 0; i < b.N; i++ {
+		table.LookupKey(key)
+	}
+}
+
+func TestRoutingTable_SetSlotPrunes(t *testing.T) {
+	var table RoutingTable
+	table.ReplaceSlots([]SlotRange{
+		{0, 0, SlotNode{IP: "10.0.0.1", Port: 7000}, nil},
+		{1, 16383, SlotNode{IP: "10.0.0.2", Port: 7000}, nil},
+	})
+	table.SetSlot(0, "10.0.0.3:7000")
+	if masters := table.Masters(); !reflect.DeepEqual([]string{"10.0.0.2:7000", "10.0.0.3:7000"}, masters) {
+		t.Errorf("expected the master without slots to be removed, got %v", masters)
+	}
+	if addr := table.Lookup(0); addr != "10.0.0.3:7000" {
+		t.Errorf("unexpected address: %q", addr)
+	}
+	if addr := table.Lookup(1); addr != "10.0.0.2:7000" {
+		t.Errorf("unexpected address: %q", addr)
+	}
+
+	table.SetSlot(0, "10.0.0.2:7000")
+	if masters := table.Masters(); !reflect.DeepEqual([]string{"10.0.0.2:7000"}, masters) {
+		t.Errorf("expected the master without slots to be removed, got %v", masters)
+	}
+	if addr := table.Lookup(0); addr != "10.0.0.2:7000" {
+		t.Errorf("unexpected address: %q", addr)
+	}
+}
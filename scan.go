@@ -0,0 +1,201 @@
+package resp
+
+import (
+	"iter"
+	"strconv"
+)
+
+// ScanOptions are the options of SCAN, HSCAN, SSCAN, and ZSCAN. Type is only
+// supported by SCAN. Zero values are omitted.
+type ScanOptions struct {
+	Match string
+	Count int
+	Type  string
+}
+
+// ParseScanReply decodes a reply of the form [cursor, [items...]] returned by
+// the SCAN family of commands. An Error reply is returned as the error.
+func ParseScanReply(obj Object) (cursor string, items [][]byte, err error) {
+	if e, ok := obj.(Error); ok {
+		return "", nil, e
+	}
+	array, ok := obj.(Array)
+	if !ok {
+		return "", nil, ErrUnexpectedReply
+	}
+	objects, err := array.Objects()
+	if err != nil || len(objects) != 2 {
+		return "", nil, ErrUnexpectedReply
+	}
+	cursor, ok = objectString(objects[0])
+	if !ok {
+		return "", nil, ErrUnexpectedReply
+	}
+	list, ok := objects[1].(Array)
+	if !ok {
+		return "", nil, ErrUnexpectedReply
+	}
+	elements, err := list.Objects()
+	if err != nil {
+		return "", nil, ErrUnexpectedReply
+	}
+	items = make([][]byte, len(elements))
+	for i, element := range elements {
+		s, ok := element.(String)
+		if !ok {
+			return "", nil, ErrUnexpectedReply
+		}
+		items[i] = s.Slice()
+	}
+	return cursor, items, nil
+}
+
+// A Scanner iterates over the items returned by one of the SCAN family of
+// commands, sending the command again with the returned cursor until the
+// iteration is complete. As with the commands themselves, items may be
+// returned more than once.
+//
+//	s := resp.Scan(conn, resp.ScanOptions{Match: "user:*"})
+//	for s.Next() {
+//		fmt.Println(string(s.Item()))
+//	}
+//	if err := s.Err(); err != nil {
+//		...
+//	}
+type Scanner struct {
+	conn  *Conn
+	name  string
+	key   string
+	opts  ScanOptions
+	pairs bool
+
+	cursor  string
+	batch   [][]byte
+	started bool
+	item    []byte
+	value   []byte
+	err     error
+}
+
+// Scan returns a Scanner over the keys of the current database.
+func Scan(conn *Conn, opts ScanOptions) *Scanner {
+	return &Scanner{conn: conn, name: "SCAN", opts: opts}
+}
+
+// HScan returns a Scanner over the fields of the hash at key. Value returns
+// the value of each field.
+func HScan(conn *Conn, key string, opts ScanOptions) *Scanner {
+	return &Scanner{conn: conn, name: "HSCAN", key: key, opts: opts, pairs: true}
+}
+
+// SScan returns a Scanner over the members of the set at key.
+func SScan(conn *Conn, key string, opts ScanOptions) *Scanner {
+	return &Scanner{conn: conn, name: "SSCAN", key: key, opts: opts}
+}
+
+// ZScan returns a Scanner over the members of the sorted set at key. Value
+// returns the score of each member.
+func ZScan(conn *Conn, key string, opts ScanOptions) *Scanner {
+	return &Scanner{conn: conn, name: "ZSCAN", key: key, opts: opts, pairs: true}
+}
+
+// Next advances to the next item, fetching more from the server if needed. It
+// returns false when the iteration is complete or fails; see Err.
+func (s *Scanner) Next() bool {
+	for {
+		if s.err != nil {
+			return false
+		}
+		if s.pairs && len(s.batch) >= 2 {
+			s.item, s.value, s.batch = s.batch[0], s.batch[1], s.batch[2:]
+			return true
+		}
+		if !s.pairs && len(s.batch) > 0 {
+			s.item, s.batch = s.batch[0], s.batch[1:]
+			return true
+		}
+		if s.started && s.cursor == "0" {
+			return false
+		}
+		s.fetch()
+	}
+}
+
+func (s *Scanner) fetch() {
+	cursor := s.cursor
+	if !s.started {
+		cursor = "0"
+	}
+	var args []interface{}
+	if s.name != "SCAN" {
+		args = append(args, s.key)
+	}
+	args = append(args, cursor)
+	if s.opts.Match != "" {
+		args = append(args, "MATCH", s.opts.Match)
+	}
+	if s.opts.Count > 0 {
+		args = append(args, "COUNT", s.opts.Count)
+	}
+	if s.opts.Type != "" {
+		args = append(args, "TYPE", s.opts.Type)
+	}
+
+	reply, err := s.conn.Do(s.name, args...)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.cursor, s.batch, s.err = ParseScanReply(reply)
+	if s.err == nil && s.pairs && len(s.batch)%2 != 0 {
+		s.err = ErrUnexpectedReply
+	}
+	s.started = true
+}
+
+// Item returns the current key or member.
+func (s *Scanner) Item() []byte {
+	return s.item
+}
+
+// Value returns the value of the current hash field or the score of the
+// current sorted set member, as returned by the server. It returns nil for
+// SCAN and SSCAN.
+func (s *Scanner) Value() []byte {
+	return s.value
+}
+
+// Score returns the score of the current sorted set member.
+func (s *Scanner) Score() (float64, error) {
+	return strconv.ParseFloat(string(s.value), 64)
+}
+
+// Err returns the error that ended the iteration, if any. Error replies are
+// returned as errors.
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// Items returns an iterator over the remaining items. Check Err after the
+// iteration.
+func (s *Scanner) Items() iter.Seq[[]byte] {
+	return func(yield func([]byte) bool) {
+		for s.Next() {
+			if !yield(s.item) {
+				return
+			}
+		}
+	}
+}
+
+// Pairs returns an iterator over the remaining items and their values. Check
+// Err after the iteration.
+func (s *Scanner) Pairs() iter.Seq2[[]byte, []byte] {
+	return func(yield func([]byte, []byte) bool) {
+		for s.Next() {
+			if !yield(s.item, s.value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package resp
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseScanReply(t *testing.T) {
+	cursor, items, err := ParseScanReply(NewArray(NewBulkString("17"), NewArray(NewBulkString("a"), NewSimpleString("b"))))
+	if err != nil || cursor != "17" || !reflect.DeepEqual([][]byte{[]byte("a"), []byte("b")}, items) {
+		t.Errorf("unexpected reply: %q, %q, %v", cursor, items, err)
+	}
+	for _, invalid := range []Object{NewBulkString("0"), NewArray(NewBulkString("0")), NewArray(NewBulkString("0"), NewArray(NewInteger(1)))} {
+		if _, _, err := ParseScanReply(invalid); err != ErrUnexpectedReply {
+			t.Errorf("%q: expected ErrUnexpectedReply, got %v", invalid, err)
+		}
+	}
+	if _, _, err := ParseScanReply(NewError("WRONGTYPE Operation against a key holding the wrong kind of value")); err == nil {
+		t.Errorf("expected the error reply")
+	}
+}
+
+// scanHandler serves SCAN-family commands over keys k0..k4, two at a time,
+// returning each key's index as its value.
+func scanHandler(commands *[]string) func(string, [][]byte) Object {
+	return func(name string, args [][]byte) Object {
+		command := name
+		for _, arg := range args {
+			command += " " + string(arg)
+		}
+		*commands = append(*commands, command)
+		if name != "SCAN" {
+			args = args[1:]
+		}
+		cursor, _ := strconv.Atoi(string(args[0]))
+		var items []Object
+		for i := cursor; i < cursor+2 && i < 5; i++ {
+			items = append(items, NewBulkString("k"+strconv.Itoa(i)))
+			if name == "HSCAN" || name == "ZSCAN" {
+				items = append(items, NewBulkString(strconv.Itoa(i)))
+			}
+		}
+		next := cursor + 2
+		if next >= 5 {
+			next = 0
+		}
+		return NewArray(NewBulkString(strconv.Itoa(next)), NewArray(items...))
+	}
+}
+
+func TestScanner(t *testing.T) {
+	var commands []string
+	conn := fakeServer(scanHandler(&commands))
+	defer conn.Close()
+
+	var keys []string
+	for key := range Scan(conn, ScanOptions{Match: "k*", Count: 2, Type: "string"}).Items() {
+		keys = append(keys, string(key))
+	}
+	if !reflect.DeepEqual([]string{"k0", "k1", "k2", "k3", "k4"}, keys) {
+		t.Errorf("unexpected keys: %q", keys)
+	}
+	expected := []string{
+		"SCAN 0 MATCH k* COUNT 2 TYPE string",
+		"SCAN 2 MATCH k* COUNT 2 TYPE string",
+		"SCAN 4 MATCH k* COUNT 2 TYPE string",
+	}
+	if !reflect.DeepEqual(expected, commands) {
+		t.Errorf("expected commands %q, got %q", expected, commands)
+	}
+
+	z := ZScan(conn, "z", ScanOptions{})
+	sum := 0.0
+	for z.Next() {
+		score, err := z.Score()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += score
+	}
+	if z.Err() != nil || sum != 10 {
+		t.Errorf("unexpected sum: %v, %v", sum, z.Err())
+	}
+	if commands[3] != "ZSCAN z 0" {
+		t.Errorf("unexpected command: %q", commands[3])
+	}
+
+	// Breaking out of the loop stops fetching
+	commands = nil
+	for field, value := range HScan(conn, "h", ScanOptions{}).Pairs() {
+		if string(field) != "k0" || string(value) != "0" {
+			t.Errorf("unexpected pair: %q, %q", field, value)
+		}
+		break
+	}
+	if len(commands) != 1 {
+		t.Errorf("expected a single command, got %q", commands)
+	}
+
+	// Error replies end the iteration
+	conn = fakeServer(func(string, [][]byte) Object {
+		return NewError("WRONGTYPE Operation against a key holding the wrong kind of value")
+	})
+	defer conn.Close()
+	s := SScan(conn, "s", ScanOptions{})
+	if s.Next() || s.Err() == nil || s.Err().(Error).Code() != "WRONGTYPE" {
+		t.Errorf("expected the error reply, got %v", s.Err())
+	}
+}
@@ -0,0 +1,86 @@
+package resp
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// A Script is a Lua script that's run with EVALSHA, falling back to EVAL when
+// the server doesn't have it cached. Each Conn remembers which scripts it
+// has loaded, until it reconnects. A Script is safe for concurrent use.
+type Script struct {
+	src  string
+	hash string
+}
+
+// NewScript returns a Script for the Lua source src.
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{src: src, hash: hex.EncodeToString(sum[:])}
+}
+
+// Hash returns the script's SHA1 digest, as used by EVALSHA.
+func (s *Script) Hash() string {
+	return s.hash
+}
+
+// Do runs the script with keys and args, which are formatted as by
+// FormatCommand. It tries EVALSHA first and falls back to EVAL if the server
+// replies NOSCRIPT. As with Conn.Do, error replies are returned as replies.
+func (s *Script) Do(conn *Conn, keys []string, args ...interface{}) (Object, error) {
+	return s.DoContext(context.Background(), conn, keys, args...)
+}
+
+// DoContext is the same as Do except that ctx bounds each round trip.
+func (s *Script) DoContext(ctx context.Context, conn *Conn, keys []string, args ...interface{}) (Object, error) {
+	reply, err := conn.DoContext(ctx, "EVALSHA", s.args(s.hash, keys, args)...)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok && e.Code() == "NOSCRIPT" {
+		reply, err = conn.DoContext(ctx, "EVAL", s.args(s.src, keys, args)...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := reply.(Error); !ok {
+		conn.markScript(s.hash)
+	}
+	return reply, nil
+}
+
+// Send queues the script to be run as by Conn.Send. Since the reply can't be
+// checked for NOSCRIPT before the pipeline is sent, Send uses EVALSHA only if
+// the Conn is known to have loaded the script and EVAL otherwise.
+func (s *Script) Send(conn *Conn, keys []string, args ...interface{}) error {
+	if conn.scripts[s.hash] {
+		return conn.Send("EVALSHA", s.args(s.hash, keys, args)...)
+	}
+	return conn.Send("EVAL", s.args(s.src, keys, args)...)
+}
+
+// Load loads the script into the server's script cache with SCRIPT LOAD. An
+// Error reply, e.g. for a compilation error, is returned as the error.
+func (s *Script) Load(conn *Conn) error {
+	reply, err := conn.Do("SCRIPT", "LOAD", s.src)
+	if err != nil {
+		return err
+	}
+	if e, ok := reply.(Error); ok {
+		return e
+	}
+	conn.markScript(s.hash)
+	return nil
+}
+
+// args returns the arguments of EVAL or EVALSHA for the given script or
+// digest.
+func (s *Script) args(script string, keys []string, args []interface{}) []interface{} {
+	all := make([]interface{}, 0, 2+len(keys)+len(args))
+	all = append(all, script, len(keys))
+	for _, key := range keys {
+		all = append(all, key)
+	}
+	return append(all, args...)
+}
@@ -0,0 +1,55 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	s := NewScript("return redis.call('GET', KEYS[1])")
+	if s.Hash() != "d3c21d0c2b9ca22f82737626a27bcaf5d288f99f" {
+		t.Errorf("unexpected hash: %s", s.Hash())
+	}
+
+	// The server caches scripts once they've been run with EVAL
+	var commands []string
+	cached := false
+	conn := fakeServer(func(name string, args [][]byte) Object {
+		commands = append(commands, name)
+		switch strings.ToUpper(name) {
+		case "EVALSHA":
+			if !cached {
+				return NewError("NOSCRIPT No matching script. Please use EVAL.")
+			}
+		case "EVAL":
+			cached = true
+		}
+		if string(args[1]) != "1" || string(args[2]) != "k" {
+			return NewError("ERR unexpected arguments")
+		}
+		return NewBulkString("v")
+	})
+	defer conn.Close()
+
+	for i := 0; i < 2; i++ {
+		reply, err := s.Do(conn, []string{"k"}, "arg")
+		if err != nil || reply.(String).String() != "v" {
+			t.Errorf("unexpected reply: %q, %v", reply, err)
+		}
+	}
+	expected := "EVALSHA EVAL EVALSHA"
+	if got := strings.Join(commands, " "); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	// Pipelines use EVALSHA once the Conn knows the script is loaded
+	commands = nil
+	s.Send(conn, []string{"k"})
+	NewScript("return 1").Send(conn, []string{"k"})
+	conn.Flush()
+	conn.Receive()
+	conn.Receive()
+	if got := strings.Join(commands, " "); got != "EVALSHA EVAL" {
+		t.Errorf("expected EVALSHA EVAL, got %s", got)
+	}
+}
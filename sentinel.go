@@ -0,0 +1,195 @@
+package resp
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotSentinelEvent is returned by ParseSentinelEvent for objects that
+	// aren't sentinel events.
+	ErrNotSentinelEvent = errors.New("resp: not a sentinel event")
+)
+
+// ParseMasterAddr decodes a SENTINEL GET-MASTER-ADDR-BY-NAME reply into the
+// master's address in host:port form. It returns "" if the sentinel doesn't
+// know the master and ErrUnexpectedReply if obj isn't shaped like a
+// GET-MASTER-ADDR-BY-NAME reply.
+func ParseMasterAddr(obj Object) (string, error) {
+	switch o := obj.(type) {
+	case Null:
+		return "", nil
+	case Array:
+		if objects, err := o.Objects(); err == nil && objects == nil {
+			return "", nil
+		}
+	}
+	objects, ok := objectArray(obj)
+	if !ok || len(objects) != 2 {
+		return "", ErrUnexpectedReply
+	}
+	ip, ok := objectString(objects[0])
+	if !ok {
+		return "", ErrUnexpectedReply
+	}
+	port, ok := objectString(objects[1])
+	if !ok {
+		return "", ErrUnexpectedReply
+	}
+	return net.JoinHostPort(ip, port), nil
+}
+
+// A SentinelInstance is a master, replica, or sentinel from a SENTINEL
+// MASTERS, SENTINEL REPLICAS, or SENTINEL SENTINELS reply. Fields holds every
+// field of the instance, including the ones decoded into the other members.
+type SentinelInstance struct {
+	Name   string
+	IP     string
+	Port   int
+	RunID  string
+	Flags  []string
+	Fields map[string]string
+}
+
+// Addr returns the instance's address in host:port form.
+func (i SentinelInstance) Addr() string {
+	return net.JoinHostPort(i.IP, strconv.Itoa(i.Port))
+}
+
+// HasFlag returns true if the instance has the given flag, e.g. "master",
+// "s_down", or "disconnected".
+func (i SentinelInstance) HasFlag(flag string) bool {
+	for _, f := range i.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSentinelInstances decodes a SENTINEL MASTERS, SENTINEL REPLICAS (or
+// SLAVES), or SENTINEL SENTINELS reply. It returns ErrUnexpectedReply if obj
+// isn't an array of instances, each of which is a flat array of field names
+// and values.
+func ParseSentinelInstances(obj Object) ([]SentinelInstance, error) {
+	objects, ok := objectArray(obj)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+
+	instances := make([]SentinelInstance, len(objects))
+	for i, object := range objects {
+		pairs, ok := objectPairs(object)
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		instance := SentinelInstance{Fields: make(map[string]string, len(pairs)/2)}
+		for j := 0; j < len(pairs); j += 2 {
+			key, ok := objectString(pairs[j])
+			if !ok {
+				return nil, ErrUnexpectedReply
+			}
+			value, ok := objectString(pairs[j+1])
+			if !ok {
+				return nil, ErrUnexpectedReply
+			}
+			instance.Fields[key] = value
+		}
+
+		instance.Name = instance.Fields["name"]
+		instance.IP = instance.Fields["ip"]
+		instance.RunID = instance.Fields["runid"]
+		if flags := instance.Fields["flags"]; flags != "" {
+			instance.Flags = strings.Split(flags, ",")
+		}
+		if port, ok := instance.Fields["port"]; ok {
+			n, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, ErrUnexpectedReply
+			}
+			instance.Port = n
+		}
+		instances[i] = instance
+	}
+	return instances, nil
+}
+
+// A SentinelEvent is an event published by a sentinel on its pub/sub
+// channels. Type is the channel the event was published on, e.g.
+// "+switch-master" or "+sdown".
+//
+// For +switch-master events, Name is the master's name, Addr its new address,
+// and OldAddr its previous address. For all other events, Role is the kind of
+// instance the event is about ("master", "slave", or "sentinel"), Name and
+// Addr identify it, and for instances other than masters, Master and
+// MasterAddr identify the master they belong to.
+type SentinelEvent struct {
+	Type       string
+	Role       string
+	Name       string
+	Addr       string
+	OldAddr    string
+	Master     string
+	MasterAddr string
+}
+
+// ParseSentinelEvent decodes a pub/sub message received from a sentinel,
+// either a "message" for a SUBSCRIBE or a "pmessage" for a PSUBSCRIBE, in
+// RESP2 or RESP3 form. It returns ErrNotSentinelEvent if obj isn't a message
+// or its payload isn't shaped like a sentinel event.
+func ParseSentinelEvent(obj Object) (*SentinelEvent, error) {
+	var objects []Object
+	switch o := obj.(type) {
+	case Array:
+		objects, _ = o.Objects()
+	case Push:
+		objects, _ = o.Objects()
+	}
+
+	var channel, payload string
+	var ok bool
+	switch {
+	case len(objects) == 3 && stringEquals(objects[0], "message"):
+		channel, ok = objectString(objects[1])
+		if ok {
+			payload, ok = objectString(objects[2])
+		}
+	case len(objects) == 4 && stringEquals(objects[0], "pmessage"):
+		channel, ok = objectString(objects[2])
+		if ok {
+			payload, ok = objectString(objects[3])
+		}
+	}
+	if !ok {
+		return nil, ErrNotSentinelEvent
+	}
+
+	event := &SentinelEvent{Type: channel}
+	fields := strings.Fields(payload)
+	if channel == "+switch-master" {
+		// <master name> <old ip> <old port> <new ip> <new port>
+		if len(fields) != 5 {
+			return nil, ErrNotSentinelEvent
+		}
+		event.Role = "master"
+		event.Name = fields[0]
+		event.OldAddr = net.JoinHostPort(fields[1], fields[2])
+		event.Addr = net.JoinHostPort(fields[3], fields[4])
+		return event, nil
+	}
+
+	// <instance type> <name> <ip> <port> [@ <master name> <master ip> <master port>]
+	if len(fields) < 4 {
+		return nil, ErrNotSentinelEvent
+	}
+	event.Role = fields[0]
+	event.Name = fields[1]
+	event.Addr = net.JoinHostPort(fields[2], fields[3])
+	if len(fields) >= 8 && fields[4] == "@" {
+		event.Master = fields[5]
+		event.MasterAddr = net.JoinHostPort(fields[6], fields[7])
+	}
+	return event, nil
+}
@@ -0,0 +1,103 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMasterAddr(t *testing.T) {
+	addr, err := ParseMasterAddr(NewArray(NewBulkString("127.0.0.1"), NewBulkString("6379")))
+	if err != nil || addr != "127.0.0.1:6379" {
+		t.Errorf("unexpected result: %q, %v", addr, err)
+	}
+	for _, unknown := range []Object{Array("*-1\r\n"), Null("_\r\n")} {
+		if addr, err := ParseMasterAddr(unknown); err != nil || addr != "" {
+			t.Errorf("%q: unexpected result: %q, %v", unknown, addr, err)
+		}
+	}
+	if _, err := ParseMasterAddr(NewError("ERR nope")); err != ErrUnexpectedReply {
+		t.Errorf("expected ErrUnexpectedReply, got %v", err)
+	}
+}
+
+func TestParseSentinelInstances(t *testing.T) {
+	reply := NewArray(
+		NewArray(
+			NewBulkString("name"), NewBulkString("mymaster"),
+			NewBulkString("ip"), NewBulkString("10.0.0.1"),
+			NewBulkString("port"), NewBulkString("6379"),
+			NewBulkString("runid"), NewBulkString("abc"),
+			NewBulkString("flags"), NewBulkString("master,s_down"),
+			NewBulkString("num-slaves"), NewBulkString("2"),
+		),
+	)
+	instances, err := ParseSentinelInstances(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 1 {
+		t.Fatalf("expected one instance, got %v", instances)
+	}
+	instance := instances[0]
+	if instance.Name != "mymaster" || instance.Addr() != "10.0.0.1:6379" || instance.RunID != "abc" {
+		t.Errorf("unexpected instance: %+v", instance)
+	}
+	if !instance.HasFlag("s_down") || instance.HasFlag("o_down") {
+		t.Errorf("unexpected flags: %v", instance.Flags)
+	}
+	if instance.Fields["num-slaves"] != "2" {
+		t.Errorf("unexpected fields: %v", instance.Fields)
+	}
+
+	invalid := []Object{
+		NewError("ERR No such master with that name"),
+		NewArray(NewArray(NewBulkString("name"))),
+		NewArray(NewArray(NewBulkString("port"), NewBulkString("x"))),
+	}
+	for i, test := range invalid {
+		if _, err := ParseSentinelInstances(test); err != ErrUnexpectedReply {
+			t.Errorf("invalid[%d]: expected ErrUnexpectedReply, got %v", i, err)
+		}
+	}
+}
+
+func TestParseSentinelEvent(t *testing.T) {
+	tests := []struct {
+		given    Object
+		expected *SentinelEvent
+	}{
+		{
+			NewArray(NewBulkString("message"), NewBulkString("+switch-master"), NewBulkString("mymaster 10.0.0.1 6379 10.0.0.2 6380")),
+			&SentinelEvent{Type: "+switch-master", Role: "master", Name: "mymaster", Addr: "10.0.0.2:6380", OldAddr: "10.0.0.1:6379"},
+		},
+		{
+			NewArray(NewBulkString("pmessage"), NewBulkString("*"), NewBulkString("+sdown"), NewBulkString("master mymaster 10.0.0.1 6379")),
+			&SentinelEvent{Type: "+sdown", Role: "master", Name: "mymaster", Addr: "10.0.0.1:6379"},
+		},
+		{
+			NewArray(NewBulkString("message"), NewBulkString("-sdown"), NewBulkString("slave 10.0.0.3:6379 10.0.0.3 6379 @ mymaster 10.0.0.1 6379")),
+			&SentinelEvent{Type: "-sdown", Role: "slave", Name: "10.0.0.3:6379", Addr: "10.0.0.3:6379", Master: "mymaster", MasterAddr: "10.0.0.1:6379"},
+		},
+	}
+	for i, test := range tests {
+		event, err := ParseSentinelEvent(test.given)
+		if err != nil {
+			t.Errorf("tests[%d]: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.expected, event) {
+			t.Errorf("tests[%d]: expected %+v, got %+v", i, test.expected, event)
+		}
+	}
+
+	invalid := []Object{
+		OK,
+		NewArray(NewBulkString("subscribe"), NewBulkString("+sdown"), NewInteger(1)),
+		NewArray(NewBulkString("message"), NewBulkString("+switch-master"), NewBulkString("mymaster")),
+	}
+	for i, test := range invalid {
+		if _, err := ParseSentinelEvent(test); err != ErrNotSentinelEvent {
+			t.Errorf("invalid[%d]: expected ErrNotSentinelEvent, got %v", i, err)
+		}
+	}
+}
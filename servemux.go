@@ -0,0 +1,100 @@
+package resp
+
+import (
+	"strings"
+	"sync"
+)
+
+// MAX_MUX_NAME_LENGTH is the longest command name a ServeMux dispatches
+// without allocating.
+const MAX_MUX_NAME_LENGTH = 64
+
+// A ServeMux is a Handler that dispatches commands to other Handlers by
+// command name, ignoring case. Commands without a Handler are passed to
+// NotFound. The zero value is ready to use, and a ServeMux is safe for
+// concurrent use.
+type ServeMux struct {
+	// NotFound handles commands that have no Handler. If it's nil, they're
+	// answered with the unknown command error Redis sends.
+	NotFound Handler
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewServeMux returns a new ServeMux.
+func NewServeMux() *ServeMux {
+	return &ServeMux{}
+}
+
+// Handle registers handler for the named command, replacing any Handler
+// already registered for it.
+func (m *ServeMux) Handle(name string, handler Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.handlers == nil {
+		m.handlers = map[string]Handler{}
+	}
+	m.handlers[strings.ToUpper(name)] = handler
+}
+
+// HandleFunc registers handler for the named command.
+func (m *ServeMux) HandleFunc(name string, handler func(w *Writer, cmd Command)) {
+	m.Handle(name, HandlerFunc(handler))
+}
+
+// Handler returns the Handler registered for cmd's name, or nil if there is
+// none. It doesn't allocate.
+func (m *ServeMux) Handler(cmd Command) Handler {
+	name, ok := commandName(cmd)
+	if !ok || len(name) > MAX_MUX_NAME_LENGTH {
+		return nil
+	}
+	var buf [MAX_MUX_NAME_LENGTH]byte
+	for i, b := range name {
+		if b >= 'a' && b <= 'z' {
+			b -= 'a' - 'A'
+		}
+		buf[i] = b
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.handlers[string(buf[:len(name)])]
+}
+
+// ServeRESP dispatches cmd to the Handler registered for its name.
+func (m *ServeMux) ServeRESP(w *Writer, cmd Command) {
+	if handler := m.Handler(cmd); handler != nil {
+		handler.ServeRESP(w, cmd)
+		return
+	}
+	if m.NotFound != nil {
+		m.NotFound.ServeRESP(w, cmd)
+		return
+	}
+	name, args, _ := ParseCommand(cmd)
+	w.WriteObject(unknownCommandError(name, args))
+}
+
+// commandName returns the name of cmd, pointing into cmd, without parsing
+// the rest of the command.
+func commandName(cmd Command) ([]byte, bool) {
+	if len(cmd) < MIN_COMMAND_LENGTH || cmd[0] != ARRAY_PREFIX {
+		return nil, false
+	}
+	count, end, err := parseLenLine(cmd)
+	if err != nil || count < 1 || end+1 >= len(cmd) || cmd[end+1] != BULK_STRING_PREFIX {
+		return nil, false
+	}
+	start := end + 1
+	length, end, err := parseLenLine(cmd[start:])
+	if err != nil || length < 0 {
+		return nil, false
+	}
+	start += end + 1
+	if start+length > len(cmd) {
+		return nil, false
+	}
+	return cmd[start : start+length], true
+}
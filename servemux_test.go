@@ -0,0 +1,74 @@
+package resp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestServeMux(t *testing.T) {
+	mux := NewServeMux()
+	mux.HandleFunc("get", func(w *Writer, cmd Command) {
+		w.WriteObject(NewBulkString("got"))
+	})
+	mux.Handle("SET", HandlerFunc(func(w *Writer, cmd Command) {
+		w.WriteObject(OK)
+	}))
+
+	addr := startServer(t, &Server{Handler: mux})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, name := range []string{"GET", "get", "gEt"} {
+		if reply, err := conn.Do(name, "a"); err != nil || reply.(String).String() != "got" {
+			t.Errorf("%s: unexpected reply: %q, %v", name, reply, err)
+		}
+	}
+	if reply, _ := conn.Do("set", "a", "b"); string(reply.Raw()) != "+OK\r\n" {
+		t.Errorf("unexpected reply: %q", reply)
+	}
+	reply, _ := conn.Do("DEL", "a")
+	if e, ok := reply.(Error); !ok || !strings.HasPrefix(e.Error(), "ERR unknown command 'DEL'") {
+		t.Errorf("expected an unknown command error, got %q", reply)
+	}
+
+	mux.NotFound = HandlerFunc(func(w *Writer, cmd Command) {
+		w.WriteObject(NewError("ERR not here"))
+	})
+	if reply, _ := conn.Do("DEL", "a"); string(reply.Raw()) != "-ERR not here\r\n" {
+		t.Errorf("unexpected reply: %q", reply)
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	tests := []struct {
+		given    Command
+		expected string
+		ok       bool
+	}{
+		{NewCommand("GET", "a"), "GET", true},
+		{NewCommand("PING"), "PING", true},
+		{Command("*1\r\n$4\r\nPI"), "", false},
+		{Command("*0\r\n"), "", false},
+		{Command(":1\r\n"), "", false},
+	}
+	for i, test := range tests {
+		name, ok := commandName(test.given)
+		if string(name) != test.expected || ok != test.ok {
+			t.Errorf("tests[%d]: expected %q, %v, got %q, %v", i, test.expected, test.ok, name, ok)
+		}
+	}
+}
+
+func BenchmarkServeMuxHandler(b *testing.B) {
+	mux := NewServeMux()
+	mux.HandleFunc("GET", func(*Writer, Command) {})
+	cmd := NewCommand("get", "key")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mux.Handler(cmd)
+	}
+}
@@ -0,0 +1,732 @@
+package resp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// SHUTDOWN_WRITE_TIMEOUT bounds the write of a Server's ShutdownReply.
+	SHUTDOWN_WRITE_TIMEOUT = time.Second
+
+	// DEFAULT_SERVER_NAME and DEFAULT_SERVER_VERSION are reported in the
+	// reply to HELLO unless a Server sets its own.
+	DEFAULT_SERVER_NAME    = "redis"
+	DEFAULT_SERVER_VERSION = "7.2.0"
+
+	// DEFAULT_DATABASES is the default Server.Databases.
+	DEFAULT_DATABASES = 16
+)
+
+// MAX_CLIENTS_ERROR is sent to connections refused because of
+// Server.MaxClients.
+var MAX_CLIENTS_ERROR = NewError("ERR max number of clients reached")
+
+// ErrServerClosed is returned by Server.Serve and Server.ListenAndServe after
+// the Server is closed or shut down.
+var ErrServerClosed = errors.New("resp: server closed")
+
+// A PanicError is passed to Server.OnError when a Handler panics while
+// handling a pipelined command in its own goroutine.
+type PanicError struct {
+	// Command is the name of the command being handled.
+	Command string
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("resp: panic handling %s: %v", e.Command, e.Value)
+}
+
+// A Handler answers the commands of a Server's clients. ServeRESP writes the
+// reply, or replies, to cmd to w; the Server flushes w once it has handled all
+// the commands a client pipelined. cmd is only valid until ServeRESP returns.
+type Handler interface {
+	ServeRESP(w *Writer, cmd Command)
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(w *Writer, cmd Command)
+
+// ServeRESP calls f(w, cmd).
+func (f HandlerFunc) ServeRESP(w *Writer, cmd Command) {
+	f(w, cmd)
+}
+
+// A SlowRequest describes a command that a Server's Handler was slow to
+// answer.
+type SlowRequest struct {
+	// Time is when the Handler was called and Duration how long it took.
+	Time     time.Time
+	Duration time.Duration
+	// Command is the command's name and arguments, with credentials
+	// redacted as by RedactCommand.
+	Command []string
+	// Addr is the client's address and Name the name it set with CLIENT
+	// SETNAME or HELLO, if any.
+	Addr string
+	Name string
+}
+
+// A Server accepts connections from Redis clients and answers their commands
+// with a Handler. Like Redis, it accepts both RESP and inline commands, which
+// are passed to the Handler as RESP commands. Each connection is served by
+// its own goroutine, which handles the connection's commands one at a time,
+// in order, unless PipelineConcurrency is set.
+//
+// The Server answers HELLO itself, switching the connection to the requested
+// protocol version. Connections start with RESP2, and handlers can tell which
+// version a client uses from their Writer's Protocol; objects written with
+// WriteObject are converted to RESP2 for RESP2 clients, so handlers can reply
+// with RESP3 types regardless. The Server also answers SELECT and CLIENT
+// SETNAME, GETNAME, SETINFO, REPLY, and ID, which handlers can see the
+// effects of with their Writer's ConnState. These built-in commands don't
+// go through the Handler, and so aren't seen by middlewares, except for the
+// credentials given to HELLO, which are passed to the Handler as AUTH. They
+// are refused with NOAUTH_ERROR until the client authenticates if the
+// Handler requires it, as RequirePass does.
+type Server struct {
+	// Addr is the address ListenAndServe listens on. Defaults to ":6379".
+	Addr string
+	// Handler answers commands. If it's nil, every command is answered with
+	// an unknown command error.
+	Handler Handler
+	// OnError, if set, is called with errors accepting connections that the
+	// Server recovers from, and with a *PanicError when a Handler panics in
+	// its own goroutine.
+	OnError func(error)
+	// PipelineConcurrency, if greater than 1, is the number of pipelined
+	// commands from a single connection that are handled at once, each in
+	// its own goroutine. Replies are still sent in the order the commands
+	// were received. Handlers must then be safe for concurrent use and must
+	// not depend on the earlier commands of a pipeline having been handled.
+	// A panic in one of these goroutines closes the connection rather than
+	// crashing the process; RecoverPanics answers the command instead.
+	PipelineConcurrency int
+	// MaxClients, if positive, is the maximum number of connections served
+	// at once. Connections beyond it are sent MAX_CLIENTS_ERROR and closed.
+	MaxClients int
+	// MaxQueryBuffer is the size a connection's read buffer may grow to in
+	// order to hold a large command. Connections sending larger commands are
+	// closed. Defaults to DEFAULT_MAX_BUFFER.
+	MaxQueryBuffer int
+	// MaxOutputBuffer, if positive, limits the size of the replies to a batch
+	// of pipelined commands, which Redis would hold in the connection's
+	// output buffer while the client is still sending commands. Connections
+	// whose replies exceed it are closed without answering further commands.
+	MaxOutputBuffer int
+	// IdleTimeout, if positive, closes connections that send no command
+	// for that long, like Redis's timeout setting. Connections subscribed
+	// through a Broker are exempt.
+	IdleTimeout time.Duration
+	// KeepAlive, if positive, is the TCP keep-alive period of connections,
+	// like Redis's tcp-keepalive setting. If it's negative, keep-alives are
+	// disabled. If it's 0, the listener's setting is kept; listeners from
+	// net.Listen send keep-alives every 15 seconds by default.
+	KeepAlive time.Duration
+	// ShutdownReply, if set, is sent by Shutdown to idle connections before
+	// closing them, e.g. an Error telling subscribers, which otherwise only
+	// see the connection close, that the server is going away.
+	ShutdownReply Object
+	// ServerName and ServerVersion are reported in the reply to HELLO.
+	// They default to DEFAULT_SERVER_NAME and DEFAULT_SERVER_VERSION.
+	ServerName    string
+	ServerVersion string
+	// Databases is the number of databases clients can SELECT. Defaults to
+	// DEFAULT_DATABASES.
+	Databases int
+	// OnSlowRequest, if set, is called with the commands whose Handler
+	// takes longer than SlowRequestThreshold, like the entries of Redis's
+	// SLOWLOG. It's called by the goroutine that handled the command, before
+	// the reply is sent, so it must be quick and, with PipelineConcurrency,
+	// safe for concurrent use.
+	OnSlowRequest        func(SlowRequest)
+	SlowRequestThreshold time.Duration
+	// NewTrace, if set, is called with the ID of each connection, and the
+	// Trace it returns is set on the connection's Reader and Writer, e.g.
+	// to record its traffic with a CaptureWriter or Metrics. Inline
+	// commands aren't passed to the Trace's ObjectRead hook.
+	NewTrace func(id int64) *Trace
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	lastID    int64
+	// closed is set by both Close and Shutdown.
+	closed bool
+}
+
+// ListenAndServe listens on the TCP address s.Addr and serves connections
+// from it. It always returns a non-nil error.
+func (s *Server) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = ":6379"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections from l and serves each of them in a new
+// goroutine. l is closed when Serve returns. It always returns a non-nil
+// error, which is ErrServerClosed after Close.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	if s.listeners == nil {
+		s.listeners = map[net.Listener]struct{}{}
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer s.removeListener(l)
+
+	var backoff time.Duration
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if s.OnError != nil {
+					s.OnError(err)
+				}
+				if backoff == 0 {
+					backoff = 5 * time.Millisecond
+				} else if backoff *= 2; backoff > time.Second {
+					backoff = time.Second
+				}
+				time.Sleep(backoff)
+				continue
+			}
+			return err
+		}
+		backoff = 0
+
+		c, err := s.newConn(conn)
+		if err == ErrServerClosed {
+			conn.Close()
+			return err
+		}
+		if err != nil {
+			go refuse(conn, err.(Error))
+			continue
+		}
+		s.setKeepAlive(conn)
+		go c.serve()
+	}
+}
+
+// setKeepAlive applies s.KeepAlive to conn if it's a TCP connection.
+func (s *Server) setKeepAlive(conn net.Conn) {
+	tc, ok := conn.(interface {
+		SetKeepAlive(bool) error
+		SetKeepAlivePeriod(time.Duration) error
+	})
+	if !ok || s.KeepAlive == 0 {
+		return
+	}
+	if s.KeepAlive < 0 {
+		tc.SetKeepAlive(false)
+		return
+	}
+	tc.SetKeepAlive(true)
+	tc.SetKeepAlivePeriod(s.KeepAlive)
+}
+
+func (s *Server) removeListener(l net.Listener) {
+	s.mu.Lock()
+	delete(s.listeners, l)
+	s.mu.Unlock()
+	l.Close()
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// newConn registers a new connection. It returns ErrServerClosed if the
+// Server is closed and an Error reply if the connection is refused.
+func (s *Server) newConn(conn net.Conn) (*serverConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrServerClosed
+	}
+	if s.MaxClients > 0 && len(s.conns) >= s.MaxClients {
+		return nil, MAX_CLIENTS_ERROR
+	}
+	s.lastID++
+	c := &serverConn{
+		server: s,
+		conn:   conn,
+		r:      NewReader(conn),
+		out:    &countingWriter{w: conn},
+		state: ConnState{
+			ID:       s.lastID,
+			Addr:     conn.RemoteAddr().String(),
+			Protocol: 2,
+		},
+	}
+	c.w = NewWriter(c.out)
+	c.w.SetProtocol(2)
+	c.w.conn = c
+	c.discard = NewWriterSize(io.Discard, 512)
+	c.discard.SetProtocol(2)
+	c.discard.conn = c
+	c.r.SetInline(true)
+	maxQuery := s.MaxQueryBuffer
+	if maxQuery <= 0 {
+		maxQuery = DEFAULT_MAX_BUFFER
+	}
+	c.r.SetMaxSize(maxQuery)
+	if s.conns == nil {
+		s.conns = map[*serverConn]struct{}{}
+	}
+	s.conns[c] = struct{}{}
+	return c, nil
+}
+
+// refuse sends reply to a connection that won't be served and closes it.
+func refuse(conn net.Conn, reply Object) {
+	conn.SetWriteDeadline(time.Now().Add(SHUTDOWN_WRITE_TIMEOUT))
+	conn.Write(reply.Raw())
+	conn.Close()
+}
+
+// Close immediately closes all listeners and connections. Commands being
+// handled aren't interrupted, but their replies are lost.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var err error
+	for l := range s.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for c := range s.conns {
+		c.mu.Lock()
+		c.closed = true
+		c.conn.Close()
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// Shutdown gracefully shuts the Server down: it closes all listeners, closes
+// idle connections, and waits for the other connections to finish answering
+// the commands they have received and become idle, at which point they're
+// closed too. If ctx ends first, Shutdown returns ctx's error and the
+// remaining connections are left open; Close closes them.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	for l := range s.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	s.mu.Unlock()
+
+	wait := time.Millisecond
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		if s.closeIdleConns() {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if wait *= 2; wait > 100*time.Millisecond {
+				wait = 100 * time.Millisecond
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// closeIdleConns closes the idle connections and returns true if there are no
+// connections left.
+func (s *Server) closeIdleConns() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.mu.Lock()
+		if c.idle && !c.closed {
+			// The serving goroutine is blocked reading
+			c.shutdown()
+		}
+		c.mu.Unlock()
+	}
+	return len(s.conns) == 0
+}
+
+// A serverConn is a client connection to a Server.
+type serverConn struct {
+	server *Server
+	conn   net.Conn
+	r      *Reader
+	w      *Writer
+	// out counts the bytes of the current batch of replies that have been
+	// written through w.
+	out *countingWriter
+	// discard replaces w for commands whose replies are turned off with
+	// CLIENT REPLY.
+	discard *Writer
+
+	// pending holds the replies being prepared concurrently, in the order
+	// of their commands.
+	pending []*pendingReply
+	// wmu guards w between the serving goroutine, which holds it unless
+	// the connection is idle, and the delivery of pub/sub messages.
+	wmu sync.Mutex
+	// subscriptions is the number of channels and patterns the connection
+	// is subscribed to through a Broker.
+	subscriptions int32
+
+	// mu guards idle, closed, state, sub, and values, so that Shutdown only
+	// closes connections that are waiting for a command. state is only
+	// changed by the serving goroutine, which can read it without mu.
+	// values holds the connection's state for middlewares.
+	mu     sync.Mutex
+	idle   bool
+	closed bool
+	state  ConnState
+	sub    *subscriber
+	values map[interface{}]interface{}
+}
+
+// A pendingReply is the reply to a command handled concurrently with others
+// from the same connection.
+type pendingReply struct {
+	buf     bytes.Buffer
+	w       *Writer
+	discard bool
+	// panicked is set if the Handler panicked, in which case the reply is
+	// incomplete.
+	panicked bool
+	done     chan struct{}
+}
+
+var pendingReplyPool = sync.Pool{
+	New: func() interface{} {
+		p := &pendingReply{}
+		p.w = NewWriterSize(&p.buf, 512)
+		return p
+	},
+}
+
+// serve handles the connection's commands until it's closed.
+func (c *serverConn) serve() {
+	defer c.close()
+
+	// wmu is held while the connection is busy, so that messages for
+	// subscribers are written between batches of replies.
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.server.NewTrace != nil {
+		trace := c.server.NewTrace(c.state.ID)
+		c.r.SetTrace(trace)
+		c.w.SetTrace(trace)
+	}
+	for {
+		// Pipelined commands are answered with a single write
+		idle := c.r.Buffered() == 0
+		if idle {
+			if !c.drain() {
+				return
+			}
+			if err := c.w.Flush(); err != nil {
+				return
+			}
+			c.wmu.Unlock()
+			if !c.setIdle(true) {
+				c.wmu.Lock()
+				return
+			}
+		}
+
+		if timeout := c.server.IdleTimeout; timeout > 0 {
+			if atomic.LoadInt32(&c.subscriptions) > 0 {
+				c.conn.SetReadDeadline(time.Time{})
+			} else {
+				c.conn.SetReadDeadline(time.Now().Add(timeout))
+			}
+		}
+		cmd, err := c.r.ReadCommand()
+		// setIdle locks c.mu, which Shutdown holds while locking wmu
+		busy := c.setIdle(false)
+		if idle {
+			c.wmu.Lock()
+			c.out.n = 0
+		}
+		if perr, ok := err.(*ProtocolError); ok && busy {
+			c.protocolError(perr)
+			return
+		}
+		if err != nil || !busy {
+			return
+		}
+
+		w := c.w
+		switch c.state.ReplyMode {
+		case REPLY_OFF:
+			w = c.discard
+		case REPLY_SKIP:
+			w = c.discard
+			c.setState(func(state *ConnState) { state.ReplyMode = REPLY_ON })
+		}
+		if isBuiltin(cmd) {
+			// Built-in commands change the state later commands are
+			// handled with.
+			if !c.drain() {
+				return
+			}
+			c.serveBuiltin(w, cmd)
+			if c.outputExceeded() {
+				return
+			}
+			continue
+		}
+		if c.server.PipelineConcurrency <= 1 {
+			c.handle(w, cmd)
+			if c.outputExceeded() {
+				return
+			}
+			continue
+		}
+		if len(c.pending) >= c.server.PipelineConcurrency {
+			if !c.writePending() {
+				return
+			}
+		}
+		c.pending = append(c.pending, c.handleAsync(cmd, w == c.discard))
+	}
+}
+
+// protocolError answers err as Redis does, after the replies to the
+// commands that preceded it. The connection is closed afterwards.
+func (c *serverConn) protocolError(err *ProtocolError) {
+	if !c.drain() {
+		return
+	}
+	c.w.WriteObject(err.Reply())
+	c.w.Flush()
+}
+
+// outputExceeded returns true if the replies to the current batch of
+// commands exceed the Server's MaxOutputBuffer.
+func (c *serverConn) outputExceeded() bool {
+	limit := c.server.MaxOutputBuffer
+	return limit > 0 && c.out.n+c.w.Buffered() > limit
+}
+
+// A countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
+// handleAsync starts handling cmd in a new goroutine. If discard is true,
+// the reply is dropped.
+func (c *serverConn) handleAsync(cmd Command, discard bool) *pendingReply {
+	p := pendingReplyPool.Get().(*pendingReply)
+	p.buf.Reset()
+	p.discard = discard
+	p.panicked = false
+	p.w.SetProtocol(c.w.Protocol())
+	p.w.conn = c
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		defer func() {
+			if v := recover(); v != nil {
+				p.panicked = true
+				if c.server.OnError != nil {
+					name, _ := commandName(cmd)
+					c.server.OnError(&PanicError{Command: string(name), Value: v, Stack: debug.Stack()})
+				}
+			}
+		}()
+		c.handle(p.w, cmd)
+		p.w.Flush()
+	}()
+	return p
+}
+
+// writePending waits for the oldest pending reply and buffers it for
+// writing. It returns false if its Handler panicked, writing failed, or the
+// replies exceed the Server's MaxOutputBuffer.
+func (c *serverConn) writePending() bool {
+	p := c.pending[0]
+	c.pending[0] = nil
+	c.pending = c.pending[1:]
+	<-p.done
+	if p.panicked {
+		// The reply is incomplete, and the Writer may be in a bad state
+		return false
+	}
+	var err error
+	if !p.discard {
+		_, err = c.w.Write(p.buf.Bytes())
+	}
+	pendingReplyPool.Put(p)
+	return err == nil && !c.outputExceeded()
+}
+
+// drain waits for all pending replies. It returns false if writing failed or
+// the replies exceed the Server's MaxOutputBuffer.
+func (c *serverConn) drain() bool {
+	for len(c.pending) > 0 {
+		if !c.writePending() {
+			return false
+		}
+	}
+	return true
+}
+
+// setIdle marks the connection as idle, i.e. waiting for a command, or busy.
+// It returns false if the connection is closed or, when becoming idle, if the
+// Server is shutting down.
+func (c *serverConn) setIdle(idle bool) bool {
+	c.server.mu.Lock()
+	closed := c.server.closed
+	c.server.mu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	if idle && closed {
+		// Close marks every connection closed, so the Server is shutting
+		// down.
+		c.shutdown()
+		return false
+	}
+	c.idle = idle
+	return true
+}
+
+// shutdown sends the Server's ShutdownReply, if any, and closes the
+// connection. c.mu must be held, and the serving goroutine must not be using
+// c.w. The reply is skipped if pub/sub messages are being written.
+func (c *serverConn) shutdown() {
+	if reply := c.server.ShutdownReply; reply != nil && c.wmu.TryLock() {
+		c.conn.SetWriteDeadline(time.Now().Add(SHUTDOWN_WRITE_TIMEOUT))
+		c.w.WriteObject(reply)
+		c.w.Flush()
+		c.wmu.Unlock()
+	}
+	c.closed = true
+	c.conn.Close()
+}
+
+func (c *serverConn) handle(w *Writer, cmd Command) {
+	if atomic.LoadInt32(&c.subscriptions) > 0 && w.Protocol() == 2 && handleSubscribed(w, cmd) {
+		return
+	}
+	if c.server.Handler == nil {
+		name, args, _ := ParseCommand(cmd)
+		w.WriteObject(unknownCommandError(name, args))
+		return
+	}
+	if c.server.OnSlowRequest == nil {
+		c.server.Handler.ServeRESP(w, cmd)
+		return
+	}
+
+	start := time.Now()
+	c.server.Handler.ServeRESP(w, cmd)
+	if elapsed := time.Since(start); elapsed > c.server.SlowRequestThreshold {
+		name, args, _ := ParseCommand(cmd)
+		state := c.connState()
+		c.server.OnSlowRequest(SlowRequest{
+			Time:     start,
+			Duration: elapsed,
+			Command:  RedactCommand(name, args),
+			Addr:     state.Addr,
+			Name:     state.Name,
+		})
+	}
+}
+
+// handleSubscribed answers the commands a RESP2 client can't send while
+// subscribed with the error Redis sends, and PING with the array Redis sends
+// subscribers. It returns false for the other commands, which are handled as
+// usual.
+func handleSubscribed(w *Writer, cmd Command) bool {
+	name, args, _ := ParseCommand(cmd)
+	switch strings.ToUpper(name) {
+	case "SUBSCRIBE", "PSUBSCRIBE", "SSUBSCRIBE", "UNSUBSCRIBE", "PUNSUBSCRIBE", "SUNSUBSCRIBE", "QUIT", "RESET":
+		return false
+	case "PING":
+		if len(args) > 1 {
+			w.WriteObject(wrongArityError(name))
+			return true
+		}
+		message := ""
+		if len(args) == 1 {
+			message = string(args[0])
+		}
+		w.WriteObject(NewArray(NewBulkString("pong"), NewBulkString(message)))
+		return true
+	}
+	w.WriteObject(NewError(fmt.Sprintf("ERR Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context", strings.ToLower(name))))
+	return true
+}
+
+func (c *serverConn) close() {
+	c.conn.Close()
+	c.mu.Lock()
+	c.closed = true
+	sub := c.sub
+	c.mu.Unlock()
+	if sub != nil {
+		sub.close()
+	}
+	c.server.mu.Lock()
+	delete(c.server.conns, c)
+	c.server.mu.Unlock()
+
+	// Only the serving goroutine uses the Reader, and writers of messages
+	// to subscribers hold wmu.
+	c.r.Release()
+	c.discard.Release()
+	c.wmu.Lock()
+	c.w.Release()
+	c.wmu.Unlock()
+}
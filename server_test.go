@@ -0,0 +1,469 @@
+package resp
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startServer serves s on a local port and returns its address. s is closed
+// when the test ends.
+func startServer(t *testing.T, s *Server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+// echoServerHandler answers commands like echoHandler.
+var echoServerHandler = HandlerFunc(func(w *Writer, cmd Command) {
+	name, args, _ := ParseCommand(cmd)
+	if reply := echoHandler(name, args); reply != nil {
+		w.WriteObject(reply)
+	}
+})
+
+func TestServer(t *testing.T) {
+	addr := startServer(t, &Server{Handler: echoServerHandler})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if reply, err := conn.Do("ECHO", "a"); err != nil || reply.(String).String() != "a" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+
+	// Pipelined commands are answered in order
+	replies, err := conn.DoPipeline([]Command{NewCommand("PING"), NewCommand("ECHO", "b"), NewCommand("NOPE")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replies[0].(String).String() != "PONG" || replies[1].(String).String() != "b" {
+		t.Errorf("unexpected replies: %q", replies)
+	}
+	if _, ok := replies[2].(Error); !ok {
+		t.Errorf("expected an error reply, got %q", replies[2])
+	}
+}
+
+func TestServer_Inline(t *testing.T) {
+	addr := startServer(t, &Server{Handler: echoServerHandler})
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("PING\r\nECHO \"hello world\"\r\n"))
+	r := NewReader(conn)
+	for _, expected := range []string{"+PONG\r\n", "$11\r\nhello world\r\n"} {
+		if reply, err := r.ReadObject(); err != nil || string(reply.Raw()) != expected {
+			t.Errorf("expected %q, got %q, %v", expected, reply, err)
+		}
+	}
+}
+
+func TestServer_ProtocolError(t *testing.T) {
+	for _, concurrency := range []int{0, 4} {
+		addr := startServer(t, &Server{Handler: echoServerHandler, PipelineConcurrency: concurrency})
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("*1\r\n$4\r\nPING\r\n*1\r\n:1\r\n"))
+		r := NewReader(conn)
+		for _, expected := range []string{"+PONG\r\n", "-ERR Protocol error: expected '$', got ':'\r\n"} {
+			if reply, err := r.ReadObject(); err != nil || string(reply.Raw()) != expected {
+				t.Errorf("concurrency %d: expected %q, got %q, %v", concurrency, expected, reply, err)
+			}
+		}
+		if _, err := r.ReadObject(); err != io.EOF {
+			t.Errorf("concurrency %d: expected the connection to be closed, got %v", concurrency, err)
+		}
+		conn.Close()
+	}
+}
+
+func TestServer_Hello(t *testing.T) {
+	handler := HandlerFunc(func(w *Writer, cmd Command) {
+		w.WriteObject(NewMap(NewBulkString("protocol"), NewInteger(int64(w.Protocol()))))
+	})
+	for _, concurrency := range []int{0, 4} {
+		addr := startServer(t, &Server{Handler: handler, PipelineConcurrency: concurrency, ServerVersion: "1.0.0"})
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("INFO\r\nHELLO 4\r\nHELLO 3 SETNAME\r\nHELLO 3 AUTH default secret SETNAME app\r\nINFO\r\nHELLO 2\r\nINFO\r\n"))
+		r := NewReader(conn)
+		expected := []string{
+			"*2\r\n$8\r\nprotocol\r\n:2\r\n",
+			"-NOPROTO unsupported protocol version\r\n",
+			"-ERR Syntax error in HELLO option 'SETNAME'\r\n",
+			"%7\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n1.0.0\r\n$5\r\nproto\r\n:3\r\n$2\r\nid\r\n:1\r\n" +
+				"$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n",
+			"%1\r\n$8\r\nprotocol\r\n:3\r\n",
+			"*14\r\n$6\r\nserver\r\n$5\r\nredis\r\n$7\r\nversion\r\n$5\r\n1.0.0\r\n$5\r\nproto\r\n:2\r\n$2\r\nid\r\n:1\r\n" +
+				"$4\r\nmode\r\n$10\r\nstandalone\r\n$4\r\nrole\r\n$6\r\nmaster\r\n$7\r\nmodules\r\n*0\r\n",
+			"*2\r\n$8\r\nprotocol\r\n:2\r\n",
+		}
+		for i, e := range expected {
+			if reply, err := r.ReadObject(); err != nil || string(reply.Raw()) != e {
+				t.Errorf("concurrency %d: replies[%d]: expected %q, got %q, %v", concurrency, i, e, reply, err)
+			}
+		}
+		conn.Close()
+	}
+}
+
+func TestServer_SlowRequest(t *testing.T) {
+	slow := make(chan SlowRequest, 10)
+	handler := HandlerFunc(func(w *Writer, cmd Command) {
+		if name, _, _ := ParseCommand(cmd); name == "AUTH" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.WriteObject(OK)
+	})
+	addr := startServer(t, &Server{
+		Handler:              handler,
+		OnSlowRequest:        func(r SlowRequest) { slow <- r },
+		SlowRequestThreshold: 10 * time.Millisecond,
+	})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.DoPipeline([]Command{NewCommand("HELLO", "2", "SETNAME", "app"), NewCommand("PING"), NewCommand("AUTH", "secret")}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-slow:
+		if !reflect.DeepEqual([]string{"AUTH", REDACTED}, r.Command) || r.Duration < 20*time.Millisecond || r.Name != "app" || r.Addr != conn.conn.LocalAddr().String() {
+			t.Errorf("unexpected slow request: %+v", r)
+		}
+	default:
+		t.Fatal("expected a slow request")
+	}
+	if len(slow) != 0 {
+		t.Errorf("expected a single slow request, got %+v", <-slow)
+	}
+}
+
+func TestServer_NoHandler(t *testing.T) {
+	addr := startServer(t, &Server{})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	reply, err := conn.Do("GET", "a")
+	if e, ok := reply.(Error); !ok || !strings.HasPrefix(string(e.Slice()), "ERR unknown command 'GET'") {
+		t.Errorf("expected an unknown command error, got %q, %v", reply, err)
+	}
+}
+
+func TestServer_Close(t *testing.T) {
+	s := &Server{Handler: echoServerHandler}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- s.Serve(l) }()
+
+	conn, err := Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	select {
+	case err := <-done:
+		if err != ErrServerClosed {
+			t.Errorf("expected ErrServerClosed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Serve to return")
+	}
+	if _, err := conn.Do("PING"); err == nil {
+		t.Errorf("expected the connection to be closed")
+	}
+	if err := s.Serve(l); err != ErrServerClosed {
+		t.Errorf("expected ErrServerClosed, got %v", err)
+	}
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	release := make(chan struct{})
+	mux := NewServeMux()
+	mux.HandleFunc("SLOW", func(w *Writer, cmd Command) {
+		<-release
+		w.WriteObject(OK)
+	})
+	s := &Server{Handler: mux, ShutdownReply: NewError("ERR server shutting down")}
+	addr := startServer(t, s)
+
+	idle, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+	busy, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	if err := busy.Send("SLOW"); err != nil {
+		t.Fatal(err)
+	}
+	busy.Flush()
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan error)
+	go func() { done <- s.Shutdown(context.Background()) }()
+
+	// Idle connections get the final reply and are closed right away
+	r := NewReader(idle)
+	if reply, err := r.ReadObject(); err != nil || string(reply.Raw()) != "-ERR server shutting down\r\n" {
+		t.Errorf("expected the shutdown reply, got %q, %v", reply, err)
+	}
+	if _, err := r.ReadObject(); err != io.EOF {
+		t.Errorf("expected the idle connection to be closed, got %v", err)
+	}
+
+	// Busy connections are answered first
+	select {
+	case <-done:
+		t.Fatal("Shutdown returned while a command was being handled")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if reply, err := busy.Receive(); err != nil || string(reply.Raw()) != "+OK\r\n" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Shutdown")
+	}
+
+	// Shutdown gives up when ctx ends
+	s = &Server{Handler: mux}
+	addr = startServer(t, s)
+	busy, err = Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	release = make(chan struct{})
+	defer close(release)
+	busy.Send("SLOW")
+	busy.Flush()
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestServer_PipelineConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	handler := HandlerFunc(func(w *Writer, cmd Command) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		_, args, _ := ParseCommand(cmd)
+		ms, _ := strconv.Atoi(string(args[0]))
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		w.WriteObject(NewBulkString(string(args[0])))
+	})
+	addr := startServer(t, &Server{Handler: handler, PipelineConcurrency: 3})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	delays := []string{"60", "10", "30", "10", "20"}
+	commands := make([]Command, len(delays))
+	for i, delay := range delays {
+		commands[i] = NewCommand("SLEEP", delay)
+	}
+	start := time.Now()
+	replies, err := conn.DoPipeline(commands)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, reply := range replies {
+		if reply.(String).String() != delays[i] {
+			t.Errorf("replies[%d]: expected %s, got %q", i, delays[i], reply)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 130*time.Millisecond {
+		t.Errorf("expected the commands to run concurrently, took %s", elapsed)
+	}
+	if max := atomic.LoadInt32(&maxRunning); max != 3 {
+		t.Errorf("expected at most 3 commands at once, got %d", max)
+	}
+}
+
+func TestServer_PipelineConcurrencyPanic(t *testing.T) {
+	errs := make(chan error, 1)
+	handler := HandlerFunc(func(w *Writer, cmd Command) {
+		if name, _, _ := ParseCommand(cmd); name == "PANIC" {
+			panic("oops")
+		}
+		echoServerHandler(w, cmd)
+	})
+	addr := startServer(t, &Server{Handler: handler, PipelineConcurrency: 2, OnError: func(err error) { errs <- err }})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	commands := []Command{NewCommand("ECHO", "a"), NewCommand("PANIC"), NewCommand("ECHO", "b")}
+	if _, err := conn.DoPipeline(commands); err == nil {
+		t.Errorf("expected the connection to be closed")
+	}
+	select {
+	case err := <-errs:
+		if e, ok := err.(*PanicError); !ok || e.Command != "PANIC" || e.Value != "oops" || len(e.Stack) == 0 {
+			t.Errorf("unexpected error: %#v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the panic to be reported")
+	}
+
+	// The server is still up
+	conn, err = Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if reply, err := conn.Do("ECHO", "c"); err != nil || reply.(String).String() != "c" {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+}
+
+func TestServer_Limits(t *testing.T) {
+	addr := startServer(t, &Server{
+		Handler:         echoServerHandler,
+		MaxClients:      1,
+		MaxQueryBuffer:  1024,
+		MaxOutputBuffer: 100,
+	})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Too many clients
+	refused, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer refused.Close()
+	r := NewReader(refused)
+	if reply, err := r.ReadObject(); err != nil || !reflect.DeepEqual(reply, Object(MAX_CLIENTS_ERROR)) {
+		t.Errorf("expected MAX_CLIENTS_ERROR, got %q, %v", reply, err)
+	}
+	if _, err := r.ReadObject(); err != io.EOF {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+
+	// Commands larger than the default read buffer are fine
+	big := strings.Repeat("x", 900)
+	if reply, err := conn.Do("ECHO", big[:50]); err != nil || reply.(String).String() != big[:50] {
+		t.Errorf("unexpected reply: %q, %v", reply, err)
+	}
+
+	// Replies to a pipeline are limited
+	commands := []Command{NewCommand("ECHO", big[:60]), NewCommand("ECHO", big[:60])}
+	if _, err := conn.DoPipeline(commands); err == nil {
+		t.Errorf("expected the connection to be closed")
+	}
+
+	// So are commands
+	conn, err = Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Do("ECHO", big+big); err == nil {
+		t.Errorf("expected the connection to be closed")
+	}
+}
+
+func TestServer_LargeCommands(t *testing.T) {
+	addr := startServer(t, &Server{Handler: echoServerHandler})
+	conn, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	big := strings.Repeat("x", 5*DEFAULT_BUFFER)
+	if reply, err := conn.Do("ECHO", big); err != nil || reply.(String).String() != big {
+		t.Errorf("unexpected reply: %d bytes, %v", len(reply.Raw()), err)
+	}
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	b := &Broker{}
+	mux := &ServeMux{NotFound: echoServerHandler}
+	mux.Handle("SUBSCRIBE", b)
+	addr := startServer(t, &Server{Handler: mux, IdleTimeout: 50 * time.Millisecond, KeepAlive: time.Minute})
+
+	idle, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+	subscriber, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer subscriber.Close()
+	subscriber.Write([]byte("SUBSCRIBE a\r\n"))
+	r := NewReader(subscriber)
+	expectReplies(t, r, []string{"*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n"})
+
+	// Connections are closed once idle for too long
+	if _, err := NewReader(idle).ReadObject(); err != io.EOF {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+
+	// Except for subscribers
+	time.Sleep(100 * time.Millisecond)
+	if n := b.Publish("a", []byte("hi")); n != 1 {
+		t.Errorf("expected 1 receiver, got %d", n)
+	}
+	expectReplies(t, r, []string{"*3\r\n$7\r\nmessage\r\n$1\r\na\r\n$2\r\nhi\r\n"})
+}
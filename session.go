@@ -0,0 +1,302 @@
+package resp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrSessionClosed is returned by Session methods after Close.
+	ErrSessionClosed = errors.New("resp: session closed")
+
+	// ErrNoUpstream is returned by Session.Forward for an upstream index that
+	// doesn't exist.
+	ErrNoUpstream = errors.New("resp: no such upstream")
+)
+
+// A Session is one client connection of a proxy, paired with the upstream
+// connections its commands are forwarded to. Commands can be forwarded to any
+// upstream and the upstreams can answer in any order relative to each other,
+// but the Session delivers replies to the client in the order the commands
+// were forwarded, as RESP requires.
+//
+// Each upstream is read by its own goroutine, started by NewSession, which
+// exits when reading from the upstream fails, e.g. because it was closed.
+// Upstreams must reply to every command exactly once, so they can't be used
+// for pub/sub or MONITOR.
+type Session struct {
+	client *Reader
+	out    *Writer
+
+	mu        sync.Mutex
+	delivered *sync.Cond
+	upstreams []*sessionUpstream
+	// order holds replies that haven't been delivered yet, in request order.
+	order []*sessionReply
+	// delivering is set while a goroutine writes replies to the client
+	// without holding mu.
+	delivering   bool
+	err          error
+	closed       bool
+	instrumenter Instrumenter
+}
+
+type sessionUpstream struct {
+	r *Reader
+	// wmu serializes writes to the upstream, which are made without holding
+	// the Session's mu.
+	wmu sync.Mutex
+	w   *Writer
+	// pending holds the replies the upstream owes, in the order the commands
+	// were written to it.
+	pending []*sessionReply
+	err     error
+}
+
+type sessionReply struct {
+	reply []byte
+	done  bool
+	// inst, exchange, and ctx are set if the Session has an Instrumenter.
+	inst     Instrumenter
+	exchange *Exchange
+	ctx      context.Context
+}
+
+// NewSession returns a Session that reads commands from and writes replies to
+// client and forwards commands to upstreams.
+func NewSession(client io.ReadWriter, upstreams ...io.ReadWriter) *Session {
+	s := &Session{
+		client: NewReader(client),
+		out:    NewWriter(client),
+	}
+	s.client.SetMaxSize(DEFAULT_MAX_BUFFER)
+	s.delivered = sync.NewCond(&s.mu)
+	for _, rw := range upstreams {
+		u := &sessionUpstream{r: NewReader(rw), w: NewWriter(rw)}
+		u.r.SetMaxSize(DEFAULT_MAX_BUFFER)
+		s.upstreams = append(s.upstreams, u)
+		go s.readReplies(u)
+	}
+	return s
+}
+
+// ReadCommand reads the next command from the client. See
+// Reader.ReadCommand.
+func (s *Session) ReadCommand() (Command, error) {
+	return s.client.ReadCommand()
+}
+
+// SetRewriter sets a Rewriter for the commands returned by ReadCommand.
+func (s *Session) SetRewriter(rw Rewriter) {
+	s.client.SetRewriter(rw)
+}
+
+// SetInstrumenter sets an Instrumenter notified of the exchange of each
+// forwarded command and its upstream's reply. It must be set before commands
+// are forwarded.
+func (s *Session) SetInstrumenter(inst Instrumenter) {
+	s.instrumenter = inst
+}
+
+// Upstreams returns the number of upstreams.
+func (s *Session) Upstreams() int {
+	return len(s.upstreams)
+}
+
+// Forward writes cmd to the upstream with the given index and reserves the
+// reply's place in the client's reply order. If the upstream has failed, the
+// error is returned and nothing is reserved. An error writing to the upstream
+// fails the upstream, so that later commands aren't forwarded to it.
+func (s *Session) Forward(upstream int, cmd Command) error {
+	return s.ForwardContext(context.Background(), upstream, cmd)
+}
+
+// ForwardContext is the same as Forward except that ctx is passed to the
+// Session's Instrumenter, e.g. so that the exchange's span is a child of the
+// client request's.
+func (s *Session) ForwardContext(ctx context.Context, upstream int, cmd Command) (err error) {
+	if upstream < 0 || upstream >= len(s.upstreams) {
+		return ErrNoUpstream
+	}
+
+	reply := &sessionReply{inst: s.instrumenter}
+	if reply.inst != nil {
+		reply.exchange = newExchange(cmd)
+		reply.ctx = reply.inst.StartExchange(ctx, reply.exchange)
+		defer func() {
+			if err != nil {
+				reply.endExchange(nil, err)
+			}
+		}()
+	}
+
+	u := s.upstreams[upstream]
+	// Holding u.wmu from the reservation to the end of the write keeps the
+	// order of u.pending the order of the commands on the connection.
+	u.wmu.Lock()
+	defer u.wmu.Unlock()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSessionClosed
+	}
+	if u.err != nil {
+		s.mu.Unlock()
+		return u.err
+	}
+	s.order = append(s.order, reply)
+	u.pending = append(u.pending, reply)
+	s.mu.Unlock()
+
+	_, err = u.w.Write(cmd)
+	if err == nil {
+		err = u.w.Flush()
+	}
+	if err != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if u.err == nil {
+			u.err = err
+		}
+		if reply.done {
+			// The upstream's reader already failed the reply, and the
+			// failure is queued for the client.
+			return nil
+		}
+		s.order = removeSessionReply(s.order, reply)
+		u.pending = removeSessionReply(u.pending, reply)
+		s.deliver()
+		return err
+	}
+	return nil
+}
+
+// removeSessionReply removes reply from replies.
+func removeSessionReply(replies []*sessionReply, reply *sessionReply) []*sessionReply {
+	for i, r := range replies {
+		if r == reply {
+			return append(replies[:i:i], replies[i+1:]...)
+		}
+	}
+	return replies
+}
+
+// Reply queues a reply that the proxy produced itself, such as an error for
+// an invalid command. It's delivered after the replies to all commands that
+// were forwarded before it.
+func (s *Session) Reply(obj Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSessionClosed
+	}
+	s.order = append(s.order, &sessionReply{reply: obj.Raw(), done: true})
+	return s.deliver()
+}
+
+// Wait blocks until every queued reply has been delivered to the client and
+// returns the first error encountered while writing to the client.
+func (s *Session) Wait() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for (len(s.order) > 0 || s.delivering) && s.err == nil && !s.closed {
+		s.delivered.Wait()
+	}
+	return s.err
+}
+
+// Close discards undelivered replies and makes further calls fail. It doesn't
+// close the client or upstream connections.
+func (s *Session) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.order = nil
+	s.delivered.Broadcast()
+}
+
+func (s *Session) readReplies(u *sessionUpstream) {
+	for {
+		reply, err := u.r.ReadObjectBytes()
+
+		s.mu.Lock()
+		if err == nil && len(u.pending) == 0 {
+			err = ErrUnexpectedReply
+		}
+		if err != nil {
+			// Fail everything the upstream still owes so that the client's
+			// replies don't stall behind it.
+			u.err = err
+			failure := NewError("ERR upstream error: " + err.Error()).Raw()
+			failed := u.pending
+			for _, pending := range failed {
+				pending.reply = failure
+				pending.done = true
+			}
+			u.pending = nil
+			s.deliver()
+			s.mu.Unlock()
+			for _, pending := range failed {
+				pending.endExchange(nil, err)
+			}
+			return
+		}
+		answered := u.pending[0]
+		answered.reply = reply
+		answered.done = true
+		u.pending = u.pending[1:]
+		s.deliver()
+		s.mu.Unlock()
+		answered.endExchange(reply, nil)
+	}
+}
+
+// endExchange ends the reply's exchange, if it has one.
+func (r *sessionReply) endExchange(reply []byte, err error) {
+	if r.inst != nil {
+		r.exchange.end(reply, err)
+		r.inst.EndExchange(r.ctx, r.exchange)
+	}
+}
+
+// deliver writes the completed replies at the front of the reply order to the
+// client. s.mu must be held, but it's released while writing, so that a slow
+// client doesn't hold up the upstreams' readers. Replies completed meanwhile
+// are written by the goroutine already delivering.
+func (s *Session) deliver() error {
+	if s.delivering {
+		return s.err
+	}
+	s.delivering = true
+	for !s.closed && s.err == nil {
+		n := 0
+		for n < len(s.order) && s.order[n].done {
+			n++
+		}
+		if n == 0 {
+			break
+		}
+		batch := s.order[:n]
+		s.order = s.order[n:]
+
+		s.mu.Unlock()
+		for _, reply := range batch {
+			s.out.Write(reply.reply)
+		}
+		err := s.out.Flush()
+		s.mu.Lock()
+
+		if err != nil && s.err == nil {
+			s.err = err
+		}
+	}
+	s.delivering = false
+	s.delivered.Broadcast()
+	return s.err
+}
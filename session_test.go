@@ -0,0 +1,200 @@
+package resp
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoUpstream answers every command on conn with its first argument once
+// release is readable. Commands are read ahead so that writes to conn don't
+// block.
+func echoUpstream(conn net.Conn, release <-chan bool) {
+	commands := make(chan Command, 10)
+	go func() {
+		defer close(commands)
+		r := NewReader(conn)
+		for {
+			cmd, err := r.ReadCommand()
+			if err != nil {
+				return
+			}
+			commands <- cmd
+		}
+	}()
+	for cmd := range commands {
+		slices, _ := cmd.Slices()
+		<-release
+		conn.Write(NewBulkString(string(slices[1])))
+	}
+}
+
+func TestSession(t *testing.T) {
+	client, proxySide := net.Pipe()
+	defer client.Close()
+	up0, server0 := net.Pipe()
+	up1, server1 := net.Pipe()
+	defer server0.Close()
+	defer server1.Close()
+
+	release0 := make(chan bool, 10)
+	release1 := make(chan bool, 10)
+	go echoUpstream(server0, release0)
+	go echoUpstream(server1, release1)
+
+	replies := make(chan Object, 10)
+	go func() {
+		r := NewReader(client)
+		for {
+			obj, err := r.ReadObject()
+			if err != nil {
+				return
+			}
+			replies <- obj
+		}
+	}()
+
+	s := NewSession(proxySide, up0, up1)
+	if s.Upstreams() != 2 {
+		t.Errorf("unexpected upstream count: %d", s.Upstreams())
+	}
+	if err := s.Forward(0, NewCommand("GET", "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Forward(1, NewCommand("GET", "b")); err != nil {
+		t.Fatal(err)
+	}
+	s.Reply(NewError("ERR unknown command"))
+	if err := s.Forward(0, NewCommand("GET", "c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Forward(2, NewCommand("GET", "d")); err != ErrNoUpstream {
+		t.Errorf("expected ErrNoUpstream, got %v", err)
+	}
+
+	// The second upstream answers first, but its reply has to wait.
+	release1 <- true
+	select {
+	case obj := <-replies:
+		t.Fatalf("expected no replies before the first upstream answers, got %q", obj)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release0 <- true
+	release0 <- true
+	if err := s.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"$1\r\na\r\n", "$1\r\nb\r\n", "-ERR unknown command\r\n", "$1\r\nc\r\n"}
+	for i, e := range expected {
+		if obj := <-replies; string(obj.Raw()) != e {
+			t.Errorf("replies[%d]: expected %q, got %q", i, e, obj.Raw())
+		}
+	}
+}
+
+func TestSession_UpstreamFailure(t *testing.T) {
+	client, proxySide := net.Pipe()
+	defer client.Close()
+	up, server := net.Pipe()
+
+	replies := make(chan Object, 10)
+	go func() {
+		r := NewReader(client)
+		for {
+			obj, err := r.ReadObject()
+			if err != nil {
+				return
+			}
+			replies <- obj
+		}
+	}()
+	go func() {
+		NewReader(server).ReadCommand()
+		server.Close()
+	}()
+
+	s := NewSession(proxySide, up)
+	if err := s.Forward(0, NewCommand("GET", "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := (<-replies).(Error); !ok {
+		t.Errorf("expected an error reply, got %q", e)
+	}
+	if err := s.Forward(0, NewCommand("GET", "b")); err == nil {
+		t.Errorf("expected the upstream to be marked as failed")
+	}
+
+	s.Close()
+	if err := s.Reply(OK); err != ErrSessionClosed {
+		t.Errorf("expected ErrSessionClosed, got %v", err)
+	}
+}
+
+func TestSession_LargeReply(t *testing.T) {
+	client, proxySide := net.Pipe()
+	defer client.Close()
+	up, server := net.Pipe()
+	defer server.Close()
+
+	value := strings.Repeat("x", 20<<10)
+	go func() {
+		r := NewReader(server)
+		r.SetMaxSize(DEFAULT_MAX_BUFFER)
+		if _, err := r.ReadCommand(); err == nil {
+			server.Write(NewBulkString(value))
+		}
+	}()
+	replies := make(chan Object, 1)
+	go func() {
+		r := NewReader(client)
+		r.SetMaxSize(DEFAULT_MAX_BUFFER)
+		obj, _ := r.ReadObject()
+		replies <- obj
+	}()
+
+	s := NewSession(proxySide, up)
+	if err := s.Forward(0, NewCommand("SET", "a", value)); err != nil {
+		t.Fatal(err)
+	}
+	if obj := <-replies; string(obj.Raw()) != string(NewBulkString(value)) {
+		t.Errorf("unexpected reply: %.40q", obj.Raw())
+	}
+}
+
+// failingUpstream fails every write and blocks reads until closed.
+type failingUpstream struct {
+	closed chan bool
+}
+
+func (u failingUpstream) Read(b []byte) (int, error) {
+	<-u.closed
+	return 0, io.EOF
+}
+
+func (u failingUpstream) Write(b []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestSession_WriteFailure(t *testing.T) {
+	client, proxySide := net.Pipe()
+	defer client.Close()
+	up := failingUpstream{closed: make(chan bool)}
+	defer close(up.closed)
+
+	s := NewSession(proxySide, up)
+	if err := s.Forward(0, NewCommand("GET", "a")); err != io.ErrClosedPipe {
+		t.Errorf("expected io.ErrClosedPipe, got %v", err)
+	}
+	if err := s.Forward(0, NewCommand("GET", "b")); err != io.ErrClosedPipe {
+		t.Errorf("expected the upstream to be marked as failed, got %v", err)
+	}
+	if err := s.Wait(); err != nil {
+		t.Errorf("expected nothing to be left to deliver, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package resp
+
+import (
+	"strconv"
+)
+
+const (
+	wordSize = strconv.IntSize / 8
+	// sliceHeaderSize is the size of a slice header: a pointer, a length,
+	// and a capacity.
+	sliceHeaderSize = 3 * wordSize
+	// interfaceSize is the size of an interface value, such as an Object:
+	// a type pointer and a data pointer.
+	interfaceSize = 2 * wordSize
+	// objectSize is the size of an Object holding a slice, whose slice
+	// header is allocated separately from the interface value.
+	objectSize = interfaceSize + sliceHeaderSize
+)
+
+// EstimateSize returns the approximate number of bytes of memory obj takes
+// up once decoded: its bytes, its header, and the slices of objects returned
+// by Objects for it and every aggregate nested in it. Nested objects point
+// into obj's bytes rather than copying them, so only their headers count. It
+// lets caches of replies keep to a budget of bytes rather than of entries.
+// Invalid objects are counted as if they weren't aggregates.
+func EstimateSize(obj Object) int {
+	b := obj.Raw()
+	return objectSize + cap(b) + decodedSize(b)
+}
+
+// EstimateFrameSize returns the approximate number of bytes of memory a
+// frame held as raw bytes takes up, such as a Frame or the bytes returned by
+// Reader.ReadObjectBytes, without decoding it.
+func EstimateFrameSize(b []byte) int {
+	return sliceHeaderSize + cap(b)
+}
+
+// decodedSize returns the size of the slices of objects that decoding the
+// object b and the objects nested in it allocates.
+func decodedSize(b []byte) int {
+	if len(b) == 0 {
+		return 0
+	}
+	switch b[0] {
+	case ARRAY_PREFIX, MAP_PREFIX, SET_PREFIX, PUSH_PREFIX, ATTRIBUTE_PREFIX:
+	default:
+		return 0
+	}
+	objects, err := aggregateObjects(b)
+	if err != nil || objects == nil {
+		return 0
+	}
+	size := sliceHeaderSize
+	for _, object := range objects {
+		size += objectSize + decodedSize(object.Raw())
+	}
+	return size
+}
@@ -0,0 +1,42 @@
+package resp
+
+import (
+	"testing"
+)
+
+func TestEstimateSize(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected int
+	}{
+		{OK, objectSize + 5},
+		{NewBulkString("hello"), objectSize + 11},
+		{Array("*-1\r\n"), objectSize + 5},
+		{NewArray(), objectSize + 4 + sliceHeaderSize},
+		{NewArray(OK, NewInteger(1)), objectSize + 13 + sliceHeaderSize + 2*objectSize},
+		{
+			NewArray(NewArray(OK), NewMap(OK, OK)),
+			objectSize + 27 + sliceHeaderSize + 2*objectSize + // the outer array
+				sliceHeaderSize + objectSize + // the inner array
+				sliceHeaderSize + 2*objectSize, // the map
+		},
+		{InvalidObject("*2\r\n:1\r\n"), objectSize + 8},
+	}
+
+	for i, test := range tests {
+		raw := test.obj.Raw()
+		// Only the length of the objects' bytes is predictable
+		obj := Parse(raw[:len(raw):len(raw)])
+		if _, ok := test.obj.(InvalidObject); ok {
+			obj = InvalidObject(raw[:len(raw):len(raw)])
+		}
+		if size := EstimateSize(obj); size != test.expected {
+			t.Errorf("tests[%d]: expected %d, got %d", i, test.expected, size)
+		}
+	}
+
+	frame := make([]byte, 5, 64)
+	if size := EstimateFrameSize(frame); size != sliceHeaderSize+64 {
+		t.Errorf("expected the frame's capacity to count, got %d", size)
+	}
+}
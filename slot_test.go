@@ -0,0 +1,71 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+type slotTest struct {
+	key  string
+	slot uint16
+}
+
+func TestSlot(t *testing.T) {
+	tests := []slotTest{
+		{"", 0},
+		{"foo", 12182},
+		{"bar", 5061},
+		{"123456789", 0x31c3 % SLOT_COUNT},
+		{"{user1000}.following", 3443},
+		{"{user1000}.followers", 3443},
+		{"user1000", 3443},
+		// empty hash tag
+		{"foo{}{bar}", crc16([]byte("foo{}{bar}")) % SLOT_COUNT},
+		// hash tag ends at the first '}'
+		{"foo{{bar}}zap", crc16([]byte("{bar")) % SLOT_COUNT},
+		// only the first hash tag counts
+		{"foo{bar}{zap}", 5061},
+		// unclosed hash tag
+		{"{foo", crc16([]byte("{foo")) % SLOT_COUNT},
+	}
+
+	for i, test := range tests {
+		slot := Slot([]byte(test.key))
+		if slot != test.slot {
+			t.Errorf("tests[%d]: %q: expected: %d, got: %d", i, test.key, test.slot, slot)
+		}
+	}
+}
+
+func TestCheckSameSlot(t *testing.T) {
+	args := func(args ...string) [][]byte {
+		slices := make([][]byte, len(args))
+		for i, arg := range args {
+			slices[i] = []byte(arg)
+		}
+		return slices
+	}
+
+	if err := CheckSameSlot("MGET", args("{user1}.a", "{user1}.b")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckSameSlot("PING", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckSameSlot("MSET", args("foo", "1", "bar", "2")); !reflect.DeepEqual(CROSSSLOT, err) {
+		t.Errorf("expected CROSSSLOT, got %v", err)
+	}
+	if err := CheckSameSlot("NOPE", args("foo")); err != ErrUnknownCommand {
+		t.Errorf("expected ErrUnknownCommand, got %v", err)
+	}
+	if string(CROSSSLOT) != "-CROSSSLOT Keys in request don't hash to the same slot\r\n" {
+		t.Errorf("unexpected error reply: %q", CROSSSLOT)
+	}
+}
+
+func BenchmarkSlot(b *testing.B) {
+	key := []byte("{user1000}.following")
+	for i := 0; i < b.N; i++ {
+		Slot(key)
+	}
+}
@@ -0,0 +1,127 @@
+package resp
+
+// A Subcommand is one of the per-key commands a multi-key command is split
+// into by SplitCommand.
+type Subcommand struct {
+	Name string
+	Args [][]byte
+	// Position is the index of the subcommand's key among the keys of the
+	// original command. It's also the index of the subcommand's reply in an
+	// MGET reply.
+	Position int
+}
+
+// Command returns the Subcommand as a Command that can be sent upstream.
+func (s Subcommand) Command() Command {
+	return newCommand(s.Name, s.Args)
+}
+
+// SplitCommand splits an MGET, MSET, DEL, UNLINK, EXISTS, or TOUCH command
+// into one subcommand per key so that each key can be routed separately. The
+// replies to the subcommands can be reassembled with MergeReplies. Other
+// commands are returned as a single subcommand. The subcommands' arguments
+// point into args. If the command has the wrong number of arguments, the
+// Error reply Redis would send is returned.
+func SplitCommand(name string, args [][]byte) ([]Subcommand, error) {
+	if err := ValidateCommand(name, args); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case splitsByKey(name):
+		subcommands := make([]Subcommand, len(args))
+		for i := range args {
+			subcommands[i] = Subcommand{name, args[i : i+1], i}
+		}
+		return subcommands, nil
+	case CommandEquals([]byte(name), "MSET"):
+		if len(args)%2 != 0 {
+			return nil, wrongArityError(name)
+		}
+		subcommands := make([]Subcommand, len(args)/2)
+		for i := range subcommands {
+			subcommands[i] = Subcommand{name, args[i*2 : i*2+2], i}
+		}
+		return subcommands, nil
+	default:
+		return []Subcommand{{name, args, 0}}, nil
+	}
+}
+
+// splitsByKey returns true for the commands SplitCommand splits into single
+// key subcommands.
+func splitsByKey(name string) bool {
+	for _, split := range []string{"MGET", "DEL", "UNLINK", "EXISTS", "TOUCH"} {
+		if CommandEquals([]byte(name), split) {
+			return true
+		}
+	}
+	return false
+}
+
+// MergeReplies reassembles the replies to the subcommands returned by
+// SplitCommand into the reply the client expects for the original command.
+// parts must be in the order of the subcommands' Positions. MGET replies are
+// merged into one array, in which a part that failed is represented by its
+// Error. DEL, UNLINK, EXISTS, and TOUCH counts are summed and MSET replies are
+// merged into OK; if any part of those failed, its Error is the reply. It
+// returns ErrInvalidArguments if a part isn't the kind of reply the command
+// should have.
+func MergeReplies(name string, parts []Object) (Object, error) {
+	if len(parts) == 0 {
+		return nil, ErrInvalidArguments
+	}
+
+	switch {
+	case CommandEquals([]byte(name), "MGET"):
+		values := make([]Object, len(parts))
+		for i, part := range parts {
+			switch p := part.(type) {
+			case Error:
+				values[i] = p
+			case Array:
+				objects, err := p.Objects()
+				if err != nil || len(objects) != 1 {
+					return nil, ErrInvalidArguments
+				}
+				values[i] = objects[0]
+			default:
+				return nil, ErrInvalidArguments
+			}
+		}
+		return NewArray(values...), nil
+	case splitsByKey(name):
+		var sum int64
+		for _, part := range parts {
+			switch p := part.(type) {
+			case Error:
+				return p, nil
+			case Integer:
+				n, err := p.Int64()
+				if err != nil {
+					return nil, ErrInvalidArguments
+				}
+				sum += n
+			default:
+				return nil, ErrInvalidArguments
+			}
+		}
+		return NewInteger(sum), nil
+	case CommandEquals([]byte(name), "MSET"):
+		for _, part := range parts {
+			switch part.(type) {
+			case Error:
+				return part, nil
+			case String:
+			default:
+				return nil, ErrInvalidArguments
+			}
+		}
+		return OK, nil
+	default:
+		if len(parts) != 1 {
+			return nil, ErrInvalidArguments
+		}
+		return parts[0], nil
+	}
+}
@@ -0,0 +1,98 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	args := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	subcommands, err := SplitCommand("mget", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Subcommand{
+		{"mget", args[0:1], 0},
+		{"mget", args[1:2], 1},
+		{"mget", args[2:3], 2},
+	}
+	if !reflect.DeepEqual(expected, subcommands) {
+		t.Errorf("expected: %v\ngot: %v", expected, subcommands)
+	}
+	if !reflect.DeepEqual(NewCommand("mget", "b"), subcommands[1].Command()) {
+		t.Errorf("unexpected command: %q", subcommands[1].Command())
+	}
+
+	args = [][]byte{[]byte("a"), []byte("1"), []byte("b"), []byte("2")}
+	subcommands, err = SplitCommand("MSET", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []Subcommand{
+		{"MSET", args[0:2], 0},
+		{"MSET", args[2:4], 1},
+	}
+	if !reflect.DeepEqual(expected, subcommands) {
+		t.Errorf("expected: %v\ngot: %v", expected, subcommands)
+	}
+
+	args = [][]byte{[]byte("a")}
+	subcommands, err = SplitCommand("GET", args)
+	if err != nil || !reflect.DeepEqual([]Subcommand{{"GET", args, 0}}, subcommands) {
+		t.Errorf("expected GET not to be split, got %v, %v", subcommands, err)
+	}
+
+	_, err = SplitCommand("MSET", [][]byte{[]byte("a"), []byte("1"), []byte("b")})
+	if !reflect.DeepEqual(NewError("ERR wrong number of arguments for 'mset' command"), err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = SplitCommand("DEL", nil)
+	if err == nil {
+		t.Errorf("expected an error but didn't get one")
+	}
+}
+
+type mergeRepliesTest struct {
+	name     string
+	parts    []Object
+	expected Object
+}
+
+func TestMergeReplies_Valid(t *testing.T) {
+	oops := NewError("ERR oops")
+	tests := []mergeRepliesTest{
+		{"MGET", []Object{Array("*1\r\n$1\r\n1\r\n"), Array("*1\r\n$-1\r\n")}, Array("*2\r\n$1\r\n1\r\n$-1\r\n")},
+		{"MGET", []Object{Array("*1\r\n$1\r\n1\r\n"), oops}, Array("*2\r\n$1\r\n1\r\n-ERR oops\r\n")},
+		{"DEL", []Object{NewInteger(1), NewInteger(0), NewInteger(1)}, Integer(":2\r\n")},
+		{"exists", []Object{NewInteger(1), oops}, oops},
+		{"MSET", []Object{OK, OK}, OK},
+		{"MSET", []Object{OK, oops}, oops},
+		{"GET", []Object{NewBulkString("x")}, NewBulkString("x")},
+	}
+
+	for i, test := range tests {
+		reply, err := MergeReplies(test.name, test.parts)
+		if err != nil {
+			t.Errorf("tests[%d]: %s", i, err.Error())
+		} else if !reflect.DeepEqual(test.expected, reply) {
+			t.Errorf("tests[%d]:\nexpected: %q\ngot: %q", i, test.expected, reply)
+		}
+	}
+}
+
+func TestMergeReplies_Invalid(t *testing.T) {
+	tests := []mergeRepliesTest{
+		{"MGET", nil, nil},
+		{"MGET", []Object{NewInteger(1)}, nil},
+		{"DEL", []Object{NewBulkString("1")}, nil},
+		{"MSET", []Object{NewInteger(1)}, nil},
+		{"GET", []Object{OK, OK}, nil},
+	}
+
+	for i, test := range tests {
+		_, err := MergeReplies(test.name, test.parts)
+		if err == nil {
+			t.Errorf("tests[%d]: expected an error but didn't get one", i)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A CommandStat holds the statistics CommandStats collects for one command.
+type CommandStat struct {
+	Calls      int64
+	ArgBytes   int64
+	ReplyBytes int64
+}
+
+// CommandStats accumulates per-command statistics for a stream of commands and
+// replies. It implements Rewriter, without rewriting anything, so that it can
+// be attached to a Reader with SetRewriter (or ChainRewriters) to count every
+// command read. It's safe for concurrent use. The zero value is ready to use.
+type CommandStats struct {
+	mu    sync.Mutex
+	stats map[string]*CommandStat
+}
+
+// Rewrite records a call to the named command and returns the command
+// unchanged.
+func (s *CommandStats) Rewrite(name string, args [][]byte) (string, [][]byte, error) {
+	s.RecordCommand(name, args)
+	return name, args, nil
+}
+
+// RecordCommand records a call to the named command with the given arguments.
+func (s *CommandStats) RecordCommand(name string, args [][]byte) {
+	var argBytes int64
+	for _, arg := range args {
+		argBytes += int64(len(arg))
+	}
+
+	s.mu.Lock()
+	stat := s.stat(name)
+	stat.Calls++
+	stat.ArgBytes += argBytes
+	s.mu.Unlock()
+}
+
+// RecordReply records the raw bytes of a reply to the named command.
+func (s *CommandStats) RecordReply(name string, reply []byte) {
+	s.mu.Lock()
+	s.stat(name).ReplyBytes += int64(len(reply))
+	s.mu.Unlock()
+}
+
+func (s *CommandStats) stat(name string) *CommandStat {
+	if canonical, ok := LookupCommand([]byte(name)); ok {
+		name = canonical
+	} else {
+		name = strings.ToUpper(name)
+	}
+
+	if s.stats == nil {
+		s.stats = map[string]*CommandStat{}
+	}
+	stat := s.stats[name]
+	if stat == nil {
+		stat = &CommandStat{}
+		s.stats[name] = stat
+	}
+	return stat
+}
+
+// Snapshot returns a copy of the current statistics keyed by upper case
+// command name.
+func (s *CommandStats) Snapshot() map[string]CommandStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]CommandStat, len(s.stats))
+	for name, stat := range s.stats {
+		snapshot[name] = *stat
+	}
+	return snapshot
+}
+
+// Reset clears all statistics.
+func (s *CommandStats) Reset() {
+	s.mu.Lock()
+	s.stats = nil
+	s.mu.Unlock()
+}
+
+// Info returns the statistics formatted like the commandstats section of
+// Redis' INFO reply, e.g. "cmdstat_get:calls=2,arg_bytes=6,reply_bytes=18".
+func (s *CommandStats) Info() string {
+	snapshot := s.Snapshot()
+	names := make([]string, 0, len(snapshot))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("# Commandstats\r\n")
+	for _, name := range names {
+		stat := snapshot[name]
+		fmt.Fprintf(&buf, "cmdstat_%s:calls=%d,arg_bytes=%d,reply_bytes=%d\r\n", strings.ToLower(name), stat.Calls, stat.ArgBytes, stat.ReplyBytes)
+	}
+	return buf.String()
+}
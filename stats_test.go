@@ -0,0 +1,43 @@
+package resp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCommandStats(t *testing.T) {
+	var stats CommandStats
+	stream := append(NewCommand("get", "foo"), NewCommand("GET", "barbaz")...)
+	stream = append(stream, NewCommand("SET", "a", "b")...)
+
+	reader := NewReader(bytes.NewReader(stream))
+	reader.SetRewriter(ChainRewriters(&stats, KeyPrefixRewriter([]byte("x"))))
+	for i := 0; i < 3; i++ {
+		if _, err := reader.ReadCommand(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats.RecordReply("get", NewBulkString("1"))
+	stats.RecordReply("set", OK)
+
+	expected := map[string]CommandStat{
+		"GET": {2, 9, 7},
+		"SET": {1, 2, 5},
+	}
+	if !reflect.DeepEqual(expected, stats.Snapshot()) {
+		t.Errorf("expected: %v\ngot: %v", expected, stats.Snapshot())
+	}
+
+	info := "# Commandstats\r\n" +
+		"cmdstat_get:calls=2,arg_bytes=9,reply_bytes=7\r\n" +
+		"cmdstat_set:calls=1,arg_bytes=2,reply_bytes=5\r\n"
+	if stats.Info() != info {
+		t.Errorf("expected: %q\ngot: %q", info, stats.Info())
+	}
+
+	stats.Reset()
+	if len(stats.Snapshot()) != 0 {
+		t.Errorf("expected no stats after Reset")
+	}
+}
@@ -0,0 +1,325 @@
+package resp
+
+import (
+	"time"
+)
+
+// A StreamEntry is an entry of a stream. Fields is nil for entries that were
+// deleted while pending, which XREADGROUP and XCLAIM report without fields.
+type StreamEntry struct {
+	ID     string
+	Fields map[string]string
+}
+
+// A StreamReadResult holds the entries XREAD or XREADGROUP read from a
+// stream.
+type StreamReadResult struct {
+	Stream  string
+	Entries []StreamEntry
+}
+
+// A StreamAutoClaim is an XAUTOCLAIM reply. Next is the ID to continue
+// scanning from, "0-0" once the whole pending entries list was scanned, and
+// Deleted holds the IDs of claimed entries that no longer exist, which
+// servers before Redis 7 don't report.
+type StreamAutoClaim struct {
+	Next    string
+	Entries []StreamEntry
+	Deleted []string
+}
+
+// A StreamPendingSummary is the reply to XPENDING without a range: the number
+// of pending entries of a group, the lowest and highest of their IDs, and the
+// number pending for each consumer.
+type StreamPendingSummary struct {
+	Count     int64
+	Lowest    string
+	Highest   string
+	Consumers map[string]int64
+}
+
+// A StreamPendingEntry is an entry of the reply to XPENDING with a range.
+type StreamPendingEntry struct {
+	ID       string
+	Consumer string
+	// Idle is the time since the entry was last delivered.
+	Idle       time.Duration
+	Deliveries int64
+}
+
+// A StreamInfo is an XINFO STREAM reply. Fields holds every field of the
+// reply, including the ones decoded into the other members.
+type StreamInfo struct {
+	Length          int64
+	LastGeneratedID string
+	Groups          int64
+	// FirstEntry and LastEntry are nil for empty streams.
+	FirstEntry *StreamEntry
+	LastEntry  *StreamEntry
+	Fields     map[string]Object
+}
+
+// ParseStreamEntries decodes the entries of an XRANGE, XREVRANGE, or XCLAIM
+// reply. It returns ErrUnexpectedReply if obj isn't an array of entries, each
+// of which is an array of an ID and a flat array of field names and values.
+func ParseStreamEntries(obj Object) ([]StreamEntry, error) {
+	objects, ok := objectArray(obj)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	entries := make([]StreamEntry, len(objects))
+	for i, object := range objects {
+		entry, err := parseStreamEntry(object)
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// ParseStreamRead decodes an XREAD or XREADGROUP reply, an array of streams
+// and their entries or, from RESP3 servers, a map. It returns nil if the
+// command timed out without reading any entries, and ErrUnexpectedReply if
+// obj isn't shaped like an XREAD reply.
+func ParseStreamRead(obj Object) ([]StreamReadResult, error) {
+	if objectIsNull(obj) {
+		return nil, nil
+	}
+
+	var pairs []Object
+	if _, ok := obj.(Map); ok {
+		pairs, ok = objectPairs(obj)
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+	} else {
+		objects, ok := objectArray(obj)
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		for _, object := range objects {
+			pair, ok := objectArray(object)
+			if !ok || len(pair) != 2 {
+				return nil, ErrUnexpectedReply
+			}
+			pairs = append(pairs, pair...)
+		}
+	}
+
+	results := make([]StreamReadResult, len(pairs)/2)
+	for i := range results {
+		stream, ok := objectString(pairs[2*i])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		entries, err := ParseStreamEntries(pairs[2*i+1])
+		if err != nil {
+			return nil, err
+		}
+		results[i] = StreamReadResult{Stream: stream, Entries: entries}
+	}
+	return results, nil
+}
+
+// ParseStreamAutoClaim decodes an XAUTOCLAIM reply. With JUSTID, the entries
+// only have IDs. It returns ErrUnexpectedReply if obj isn't shaped like an
+// XAUTOCLAIM reply.
+func ParseStreamAutoClaim(obj Object) (*StreamAutoClaim, error) {
+	objects, ok := objectArray(obj)
+	if !ok || len(objects) < 2 || len(objects) > 3 {
+		return nil, ErrUnexpectedReply
+	}
+	claim := &StreamAutoClaim{}
+	if claim.Next, ok = objectString(objects[0]); !ok {
+		return nil, ErrUnexpectedReply
+	}
+
+	entries, ok := objectArray(objects[1])
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	claim.Entries = make([]StreamEntry, len(entries))
+	for i, object := range entries {
+		if id, ok := objectString(object); ok {
+			claim.Entries[i] = StreamEntry{ID: id}
+			continue
+		}
+		entry, err := parseStreamEntry(object)
+		if err != nil {
+			return nil, err
+		}
+		claim.Entries[i] = entry
+	}
+
+	if len(objects) == 3 {
+		deleted, ok := objectArray(objects[2])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		claim.Deleted = make([]string, len(deleted))
+		for i, object := range deleted {
+			if claim.Deleted[i], ok = objectString(object); !ok {
+				return nil, ErrUnexpectedReply
+			}
+		}
+	}
+	return claim, nil
+}
+
+// ParseStreamPendingSummary decodes the reply to XPENDING without a range.
+// Lowest and Highest are "" if nothing is pending. It returns
+// ErrUnexpectedReply if obj isn't shaped like an XPENDING summary.
+func ParseStreamPendingSummary(obj Object) (*StreamPendingSummary, error) {
+	objects, ok := objectArray(obj)
+	if !ok || len(objects) != 4 {
+		return nil, ErrUnexpectedReply
+	}
+	summary := &StreamPendingSummary{Consumers: map[string]int64{}}
+	if summary.Count, ok = objectInt(objects[0]); !ok {
+		return nil, ErrUnexpectedReply
+	}
+	if summary.Count == 0 {
+		return summary, nil
+	}
+	summary.Lowest, ok = objectString(objects[1])
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	summary.Highest, ok = objectString(objects[2])
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	consumers, ok := objectArray(objects[3])
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	for _, object := range consumers {
+		pair, ok := objectArray(object)
+		if !ok || len(pair) != 2 {
+			return nil, ErrUnexpectedReply
+		}
+		name, ok := objectString(pair[0])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		// The counts are sent as strings
+		count, ok := objectString(pair[1])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		n, err := ParseInt([]byte(count))
+		if err != nil {
+			return nil, ErrUnexpectedReply
+		}
+		summary.Consumers[name] = n
+	}
+	return summary, nil
+}
+
+// ParseStreamPending decodes the reply to XPENDING with a range. It returns
+// ErrUnexpectedReply if obj isn't an array of pending entries.
+func ParseStreamPending(obj Object) ([]StreamPendingEntry, error) {
+	objects, ok := objectArray(obj)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	pending := make([]StreamPendingEntry, len(objects))
+	for i, object := range objects {
+		fields, ok := objectArray(object)
+		if !ok || len(fields) != 4 {
+			return nil, ErrUnexpectedReply
+		}
+		entry := &pending[i]
+		if entry.ID, ok = objectString(fields[0]); !ok {
+			return nil, ErrUnexpectedReply
+		}
+		if entry.Consumer, ok = objectString(fields[1]); !ok {
+			return nil, ErrUnexpectedReply
+		}
+		idle, ok := objectInt(fields[2])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		entry.Idle = time.Duration(idle) * time.Millisecond
+		if entry.Deliveries, ok = objectInt(fields[3]); !ok {
+			return nil, ErrUnexpectedReply
+		}
+	}
+	return pending, nil
+}
+
+// ParseStreamInfo decodes an XINFO STREAM reply, without FULL. It returns
+// ErrUnexpectedReply if obj isn't a map or flat array of field names and
+// values.
+func ParseStreamInfo(obj Object) (*StreamInfo, error) {
+	pairs, ok := objectPairs(obj)
+	if !ok {
+		return nil, ErrUnexpectedReply
+	}
+	info := &StreamInfo{Fields: make(map[string]Object, len(pairs)/2)}
+	for i := 0; i < len(pairs); i += 2 {
+		name, ok := objectString(pairs[i])
+		if !ok {
+			return nil, ErrUnexpectedReply
+		}
+		info.Fields[name] = pairs[i+1]
+	}
+
+	info.Length, _ = objectInt(info.Fields["length"])
+	info.Groups, _ = objectInt(info.Fields["groups"])
+	info.LastGeneratedID, _ = objectString(info.Fields["last-generated-id"])
+	var err error
+	if info.FirstEntry, err = parseOptionalStreamEntry(info.Fields["first-entry"]); err != nil {
+		return nil, err
+	}
+	if info.LastEntry, err = parseOptionalStreamEntry(info.Fields["last-entry"]); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// parseStreamEntry decodes an array of an entry's ID and fields.
+func parseStreamEntry(obj Object) (StreamEntry, error) {
+	objects, ok := objectArray(obj)
+	if !ok || len(objects) != 2 {
+		return StreamEntry{}, ErrUnexpectedReply
+	}
+	id, ok := objectString(objects[0])
+	if !ok {
+		return StreamEntry{}, ErrUnexpectedReply
+	}
+	entry := StreamEntry{ID: id}
+	if objectIsNull(objects[1]) {
+		return entry, nil
+	}
+	pairs, ok := objectPairs(objects[1])
+	if !ok {
+		return StreamEntry{}, ErrUnexpectedReply
+	}
+	entry.Fields = make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		field, ok := objectString(pairs[i])
+		if !ok {
+			return StreamEntry{}, ErrUnexpectedReply
+		}
+		value, ok := objectString(pairs[i+1])
+		if !ok {
+			return StreamEntry{}, ErrUnexpectedReply
+		}
+		entry.Fields[field] = value
+	}
+	return entry, nil
+}
+
+// parseOptionalStreamEntry decodes an entry that may be missing or null.
+func parseOptionalStreamEntry(obj Object) (*StreamEntry, error) {
+	if obj == nil || objectIsNull(obj) {
+		return nil, nil
+	}
+	entry, err := parseStreamEntry(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -0,0 +1,182 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testStreamEntry(id string, fields ...string) Object {
+	values := make([]Object, len(fields))
+	for i, field := range fields {
+		values[i] = NewBulkString(field)
+	}
+	return NewArray(NewBulkString(id), NewArray(values...))
+}
+
+func TestParseStreamEntries(t *testing.T) {
+	entries, err := ParseStreamEntries(NewArray(
+		testStreamEntry("1-0", "a", "1", "b", "2"),
+		NewArray(NewBulkString("2-0"), String("$-1\r\n")),
+		NewArray(NewBulkString("3-0"), Array("*-1\r\n")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []StreamEntry{
+		{ID: "1-0", Fields: map[string]string{"a": "1", "b": "2"}},
+		{ID: "2-0"},
+		{ID: "3-0"},
+	}
+	if !reflect.DeepEqual(expected, entries) {
+		t.Errorf("expected %+v, got %+v", expected, entries)
+	}
+
+	for _, obj := range []Object{
+		NewInteger(1),
+		NewArray(NewBulkString("1-0")),
+		NewArray(NewArray(NewBulkString("1-0"), NewArray(NewBulkString("a")))),
+		NewArray(NewArray(NewInteger(1), NewArray())),
+	} {
+		if _, err := ParseStreamEntries(obj); err != ErrUnexpectedReply {
+			t.Errorf("expected ErrUnexpectedReply for %q, got %v", obj.Raw(), err)
+		}
+	}
+}
+
+func TestParseStreamRead(t *testing.T) {
+	expected := []StreamReadResult{
+		{Stream: "s1", Entries: []StreamEntry{{ID: "1-0", Fields: map[string]string{"a": "1"}}}},
+		{Stream: "s2", Entries: []StreamEntry{}},
+	}
+	for _, obj := range []Object{
+		NewArray(
+			NewArray(NewBulkString("s1"), NewArray(testStreamEntry("1-0", "a", "1"))),
+			NewArray(NewBulkString("s2"), NewArray()),
+		),
+		NewMap(
+			NewBulkString("s1"), NewArray(testStreamEntry("1-0", "a", "1")),
+			NewBulkString("s2"), NewArray(),
+		),
+	} {
+		results, err := ParseStreamRead(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, results) {
+			t.Errorf("expected %+v, got %+v", expected, results)
+		}
+	}
+
+	for _, obj := range []Object{NULL, Array("*-1\r\n")} {
+		if results, err := ParseStreamRead(obj); results != nil || err != nil {
+			t.Errorf("expected nothing for a timeout, got %v, %v", results, err)
+		}
+	}
+	if _, err := ParseStreamRead(NewArray(NewArray(NewBulkString("s1")))); err != ErrUnexpectedReply {
+		t.Errorf("expected ErrUnexpectedReply, got %v", err)
+	}
+}
+
+func TestParseStreamAutoClaim(t *testing.T) {
+	claim, err := ParseStreamAutoClaim(NewArray(
+		NewBulkString("5-0"),
+		NewArray(testStreamEntry("1-0", "a", "1")),
+		NewArray(NewBulkString("2-0")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &StreamAutoClaim{
+		Next:    "5-0",
+		Entries: []StreamEntry{{ID: "1-0", Fields: map[string]string{"a": "1"}}},
+		Deleted: []string{"2-0"},
+	}
+	if !reflect.DeepEqual(expected, claim) {
+		t.Errorf("expected %+v, got %+v", expected, claim)
+	}
+
+	// JUSTID, before Redis 7
+	claim, err = ParseStreamAutoClaim(NewArray(NewBulkString("0-0"), NewArray(NewBulkString("1-0"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = &StreamAutoClaim{Next: "0-0", Entries: []StreamEntry{{ID: "1-0"}}}
+	if !reflect.DeepEqual(expected, claim) {
+		t.Errorf("expected %+v, got %+v", expected, claim)
+	}
+
+	if _, err := ParseStreamAutoClaim(NewArray(NewBulkString("0-0"))); err != ErrUnexpectedReply {
+		t.Errorf("expected ErrUnexpectedReply, got %v", err)
+	}
+}
+
+func TestParseStreamPending(t *testing.T) {
+	summary, err := ParseStreamPendingSummary(NewArray(
+		NewInteger(3),
+		NewBulkString("1-0"),
+		NewBulkString("3-0"),
+		NewArray(
+			NewArray(NewBulkString("alice"), NewBulkString("2")),
+			NewArray(NewBulkString("bob"), NewBulkString("1")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedSummary := &StreamPendingSummary{Count: 3, Lowest: "1-0", Highest: "3-0", Consumers: map[string]int64{"alice": 2, "bob": 1}}
+	if !reflect.DeepEqual(expectedSummary, summary) {
+		t.Errorf("expected %+v, got %+v", expectedSummary, summary)
+	}
+
+	summary, err = ParseStreamPendingSummary(NewArray(NewInteger(0), String("$-1\r\n"), String("$-1\r\n"), Array("*-1\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Count != 0 || summary.Lowest != "" || len(summary.Consumers) != 0 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	pending, err := ParseStreamPending(NewArray(NewArray(NewBulkString("1-0"), NewBulkString("alice"), NewInteger(1500), NewInteger(2))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []StreamPendingEntry{{ID: "1-0", Consumer: "alice", Idle: 1500 * time.Millisecond, Deliveries: 2}}
+	if !reflect.DeepEqual(expected, pending) {
+		t.Errorf("expected %+v, got %+v", expected, pending)
+	}
+
+	if _, err := ParseStreamPending(NewArray(NewArray(NewBulkString("1-0")))); err != ErrUnexpectedReply {
+		t.Errorf("expected ErrUnexpectedReply, got %v", err)
+	}
+}
+
+func TestParseStreamInfo(t *testing.T) {
+	pairs := []Object{
+		NewBulkString("length"), NewInteger(2),
+		NewBulkString("groups"), NewInteger(1),
+		NewBulkString("last-generated-id"), NewBulkString("2-0"),
+		NewBulkString("first-entry"), testStreamEntry("1-0", "a", "1"),
+		NewBulkString("last-entry"), testStreamEntry("2-0", "b", "2"),
+	}
+	for _, obj := range []Object{NewArray(pairs...), NewMap(pairs...)} {
+		info, err := ParseStreamInfo(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Length != 2 || info.Groups != 1 || info.LastGeneratedID != "2-0" || len(info.Fields) != 5 {
+			t.Errorf("unexpected info %+v", info)
+		}
+		if info.FirstEntry == nil || info.FirstEntry.ID != "1-0" || info.LastEntry == nil || info.LastEntry.Fields["b"] != "2" {
+			t.Errorf("unexpected entries %+v, %+v", info.FirstEntry, info.LastEntry)
+		}
+	}
+
+	info, err := ParseStreamInfo(NewArray(NewBulkString("length"), NewInteger(0), NewBulkString("first-entry"), String("$-1\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.FirstEntry != nil || info.LastEntry != nil {
+		t.Errorf("expected no entries, got %+v", info)
+	}
+}
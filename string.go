@@ -54,6 +54,9 @@ func (s String) Bytes() []byte {
 	return bytes
 }
 
+// String returns the contents of the string. In builds with the resp_unsafe
+// tag, the returned string points into s and is only valid as long as s is,
+// e.g. until the next read if s was returned by Reader.ReadObjectSlice.
 func (s String) String() string {
-	return string(s.Slice())
+	return bytesToString(s.Slice())
 }
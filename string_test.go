@@ -2,7 +2,9 @@ package resp
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNewBulkString(t *testing.T) {
@@ -20,3 +22,32 @@ func TestNewSimpleString(t *testing.T) {
 		t.Errorf("expected: %v\ngot: %v", expected, s)
 	}
 }
+
+func TestBytesToString(t *testing.T) {
+	b := []byte("GET")
+	s := bytesToString(b)
+	b[0] = 'S'
+	expected := "GET"
+	if UNSAFE_STRINGS {
+		expected = "SET"
+	}
+	if s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+
+	// Names given to Rewriters remain valid after the next read
+	var names []string
+	r := NewReader(iotest.OneByteReader(strings.NewReader("*1\r\n$4\r\nPING\r\n*1\r\n$4\r\nINFO\r\n")))
+	r.SetRewriter(RewriterFunc(func(name string, args [][]byte) (string, [][]byte, error) {
+		names = append(names, name)
+		return name, args, nil
+	}))
+	for i := 0; i < 2; i++ {
+		if _, err := r.ReadCommand(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual([]string{"PING", "INFO"}, names) {
+		t.Errorf("unexpected names: %q", names)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !resp_unsafe
+
+package resp
+
+// UNSAFE_STRINGS is true if the package was built with the resp_unsafe build
+// tag. See bytesToString.
+const UNSAFE_STRINGS = false
+
+// bytesToString returns b as a string. In builds with the resp_unsafe tag, it
+// doesn't copy b, and the string changes if b does, e.g. when b points into
+// a Reader's buffer and the next object is read. It's used for the strings
+// returned by String.String and ParseCommand, which are mostly compared and
+// discarded, e.g. to dispatch commands by name.
+func bytesToString(b []byte) string {
+	return string(b)
+}
@@ -0,0 +1,14 @@
+//go:build resp_unsafe
+
+package resp
+
+import "unsafe"
+
+// UNSAFE_STRINGS is true if the package was built with the resp_unsafe build
+// tag. See bytesToString.
+const UNSAFE_STRINGS = true
+
+// bytesToString returns b as a string without copying it.
+func bytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
@@ -0,0 +1,122 @@
+package resp
+
+import (
+	"sort"
+)
+
+// A SubscriptionTracker follows the pub/sub state of a connection by observing
+// the commands sent on it and the replies received from it. It can tell
+// whether the connection is in subscriber mode, in which only pub/sub
+// commands are allowed and replies can't be paired with requests. The zero
+// value is ready to use.
+type SubscriptionTracker struct {
+	// Number of subscribe confirmations that haven't been received yet.
+	pending       int
+	channels      map[string]bool
+	patterns      map[string]bool
+	shardChannels map[string]bool
+}
+
+// ObserveCommand records a command sent on the connection. SUBSCRIBE,
+// PSUBSCRIBE, and SSUBSCRIBE commands put the connection into subscriber mode
+// immediately, before their confirmations are received.
+func (s *SubscriptionTracker) ObserveCommand(cmd Command) {
+	slices, err := cmd.Slices()
+	if err != nil || len(slices) == 0 {
+		return
+	}
+	name := slices[0]
+	if CommandEquals(name, "SUBSCRIBE") || CommandEquals(name, "PSUBSCRIBE") || CommandEquals(name, "SSUBSCRIBE") {
+		s.pending += len(slices) - 1
+	}
+}
+
+// ObserveReply records a reply or push received on the connection and returns
+// true if it was a subscribe or unsubscribe confirmation.
+func (s *SubscriptionTracker) ObserveReply(obj Object) bool {
+	var objects []Object
+	switch o := obj.(type) {
+	case Array:
+		objects, _ = o.Objects()
+	case Push:
+		objects, _ = o.Objects()
+	}
+	if len(objects) != 3 {
+		return false
+	}
+	kind, ok := objects[0].(String)
+	if !ok {
+		return false
+	}
+	if _, ok := objects[2].(Integer); !ok {
+		return false
+	}
+	var name []byte
+	if channel, ok := objects[1].(String); ok {
+		name = channel.Slice()
+	}
+
+	switch string(kind.Slice()) {
+	case "subscribe":
+		s.confirm(&s.channels, name)
+	case "psubscribe":
+		s.confirm(&s.patterns, name)
+	case "ssubscribe":
+		s.confirm(&s.shardChannels, name)
+	case "unsubscribe":
+		delete(s.channels, string(name))
+	case "punsubscribe":
+		delete(s.patterns, string(name))
+	case "sunsubscribe":
+		delete(s.shardChannels, string(name))
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *SubscriptionTracker) confirm(set *map[string]bool, name []byte) {
+	if s.pending > 0 {
+		s.pending--
+	}
+	if *set == nil {
+		*set = map[string]bool{}
+	}
+	(*set)[string(name)] = true
+}
+
+// Subscribed returns true if the connection is in subscriber mode.
+func (s *SubscriptionTracker) Subscribed() bool {
+	return s.pending > 0 || len(s.channels) > 0 || len(s.patterns) > 0 || len(s.shardChannels) > 0
+}
+
+// Channels returns the sorted names of the channels the connection is
+// subscribed to.
+func (s *SubscriptionTracker) Channels() []string {
+	return sortedKeys(s.channels)
+}
+
+// Patterns returns the sorted patterns the connection is subscribed to.
+func (s *SubscriptionTracker) Patterns() []string {
+	return sortedKeys(s.patterns)
+}
+
+// ShardChannels returns the sorted names of the shard channels the connection
+// is subscribed to.
+func (s *SubscriptionTracker) ShardChannels() []string {
+	return sortedKeys(s.shardChannels)
+}
+
+// Reset forgets all subscription state, e.g. after a reconnect.
+func (s *SubscriptionTracker) Reset() {
+	*s = SubscriptionTracker{}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
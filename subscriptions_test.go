@@ -0,0 +1,50 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubscriptionTracker(t *testing.T) {
+	var s SubscriptionTracker
+	if s.Subscribed() {
+		t.Errorf("expected a new tracker not to be subscribed")
+	}
+
+	s.ObserveCommand(NewCommand("SUBSCRIBE", "a", "b"))
+	if !s.Subscribed() {
+		t.Errorf("expected subscriber mode after SUBSCRIBE")
+	}
+
+	replies := []Object{
+		Array("*3\r\n$9\r\nsubscribe\r\n$1\r\na\r\n:1\r\n"),
+		Array("*3\r\n$9\r\nsubscribe\r\n$1\r\nb\r\n:2\r\n"),
+		Push(">3\r\n$10\r\npsubscribe\r\n$2\r\nc*\r\n:3\r\n"),
+	}
+	for i, reply := range replies {
+		if !s.ObserveReply(reply) {
+			t.Errorf("replies[%d]: expected a confirmation", i)
+		}
+	}
+	if s.ObserveReply(Array("*3\r\n$7\r\nmessage\r\n$1\r\na\r\n$2\r\nhi\r\n")) {
+		t.Errorf("expected a message not to be a confirmation")
+	}
+	if !reflect.DeepEqual([]string{"a", "b"}, s.Channels()) {
+		t.Errorf("unexpected channels: %v", s.Channels())
+	}
+	if !reflect.DeepEqual([]string{"c*"}, s.Patterns()) {
+		t.Errorf("unexpected patterns: %v", s.Patterns())
+	}
+
+	s.ObserveReply(Array("*3\r\n$11\r\nunsubscribe\r\n$1\r\na\r\n:2\r\n"))
+	s.ObserveReply(Array("*3\r\n$11\r\nunsubscribe\r\n$1\r\nb\r\n:1\r\n"))
+	s.ObserveReply(Array("*3\r\n$12\r\npunsubscribe\r\n$2\r\nc*\r\n:0\r\n"))
+	if s.Subscribed() {
+		t.Errorf("expected to leave subscriber mode, channels: %v, patterns: %v", s.Channels(), s.Patterns())
+	}
+
+	// Unsubscribing with no subscriptions confirms a null channel
+	if !s.ObserveReply(Array("*3\r\n$11\r\nunsubscribe\r\n$-1\r\n:0\r\n")) {
+		t.Errorf("expected a confirmation")
+	}
+}
@@ -0,0 +1,260 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoNodes is returned by TopologyRefresher.Refresh when there are no
+	// nodes to ask for the cluster topology.
+	ErrNoNodes = errors.New("resp: no nodes to refresh topology from")
+
+	clusterSlots  = NewCommand("CLUSTER", "SLOTS")
+	clusterShards = NewCommand("CLUSTER", "SHARDS")
+)
+
+const (
+	// DEFAULT_REFRESH_DEBOUNCE is the time a TopologyRefresher waits after
+	// a MOVED redirect before refreshing if its Debounce is zero.
+	DEFAULT_REFRESH_DEBOUNCE = 100 * time.Millisecond
+	// DEFAULT_REFRESH_TIMEOUT bounds each node's answer to a
+	// TopologyRefresher if its Timeout is zero.
+	DEFAULT_REFRESH_TIMEOUT = 5 * time.Second
+)
+
+// A TopologyRefresher keeps a RoutingTable up to date by watching for MOVED
+// redirects. Each MOVED redirect is applied to the table immediately and
+// schedules a full refresh of the table from CLUSTER SLOTS (or CLUSTER
+// SHARDS). Redirects are debounced, so a burst of them, as seen when a slot
+// range is migrated, leads to a single refresh. The table is updated
+// atomically, so lookups can continue while a refresh is in progress.
+type TopologyRefresher struct {
+	// Table is the table to keep up to date.
+	Table *RoutingTable
+	// Dial opens a connection to the node at addr. The connection is closed
+	// after one request. If it's nil, nodes are dialed over TCP.
+	Dial func(addr string) (io.ReadWriteCloser, error)
+	// Seeds are the addresses of nodes to ask for the topology if none of the
+	// masters in Table answer, e.g. the startup nodes.
+	Seeds []string
+	// Debounce is the time to wait after a MOVED redirect before refreshing.
+	// Redirects seen in the meantime are folded into the same refresh.
+	Debounce time.Duration
+	// Timeout bounds connecting to a node and reading its answer, so that a
+	// node that hangs doesn't stall refreshes. The connections returned by
+	// Dial only get a deadline if they have a SetDeadline method, as
+	// net.Conn does. Defaults to DEFAULT_REFRESH_TIMEOUT.
+	Timeout time.Duration
+	// UseShards makes refreshes use CLUSTER SHARDS instead of CLUSTER SLOTS.
+	UseShards bool
+	// OnError, if set, is called with the error from a failed background
+	// refresh.
+	OnError func(error)
+
+	mu         sync.Mutex
+	timer      *time.Timer
+	refreshing bool
+	// pending is true if a refresh was requested while one was running.
+	pending bool
+	closed  bool
+}
+
+// ObserveRedirect records a redirect received from a cluster node. MOVED
+// redirects update the slot in Table and schedule a background refresh. ASK
+// redirects are temporary and are ignored.
+func (t *TopologyRefresher) ObserveRedirect(r *Redirect) {
+	if r == nil || r.Kind != REDIRECT_MOVED {
+		return
+	}
+	t.Table.SetSlot(r.Slot, r.Addr)
+	t.schedule()
+}
+
+// ObserveError is the same as ObserveRedirect except that it takes an error
+// reply, which is ignored if it isn't a redirect.
+func (t *TopologyRefresher) ObserveError(e Error) {
+	if r, ok := ParseRedirect(e); ok {
+		t.ObserveRedirect(r)
+	}
+}
+
+// schedule starts the debounce timer unless it's already running. If a
+// refresh is in progress, another one is run once it finishes.
+func (t *TopologyRefresher) schedule() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed || t.timer != nil {
+		return
+	}
+	if t.refreshing {
+		t.pending = true
+		return
+	}
+	debounce := t.Debounce
+	if debounce == 0 {
+		debounce = DEFAULT_REFRESH_DEBOUNCE
+	}
+	t.timer = time.AfterFunc(debounce, t.background)
+}
+
+func (t *TopologyRefresher) background() {
+	t.mu.Lock()
+	t.timer = nil
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.refreshing = true
+	t.mu.Unlock()
+
+	err := t.Refresh()
+	if err != nil && t.OnError != nil {
+		t.OnError(err)
+	}
+
+	t.mu.Lock()
+	t.refreshing = false
+	pending := t.pending
+	t.pending = false
+	t.mu.Unlock()
+	if pending {
+		t.schedule()
+	}
+}
+
+// Refresh replaces the slot assignments in Table with the ones reported by the
+// first node that answers, trying the masters in Table before the Seeds. It
+// returns the error from the last node tried if none of them answer.
+func (t *TopologyRefresher) Refresh() error {
+	addrs := append(t.Table.Masters(), t.Seeds...)
+	if len(addrs) == 0 {
+		return ErrNoNodes
+	}
+
+	tried := make(map[string]bool, len(addrs))
+	var err error
+	for _, addr := range addrs {
+		if tried[addr] {
+			continue
+		}
+		tried[addr] = true
+		if err = t.refreshFrom(addr); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (t *TopologyRefresher) refreshFrom(addr string) error {
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_REFRESH_TIMEOUT
+	}
+	deadline := time.Now().Add(timeout)
+	conn, err := t.dial(addr, deadline)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if d, ok := conn.(interface{ SetDeadline(time.Time) error }); ok {
+		d.SetDeadline(deadline)
+	}
+
+	cmd := clusterSlots
+	if t.UseShards {
+		cmd = clusterShards
+	}
+	if _, err := conn.Write(cmd); err != nil {
+		return err
+	}
+	r := NewReader(conn)
+	// Replies from clusters with many nodes don't fit in the default buffer
+	r.SetMaxSize(DEFAULT_MAX_BUFFER)
+	reply, err := r.ReadObject()
+	if err != nil {
+		return err
+	}
+	if e, ok := reply.(Error); ok {
+		return e
+	}
+
+	if t.UseShards {
+		shards, err := ParseClusterShards(reply)
+		if err != nil {
+			return err
+		}
+		t.Table.ReplaceShards(shards)
+	} else {
+		ranges, err := ParseClusterSlots(reply)
+		if err != nil {
+			return err
+		}
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			for i := range ranges {
+				fillSlotNodeIP(&ranges[i].Master, host)
+				for j := range ranges[i].Replicas {
+					fillSlotNodeIP(&ranges[i].Replicas[j], host)
+				}
+			}
+		}
+		t.Table.ReplaceSlots(ranges)
+	}
+	return nil
+}
+
+// fillSlotNodeIP sets the IP of n to host if the reply gave a null endpoint.
+func fillSlotNodeIP(n *SlotNode, host string) {
+	if n.IP == "" {
+		n.IP = host
+	}
+}
+
+// dial connects to the node at addr, giving up at deadline.
+func (t *TopologyRefresher) dial(addr string, deadline time.Time) (io.ReadWriteCloser, error) {
+	if t.Dial == nil {
+		return net.DialTimeout("tcp", addr, time.Until(deadline))
+	}
+
+	type dialed struct {
+		conn io.ReadWriteCloser
+		err  error
+	}
+	done := make(chan dialed, 1)
+	go func() {
+		conn, err := t.Dial(addr)
+		done <- dialed{conn, err}
+	}()
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case d := <-done:
+		return d.conn, d.err
+	case <-timer.C:
+		go func() {
+			// Close the connection if the dial completes after all
+			if d := <-done; d.err == nil {
+				d.conn.Close()
+			}
+		}()
+		return nil, os.ErrDeadlineExceeded
+	}
+}
+
+// Close stops any scheduled refresh. A refresh that's already running is
+// allowed to finish.
+func (t *TopologyRefresher) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+}
@@ -0,0 +1,152 @@
+package resp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeNode returns a Dial function that answers every connection's first
+// command with reply and counts the connections made.
+func fakeNode(reply Object, dials *int32) func(string) (io.ReadWriteCloser, error) {
+	return func(addr string) (io.ReadWriteCloser, error) {
+		atomic.AddInt32(dials, 1)
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			if _, err := NewReader(server).ReadCommand(); err != nil {
+				return
+			}
+			server.Write(reply.Raw())
+		}()
+		return client, nil
+	}
+}
+
+func TestTopologyRefresher(t *testing.T) {
+	reply := NewArray(
+		NewArray(NewInteger(0), NewInteger(16383), NewArray(NewBulkString("10.0.0.2"), NewInteger(7000))),
+	)
+	var dials int32
+	refresher := &TopologyRefresher{
+		Table:    NewRoutingTable(),
+		Dial:     fakeNode(reply, &dials),
+		Debounce: 20 * time.Millisecond,
+	}
+	defer refresher.Close()
+
+	if err := refresher.Refresh(); err != ErrNoNodes {
+		t.Errorf("expected ErrNoNodes, got %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		refresher.ObserveError(NewError("MOVED 3999 10.0.0.1:7000"))
+	}
+	if addr := refresher.Table.Lookup(3999); addr != "10.0.0.1:7000" {
+		t.Errorf("expected MOVED to be applied immediately, got %q", addr)
+	}
+	refresher.ObserveError(NewError("ASK 100 10.0.0.3:7000"))
+	refresher.ObserveError(NewError("ERR nope"))
+
+	deadline := time.Now().Add(time.Second)
+	for refresher.Table.Lookup(3999) != "10.0.0.2:7000" {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for refresh")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("expected redirects to be debounced into one refresh, got %d", n)
+	}
+	if addr := refresher.Table.Lookup(100); addr != "10.0.0.2:7000" {
+		t.Errorf("expected ASK to be ignored, got %q", addr)
+	}
+}
+
+func TestTopologyRefresher_Errors(t *testing.T) {
+	var dials int32
+	refresher := &TopologyRefresher{
+		Table: NewRoutingTable(),
+		Dial:  fakeNode(NewError("ERR This instance has cluster support disabled"), &dials),
+		Seeds: []string{"a:1", "b:1", "a:1"},
+	}
+	err := refresher.Refresh()
+	if e, ok := err.(Error); !ok || e.Error() != "ERR This instance has cluster support disabled" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if dials != 2 {
+		t.Errorf("expected each seed to be tried once, got %d dials", dials)
+	}
+}
+
+func TestTopologyRefresher_LargeReply(t *testing.T) {
+	// A reply from a cluster with many nodes, well over the default buffer
+	var ranges []Object
+	for i := 0; i < 1000; i++ {
+		node := NewArray(NewBulkString("10.0.0.2"), NewInteger(7000), NewBulkString("e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca"))
+		ranges = append(ranges, NewArray(NewInteger(int64(i)), NewInteger(int64(i)), node))
+	}
+	var dials int32
+	refresher := &TopologyRefresher{
+		Table: NewRoutingTable(),
+		Dial:  fakeNode(NewArray(ranges...), &dials),
+		Seeds: []string{"a:1"},
+	}
+	if err := refresher.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if addr := refresher.Table.Lookup(999); addr != "10.0.0.2:7000" {
+		t.Errorf("unexpected address: %q", addr)
+	}
+}
+
+func TestTopologyRefresher_NullEndpoint(t *testing.T) {
+	reply := NewArray(
+		NewArray(NewInteger(0), NewInteger(16383), NewArray(String("$-1\r\n"), NewInteger(7000))),
+	)
+	var dials int32
+	refresher := &TopologyRefresher{
+		Table: NewRoutingTable(),
+		Dial:  fakeNode(reply, &dials),
+		Seeds: []string{"10.0.0.1:7001"},
+	}
+	if err := refresher.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if addr := refresher.Table.Lookup(0); addr != "10.0.0.1:7000" {
+		t.Errorf("expected the host the request was sent to, got %q", addr)
+	}
+}
+
+func TestTopologyRefresher_Timeout(t *testing.T) {
+	// A node that never answers
+	refresher := &TopologyRefresher{
+		Table: NewRoutingTable(),
+		Dial: func(addr string) (io.ReadWriteCloser, error) {
+			client, server := net.Pipe()
+			go io.Copy(io.Discard, server)
+			return client, nil
+		},
+		Seeds:   []string{"a:1"},
+		Timeout: 20 * time.Millisecond,
+	}
+	if err := refresher.Refresh(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+
+	// A node that can't be connected to
+	release := make(chan bool)
+	defer close(release)
+	refresher.Dial = func(addr string) (io.ReadWriteCloser, error) {
+		<-release
+		return nil, io.EOF
+	}
+	if err := refresher.Refresh(); err != os.ErrDeadlineExceeded {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+}
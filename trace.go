@@ -0,0 +1,83 @@
+package resp
+
+// A Trace holds hooks called by the Readers and Writers it's set on, e.g. to
+// instrument them, much like net/http/httptrace. Any of the hooks may be nil.
+// They're called synchronously, by the goroutine using the Reader or Writer.
+type Trace struct {
+	// ObjectRead is called with each object read by a Reader, before it's
+	// returned. It points into the Reader's buffer, so it's only valid
+	// during the call. Inline commands aren't objects, and aren't passed.
+	ObjectRead func(obj []byte)
+	// ObjectWritten is called with the bytes passed to each call of
+	// Writer.Write, or written by WriteObject, which hold one or more
+	// objects, before they're buffered. They're only valid during the
+	// call.
+	ObjectWritten func(b []byte)
+
+	// FillStart and FillEnd are called before and after a Reader reads
+	// from its io.Reader, with the number of bytes read and the error
+	// returned.
+	FillStart func()
+	FillEnd   func(n int, err error)
+	// FlushStart and FlushEnd are called before and after a Writer writes
+	// to its io.Writer, with the number of bytes to write, and then the
+	// number written and the error returned.
+	FlushStart func(n int)
+	FlushEnd   func(n int, err error)
+
+	// ErrorEncountered is called with the errors Readers and Writers
+	// return, including io.EOF at the end of a stream.
+	ErrorEncountered func(err error)
+}
+
+// SetTrace sets the hooks called by the Reader. A nil Trace removes them.
+func (r *Reader) SetTrace(t *Trace) {
+	r.trace = t
+}
+
+// SetTrace sets the hooks called by the Writer. A nil Trace removes them.
+func (w *Writer) SetTrace(t *Trace) {
+	w.trace = t
+}
+
+func (t *Trace) objectRead(obj []byte) {
+	if t != nil && t.ObjectRead != nil {
+		t.ObjectRead(obj)
+	}
+}
+
+func (t *Trace) objectWritten(b []byte) {
+	if t != nil && t.ObjectWritten != nil {
+		t.ObjectWritten(b)
+	}
+}
+
+func (t *Trace) fillStart() {
+	if t != nil && t.FillStart != nil {
+		t.FillStart()
+	}
+}
+
+func (t *Trace) fillEnd(n int, err error) {
+	if t != nil && t.FillEnd != nil {
+		t.FillEnd(n, err)
+	}
+}
+
+func (t *Trace) flushStart(n int) {
+	if t != nil && t.FlushStart != nil {
+		t.FlushStart(n)
+	}
+}
+
+func (t *Trace) flushEnd(n int, err error) {
+	if t != nil && t.FlushEnd != nil {
+		t.FlushEnd(n, err)
+	}
+}
+
+func (t *Trace) errorEncountered(err error) {
+	if t != nil && t.ErrorEncountered != nil {
+		t.ErrorEncountered(err)
+	}
+}
@@ -0,0 +1,112 @@
+package resp
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReader_SetTrace(t *testing.T) {
+	var objects []string
+	var errs []error
+	fills, filled := 0, 0
+	r := NewReader(iotest.OneByteReader(strings.NewReader("+OK\r\n:1\r\n")))
+	r.SetTrace(&Trace{
+		ObjectRead:       func(obj []byte) { objects = append(objects, string(obj)) },
+		FillStart:        func() { fills++ },
+		FillEnd:          func(n int, err error) { filled += n },
+		ErrorEncountered: func(err error) { errs = append(errs, err) },
+	})
+	for {
+		if _, err := r.ReadObjectSlice(); err != nil {
+			break
+		}
+	}
+
+	if expected := []string{"+OK\r\n", ":1\r\n"}; !reflect.DeepEqual(expected, objects) {
+		t.Errorf("expected %q, got %q", expected, objects)
+	}
+	if expected := []error{io.EOF}; !reflect.DeepEqual(expected, errs) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+	if filled != 9 {
+		t.Errorf("expected 9 bytes filled, got %d", filled)
+	}
+	if fills != 10 {
+		t.Errorf("expected 10 fills, got %d", fills)
+	}
+
+	// Hooks that aren't set, and a nil Trace, are skipped.
+	r = NewReader(strings.NewReader("+OK\r\n"))
+	r.SetTrace(&Trace{})
+	if _, err := r.ReadObjectSlice(); err != nil {
+		t.Fatal(err)
+	}
+	r.SetTrace(nil)
+	if _, err := r.ReadObjectSlice(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReader_SetTrace_ReadObjects(t *testing.T) {
+	var objects []string
+	r := NewReader(strings.NewReader("+OK\r\n:1\r\n$1\r\na\r\n"))
+	r.SetTrace(&Trace{ObjectRead: func(obj []byte) { objects = append(objects, string(obj)) }})
+	if _, err := r.ReadObjects(nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"+OK\r\n", ":1\r\n", "$1\r\na\r\n"}; !reflect.DeepEqual(expected, objects) {
+		t.Errorf("expected %q, got %q", expected, objects)
+	}
+}
+
+func TestWriter_SetTrace(t *testing.T) {
+	var written, events []string
+	var errs []error
+	trace := &Trace{
+		ObjectWritten:    func(b []byte) { written = append(written, string(b)) },
+		FlushStart:       func(n int) { events = append(events, "start") },
+		FlushEnd:         func(n int, err error) { events = append(events, "end") },
+		ErrorEncountered: func(err error) { errs = append(errs, err) },
+	}
+
+	var buf bytes.Buffer
+	w := NewWriterSize(&buf, 16)
+	w.SetTrace(trace)
+	w.WriteObject(String("+OK\r\n"))
+	large := "$20\r\n" + strings.Repeat("x", 20) + "\r\n"
+	w.Write([]byte(large))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"+OK\r\n", large}; !reflect.DeepEqual(expected, written) {
+		t.Errorf("expected %q, got %q", expected, written)
+	}
+	if buf.String() != "+OK\r\n"+large {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+	if len(events) == 0 || len(events)%2 != 0 {
+		t.Errorf("expected paired flush events, got %v", events)
+	}
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	events = nil
+	w = NewWriter(failingWriter{})
+	w.SetTrace(trace)
+	w.Write([]byte("+OK\r\n"))
+	err := w.Flush()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if expected := []string{"start", "end"}; !reflect.DeepEqual(expected, events) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+	if expected := []error{err}; !reflect.DeepEqual(expected, errs) {
+		t.Errorf("expected %v, got %v", expected, errs)
+	}
+}
@@ -0,0 +1,80 @@
+package resp
+
+// A Transaction is a group of commands that make up a MULTI/EXEC transaction.
+// Commands includes the opening MULTI and the closing EXEC or DISCARD.
+type Transaction struct {
+	Commands []Command
+	// Discarded is true if the transaction was ended with DISCARD rather than
+	// EXEC.
+	Discarded bool
+}
+
+// A TransactionGrouper recognizes MULTI ... EXEC and MULTI ... DISCARD
+// sequences in a stream of commands and groups them into Transactions so that
+// they can be forwarded to a single upstream together. The zero value is
+// ready to use.
+type TransactionGrouper struct {
+	tx *Transaction
+}
+
+// Add feeds the next command in the stream to the grouper. The grouper keeps
+// a reference to commands that are part of a transaction, so they must not
+// point into a Reader's buffer (see Reader.ReadObjectBytes).
+//
+// When cmd completes a transaction, Add returns the Transaction. When cmd is
+// buffered as part of a transaction that's still open, Add returns nil and
+// true. Otherwise cmd isn't part of a transaction and Add returns nil and
+// false. Add returns the Error reply Redis would send for commands that are
+// invalid in the current state, such as a nested MULTI or an EXEC without
+// MULTI. Such commands aren't buffered.
+func (g *TransactionGrouper) Add(cmd Command) (*Transaction, bool, error) {
+	slices, err := cmd.Slices()
+	if err == nil && len(slices) == 0 {
+		err = ErrSyntaxError
+	}
+	if err != nil {
+		return nil, g.tx != nil, err
+	}
+	name := slices[0]
+
+	if g.tx == nil {
+		switch {
+		case CommandEquals(name, "MULTI"):
+			g.tx = &Transaction{Commands: []Command{cmd}}
+			return nil, true, nil
+		case CommandEquals(name, "EXEC"):
+			return nil, false, NewError("ERR EXEC without MULTI")
+		case CommandEquals(name, "DISCARD"):
+			return nil, false, NewError("ERR DISCARD without MULTI")
+		default:
+			return nil, false, nil
+		}
+	}
+
+	switch {
+	case CommandEquals(name, "MULTI"):
+		return nil, true, NewError("ERR MULTI calls can not be nested")
+	case CommandEquals(name, "WATCH"):
+		return nil, true, NewError("ERR WATCH inside MULTI is not allowed")
+	case CommandEquals(name, "EXEC"), CommandEquals(name, "DISCARD"):
+		tx := g.tx
+		tx.Commands = append(tx.Commands, cmd)
+		tx.Discarded = CommandEquals(name, "DISCARD")
+		g.tx = nil
+		return tx, false, nil
+	default:
+		g.tx.Commands = append(g.tx.Commands, cmd)
+		return nil, true, nil
+	}
+}
+
+// InTransaction returns true if a MULTI has been seen and the transaction
+// hasn't been ended yet.
+func (g *TransactionGrouper) InTransaction() bool {
+	return g.tx != nil
+}
+
+// Reset discards any open transaction.
+func (g *TransactionGrouper) Reset() {
+	g.tx = nil
+}
@@ -0,0 +1,59 @@
+package resp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransactionGrouper(t *testing.T) {
+	var g TransactionGrouper
+
+	tx, pending, err := g.Add(NewCommand("GET", "foo"))
+	if tx != nil || pending || err != nil {
+		t.Errorf("expected GET to pass through, got %v, %v, %v", tx, pending, err)
+	}
+
+	commands := []Command{
+		NewCommand("MULTI"),
+		NewCommand("SET", "foo", "bar"),
+		NewCommand("INCR", "n"),
+	}
+	for i, cmd := range commands {
+		tx, pending, err = g.Add(cmd)
+		if tx != nil || !pending || err != nil {
+			t.Errorf("commands[%d]: expected to be buffered, got %v, %v, %v", i, tx, pending, err)
+		}
+	}
+	if !g.InTransaction() {
+		t.Errorf("expected to be in a transaction")
+	}
+
+	_, pending, err = g.Add(NewCommand("MULTI"))
+	if !pending || !reflect.DeepEqual(err, NewError("ERR MULTI calls can not be nested")) {
+		t.Errorf("expected nested MULTI error, got %v, %v", pending, err)
+	}
+
+	exec := NewCommand("exec")
+	tx, pending, err = g.Add(exec)
+	if tx == nil || pending || err != nil {
+		t.Fatalf("expected a transaction, got %v, %v, %v", tx, pending, err)
+	}
+	expected := &Transaction{Commands: append(commands, exec)}
+	if !reflect.DeepEqual(expected, tx) {
+		t.Errorf("expected: %v\ngot: %v", expected, tx)
+	}
+	if g.InTransaction() {
+		t.Errorf("expected transaction to be closed")
+	}
+
+	g.Add(NewCommand("MULTI"))
+	tx, _, _ = g.Add(NewCommand("DISCARD"))
+	if tx == nil || !tx.Discarded || len(tx.Commands) != 2 {
+		t.Errorf("expected a discarded transaction, got %v", tx)
+	}
+
+	_, pending, err = g.Add(NewCommand("EXEC"))
+	if pending || !reflect.DeepEqual(err, NewError("ERR EXEC without MULTI")) {
+		t.Errorf("expected EXEC without MULTI error, got %v, %v", pending, err)
+	}
+}
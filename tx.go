@@ -0,0 +1,81 @@
+package resp
+
+import (
+	"errors"
+)
+
+// ErrTxAborted is returned by Conn.Tx when EXEC returns a null reply because
+// a key watched with WATCH was modified.
+var ErrTxAborted = errors.New("resp: transaction aborted by WATCH")
+
+// A Tx queues the commands of a MULTI/EXEC transaction. See Conn.Tx.
+type Tx struct {
+	commands []Command
+}
+
+// Send queues a command and returns the index of its reply in the results of
+// Conn.Tx. The arguments are formatted as by FormatCommand.
+func (tx *Tx) Send(name string, args ...interface{}) int {
+	return tx.SendCommand(FormatCommand(name, args...))
+}
+
+// SendCommand is the same as Send except that it takes a Command.
+func (tx *Tx) SendCommand(cmd Command) int {
+	tx.commands = append(tx.commands, cmd)
+	return len(tx.commands) - 1
+}
+
+// Len returns the number of queued commands.
+func (tx *Tx) Len() int {
+	return len(tx.commands)
+}
+
+// Tx runs a MULTI/EXEC transaction made of the commands queued by fn, which
+// are sent in a single write together with MULTI and EXEC. It returns the
+// reply to each command, in order. Error replies to individual commands are
+// returned as replies, as with Do. If fn returns an error, nothing is sent
+// and the error is returned.
+//
+// If any command is rejected when queued, the transaction is discarded by
+// the server and Tx returns EXEC's EXECABORT Error reply as the error. If a
+// key watched with WATCH beforehand was modified, Tx returns ErrTxAborted.
+func (c *Conn) Tx(fn func(tx *Tx) error) ([]Object, error) {
+	var tx Tx
+	if err := fn(&tx); err != nil {
+		return nil, err
+	}
+
+	commands := make([]Command, 0, len(tx.commands)+2)
+	commands = append(commands, NewCommand("MULTI"))
+	commands = append(commands, tx.commands...)
+	commands = append(commands, NewCommand("EXEC"))
+	replies, err := c.DoPipeline(commands)
+	if err != nil {
+		return nil, err
+	}
+
+	// MULTI and each queued command are confirmed with +OK and +QUEUED before
+	// EXEC's reply. Commands rejected when queued make EXEC fail.
+	if e, ok := replies[0].(Error); ok {
+		return nil, e
+	}
+	switch exec := replies[len(replies)-1].(type) {
+	case Error:
+		return nil, exec
+	case Null:
+		return nil, ErrTxAborted
+	case Array:
+		results, err := exec.Objects()
+		if err != nil {
+			return nil, ErrUnexpectedReply
+		}
+		if results == nil {
+			return nil, ErrTxAborted
+		}
+		if len(results) != len(tx.commands) {
+			return nil, ErrUnexpectedReply
+		}
+		return results, nil
+	}
+	return nil, ErrUnexpectedReply
+}
@@ -0,0 +1,95 @@
+package resp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// txHandler answers like a server that runs transactions of INCR commands.
+// Unknown commands are rejected when queued, and EXEC returns a null reply if
+// WATCH was sent.
+func txHandler() func(string, [][]byte) Object {
+	var queued []Object
+	rejected, watched, counter := false, false, int64(0)
+	return func(name string, args [][]byte) Object {
+		switch strings.ToUpper(name) {
+		case "WATCH":
+			watched = true
+			return OK
+		case "MULTI":
+			queued, rejected = nil, false
+			return OK
+		case "EXEC":
+			switch {
+			case rejected:
+				return NewError("EXECABORT Transaction discarded because of previous errors.")
+			case watched:
+				watched = false
+				return Array("*-1\r\n")
+			}
+			return NewArray(queued...)
+		case "INCR":
+			counter++
+			queued = append(queued, NewInteger(counter))
+			return NewSimpleString("QUEUED")
+		case "HGET":
+			queued = append(queued, NewError("WRONGTYPE Operation against a key holding the wrong kind of value"))
+			return NewSimpleString("QUEUED")
+		}
+		rejected = true
+		return NewError("ERR unknown command")
+	}
+}
+
+func TestConnTx(t *testing.T) {
+	conn := fakeServer(txHandler())
+	defer conn.Close()
+
+	var hget int
+	results, err := conn.Tx(func(tx *Tx) error {
+		tx.Send("INCR", "a")
+		hget = tx.Send("HGET", "a", "b")
+		tx.Send("INCR", "a")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || !reflect.DeepEqual(results[2], Object(NewInteger(2))) {
+		t.Errorf("unexpected results: %q", results)
+	}
+	if e, ok := results[hget].(Error); !ok || e.Code() != "WRONGTYPE" {
+		t.Errorf("expected an error reply, got %q", results[hget])
+	}
+
+	// Commands rejected when queued abort the transaction
+	_, err = conn.Tx(func(tx *Tx) error {
+		tx.Send("INCR", "a")
+		tx.Send("NOPE")
+		return nil
+	})
+	if e, ok := err.(Error); !ok || e.Code() != "EXECABORT" {
+		t.Errorf("expected EXECABORT, got %v", err)
+	}
+
+	// WATCH
+	conn.Do("WATCH", "a")
+	if _, err := conn.Tx(func(tx *Tx) error {
+		tx.Send("INCR", "a")
+		return nil
+	}); err != ErrTxAborted {
+		t.Errorf("expected ErrTxAborted, got %v", err)
+	}
+
+	// fn's errors
+	if _, err := conn.Tx(func(tx *Tx) error {
+		tx.Send("INCR", "a")
+		return ErrInvalidArguments
+	}); err != ErrInvalidArguments {
+		t.Errorf("expected ErrInvalidArguments, got %v", err)
+	}
+	if conn.Pending() != 0 {
+		t.Errorf("expected nothing to be sent")
+	}
+}
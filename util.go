@@ -1,38 +1,349 @@
 package resp
 
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+)
+
 // parseLenLine takes a slice that points to the start of a RESP array or bulk
-// string length specification line and returns the array size or bulk string
-// length (respectively) and the end index of the length specification line in
-// the given slice. If the line is invalid, an error will be returned. All
-// bytes after the end of the length specification line are ignored.
+// string length specification line (or the length line of a RESP3 aggregate
+// or blob) and returns the array size or bulk string length (respectively)
+// and the end index of the length specification line in the given slice. If
+// the line is invalid, an error will be returned. All bytes after the end of
+// the length specification line are ignored.
 func parseLenLine(line []byte) (length int, endIndex int, err error) {
-	if len(line) < MIN_OBJECT_LENGTH {
-		// Bad line length
+	if len(line) < MIN_OBJECT_LENGTH || !hasLenLine(line[0]) {
+		// Bad line length or prefix
+		return 0, 0, ErrSyntaxError
+	}
+	if line[1] == '-' {
+		// Null length
+		if len(line) >= 5 && line[2] == '1' && line[3] == '\r' && line[4] == '\n' {
+			return -1, 4, nil
+		}
 		return 0, 0, ErrSyntaxError
 	}
-	if line[0] != ARRAY_PREFIX && line[0] != BULK_STRING_PREFIX {
-		// Bad line prefix
+
+	// Lengths are parsed with parseDigits rather than ParseInt, which is
+	// slower to call, as they're parsed for every bulk string read.
+	n, digits := parseDigits(line[1:])
+	i := digits + 1
+	if digits == 0 || digits > 18 || uint64(int(n)) != n {
 		return 0, 0, ErrSyntaxError
 	}
-	if len(line) >= 5 && line[1] == '-' && line[2] == '1' && line[3] == '\r' && line[4] == '\n' {
-		// Null length shortcut
-		return -1, 4, nil
+	if i+1 >= len(line) || line[i] != '\r' || line[i+1] != '\n' {
+		// Missing line ending
+		return 0, 0, ErrSyntaxError
 	}
+	return int(n), i + 1, nil
+}
 
-	var n int
-	var b byte
-	var i int
-	for i, b = range line[1:] {
-		if b < '0' || b > '9' {
-			if b == '\r' && len(line) > i+2 && line[i+2] == '\n' {
-				return n, i + 2, nil
-			} else {
-				return 0, 0, ErrSyntaxError
+// hasLenLine returns true if objects with the given prefix start with a
+// length line.
+func hasLenLine(prefix byte) bool {
+	switch prefix {
+	case ARRAY_PREFIX, BULK_STRING_PREFIX, PUSH_PREFIX, MAP_PREFIX, SET_PREFIX, ATTRIBUTE_PREFIX, VERBATIM_STRING_PREFIX, BLOB_ERROR_PREFIX:
+		return true
+	}
+	return false
+}
+
+// objectEnd returns the index of the final byte of the RESP object at the
+// start of b. It returns -1 if b doesn't contain the whole object yet and an
+// error if the object is invalid. All bytes after the object are ignored.
+func objectEnd(b []byte) (int, error) {
+	if len(b) < 3 {
+		return -1, nil
+	}
+
+	switch b[0] {
+	case SIMPLE_STRING_PREFIX, ERROR_PREFIX, INTEGER_PREFIX, BOOLEAN_PREFIX, DOUBLE_PREFIX, BIG_NUMBER_PREFIX:
+		lineEnd := indexCRLF(b)
+		if lineEnd < 0 {
+			return -1, nil
+		}
+		if lineEnd+2 < MIN_OBJECT_LENGTH {
+			return -1, ErrSyntaxError
+		}
+		return lineEnd + 1, nil
+	case NULL_PREFIX:
+		if b[1] != '\r' || b[2] != '\n' {
+			return -1, ErrSyntaxError
+		}
+		return 2, nil
+	case BULK_STRING_PREFIX, VERBATIM_STRING_PREFIX, BLOB_ERROR_PREFIX:
+		length, lineEnd, err := parseLenLine(b)
+		if err != nil {
+			return -1, lenLineErr(b, err)
+		}
+		if length == -1 {
+			return lineEnd, nil
+		}
+		bulkStringEnd := lineEnd + length + 2
+		if bulkStringEnd >= len(b) {
+			return -1, nil
+		}
+		return bulkStringEnd, nil
+	case ARRAY_PREFIX, PUSH_PREFIX, SET_PREFIX, MAP_PREFIX, ATTRIBUTE_PREFIX:
+		length, lineEnd, err := parseLenLine(b)
+		if err != nil {
+			return -1, lenLineErr(b, err)
+		}
+		length = aggregateLength(b[0], length)
+		end := lineEnd
+		for i := 0; i < length; i++ {
+			n, err := objectEnd(b[end+1:])
+			if n < 0 {
+				return -1, err
 			}
+			end += n + 1
 		}
-		n = (n * 10) + int(b-'0')
+		return end, nil
+	default:
+		return -1, ErrSyntaxError
 	}
+}
 
-	// Missing line ending
-	return 0, 0, ErrSyntaxError
+// indexCRLF returns the index of the first "\r\n" in b, or -1 if there's
+// none. Most lines, such as "+OK\r\n", are short, so the first bytes are
+// scanned a word at a time, looking for '\r' in each of the 8 bytes at once,
+// which beats the overhead of bytes.Index. Longer lines are left to
+// bytes.Index, which is vectorized.
+func indexCRLF(b []byte) int {
+	const (
+		ones  = 0x0101010101010101
+		highs = 0x8080808080808080
+		crs   = ones * '\r'
+	)
+	i := 0
+	// b[i+8] is read too, so that a '\r' in the word can be followed by '\n'
+	for ; i+8 < len(b) && i < 64; i += 8 {
+		// The high bit is set in the bytes of x that were '\r', and in
+		// some that follow them, which are told apart by checking b[j].
+		w := binary.LittleEndian.Uint64(b[i:]) ^ crs
+		x := (w - ones) &^ w & highs
+		for x != 0 {
+			j := i + bits.TrailingZeros64(x)/8
+			if b[j] == '\r' && b[j+1] == '\n' {
+				return j
+			}
+			x &= x - 1
+		}
+	}
+	if len(b)-i > 16 {
+		if j := bytes.Index(b[i:], lineSuffix); j >= 0 {
+			return i + j
+		}
+		return -1
+	}
+	for ; i+1 < len(b); i++ {
+		if b[i] == '\r' && b[i+1] == '\n' {
+			return i
+		}
+	}
+	return -1
+}
+
+// An objectScanner finds the end of an object like objectEnd, but resumes
+// where it left off when more of the object has been received, so that a
+// large aggregate arriving in many reads is scanned once rather than once per
+// read.
+type objectScanner struct {
+	// pos is the offset of the next object to scan.
+	pos int
+	// left holds the number of objects left to scan in each enclosing
+	// aggregate, innermost last.
+	left []int
+}
+
+// reset prepares s to scan a new object.
+func (s *objectScanner) reset() {
+	s.pos = 0
+	s.left = s.left[:0]
+}
+
+// scan behaves like objectEnd. b must start with the bytes given to the
+// previous calls since s was reset.
+func (s *objectScanner) scan(b []byte) (int, error) {
+	if s.pos == 0 && len(s.left) == 0 {
+		// Objects are usually whole by the time they're first scanned, and
+		// objectEnd finds their end faster.
+		if end, err := objectEnd(b); end >= 0 || err != nil {
+			return end, err
+		}
+		s.left = append(s.left, 1)
+	}
+	for len(s.left) > 0 {
+		last := len(s.left) - 1
+		if s.left[last] == 0 {
+			s.left = s.left[:last]
+			continue
+		}
+		rest := b[s.pos:]
+		if len(rest) == 0 {
+			return -1, nil
+		}
+		switch rest[0] {
+		case ARRAY_PREFIX, PUSH_PREFIX, SET_PREFIX, MAP_PREFIX, ATTRIBUTE_PREFIX:
+			length, lineEnd, err := parseLenLine(rest)
+			if err != nil {
+				return -1, lenLineErr(rest, err)
+			}
+			s.left[last]--
+			s.pos += lineEnd + 1
+			if n := aggregateLength(rest[0], length); n > 0 {
+				s.left = append(s.left, n)
+			}
+		default:
+			end, err := objectEnd(rest)
+			if end < 0 {
+				return -1, err
+			}
+			s.left[last]--
+			s.pos += end + 1
+		}
+	}
+	return s.pos - 1, nil
+}
+
+// lenLineErr returns err unless the length line at the start of b simply
+// hasn't been fully received yet, in which case it returns nil.
+func lenLineErr(b []byte, err error) error {
+	if bytes.IndexByte(b, '\n') < 0 {
+		return nil
+	}
+	return err
+}
+
+// aggregateLength returns the number of objects in an aggregate with the
+// given prefix and length. Maps and attributes hold a key and a value per
+// entry.
+func aggregateLength(prefix byte, length int) int {
+	if prefix == MAP_PREFIX || prefix == ATTRIBUTE_PREFIX {
+		return length * 2
+	}
+	return length
+}
+
+// aggregateObjects returns the objects contained in the RESP array or RESP3
+// aggregate at the start of b. The objects point into b. It returns nil for a
+// null array.
+func aggregateObjects(b []byte) ([]Object, error) {
+	return aggregateObjectsIn(nil, b)
+}
+
+// aggregateObjectsIn is the same as aggregateObjects except that the slice of
+// objects is allocated from a, if it isn't nil.
+func aggregateObjectsIn(a *Arena, b []byte) ([]Object, error) {
+	length, cursor, err := parseLenLine(b)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		return nil, nil
+	}
+	length = aggregateLength(b[0], length)
+
+	objects := a.objectSlice(length)
+	for i := range objects {
+		cursor++
+		end, err := objectEnd(b[cursor:])
+		if err != nil {
+			return nil, err
+		}
+		if end < 0 {
+			return nil, ErrSyntaxError
+		}
+		objects[i] = Parse(b[cursor : cursor+end+1])
+		cursor += end
+	}
+
+	return objects, nil
+}
+
+// stringEquals returns true if obj is a simple or bulk string containing s.
+func stringEquals(obj Object, s string) bool {
+	str, ok := obj.(String)
+	return ok && string(str.Slice()) == s
+}
+
+// objectString returns the contents of obj if it's a non-null simple or bulk
+// string.
+func objectString(obj Object) (string, bool) {
+	str, ok := obj.(String)
+	if !ok {
+		return "", false
+	}
+	slice := str.Slice()
+	if slice == nil {
+		return "", false
+	}
+	return string(slice), true
+}
+
+// objectText returns the text of a reply made of lines, such as INFO, which
+// RESP3 servers send as a verbatim string. An Error reply is returned as an
+// error, and other replies as ErrUnexpectedReply.
+func objectText(obj Object) (string, error) {
+	switch o := obj.(type) {
+	case String:
+		if o.Slice() == nil {
+			return "", ErrUnexpectedReply
+		}
+		return string(o.Slice()), nil
+	case VerbatimString:
+		return string(o.Slice()), nil
+	case Error:
+		return "", o
+	default:
+		return "", ErrUnexpectedReply
+	}
+}
+
+// objectInt returns the value of obj if it's an integer.
+func objectInt(obj Object) (int64, bool) {
+	i, ok := obj.(Integer)
+	if !ok {
+		return 0, false
+	}
+	n, err := i.Int64()
+	return n, err == nil
+}
+
+// objectIsNull returns true if obj is a RESP3 null, a null bulk string, or a
+// null array.
+func objectIsNull(obj Object) bool {
+	switch o := obj.(type) {
+	case Null:
+		return true
+	case String:
+		return o[0] == BULK_STRING_PREFIX && o.Slice() == nil
+	case Array:
+		objects, err := o.Objects()
+		return err == nil && objects == nil
+	default:
+		return false
+	}
+}
+
+// objectArray returns the objects contained in obj if it's a non-null array.
+func objectArray(obj Object) ([]Object, bool) {
+	a, ok := obj.(Array)
+	if !ok {
+		return nil, false
+	}
+	objects, err := a.Objects()
+	return objects, err == nil && objects != nil
+}
+
+// objectPairs returns the alternating keys and values of obj if it's a RESP3
+// map or a map encoded as a flat array of key/value pairs.
+func objectPairs(obj Object) ([]Object, bool) {
+	if m, ok := obj.(Map); ok {
+		objects, err := m.Objects()
+		return objects, err == nil
+	}
+	objects, ok := objectArray(obj)
+	return objects, ok && len(objects)%2 == 0
 }
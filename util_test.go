@@ -1,6 +1,8 @@
 package resp
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -58,3 +60,84 @@ func BenchmarkParseLenLine(b *testing.B) {
 		parseLenLine(line)
 	}
 }
+
+func TestIndexCRLF(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	tests := []struct {
+		given    string
+		expected int
+	}{
+		{"", -1},
+		{"\r", -1},
+		{"\r\n", 0},
+		{"+OK\r\n", 3},
+		{"+OK\n\r", -1},
+		{"+0123456789\r\n", 11},
+		{"+0123456\r\n", 8},
+		{"+\r\r\r\r\r\r\r\r\r\r\r\r\n", 12},
+		// '\f' is '\r' ^ 1, which the word scan can mistake for '\r'
+		{"+abc\r\f\nxyz\r\n", 10},
+		{"+" + long + "\r\n$1\r\n", 101},
+		{"+" + long + "\r", -1},
+		{"+" + long[:60] + "\r\n" + long, 61},
+	}
+	for i, test := range tests {
+		if index := indexCRLF([]byte(test.given)); index != test.expected {
+			t.Errorf("tests[%d]: expected %d, got %d", i, test.expected, index)
+		}
+		if index := bytes.Index([]byte(test.given), lineSuffix); index != test.expected {
+			t.Errorf("tests[%d]: bad test: bytes.Index returned %d", i, index)
+		}
+	}
+	// Every position in and around a word
+	for n := 2; n < 90; n++ {
+		b := []byte(strings.Repeat("\r", n))
+		b[n-1] = '\n'
+		if index := indexCRLF(b); index != n-2 {
+			t.Errorf("%d bytes: expected %d, got %d", n, n-2, index)
+		}
+	}
+}
+
+func BenchmarkIndexCRLF(b *testing.B) {
+	line := []byte("+OK\r\n*2\r\n$4\r\nINFO\r\n$3\r\nALL\r\n")
+	for i := 0; i < b.N; i++ {
+		indexCRLF(line)
+	}
+}
+
+func TestObjectScanner(t *testing.T) {
+	tests := []string{
+		"+OK\r\n",
+		"$-1\r\n",
+		"*0\r\n",
+		"*-1\r\n",
+		"*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$5\r\nvalue\r\n",
+		"*2\r\n*2\r\n:1\r\n*0\r\n%1\r\n+a\r\n|1\r\n+b\r\n_\r\n#t\r\n",
+		">3\r\n$7\r\nmessage\r\n*-1\r\n~2\r\n,1.5\r\n(123\r\n",
+		// Invalid objects
+		"*2\r\n:1\r\n?\r\n",
+		"*2\r\n*x\r\n",
+		"*1\r\n$3\r\nabcd\r\n",
+	}
+	for i, test := range tests {
+		b := []byte(test)
+		expectedEnd, expectedErr := objectEnd(b)
+		// Every object is scanned as it would be if received one byte at a
+		// time.
+		var s objectScanner
+		s.reset()
+		for n := 0; n <= len(b); n++ {
+			end, err := s.scan(b[:n])
+			if err != nil || end >= 0 {
+				if end != expectedEnd || err != expectedErr {
+					t.Errorf("tests[%d]: expected %d, %v, got %d, %v after %d bytes", i, expectedEnd, expectedErr, end, err, n)
+				}
+				break
+			}
+			if n == len(b) {
+				t.Errorf("tests[%d]: expected %d, %v, got an incomplete object", i, expectedEnd, expectedErr)
+			}
+		}
+	}
+}
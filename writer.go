@@ -0,0 +1,156 @@
+package resp
+
+import (
+	"io"
+)
+
+// Writer implements a buffered RESP object writer for an io.Writer object.
+// After a write error, all further writes and flushes return the same error.
+type Writer struct {
+	wr  io.Writer
+	buf []byte
+	n   int
+	err error
+
+	// protocol is the version set by SetProtocol. scratch holds objects
+	// being converted to RESP2.
+	protocol int
+	scratch  []byte
+	// conn is the Server connection the Writer writes replies to, if any.
+	conn  *serverConn
+	trace *Trace
+}
+
+// NewWriter returns a new Writer with the default buffer size.
+func NewWriter(w io.Writer) *Writer {
+	return NewWriterSize(w, -1)
+}
+
+// NewWriterSize returns a new Writer with the given buffer size. If the buffer
+// size is less than 1, the default buffer size will be used. Buffers of the
+// default size, and other powers of two up to MAX_POOLED_BUFFER, are pooled;
+// see Release.
+func NewWriterSize(w io.Writer, size int) *Writer {
+	if size < 1 {
+		size = DEFAULT_BUFFER
+	}
+
+	return &Writer{
+		wr:  w,
+		buf: getBuffer(size),
+	}
+}
+
+// Release returns the Writer's buffer to the pool it was drawn from, so that
+// it can be reused by new Readers and Writers. Buffered data that hasn't been
+// flushed is discarded, and writing afterwards fails with ErrReleased.
+// Release is optional: buffers of unreleased Writers are garbage collected as
+// usual.
+func (w *Writer) Release() {
+	putBuffer(w.buf)
+	w.buf = nil
+	w.n = 0
+	w.err = ErrReleased
+}
+
+// SetProtocol sets the RESP version of the client the Writer writes to, 2 or
+// 3. If it's 2, WriteObject converts RESP3 objects to their RESP2
+// equivalents as Redis does, e.g. maps to flat arrays of keys and values and
+// booleans to integers, so that replies can be written as RESP3 regardless of
+// the client. By default, objects are written as they are.
+func (w *Writer) SetProtocol(version int) {
+	w.protocol = version
+}
+
+// Protocol returns the version set by SetProtocol, or 0 if it isn't set.
+func (w *Writer) Protocol() int {
+	return w.protocol
+}
+
+// WriteObject buffers the raw bytes of obj, converted to RESP2 if the
+// Writer's protocol is 2.
+func (w *Writer) WriteObject(obj Object) error {
+	raw := obj.Raw()
+	if w.protocol == 2 && len(raw) > 0 {
+		switch raw[0] {
+		case SIMPLE_STRING_PREFIX, ERROR_PREFIX, INTEGER_PREFIX, BULK_STRING_PREFIX:
+		default:
+			w.scratch = appendRESP2(w.scratch[:0], raw)
+			raw = w.scratch
+		}
+	}
+	_, err := w.Write(raw)
+	return err
+}
+
+// Write buffers p, which should hold one or more complete RESP objects. Data
+// that doesn't fit in the buffer is written through to the underlying
+// io.Writer.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.trace.objectWritten(p)
+	written := 0
+	for len(p) > w.Available() && w.err == nil {
+		var n int
+		if w.n == 0 {
+			// Large write with an empty buffer; skip the copy.
+			w.trace.flushStart(len(p))
+			n, w.err = w.wr.Write(p)
+			w.trace.flushEnd(n, w.err)
+			if w.err != nil {
+				w.trace.errorEncountered(w.err)
+			}
+		} else {
+			n = copy(w.buf[w.n:], p)
+			w.n += n
+			w.Flush()
+		}
+		written += n
+		p = p[n:]
+	}
+	if w.err != nil {
+		return written, w.err
+	}
+	n := copy(w.buf[w.n:], p)
+	w.n += n
+	return written + n, nil
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.n == 0 {
+		return nil
+	}
+
+	w.trace.flushStart(w.n)
+	n, err := w.wr.Write(w.buf[:w.n])
+	if n < w.n && err == nil {
+		err = io.ErrShortWrite
+	}
+	w.trace.flushEnd(n, err)
+	if err != nil {
+		w.trace.errorEncountered(err)
+		if n > 0 && n < w.n {
+			copy(w.buf, w.buf[n:w.n])
+		}
+		w.n -= n
+		w.err = err
+		return err
+	}
+	w.n = 0
+	return nil
+}
+
+// Buffered returns the number of bytes that have been written but not yet
+// flushed.
+func (w *Writer) Buffered() int {
+	return w.n
+}
+
+// Available returns the number of bytes that can be written before the buffer
+// is flushed.
+func (w *Writer) Available() int {
+	return len(w.buf) - w.n
+}
@@ -0,0 +1,91 @@
+package resp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken")
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriterSize(&out, 16)
+
+	w.WriteObject(OK)
+	w.WriteObject(NewInteger(12))
+	if out.Len() != 0 || w.Buffered() != 10 {
+		t.Errorf("expected writes to be buffered, got %q and %d buffered", out.String(), w.Buffered())
+	}
+
+	// Larger than the remaining buffer
+	w.WriteObject(NewBulkString("hello world"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "+OK\r\n:12\r\n$11\r\nhello world\r\n"
+	if out.String() != expected {
+		t.Errorf("expected: %q\ngot: %q", expected, out.String())
+	}
+
+	// Larger than the whole buffer
+	out.Reset()
+	big := NewBulkString(string(bytes.Repeat([]byte("x"), 100)))
+	if n, err := w.Write(big); err != nil || n != len(big) {
+		t.Errorf("unexpected result: %d, %v", n, err)
+	}
+	if out.String() != string(big) || w.Buffered() != 0 {
+		t.Errorf("expected a direct write, got %q", out.String())
+	}
+}
+
+func TestWriter_Error(t *testing.T) {
+	w := NewWriterSize(failingWriter{}, 16)
+	if err := w.WriteObject(OK); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err == nil {
+		t.Fatal("expected an error but didn't get one")
+	}
+	if err := w.WriteObject(NewBulkString("more than sixteen bytes")); err == nil {
+		t.Errorf("expected the error to stick")
+	}
+}
+
+func TestWriter_SetProtocol(t *testing.T) {
+	tests := []struct {
+		obj      Object
+		expected string
+	}{
+		{OK, "+OK\r\n"},
+		{NewMap(NewBulkString("a"), NewBoolean(true)), "*2\r\n$1\r\na\r\n:1\r\n"},
+		{NewSet(NewBoolean(false), Null("_\r\n")), "*2\r\n:0\r\n$-1\r\n"},
+		{NewArray(NewMap(NewBulkString("x"), NewDouble(1.5))), "*1\r\n*2\r\n$1\r\nx\r\n$3\r\n1.5\r\n"},
+		{NewPush(NewBulkString("message")), "*1\r\n$7\r\nmessage\r\n"},
+		{BigNumber("(12345678901234567890\r\n"), "$20\r\n12345678901234567890\r\n"},
+		{VerbatimString("=8\r\ntxt:text\r\n"), "$4\r\ntext\r\n"},
+		{BlobError("!7\r\nERR bad\r\n"), "-ERR bad\r\n"},
+	}
+	for i, test := range tests {
+		var out bytes.Buffer
+		w := NewWriter(&out)
+		w.SetProtocol(2)
+		w.WriteObject(test.obj)
+		w.Flush()
+		if out.String() != test.expected {
+			t.Errorf("tests[%d]: expected %q, got %q", i, test.expected, out.String())
+		}
+
+		out.Reset()
+		w.SetProtocol(3)
+		w.WriteObject(test.obj)
+		w.Flush()
+		if out.String() != string(test.obj.Raw()) {
+			t.Errorf("tests[%d]: expected RESP3 %q, got %q", i, test.obj.Raw(), out.String())
+		}
+	}
+}